@@ -0,0 +1,313 @@
+// Command dlq xem, sửa và replay các message trong dead-letter queue của Kafka.
+//
+//	go run ./cmd/dlq list -topic chat-topic [-kind retries_exhausted] [-reason "timeout"] [-pending]
+//	go run ./cmd/dlq show -topic chat-topic -partition 0 -offset 12
+//	go run ./cmd/dlq replay -topic chat-topic -partition 0 -offset 12 [-value-file fixed.json] [-force]
+//	go run ./cmd/dlq replay-all -topic chat-topic [-kind ...] [-reason ...] [-dry-run] [-force]
+//	go run ./cmd/dlq replay-spool [-file ./data/kafka-spool.jsonl]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"my-app/common/kafka"
+	"my-app/config"
+	"my-app/database"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const usage = `usage: dlq <command> [flags]
+
+commands:
+  list          liệt kê bản ghi trong DLQ của một topic
+  show          xem chi tiết một bản ghi
+  replay        gửi lại một bản ghi về topic gốc (có thể kèm payload đã sửa)
+  replay-all    gửi lại mọi bản ghi chưa replay khớp bộ lọc
+  replay-spool  gửi lại các message producer không gửi được (file spool)`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.LoadAppConfig()
+	ctx := context.Background()
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = runList(ctx, cfg, os.Args[2:])
+	case "show":
+		err = runShow(cfg, os.Args[2:])
+	case "replay":
+		err = runReplay(ctx, cfg, os.Args[2:])
+	case "replay-all":
+		err = runReplayAll(ctx, cfg, os.Args[2:])
+	case "replay-spool":
+		err = runReplaySpool(cfg, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("dlq %s: %v", os.Args[1], err)
+	}
+}
+
+// filterFlags - bộ lọc dùng chung cho list và replay-all
+type filterFlags struct {
+	topic  string
+	kind   string
+	reason string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.topic, "topic", "", "topic gốc hoặc topic DLQ (bắt buộc)")
+	fs.StringVar(&f.kind, "kind", "", "lọc theo loại lỗi: unmarshal, invalid, retries_exhausted")
+	fs.StringVar(&f.reason, "reason", "", "lọc theo chuỗi con trong lý do lỗi")
+}
+
+func (f *filterFlags) match(dl kafka.DeadLetter) bool {
+	if f.kind != "" && dl.Kind != f.kind {
+		return false
+	}
+	return f.reason == "" || strings.Contains(strings.ToLower(dl.Reason), strings.ToLower(f.reason))
+}
+
+func runList(ctx context.Context, cfg config.AppConfig, args []string) error {
+	var filter filterFlags
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	filter.register(fs)
+	pending := fs.Bool("pending", false, "chỉ hiện bản ghi chưa replay")
+	limit := fs.Int("limit", 100, "số bản ghi tối đa")
+	_ = fs.Parse(args)
+	if filter.topic == "" {
+		return errors.New("missing -topic")
+	}
+
+	admin, err := kafka.NewDeadLetterAdmin(cfg.Kafka.Brokers)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	letters, err := admin.List(filter.topic, filter.match)
+	if err != nil {
+		return err
+	}
+
+	replays, err := newReplayStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	shown := 0
+	for _, dl := range letters {
+		if shown >= *limit {
+			break
+		}
+		rec, err := replays.find(ctx, dl)
+		if err != nil {
+			return err
+		}
+		if *pending && rec != nil {
+			continue
+		}
+		if err := enc.Encode(listEntry{DeadLetter: dl, Replay: rec}); err != nil {
+			return err
+		}
+		shown++
+	}
+	log.Printf("%d/%d dead letters shown", shown, len(letters))
+	return nil
+}
+
+type listEntry struct {
+	kafka.DeadLetter
+	Replay *replayRecord `json:"replay,omitempty"`
+}
+
+func runShow(cfg config.AppConfig, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	topic := fs.String("topic", "", "topic gốc hoặc topic DLQ (bắt buộc)")
+	partition := fs.Int("partition", 0, "partition trong DLQ")
+	offset := fs.Int64("offset", -1, "offset trong DLQ (bắt buộc)")
+	_ = fs.Parse(args)
+	if *topic == "" || *offset < 0 {
+		return errors.New("missing -topic or -offset")
+	}
+
+	admin, err := kafka.NewDeadLetterAdmin(cfg.Kafka.Brokers)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	dl, err := admin.Get(*topic, int32(*partition), *offset)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dl)
+}
+
+func runReplay(ctx context.Context, cfg config.AppConfig, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	topic := fs.String("topic", "", "topic gốc hoặc topic DLQ (bắt buộc)")
+	partition := fs.Int("partition", 0, "partition trong DLQ")
+	offset := fs.Int64("offset", -1, "offset trong DLQ (bắt buộc)")
+	value := fs.String("value", "", "payload JSON đã sửa")
+	valueFile := fs.String("value-file", "", "file chứa payload JSON đã sửa")
+	force := fs.Bool("force", false, "replay cả bản ghi đã replay trước đó")
+	_ = fs.Parse(args)
+	if *topic == "" || *offset < 0 {
+		return errors.New("missing -topic or -offset")
+	}
+
+	edited, err := editedValue(*value, *valueFile)
+	if err != nil {
+		return err
+	}
+
+	admin, err := kafka.NewDeadLetterAdmin(cfg.Kafka.Brokers)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	dl, err := admin.Get(*topic, int32(*partition), *offset)
+	if err != nil {
+		return err
+	}
+
+	replays, err := newReplayStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := replayOne(ctx, admin, replays, dl, edited, *force); err != nil {
+		return err
+	}
+	log.Printf("replayed %s/%d@%d -> %s", dl.Topic, dl.Partition, dl.Offset, dl.OriginalTopic)
+	return nil
+}
+
+func runReplayAll(ctx context.Context, cfg config.AppConfig, args []string) error {
+	var filter filterFlags
+	fs := flag.NewFlagSet("replay-all", flag.ExitOnError)
+	filter.register(fs)
+	dryRun := fs.Bool("dry-run", false, "chỉ liệt kê, không gửi")
+	force := fs.Bool("force", false, "replay cả bản ghi đã replay trước đó")
+	_ = fs.Parse(args)
+	if filter.topic == "" {
+		return errors.New("missing -topic")
+	}
+
+	admin, err := kafka.NewDeadLetterAdmin(cfg.Kafka.Brokers)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	letters, err := admin.List(filter.topic, filter.match)
+	if err != nil {
+		return err
+	}
+
+	replays, err := newReplayStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	replayed, skipped := 0, 0
+	for i := range letters {
+		dl := &letters[i]
+		if *dryRun {
+			log.Printf("[dry-run] %s/%d@%d (%s): %s", dl.Topic, dl.Partition, dl.Offset, dl.Kind, dl.Reason)
+			continue
+		}
+		err := replayOne(ctx, admin, replays, dl, nil, *force)
+		if errors.Is(err, errAlreadyReplayed) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("replay %s/%d@%d: %w", dl.Topic, dl.Partition, dl.Offset, err)
+		}
+		replayed++
+	}
+	log.Printf("replayed %d, skipped %d already replayed, matched %d", replayed, skipped, len(letters))
+	return nil
+}
+
+func runReplaySpool(cfg config.AppConfig, args []string) error {
+	fs := flag.NewFlagSet("replay-spool", flag.ExitOnError)
+	file := fs.String("file", cfg.Kafka.SpoolPath, "file spool")
+	_ = fs.Parse(args)
+
+	admin, err := kafka.NewDeadLetterAdmin(cfg.Kafka.Brokers)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	replayed, failed, err := kafka.ReplaySpool(*file, func(m kafka.SpooledMessage) error {
+		return admin.Send(m.Topic, m.Key, m.Value)
+	})
+	log.Printf("spool replayed %d, failed %d (kept in %s)", replayed, failed, *file)
+	return err
+}
+
+// replayOne ghi nhận replay trước rồi mới gửi, gửi lỗi thì xóa bản ghi để lần sau replay lại được
+func replayOne(ctx context.Context, admin *kafka.DeadLetterAdmin, replays *replayStore, dl *kafka.DeadLetter, edited []byte, force bool) error {
+	if err := replays.claim(ctx, dl, edited != nil, force); err != nil {
+		return err
+	}
+	if err := admin.Replay(dl, edited); err != nil {
+		_ = replays.release(ctx, dl)
+		return err
+	}
+	return nil
+}
+
+func editedValue(value, file string) ([]byte, error) {
+	var data []byte
+	switch {
+	case value != "" && file != "":
+		return nil, errors.New("use either -value or -value-file")
+	case value != "":
+		data = []byte(value)
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	default:
+		return nil, nil
+	}
+	// Mọi topic trong pipeline đều dùng payload JSON
+	if !json.Valid(data) {
+		return nil, errors.New("edited value is not valid JSON")
+	}
+	return data, nil
+}
+
+func connectMongo(ctx context.Context, cfg config.AppConfig) (*mongo.Database, error) {
+	db, err := database.ConnectMongo(ctx, cfg.Mongo.URI, cfg.Mongo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("connect mongo: %w", err)
+	}
+	return db, nil
+}
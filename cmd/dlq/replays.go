@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"my-app/common/kafka"
+	"my-app/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errAlreadyReplayed = errors.New("dead letter already replayed (use -force to replay again)")
+
+// replayRecord - đánh dấu bản ghi DLQ đã replay, Kafka không cho xóa / sửa message nên phải lưu bên ngoài
+type replayRecord struct {
+	DLQTopic      string    `bson:"dlq_topic" json:"-"`
+	Partition     int32     `bson:"partition" json:"-"`
+	Offset        int64     `bson:"offset" json:"-"`
+	OriginalTopic string    `bson:"original_topic" json:"original_topic"`
+	Edited        bool      `bson:"edited" json:"edited"`
+	Count         int       `bson:"count" json:"count"`
+	ReplayedAt    time.Time `bson:"replayed_at" json:"replayed_at"`
+}
+
+type replayStore struct {
+	col *mongo.Collection
+}
+
+func newReplayStore(ctx context.Context, cfg config.AppConfig) (*replayStore, error) {
+	db, err := connectMongo(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &replayStore{col: db.Collection("dead_letter_replays")}, nil
+}
+
+func replayKey(dl *kafka.DeadLetter) bson.M {
+	return bson.M{"dlq_topic": dl.Topic, "partition": dl.Partition, "offset": dl.Offset}
+}
+
+func (s *replayStore) find(ctx context.Context, dl kafka.DeadLetter) (*replayRecord, error) {
+	var rec replayRecord
+	err := s.col.FindOne(ctx, replayKey(&dl)).Decode(&rec)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// claim ghi nhận replay; bản ghi đã replay chỉ được replay lại khi force (unique index chặn replay đồng thời)
+func (s *replayStore) claim(ctx context.Context, dl *kafka.DeadLetter, edited, force bool) error {
+	now := time.Now()
+	if force {
+		_, err := s.col.UpdateOne(ctx, replayKey(dl), bson.M{
+			"$set": bson.M{"original_topic": dl.OriginalTopic, "edited": edited, "replayed_at": now},
+			"$inc": bson.M{"count": 1},
+		}, options.Update().SetUpsert(true))
+		return err
+	}
+
+	_, err := s.col.InsertOne(ctx, replayRecord{
+		DLQTopic:      dl.Topic,
+		Partition:     dl.Partition,
+		Offset:        dl.Offset,
+		OriginalTopic: dl.OriginalTopic,
+		Edited:        edited,
+		Count:         1,
+		ReplayedAt:    now,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errAlreadyReplayed
+	}
+	return err
+}
+
+func (s *replayStore) release(ctx context.Context, dl *kafka.DeadLetter) error {
+	_, err := s.col.UpdateOne(ctx, replayKey(dl), bson.M{"$inc": bson.M{"count": -1}})
+	if err != nil {
+		return err
+	}
+	_, err = s.col.DeleteOne(ctx, bson.M{"dlq_topic": dl.Topic, "partition": dl.Partition, "offset": dl.Offset, "count": bson.M{"$lte": 0}})
+	return err
+}
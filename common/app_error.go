@@ -68,7 +68,7 @@ func ErrDB(err error) *AppError {
 }
 
 func ErrRequest(err error) *AppError {
-	return NewErrorResponse(err, "Bạn đã gửi quá nhiều request, xin hãy gửi lại sau", err.Error(), "ErrRequest")
+	return NewFullErrorResponse(http.StatusTooManyRequests, err, "Bạn đã gửi quá nhiều request, xin hãy gửi lại sau", err.Error(), "ErrRequest")
 }
 
 func ErrApiKey(err error) *AppError {
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	appConfig "my-app/config"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EventBus - nơi publish / subscribe sự kiện của hệ thống.
+// Subscribe giao message theo mô hình consumer group của sarama: handler (chatConsumer) nhận
+// sarama.ConsumerGroupSession + ConsumerGroupClaim và commit qua session.MarkMessage / Commit,
+// nên cùng một handler chạy được trên Kafka lẫn bus nội bộ (memory / mongo).
+type EventBus interface {
+	// Publish gửi sự kiện; Kafka gửi bất đồng bộ (lỗi sau cùng được ghi spool), bus nội bộ ghi xong mới trả về
+	Publish(topic, key string, value []byte, headers ...sarama.RecordHeader) error
+	// Subscribe chặn tới khi ctx bị hủy, trả về ctx.Err() hoặc lỗi không tự phục hồi được
+	Subscribe(ctx context.Context, groupID string, topics []string, handler sarama.ConsumerGroupHandler) error
+	// Ping kiểm tra bus còn nhận sự kiện được (dùng cho /readyz)
+	Ping(ctx context.Context) error
+	// Lag trả về số sự kiện group chưa xử lý trên từng partition của topics
+	Lag(ctx context.Context, groupID string, topics []string) ([]PartitionLag, error)
+	// Close đợi sự kiện đang gửi được xác nhận tới khi ctx hết hạn
+	Close(ctx context.Context) error
+}
+
+const (
+	BusDriverKafka  = "kafka"
+	BusDriverMemory = "memory"
+	BusDriverMongo  = "mongo"
+)
+
+var (
+	busMu sync.RWMutex
+	bus   EventBus = &kafkaBus{}
+)
+
+// NewEventBus tạo bus theo EVENT_BUS và đặt làm bus mặc định cho SendMessageAsync
+func NewEventBus(cfg appConfig.EventBusConfig, brokers []string, db *mongo.Database) (EventBus, error) {
+	var b EventBus
+	switch cfg.Driver {
+	case "", BusDriverKafka:
+		kb, err := newKafkaBus(brokers)
+		if err != nil {
+			// Giữ bus Kafka để consumer / relay tự thử lại; message gửi lỗi được ghi spool
+			SetEventBus(kb)
+			return kb, err
+		}
+		b = kb
+	case BusDriverMemory:
+		log.Println("⚠️ EVENT_BUS=memory: sự kiện chưa xử lý sẽ mất khi restart, chỉ dùng cho dev")
+		b = newLocalBus(newMemoryLog(), cfg.PollInterval)
+	case BusDriverMongo:
+		b = newLocalBus(newMongoLog(db), cfg.PollInterval)
+	default:
+		return nil, fmt.Errorf("EVENT_BUS không hợp lệ: %q (kafka, memory, mongo)", cfg.Driver)
+	}
+
+	SetEventBus(b)
+	log.Printf("✅ Event bus: %s", busDriver(b))
+	return b, nil
+}
+
+func SetEventBus(b EventBus) {
+	busMu.Lock()
+	defer busMu.Unlock()
+	bus = b
+}
+
+func currentBus() EventBus {
+	busMu.RLock()
+	defer busMu.RUnlock()
+	return bus
+}
+
+func busDriver(b EventBus) string {
+	switch b := b.(type) {
+	case *kafkaBus:
+		return BusDriverKafka
+	case *localBus:
+		if _, ok := b.log.(*mongoLog); ok {
+			return BusDriverMongo
+		}
+		return BusDriverMemory
+	}
+	return fmt.Sprintf("%T", b)
+}
+
+// busProducer cho DeadLetterPublisher gửi vào bus nội bộ như một SyncProducer
+type busProducer struct {
+	bus EventBus
+}
+
+func (p busProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	key, err := encode(msg.Key)
+	if err != nil {
+		return 0, 0, err
+	}
+	value, err := encode(msg.Value)
+	if err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, p.bus.Publish(msg.Topic, string(key), value, msg.Headers...)
+}
+
+func (p busProducer) Close() error {
+	return nil
+}
+
+func encode(e sarama.Encoder) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return e.Encode()
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// PartitionLag - độ trễ của một consumer group trên một partition
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Committed int64  `json:"committed"` // -1 nếu group chưa commit
+	Newest    int64  `json:"newest"`
+	Lag       int64  `json:"lag"`
+}
+
+func (b *kafkaBus) Ping(ctx context.Context) error {
+	if AsyncProducer == nil {
+		return errors.New("producer chưa được khởi tạo")
+	}
+	if !IsHealthy() {
+		return fmt.Errorf("producer quá tải: pending=%d errors=%d",
+			atomic.LoadInt64(&metrics.pendingCount), atomic.LoadInt64(&metrics.errorCount))
+	}
+
+	// Producer không tự báo mất kết nối, thử mở TCP tới broker
+	var dialer net.Dialer
+	var lastErr error
+	for _, broker := range b.brokers {
+		conn, err := dialer.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("chưa cấu hình broker")
+	}
+	return lastErr
+}
+
+func (b *kafkaBus) Lag(ctx context.Context, groupID string, topics []string) ([]PartitionLag, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Net.DialTimeout = 3 * time.Second
+	config.Net.ReadTimeout = 5 * time.Second
+	config.Metadata.Retry.Max = 1
+
+	client, err := sarama.NewClient(b.brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	defer admin.Close() // đóng luôn client
+
+	committed, err := admin.ListConsumerGroupOffsets(groupID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var lags []PartitionLag
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("topic %s: %w", topic, err)
+		}
+		for _, partition := range partitions {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("topic %s/%d: %w", topic, partition, err)
+			}
+			lag := PartitionLag{Topic: topic, Partition: partition, Committed: -1, Newest: newest}
+			if block := committed.GetBlock(topic, partition); block != nil && block.Offset >= 0 {
+				lag.Committed = block.Offset
+				lag.Lag = max(newest-block.Offset, 0)
+			}
+			lags = append(lags, lag)
+		}
+	}
+	return lags, nil
+}
+
+func (b *localBus) Ping(ctx context.Context) error {
+	return b.log.ping(ctx)
+}
+
+func (b *localBus) Lag(ctx context.Context, groupID string, topics []string) ([]PartitionLag, error) {
+	lags := make([]PartitionLag, 0, len(topics))
+	for _, topic := range topics {
+		newest, err := b.log.head(ctx, topic)
+		if err != nil {
+			return nil, fmt.Errorf("topic %s: %w", topic, err)
+		}
+		committed, err := b.log.committed(ctx, groupID, topic)
+		if err != nil {
+			return nil, fmt.Errorf("topic %s: %w", topic, err)
+		}
+		lags = append(lags, PartitionLag{
+			Topic:     topic,
+			Committed: committed,
+			Newest:    newest,
+			Lag:       max(newest-committed, 0),
+		})
+	}
+	return lags, nil
+}
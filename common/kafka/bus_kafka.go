@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// kafkaBus - EventBus trên Kafka: publish qua AsyncProducer, subscribe bằng consumer group của sarama
+type kafkaBus struct {
+	brokers []string
+}
+
+func newKafkaBus(brokers []string) (*kafkaBus, error) {
+	b := &kafkaBus{brokers: brokers}
+	if err := InitAsyncProducer(brokers); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+func (b *kafkaBus) Publish(topic, key string, value []byte, headers ...sarama.RecordHeader) error {
+	return sendAsync(&sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     sarama.StringEncoder(key),
+		Value:   sarama.ByteEncoder(value),
+		Headers: headers,
+	})
+}
+
+func (b *kafkaBus) Subscribe(ctx context.Context, groupID string, topics []string, handler sarama.ConsumerGroupHandler) error {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Return.Errors = true
+	config.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategyRoundRobin()
+
+	// FIX: DISABLE auto-commit
+	config.Consumer.Offsets.AutoCommit.Enable = false
+
+	config.ChannelBufferSize = 20000
+	config.Consumer.Fetch.Min = 1024 * 1024 * 5
+	config.Consumer.Fetch.Default = 1024 * 1024 * 20
+	config.Consumer.MaxProcessingTime = 120 * time.Second
+	config.Consumer.Group.Session.Timeout = 30 * time.Second
+	config.Consumer.Group.Heartbeat.Interval = 10 * time.Second
+	config.Consumer.MaxWaitTime = 1000 * time.Millisecond
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+
+	consumerGroup, err := sarama.NewConsumerGroup(b.brokers, groupID, config)
+	if err != nil {
+		return fmt.Errorf("create consumer group: %w", err)
+	}
+	defer consumerGroup.Close()
+
+	for {
+		if err := consumerGroup.Consume(ctx, topics, handler); err != nil && ctx.Err() == nil {
+			log.Printf(" Consumer error: %v", err)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *kafkaBus) Close(ctx context.Context) error {
+	return CloseProducerContext(ctx)
+}
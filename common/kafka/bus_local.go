@@ -0,0 +1,265 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// eventLog - log sự kiện theo topic cho bus nội bộ, mỗi topic một partition (0) với offset tăng dần
+type eventLog interface {
+	append(ctx context.Context, msg *sarama.ConsumerMessage) error
+	// read trả về tối đa limit message có offset >= from, theo thứ tự offset
+	read(ctx context.Context, topic string, from int64, limit int) ([]*sarama.ConsumerMessage, error)
+	// committed trả về offset kế tiếp cần đọc của group (0 nếu chưa commit)
+	committed(ctx context.Context, groupID, topic string) (int64, error)
+	commit(ctx context.Context, groupID string, offsets map[string]int64) error
+	// head trả về offset sẽ cấp cho sự kiện kế tiếp của topic
+	head(ctx context.Context, topic string) (int64, error)
+	ping(ctx context.Context) error
+}
+
+const (
+	localReadBatch = 500
+	// Offset bị thiếu (process ghi log chết giữa chừng) quá thời gian này thì bỏ qua
+	localGapTimeout = 10 * time.Second
+)
+
+// localBus - EventBus chạy trong process (EVENT_BUS=memory/mongo), cùng handler với Kafka.
+// Chỉ nên có một instance consume mỗi group: bus nội bộ không chia partition giữa các instance.
+type localBus struct {
+	log          eventLog
+	pollInterval time.Duration
+
+	mu       sync.Mutex
+	watchers map[string]map[chan struct{}]struct{} // topic -> kênh đánh thức feeder khi có sự kiện mới
+}
+
+func newLocalBus(l eventLog, pollInterval time.Duration) *localBus {
+	if pollInterval <= 0 {
+		pollInterval = 200 * time.Millisecond
+	}
+	return &localBus{
+		log:          l,
+		pollInterval: pollInterval,
+		watchers:     make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+func (b *localBus) Publish(topic, key string, value []byte, headers ...sarama.RecordHeader) error {
+	msg := &sarama.ConsumerMessage{
+		Topic:     topic,
+		Key:       []byte(key),
+		Value:     append([]byte(nil), value...),
+		Timestamp: time.Now(),
+	}
+	for i := range headers {
+		h := headers[i]
+		msg.Headers = append(msg.Headers, &h)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.log.append(ctx, msg); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	for wake := range b.watchers[topic] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *localBus) Subscribe(ctx context.Context, groupID string, topics []string, handler sarama.ConsumerGroupHandler) error {
+	sess := &localSession{ctx: ctx, log: b.log, groupID: groupID, topics: topics, marked: make(map[string]int64)}
+	if err := handler.Setup(sess); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		from, err := b.log.committed(ctx, groupID, topic)
+		if err != nil {
+			log.Printf("⚠️ [EventBus] Không đọc được offset của %s/%s, đọc từ đầu: %v", groupID, topic, err)
+		}
+		claim := &localClaim{topic: topic, initial: from, messages: make(chan *sarama.ConsumerMessage, 256)}
+		claim.highWater.Store(from)
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b.feed(ctx, claim)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := handler.ConsumeClaim(sess, claim); err != nil {
+				log.Printf("⚠️ [EventBus] ConsumeClaim %s: %v", claim.topic, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := handler.Cleanup(sess)
+	sess.Commit()
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (b *localBus) Close(context.Context) error {
+	return nil
+}
+
+func (b *localBus) watch(topic string) chan struct{} {
+	wake := make(chan struct{}, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.watchers[topic] == nil {
+		b.watchers[topic] = make(map[chan struct{}]struct{})
+	}
+	b.watchers[topic][wake] = struct{}{}
+	return wake
+}
+
+func (b *localBus) unwatch(topic string, wake chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.watchers[topic], wake)
+}
+
+// feed đọc log từ offset đã commit và đẩy vào claim theo đúng thứ tự offset tới khi ctx bị hủy
+func (b *localBus) feed(ctx context.Context, claim *localClaim) {
+	defer close(claim.messages)
+
+	wake := b.watch(claim.topic)
+	defer b.unwatch(claim.topic, wake)
+
+	ticker := time.NewTicker(b.pollInterval)
+	defer ticker.Stop()
+
+	next := claim.initial
+	var gapSince time.Time
+	for {
+		msgs, err := b.log.read(ctx, claim.topic, next, localReadBatch)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ [EventBus] Đọc %s từ offset %d lỗi: %v", claim.topic, next, err)
+		}
+
+		full := len(msgs) == localReadBatch
+		for _, msg := range msgs {
+			if msg.Offset > next {
+				// Offset next chưa được ghi xong (ghi song song) - chờ, quá lâu thì bỏ qua
+				if gapSince.IsZero() {
+					gapSince = time.Now()
+				}
+				if time.Since(gapSince) < localGapTimeout {
+					full = false
+					break
+				}
+				log.Printf("⚠️ [EventBus] Bỏ qua offset %d-%d của %s (không được ghi)", next, msg.Offset-1, claim.topic)
+			}
+			gapSince = time.Time{}
+
+			select {
+			case claim.messages <- msg:
+				next = msg.Offset + 1
+				claim.highWater.Store(next)
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if full {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+type localClaim struct {
+	topic     string
+	initial   int64
+	highWater atomic.Int64
+	messages  chan *sarama.ConsumerMessage
+}
+
+func (c *localClaim) Topic() string                            { return c.topic }
+func (c *localClaim) Partition() int32                         { return 0 }
+func (c *localClaim) InitialOffset() int64                     { return c.initial }
+func (c *localClaim) HighWaterMarkOffset() int64               { return c.highWater.Load() }
+func (c *localClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// localSession - ConsumerGroupSession của bus nội bộ: MarkMessage ghi nhận offset, Commit lưu vào log
+type localSession struct {
+	ctx     context.Context
+	log     eventLog
+	groupID string
+	topics  []string
+
+	mu     sync.Mutex
+	marked map[string]int64 // topic -> offset kế tiếp cần đọc
+}
+
+func (s *localSession) Claims() map[string][]int32 {
+	claims := make(map[string][]int32, len(s.topics))
+	for _, topic := range s.topics {
+		claims[topic] = []int32{0}
+	}
+	return claims
+}
+
+func (s *localSession) MemberID() string         { return "local-" + s.groupID }
+func (s *localSession) GenerationID() int32      { return 1 }
+func (s *localSession) Context() context.Context { return s.ctx }
+
+func (s *localSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset > s.marked[topic] {
+		s.marked[topic] = offset
+	}
+}
+
+func (s *localSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked[topic] = offset
+}
+
+func (s *localSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+// Commit dùng context riêng: commit cuối cùng chạy sau khi ctx của session đã bị hủy
+func (s *localSession) Commit() {
+	s.mu.Lock()
+	offsets := make(map[string]int64, len(s.marked))
+	for topic, offset := range s.marked {
+		offsets[topic] = offset
+	}
+	s.mu.Unlock()
+	if len(offsets) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.log.commit(ctx, s.groupID, offsets); err != nil {
+		log.Printf("⚠️ [EventBus] Commit offset của group %s lỗi: %v", s.groupID, err)
+	}
+}
@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// recordingHandler - handler tối giản theo đúng hợp đồng sarama mà chatConsumer dùng:
+// MarkMessage từng message rồi Commit.
+type recordingHandler struct {
+	mu     sync.Mutex
+	values []string
+	got    chan struct{}
+}
+
+func (h *recordingHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *recordingHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *recordingHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		h.mu.Lock()
+		h.values = append(h.values, string(msg.Value))
+		h.mu.Unlock()
+		sess.MarkMessage(msg, "")
+		sess.Commit()
+		h.got <- struct{}{}
+	}
+	return nil
+}
+
+func (h *recordingHandler) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-h.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("chỉ nhận được %d/%d message", i, n)
+		}
+	}
+}
+
+func TestLocalBus_DeliversInOrderAndResumesFromCommit(t *testing.T) {
+	b := newLocalBus(newMemoryLog(), 10*time.Millisecond)
+	const topic = "chat-topic"
+
+	for i := 0; i < 5; i++ {
+		if err := b.Publish(topic, "u1", []byte(fmt.Sprintf("m%d", i))); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	h := &recordingHandler{got: make(chan struct{}, 16)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Subscribe(ctx, "group", []string{topic}, h) }()
+
+	h.wait(t, 5)
+	// Message publish sau khi đã subscribe được đánh thức ngay, không cần chờ poll
+	if err := b.Publish(topic, "u1", []byte("m5")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	h.wait(t, 1)
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Subscribe trả về %v, muốn context.Canceled", err)
+	}
+
+	h.mu.Lock()
+	for i, v := range h.values {
+		if want := fmt.Sprintf("m%d", i); v != want {
+			t.Fatalf("message thứ %d = %s, muốn %s", i, v, want)
+		}
+	}
+	h.mu.Unlock()
+
+	// Subscribe lại cùng group chỉ nhận message chưa commit
+	if err := b.Publish(topic, "u1", []byte("m6")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	h2 := &recordingHandler{got: make(chan struct{}, 16)}
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	go func() { _ = b.Subscribe(ctx2, "group", []string{topic}, h2) }()
+
+	h2.wait(t, 1)
+	select {
+	case <-h2.got:
+		t.Fatal("nhận lại message đã commit")
+	case <-time.After(100 * time.Millisecond):
+	}
+	h2.mu.Lock()
+	defer h2.mu.Unlock()
+	if len(h2.values) != 1 || h2.values[0] != "m6" {
+		t.Fatalf("sau khi subscribe lại nhận %v, muốn [m6]", h2.values)
+	}
+}
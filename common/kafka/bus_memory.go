@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// Topic không có group nào commit (VD: DLQ) chỉ giữ tối đa chừng này message trong RAM
+const memoryTopicLimit = 100000
+
+type memoryTopic struct {
+	base     int64 // offset của messages[0]
+	messages []*sarama.ConsumerMessage
+}
+
+// memoryLog - log sự kiện trong RAM cho EVENT_BUS=memory. Message đã được mọi group commit thì được dọn.
+type memoryLog struct {
+	mu      sync.RWMutex
+	topics  map[string]*memoryTopic
+	offsets map[string]map[string]int64 // topic -> group -> offset kế tiếp
+}
+
+func newMemoryLog() *memoryLog {
+	return &memoryLog{
+		topics:  make(map[string]*memoryTopic),
+		offsets: make(map[string]map[string]int64),
+	}
+}
+
+func (l *memoryLog) append(_ context.Context, msg *sarama.ConsumerMessage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	t := l.topics[msg.Topic]
+	if t == nil {
+		t = &memoryTopic{}
+		l.topics[msg.Topic] = t
+	}
+	msg.Offset = t.base + int64(len(t.messages))
+	t.messages = append(t.messages, msg)
+
+	if len(t.messages) > memoryTopicLimit {
+		drop := len(t.messages) - memoryTopicLimit
+		log.Printf("⚠️ [EventBus] Topic %s vượt %d message trong RAM, bỏ %d message cũ nhất", msg.Topic, memoryTopicLimit, drop)
+		t.trim(t.base + int64(drop))
+	}
+	return nil
+}
+
+func (l *memoryLog) read(_ context.Context, topic string, from int64, limit int) ([]*sarama.ConsumerMessage, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	t := l.topics[topic]
+	if t == nil {
+		return nil, nil
+	}
+	start := max(from-t.base, 0)
+	if start >= int64(len(t.messages)) {
+		return nil, nil
+	}
+	end := min(start+int64(limit), int64(len(t.messages)))
+	return append([]*sarama.ConsumerMessage(nil), t.messages[start:end]...), nil
+}
+
+func (l *memoryLog) committed(_ context.Context, groupID, topic string) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.offsets[topic][groupID], nil
+}
+
+func (l *memoryLog) commit(_ context.Context, groupID string, offsets map[string]int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for topic, offset := range offsets {
+		if l.offsets[topic] == nil {
+			l.offsets[topic] = make(map[string]int64)
+		}
+		l.offsets[topic][groupID] = offset
+
+		// Dọn message mọi group đã commit
+		low := offset
+		for _, o := range l.offsets[topic] {
+			low = min(low, o)
+		}
+		if t := l.topics[topic]; t != nil {
+			t.trim(low)
+		}
+	}
+	return nil
+}
+
+// trim bỏ các message có offset < until
+func (t *memoryTopic) trim(until int64) {
+	n := until - t.base
+	if n <= 0 {
+		return
+	}
+	n = min(n, int64(len(t.messages)))
+	t.messages = append([]*sarama.ConsumerMessage(nil), t.messages[n:]...)
+	t.base += n
+}
+
+func (l *memoryLog) head(_ context.Context, topic string) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	t := l.topics[topic]
+	if t == nil {
+		return 0, nil
+	}
+	return t.base + int64(len(t.messages)), nil
+}
+
+func (l *memoryLog) ping(context.Context) error {
+	return nil
+}
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	eventLogCollection       = "event_log"
+	eventSequencesCollection = "event_sequences"
+	eventOffsetsCollection   = "event_offsets"
+)
+
+type eventLogHeader struct {
+	Key   string `bson:"key"`
+	Value string `bson:"value"`
+}
+
+type eventLogEntry struct {
+	Topic     string           `bson:"topic"`
+	Offset    int64            `bson:"offset"`
+	Key       []byte           `bson:"key,omitempty"`
+	Value     []byte           `bson:"value"`
+	Headers   []eventLogHeader `bson:"headers,omitempty"`
+	CreatedAt time.Time        `bson:"created_at"`
+}
+
+// mongoLog - log sự kiện trên Mongo cho EVENT_BUS=mongo: sự kiện chưa xử lý còn nguyên sau restart.
+// Offset cấp bằng bộ đếm theo topic; sự kiện cũ tự xóa theo TTL (internal/indexer).
+type mongoLog struct {
+	db *mongo.Database
+}
+
+func newMongoLog(db *mongo.Database) *mongoLog {
+	return &mongoLog{db: db}
+}
+
+func (l *mongoLog) append(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	var seq struct {
+		Next int64 `bson:"next"`
+	}
+	err := l.db.Collection(eventSequencesCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": msg.Topic},
+		bson.M{"$inc": bson.M{"next": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&seq)
+	if err != nil {
+		return err
+	}
+	msg.Offset = seq.Next - 1
+
+	entry := eventLogEntry{
+		Topic:     msg.Topic,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		CreatedAt: msg.Timestamp,
+	}
+	for _, h := range msg.Headers {
+		entry.Headers = append(entry.Headers, eventLogHeader{Key: string(h.Key), Value: string(h.Value)})
+	}
+	_, err = l.db.Collection(eventLogCollection).InsertOne(ctx, entry)
+	return err
+}
+
+func (l *mongoLog) read(ctx context.Context, topic string, from int64, limit int) ([]*sarama.ConsumerMessage, error) {
+	cursor, err := l.db.Collection(eventLogCollection).Find(ctx,
+		bson.M{"topic": topic, "offset": bson.M{"$gte": from}},
+		options.Find().SetSort(bson.D{{Key: "offset", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []eventLogEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*sarama.ConsumerMessage, 0, len(entries))
+	for _, e := range entries {
+		msg := &sarama.ConsumerMessage{
+			Topic:     e.Topic,
+			Offset:    e.Offset,
+			Key:       e.Key,
+			Value:     e.Value,
+			Timestamp: e.CreatedAt,
+		}
+		for _, h := range e.Headers {
+			msg.Headers = append(msg.Headers, &sarama.RecordHeader{Key: []byte(h.Key), Value: []byte(h.Value)})
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (l *mongoLog) committed(ctx context.Context, groupID, topic string) (int64, error) {
+	var doc struct {
+		Offset int64 `bson:"offset"`
+	}
+	err := l.db.Collection(eventOffsetsCollection).FindOne(ctx, bson.M{"_id": groupID + "/" + topic}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return doc.Offset, err
+}
+
+func (l *mongoLog) commit(ctx context.Context, groupID string, offsets map[string]int64) error {
+	models := make([]mongo.WriteModel, 0, len(offsets))
+	now := time.Now()
+	for topic, offset := range offsets {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": groupID + "/" + topic}).
+			SetUpdate(bson.M{"$max": bson.M{"offset": offset}, "$set": bson.M{
+				"group":      groupID,
+				"topic":      topic,
+				"updated_at": now,
+			}}).
+			SetUpsert(true))
+	}
+	_, err := l.db.Collection(eventOffsetsCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (l *mongoLog) head(ctx context.Context, topic string) (int64, error) {
+	var seq struct {
+		Next int64 `bson:"next"`
+	}
+	err := l.db.Collection(eventSequencesCollection).FindOne(ctx, bson.M{"_id": topic}).Decode(&seq)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return seq.Next, err
+}
+
+func (l *mongoLog) ping(ctx context.Context) error {
+	return l.db.Client().Ping(ctx, nil)
+}
@@ -3,14 +3,20 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"my-app/common/logger"
+	"my-app/common/telemetry"
+	appConfig "my-app/config"
+	mediaAdapter "my-app/internal/adapter/media"
 	"my-app/modules/chat/biz"
 	"my-app/modules/chat/models"
 	"my-app/modules/chat/storage"
 	BizUser "my-app/modules/user/biz"
 	ModelsUser "my-app/modules/user/models"
 	StorageUser "my-app/modules/user/storage"
+	"strconv"
 	"strings"
 
 	BizGroup "my-app/modules/group/biz"
@@ -24,21 +30,34 @@ import (
 	"github.com/elastic/go-elasticsearch/v8"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ======================== FIX #1: Manual commit AFTER insert success ========================
 type chatConsumer struct {
 	db             *mongo.Database
 	es             *elasticsearch.Client
-	workerPool     chan struct{}
+	lanes          *keyedLanes
+	offsets        *offsetTracker
 	batchProcessor *BatchProcessor
 	wg             sync.WaitGroup
 	commitQueue    chan *commitTask
+	commitFlush    chan chan struct{} // yêu cầu commitWorker commit ngay mọi task đang chờ
+	commitDone     chan struct{}
+	deadLetters    *DeadLetterPublisher
+	events         *eventTracker
+
+	// Pipeline tạo thumbnail/WebP/poster, giới hạn số ffmpeg chạy song song
+	mediaProcessing *biz.MediaProcessingBiz
+	mediaSlots      chan struct{}
 }
 
 type commitTask struct {
-	session sarama.ConsumerGroupSession
-	message *sarama.ConsumerMessage
+	session      sarama.ConsumerGroupSession
+	message      *sarama.ConsumerMessage
+	deadLettered bool // commit sau khi chuyển DLQ, không tính là đã xử lý
 }
 
 // ======================== FIX #3: Batch with synchronous insert + retry ========================
@@ -51,7 +70,12 @@ type BatchProcessor struct {
 	db           *mongo.Database
 	es           *elasticsearch.Client
 	commitQueue  chan *commitTask
+	deadLetters  *DeadLetterPublisher
+	events       *eventTracker
 	processingWg sync.WaitGroup
+
+	// Batch xử lý lần lượt để tin nhắn cùng key ở batch sau không được lưu trước batch trước
+	batches chan []messageWithCommit
 }
 
 type messageWithCommit struct {
@@ -60,7 +84,7 @@ type messageWithCommit struct {
 	kafkaMsg *sarama.ConsumerMessage
 }
 
-func NewBatchProcessor(db *mongo.Database, es *elasticsearch.Client, batchSize int, commitQueue chan *commitTask) *BatchProcessor {
+func NewBatchProcessor(db *mongo.Database, es *elasticsearch.Client, batchSize int, commitQueue chan *commitTask, deadLetters *DeadLetterPublisher, events *eventTracker) *BatchProcessor {
 	bp := &BatchProcessor{
 		messages:     make([]messageWithCommit, 0, batchSize),
 		maxBatchSize: batchSize,
@@ -69,9 +93,13 @@ func NewBatchProcessor(db *mongo.Database, es *elasticsearch.Client, batchSize i
 		db:           db,
 		es:           es,
 		commitQueue:  commitQueue,
+		deadLetters:  deadLetters,
+		events:       events,
+		batches:      make(chan []messageWithCommit, 2),
 	}
 
 	go bp.autoFlush()
+	go bp.runBatches()
 	return bp
 }
 
@@ -118,18 +146,60 @@ func (bp *BatchProcessor) flushLocked() {
 	copy(messages, bp.messages)
 	bp.messages = bp.messages[:0]
 
-	// Process in a separate goroutine to avoid blocking the lock
+	// Chuyển cho runBatches; hàng đợi đầy thì Add bị chặn, tạo backpressure lên các lane
 	bp.processingWg.Add(1)
-	go bp.processBatch(messages)
+	bp.batches <- messages
+}
+
+func (bp *BatchProcessor) runBatches() {
+	for messages := range bp.batches {
+		bp.processBatch(messages)
+	}
+}
+
+// groupByLane tách batch thành các nhóm cùng laneKey, giữ nguyên thứ tự nhận trong mỗi nhóm
+func groupByLane(messages []messageWithCommit) [][]messageWithCommit {
+	index := make(map[string]int)
+	var groups [][]messageWithCommit
+	for _, mwc := range messages {
+		key := laneKey(mwc.kafkaMsg)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], mwc)
+	}
+	return groups
 }
 
 // ======================== FIX #3: Synchronous insert with retry + commit ONLY on success ========================
 func (bp *BatchProcessor) processBatch(messages []messageWithCommit) {
 	defer bp.processingWg.Done()
 
+	start := time.Now()
+	defer func() {
+		telemetry.KafkaBatchFlushDuration.WithLabelValues("chat-topic").Observe(time.Since(start).Seconds())
+	}()
+	telemetry.KafkaBatchSize.Observe(float64(len(messages)))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
+	// Span của batch liên kết tới trace của từng tin nhắn; span lưu từng tin nhắn nằm trong trace gốc của nó
+	links := make([]trace.Link, 0, len(messages))
+	for _, mwc := range messages {
+		if sc := trace.SpanContextFromContext(messageContext(ctx, mwc.kafkaMsg)); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	ctx, span := telemetry.Tracer().Start(ctx, "kafka.process_batch chat-topic",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(messages))),
+	)
+	defer span.End()
+
 	// Tái sử dụng Store cho toàn bộ batch
 	chatStore := storage.NewMongoChatStore(bp.db)
 	esChatStore := storage.NewESChatStore(bp.es)
@@ -140,59 +210,94 @@ func (bp *BatchProcessor) processBatch(messages []messageWithCommit) {
 	semaphore := make(chan struct{}, 50)
 	var batchWg sync.WaitGroup
 
-	for _, msgWithCommit := range messages {
+	// Mỗi nhóm cùng key lưu tuần tự theo thứ tự offset, các nhóm chạy song song
+	for _, group := range groupByLane(messages) {
 		batchWg.Add(1)
 		semaphore <- struct{}{} // Acquire
 
-		go func(mwc messageWithCommit) {
+		go func(group []messageWithCommit) {
 			defer batchWg.Done()
 			defer func() { <-semaphore }() // Release
 
-			msg := mwc.message
-
-			// Retry logic: max 3 attempts
-			var err error
-			for retry := 0; retry < 3; retry++ {
-				_, err = chatBiz.HandleMessage(ctx,
-					msg.ID,
-					msg.SenderID.Hex(),
-					msg.ReceiverID.Hex(),
-					msg.Content,
-					msg.Status,
-					msg.GroupID.Hex(),
-					msg.Type,
-					msg.MediaIDs,
-					msg.CreatedAt,
-					msg.Reply,
-					msg.Task,
-					msg.ParentID,
-				)
-
-				if err == nil {
-					bp.commitQueue <- &commitTask{
-						session: mwc.session,
-						message: mwc.kafkaMsg,
-					}
-					return
-				}
+			for _, mwc := range group {
+				bp.handleMessage(ctx, chatBiz, mwc)
+			}
+		}(group)
+	}
 
-				// Nếu là lỗi logic (không tìm thấy người gửi), không cần retry tốn tài nguyên
-				if strings.Contains(err.Error(), "người gửi") || strings.Contains(err.Error(), "không tồn tại") {
-					break
-				}
+	batchWg.Wait()
+}
 
-				if retry < 2 {
-					time.Sleep(time.Duration(retry+1) * 100 * time.Millisecond)
-				}
+func (bp *BatchProcessor) handleMessage(ctx context.Context, chatBiz *biz.ChatBiz, mwc messageWithCommit) {
+	msg := mwc.message
+
+	ctx, span := startConsumeSpan(ctx, mwc.kafkaMsg, "chat.persist",
+		trace.WithLinks(trace.LinkFromContext(ctx)))
+	defer span.End()
+	span.SetAttributes(attribute.String("chat.message_id", msg.ID.Hex()))
+	ctx = logger.WithUserID(ctx, msg.SenderID.Hex())
+	ctx = logger.WithConversationID(ctx, storage.ConversationIDOf(msg.SenderID, msg.ReceiverID, msg.GroupID).Hex())
+
+	// Retry logic: max 3 attempts
+	var err error
+	attempts := 0
+	for retry := 0; retry < 3; retry++ {
+		attempts++
+		_, err = chatBiz.HandleMessage(ctx,
+			msg.ID,
+			msg.SenderID.Hex(),
+			msg.ReceiverID.Hex(),
+			msg.Content,
+			msg.Status,
+			msg.GroupID.Hex(),
+			msg.Type,
+			msg.MediaIDs,
+			msg.CreatedAt,
+			msg.Reply,
+			msg.Task,
+			msg.ParentID,
+			msg.ClientMessageID,
+			msg.Mentions,
+			msg.MentionTargets,
+		)
+
+		if err == nil {
+			bp.commitQueue <- &commitTask{
+				session: mwc.session,
+				message: mwc.kafkaMsg,
 			}
+			return
+		}
 
-			if err != nil {
-				log.Printf(" [Consumer] Failed to insert message %s: %v", msg.ID.Hex(), err)
+		// Tin nhắn bị chặn theo quy tắc nghiệp vụ: replay cũng không gửi được, commit luôn
+		if errors.Is(err, biz.ErrUserBlocked) || errors.Is(err, biz.ErrGroupRestricted) || errors.Is(err, biz.ErrMediaAttachDenied) {
+			logger.Ctx(ctx, "kafka").Warn().Err(err).Str("message_id", msg.ID.Hex()).Msg("Message rejected")
+			bp.commitQueue <- &commitTask{
+				session: mwc.session,
+				message: mwc.kafkaMsg,
 			}
-		}(msgWithCommit)
+			return
+		}
+
+		// Nếu là lỗi logic (không tìm thấy người gửi), không cần retry tốn tài nguyên
+		if strings.Contains(err.Error(), "người gửi") || strings.Contains(err.Error(), "không tồn tại") {
+			break
+		}
+
+		if retry < 2 {
+			time.Sleep(time.Duration(retry+1) * 100 * time.Millisecond)
+		}
 	}
 
-	batchWg.Wait()
+	logger.Ctx(ctx, "kafka").Error().Err(err).Str("message_id", msg.ID.Hex()).Int("attempts", attempts).Msg("Failed to insert message")
+	span.SetStatus(codes.Error, err.Error())
+	sendToDeadLetter(bp.deadLetters, bp.events, bp.commitQueue, mwc.session, mwc.kafkaMsg, DLQKindExhausted, err, attempts)
+}
+
+// Drain lưu ngay batch đang gom và đợi mọi batch xử lý xong (task commit đã vào commitQueue)
+func (bp *BatchProcessor) Drain() {
+	bp.Flush()
+	bp.processingWg.Wait()
 }
 
 func (bp *BatchProcessor) Close() {
@@ -200,64 +305,56 @@ func (bp *BatchProcessor) Close() {
 	bp.flushTicker.Stop()
 	bp.Flush()
 	bp.processingWg.Wait() // Wait for all batches to complete
+	close(bp.batches)
 }
 
 // ======================== FIX #1: Manual commit with dedicated goroutine ========================
-func StartConsumer(ctx context.Context, brokers []string, groupID string, topics []string, db *mongo.Database, es *elasticsearch.Client) error {
-	config := sarama.NewConfig()
-	config.Version = sarama.V2_8_0_0
-	config.Consumer.Return.Errors = true
-	config.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategyRoundRobin()
-
-	// FIX: DISABLE auto-commit
-	config.Consumer.Offsets.AutoCommit.Enable = false
-
-	config.ChannelBufferSize = 20000
-	config.Consumer.Fetch.Min = 1024 * 1024 * 5
-	config.Consumer.Fetch.Default = 1024 * 1024 * 20
-	config.Consumer.MaxProcessingTime = 120 * time.Second
-	config.Consumer.Group.Session.Timeout = 30 * time.Second
-	config.Consumer.Group.Heartbeat.Interval = 10 * time.Second
-	config.Consumer.MaxWaitTime = 1000 * time.Millisecond
-	config.Consumer.Offsets.Initial = sarama.OffsetNewest
-
-	consumerGroup, err := sarama.NewConsumerGroup(brokers, groupID, config)
+// StartConsumer chạy handler trên event bus (Kafka hoặc bus nội bộ) tới khi ctx bị hủy
+func StartConsumer(ctx context.Context, bus EventBus, groupID string, topics []string, db *mongo.Database, es *elasticsearch.Client) error {
+	deadLetters, err := newDeadLetterPublisherFor(bus)
 	if err != nil {
-		return fmt.Errorf("create consumer group: %w", err)
+		return err
 	}
-	defer consumerGroup.Close()
 
 	commitQueue := make(chan *commitTask, 1000)
+	events := newEventTracker(db)
+	mediaCfg := appConfig.LoadMediaProcessing()
 	handler := &chatConsumer{
 		db:             db,
 		es:             es,
-		workerPool:     make(chan struct{}, 200),
-		batchProcessor: NewBatchProcessor(db, es, 500, commitQueue),
+		offsets:        newOffsetTracker(),
+		batchProcessor: NewBatchProcessor(db, es, 500, commitQueue, deadLetters, events),
 		commitQueue:    commitQueue,
+		commitFlush:    make(chan chan struct{}),
+		commitDone:     make(chan struct{}),
+		deadLetters:    deadLetters,
+		events:         events,
+		mediaProcessing: biz.NewMediaProcessingBiz(
+			storage.NewMongoChatStore(db),
+			mediaAdapter.NewFFmpeg(mediaCfg.FFmpegPath, mediaCfg.FFprobePath),
+			mediaCfg.WorkDir,
+			mediaCfg.Timeout,
+		),
+		mediaSlots: make(chan struct{}, max(1, mediaCfg.Concurrency)),
 	}
+	// 200 lane thay cho worker pool 200 goroutine trước đây: cùng mức song song, nhưng giữ thứ tự theo key
+	handler.lanes = newKeyedLanes(200, 100, handler.processMessage)
 	// Dedicated commit goroutine
-	go handler.commitWorker(ctx)
+	go handler.commitWorker()
 
-	log.Println(" Kafka Consumer started with MANUAL commit")
+	log.Printf(" Consumer started on %s event bus with MANUAL commit", busDriver(bus))
 
-	for {
-		if err := consumerGroup.Consume(ctx, topics, handler); err != nil {
-			if ctx.Err() != nil {
-				handler.Shutdown()
-				return ctx.Err()
-			}
-			log.Printf(" Consumer error: %v", err)
-		}
-
-		if ctx.Err() != nil {
-			handler.Shutdown()
-			return ctx.Err()
-		}
-	}
+	err = bus.Subscribe(ctx, groupID, topics, handler)
+	handler.Shutdown()
+	return err
 }
 
 // ======================== FIX #1: Commit only after successful insert ========================
-func (c *chatConsumer) commitWorker(ctx context.Context) {
+// commitWorker chạy tới khi commitQueue bị đóng (Shutdown), không dừng theo ctx: batch lưu sau khi ctx
+// bị hủy vẫn phải được commit trong Cleanup trước khi session kết thúc
+func (c *chatConsumer) commitWorker() {
+	defer close(c.commitDone)
+
 	commitBatch := make([]*commitTask, 0, 100)
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -266,6 +363,11 @@ func (c *chatConsumer) commitWorker(ctx context.Context) {
 		select {
 		case task, ok := <-c.commitQueue:
 			if !ok {
+				// Final flush on shutdown
+				if len(commitBatch) > 0 {
+					c.commitTasks(commitBatch)
+					log.Printf(" [Consumer] Final flush: committed %d messages before shutdown", len(commitBatch))
+				}
 				return
 			}
 			commitBatch = append(commitBatch, task)
@@ -275,30 +377,58 @@ func (c *chatConsumer) commitWorker(ctx context.Context) {
 				continue
 			}
 
-			// Commit all messages
-			for _, t := range commitBatch {
-				t.session.MarkMessage(t.message, "")
-			}
-
-			// Commit batch offset
-			commitBatch[0].session.Commit()
-
+			c.commitTasks(commitBatch)
 			commitBatch = commitBatch[:0]
 
-		case <-ctx.Done():
-			// Final flush on shutdown
-			if len(commitBatch) > 0 {
-				for _, t := range commitBatch {
-					t.session.MarkMessage(t.message, "")
+		case done := <-c.commitFlush:
+		drain:
+			for {
+				select {
+				case task, ok := <-c.commitQueue:
+					if !ok {
+						break drain
+					}
+					commitBatch = append(commitBatch, task)
+				default:
+					break drain
 				}
-				commitBatch[0].session.Commit()
-				log.Printf(" [Consumer] Final flush: committed %d messages before shutdown", len(commitBatch))
 			}
-			return
+			if len(commitBatch) > 0 {
+				c.commitTasks(commitBatch)
+				commitBatch = commitBatch[:0]
+			}
+			close(done)
 		}
 	}
 }
 
+// flushCommits commit ngay mọi task đã vào commitQueue
+func (c *chatConsumer) flushCommits() {
+	done := make(chan struct{})
+	c.commitFlush <- done
+	<-done
+}
+
+// commitTasks chỉ mark offset liên tục đã xử lý xong của mỗi partition (xem offsetTracker)
+func (c *chatConsumer) commitTasks(tasks []*commitTask) {
+	// Ghi nhận idempotency key trước khi commit offset
+	c.events.settle(tasks)
+
+	sessions := make(map[sarama.ConsumerGroupSession]struct{})
+	for _, t := range tasks {
+		sess, msg := c.offsets.complete(t.message)
+		if msg == nil {
+			continue
+		}
+		sess.MarkMessage(msg, "")
+		sessions[sess] = struct{}{}
+	}
+
+	for sess := range sessions {
+		sess.Commit()
+	}
+}
+
 func (c *chatConsumer) Setup(_ sarama.ConsumerGroupSession) error {
 	log.Println("Consumer group rebalanced - setup")
 	return nil
@@ -307,29 +437,47 @@ func (c *chatConsumer) Setup(_ sarama.ConsumerGroupSession) error {
 func (c *chatConsumer) Cleanup(_ sarama.ConsumerGroupSession) error {
 	log.Println("⏳ Consumer group rebalanced - cleanup")
 	c.wg.Wait()
+	// Lưu nốt batch đang gom và commit offset khi session còn hiệu lực (rebalance hoặc shutdown),
+	// nếu không các tin nhắn này bị consumer kế tiếp xử lý lại
+	c.batchProcessor.Drain()
+	c.flushCommits()
 	return nil
 }
 
 func (c *chatConsumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	partition := strconv.Itoa(int(claim.Partition()))
 	for msg := range claim.Messages() {
-		c.workerPool <- struct{}{}
+		telemetry.KafkaConsumerLag.WithLabelValues(msg.Topic, partition).Set(float64(max(claim.HighWaterMarkOffset()-msg.Offset-1, 0)))
+
 		c.wg.Add(1)
+		c.offsets.track(sess, msg)
+
+		// Xử lý media chạy ffmpeg lâu và không cần thứ tự, không để chiếm lane của tin nhắn
+		if msg.Topic == "media-processing-topic" {
+			go c.processMessage(sess, msg)
+			continue
+		}
 
 		// FIX: DO NOT commit here anymore
-		go c.processMessage(sess, msg)
+		c.lanes.dispatch(sess, msg)
 	}
 	return nil
 }
 
 func (c *chatConsumer) processMessage(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
-	defer func() {
-		<-c.workerPool
-		c.wg.Done()
-	}()
+	defer c.wg.Done()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	ctx, span := startConsumeSpan(ctx, msg, "kafka.consume "+msg.Topic)
+	defer span.End()
+
+	// chat-topic cần đọc payload để lấy key của tin nhắn giao việc, nhận key trong processChatMessage
+	if msg.Topic != "chat-topic" && !c.claimEvent(ctx, sess, msg, idempotencyKey(msg)) {
+		return
+	}
+
 	switch msg.Topic {
 	case "chat-topic":
 		c.processChatMessage(ctx, sess, msg)
@@ -351,28 +499,114 @@ func (c *chatConsumer) processMessage(sess sarama.ConsumerGroupSession, msg *sar
 		c.processGroupMember(ctx, sess, msg)
 	case "chat-notification-all":
 		c.processNotificationAll(ctx, sess, msg)
+	case "media-processing-topic":
+		c.processMediaJob(sess, msg)
+	default:
+		// Topic không có handler vẫn phải commit, nếu không offset của partition bị kẹt ở offsetTracker
+		logger.Ctx(ctx, "kafka").Warn().Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Msg("No handler for topic, skip")
+		c.commitQueue <- &commitTask{session: sess, message: msg}
 	}
 }
 
+// claimEvent nhận idempotency key của message; key đã xử lý (outbox gửi lại, client gửi trùng) thì commit và bỏ qua.
+// Key đang được xử lý ở nơi khác thì không commit: lượt đó có thể chết trước khi lưu, message được thử lại khi lượt giữ hết hạn.
+func (c *chatConsumer) claimEvent(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, key string) bool {
+	state, retryAfter := c.events.claim(ctx, msg, key)
+	switch state {
+	case claimAcquired:
+		return true
+	case claimDone:
+		logger.Ctx(ctx, "kafka").Info().Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Str("key", key).Msg("Skip duplicate")
+		c.commitQueue <- &commitTask{session: sess, message: msg}
+	default:
+		logger.Ctx(ctx, "kafka").Info().Str("topic", msg.Topic).Int32("partition", msg.Partition).Int64("offset", msg.Offset).Str("key", key).Dur("retry_after", retryAfter).Msg("Key in flight, retry later")
+		c.retryLater(sess, msg, retryAfter)
+	}
+	return false
+}
+
+// retryLater giao lại message (đã track, chưa commit) sau wait. Session kết thúc trước đó (rebalance / shutdown)
+// thì bỏ, offset chưa commit nên Kafka giao lại cho consumer kế tiếp.
+func (c *chatConsumer) retryLater(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, wait time.Duration) {
+	c.wg.Add(1)
+	go func() {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			if msg.Topic == "media-processing-topic" {
+				c.processMessage(sess, msg)
+				return
+			}
+			c.lanes.dispatch(sess, msg)
+		case <-sess.Context().Done():
+			c.wg.Done()
+		}
+	}()
+}
+
+// sendToDeadLetter chuyển message lỗi sang DLQ của topic rồi mới commit.
+// Chưa ghi được DLQ thì không commit, Kafka sẽ giao lại message sau khi rebalance / restart.
+func sendToDeadLetter(dlq *DeadLetterPublisher, events *eventTracker, commitQueue chan *commitTask, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, kind string, reason error, attempts int) {
+	if err := dlq.Publish(msg, kind, reason, attempts); err != nil {
+		log.Printf("❌ [DLQ] Failed to publish %s/%d@%d: %v (reason: %v)", msg.Topic, msg.Partition, msg.Offset, err, reason)
+		events.release(msg)
+		return
+	}
+	log.Printf("[DLQ] %s/%d@%d -> %s (%s, attempts=%d): %v", msg.Topic, msg.Partition, msg.Offset, DeadLetterTopic(msg.Topic), kind, attempts, reason)
+	commitQueue <- &commitTask{session: sess, message: msg, deadLettered: true}
+}
+
+func (c *chatConsumer) deadLetter(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, kind string, reason error, attempts int) {
+	sendToDeadLetter(c.deadLetters, c.events, c.commitQueue, sess, msg, kind, reason, attempts)
+}
+
+// processMediaJob - tạo biến thể cho media vừa upload. Lỗi xử lý đã được ghi vào media (processing_status=failed)
+// nên vẫn commit, tránh ffmpeg chạy lại vô hạn với file hỏng.
+func (c *chatConsumer) processMediaJob(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+	var job models.MediaProcessingJob
+	if err := json.Unmarshal(msg.Value, &job); err != nil {
+		log.Printf("[media-processing] Unmarshal error: %v | payload: %s", err, string(msg.Value))
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
+		return
+	}
+
+	mediaID, err := primitive.ObjectIDFromHex(job.MediaID)
+	if err != nil {
+		c.deadLetter(sess, msg, DLQKindInvalid, fmt.Errorf("invalid media_id %q", job.MediaID), 1)
+		return
+	}
+
+	c.mediaSlots <- struct{}{}
+	defer func() { <-c.mediaSlots }()
+
+	if err := c.mediaProcessing.Process(context.Background(), mediaID); err != nil {
+		log.Printf("[media-processing] media %s: %v", job.MediaID, err)
+	}
+
+	c.commitQueue <- &commitTask{session: sess, message: msg}
+}
+
 // processNotificationAll - Send system notification to ALL users
 func (c *chatConsumer) processNotificationAll(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
 	var notification models.MessageNotificationResponse
 	if err := json.Unmarshal(msg.Value, &notification); err != nil {
 		log.Printf("[chat-notification-all] Unmarshal error: %v", err)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
 	// Validate: must be a system notification
 	if notification.NotificationType != models.NotificationTypeSystem {
 		log.Printf("[chat-notification-all] Not a system notification: %v", notification.NotificationType)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindInvalid, fmt.Errorf("not a system notification: %v", notification.NotificationType), 1)
 		return
 	}
 
 	if notification.SenderID.IsZero() {
 		log.Printf("[chat-notification-all] Missing SenderID")
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindInvalid, errors.New("missing sender_id"), 1)
 		return
 	}
 
@@ -384,13 +618,13 @@ func (c *chatConsumer) processNotificationAll(ctx context.Context, sess sarama.C
 	allUserIDs, err := userBiz.GetAllUserIDs(ctx)
 	if err != nil {
 		log.Printf("[chat-notification-all] Failed to get all user IDs: %v", err)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindExhausted, err, 1)
 		return
 	}
 
 	if len(allUserIDs) == 0 {
 		log.Println("[chat-notification-all] No users in system")
-		sess.MarkMessage(msg, "")
+		c.commitQueue <- &commitTask{session: sess, message: msg}
 		return
 	}
 
@@ -434,6 +668,9 @@ func (c *chatConsumer) processNotificationAll(ctx context.Context, sess sarama.C
 			models.ReplyMessageMini{}, // zero value – no reply
 			&models.Task{},
 			"",
+			"",
+			nil,
+			nil,
 		)
 
 		if err != nil {
@@ -458,16 +695,16 @@ func (c *chatConsumer) processGroupMember(ctx context.Context, sess sarama.Consu
 
 	if err := json.Unmarshal(msg.Value, &payload); err != nil {
 		log.Printf("[group-member] Unmarshal error: %v | payload: %s", err, string(msg.Value))
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
 	// Biz / Storage
 	groupStore := StorageGroup.NewMongoStoreGroup(c.db)
 	groupBiz := BizGroup.CreateGroupMemberStorage(groupStore)
-	fmt.Println("add-group-member Kaffka", payload)
 
 	// Iterate through all members
+	var failedErr error
 	for _, m := range payload.Members {
 		roleCode := m.Role
 		if roleCode == "" || roleCode == "member" {
@@ -502,10 +739,17 @@ func (c *chatConsumer) processGroupMember(ctx context.Context, sess sarama.Consu
 
 		if rbErr != nil {
 			log.Printf("[group-member] RBAC Sync FAILED after retries for user %s: %v", m.UserID.Hex(), rbErr)
+			failedErr = fmt.Errorf("rbac sync for user %s: %w", m.UserID.Hex(), rbErr)
 			continue
 		}
 	}
 
+	// Có member chưa đồng bộ được -> DLQ để replay (tạo member / cập nhật role đều idempotent)
+	if failedErr != nil {
+		c.deadLetter(sess, msg, DLQKindExhausted, failedErr, 3)
+		return
+	}
+
 	// If all successful -> commit Kafka
 	c.commitQueue <- &commitTask{
 		session: sess,
@@ -519,7 +763,7 @@ func (c *chatConsumer) processUnPinnedMessage(ctx context.Context, sess sarama.C
 
 	if err := json.Unmarshal(msg.Value, &payload); err != nil {
 		log.Printf("[recall-message] Unmarshal error: %v | payload: %s", err, string(msg.Value))
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 	// Biz
@@ -529,7 +773,6 @@ func (c *chatConsumer) processUnPinnedMessage(ctx context.Context, sess sarama.C
 	MessageID, _ := primitive.ObjectIDFromHex(payload.MessageID)
 	ConversationID, _ := primitive.ObjectIDFromHex(payload.ConversationID)
 
-	fmt.Println("ConversationID Kaffka", ConversationID)
 	// Retry 3 times
 	var recallErr error
 	for attempt := 0; attempt < 3; attempt++ {
@@ -550,6 +793,7 @@ func (c *chatConsumer) processUnPinnedMessage(ctx context.Context, sess sarama.C
 	}
 
 	log.Printf("[pinned-message] FAILED after retries: %v", recallErr)
+	c.deadLetter(sess, msg, DLQKindExhausted, recallErr, 3)
 }
 
 func (c *chatConsumer) processPinnedMessage(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
@@ -557,7 +801,7 @@ func (c *chatConsumer) processPinnedMessage(ctx context.Context, sess sarama.Con
 
 	if err := json.Unmarshal(msg.Value, &payload); err != nil {
 		log.Printf("[pinned-message] Unmarshal error: %v | payload: %s", err, string(msg.Value))
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
@@ -565,14 +809,14 @@ func (c *chatConsumer) processPinnedMessage(ctx context.Context, sess sarama.Con
 	messageID, errMsg := primitive.ObjectIDFromHex(payload.MessageID)
 	if errMsg != nil {
 		log.Printf("[pinned-message] Invalid MessageID: %v", errMsg)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindInvalid, fmt.Errorf("invalid message_id: %w", errMsg), 1)
 		return
 	}
 
 	pinnedByID, errPin := primitive.ObjectIDFromHex(payload.PinnedByID)
 	if errPin != nil {
 		log.Printf("[pinned-message] Invalid PinnedByID: %v", errPin)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindInvalid, fmt.Errorf("invalid pinned_by_id: %w", errPin), 1)
 		return
 	}
 
@@ -589,14 +833,14 @@ func (c *chatConsumer) processPinnedMessage(ctx context.Context, sess sarama.Con
 		// Priority 2: No GroupID -> personal chat -> use ConversationID (string)
 		if payload.ConversationID == "" {
 			log.Printf("[pinned-message] Missing both GroupID and ConversationID")
-			sess.MarkMessage(msg, "")
+			c.deadLetter(sess, msg, DLQKindInvalid, errors.New("missing both group_id and conversation_id"), 1)
 			return
 		}
 
 		convIDFromHex, errConv = primitive.ObjectIDFromHex(payload.ConversationID)
 		if errConv != nil {
 			log.Printf("[pinned-message] Invalid ConversationID format: %v", errConv)
-			sess.MarkMessage(msg, "")
+			c.deadLetter(sess, msg, DLQKindInvalid, fmt.Errorf("invalid conversation_id: %w", errConv), 1)
 			return
 		}
 		targetConvID = convIDFromHex
@@ -629,7 +873,7 @@ func (c *chatConsumer) processPinnedMessage(ctx context.Context, sess sarama.Con
 	// Failed after 3 retries
 	log.Printf("[pinned-message] FAILED after 3 retries for message %s in conv %s: %v",
 		payload.MessageID, targetConvID.Hex(), pinErr)
-	sess.MarkMessage(msg, "") // Still mark to avoid perpetual reconsumption
+	c.deadLetter(sess, msg, DLQKindExhausted, pinErr, 3)
 }
 
 func (c *chatConsumer) processRecallMessage(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
@@ -637,7 +881,11 @@ func (c *chatConsumer) processRecallMessage(ctx context.Context, sess sarama.Con
 
 	if err := json.Unmarshal(msg.Value, &payload); err != nil {
 		log.Printf("[recall-message] Unmarshal error: %v | payload: %s", err, string(msg.Value))
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
+		return
+	}
+	if payload.RecalledBy == nil {
+		c.deadLetter(sess, msg, DLQKindInvalid, errors.New("missing recalled_by"), 1)
 		return
 	}
 	// Biz
@@ -665,13 +913,29 @@ func (c *chatConsumer) processRecallMessage(ctx context.Context, sess sarama.Con
 	}
 
 	log.Printf("[recall-message] FAILED after retries: %v", recallErr)
-	// ❗ Do not commit -> Kafka will deliver this message again
+	c.deadLetter(sess, msg, DLQKindExhausted, recallErr, 3)
 }
 
 func (c *chatConsumer) processChatMessage(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
 	var chatMsg models.MessageResponse
 	if err := json.Unmarshal(msg.Value, &chatMsg); err != nil {
 		log.Printf(" Unmarshal error: %v", err)
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
+		return
+	}
+
+	// Tin nhắn giao việc từ websocket không có header, dùng key theo task để trùng với bản outbox;
+	// tin nhắn có client_message_id dùng key theo cuộc trò chuyện để bỏ các bản client gửi lại
+	key := idempotencyKey(msg)
+	if key == "" && chatMsg.Type == models.MediaTypeTask && chatMsg.Task != nil {
+		key = models.TaskMessageKey(chatMsg.Task.ID, chatMsg.ReceiverID, chatMsg.GroupID)
+	}
+	if key == "" {
+		if clientID := models.NormalizeClientMessageID(chatMsg.ClientMessageID); clientID != "" {
+			key = models.ClientMessageKey(chatMsg.SenderID, chatMsg.ReceiverID, chatMsg.GroupID, clientID)
+		}
+	}
+	if !c.claimEvent(ctx, sess, msg, key) {
 		return
 	}
 
@@ -683,12 +947,14 @@ func (c *chatConsumer) processUpdateStatus(ctx context.Context, sess sarama.Cons
 	var statusMsg models.MessageStatusRequest
 	if err := json.Unmarshal(msg.Value, &statusMsg); err != nil {
 		log.Printf(" Unmarshal error: %v", err)
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
 	// Process synchronously với retry
+	var err error
 	for retry := 0; retry < 3; retry++ {
-		err := c.updateStatusWithRetry(ctx, &statusMsg)
+		err = c.updateStatusWithRetry(ctx, &statusMsg)
 		if err == nil {
 			c.commitQueue <- &commitTask{session: sess, message: msg}
 			return
@@ -698,7 +964,8 @@ func (c *chatConsumer) processUpdateStatus(ctx context.Context, sess sarama.Cons
 		}
 	}
 
-	log.Printf(" Failed to update status after 3 retries")
+	log.Printf(" Failed to update status after 3 retries: %v", err)
+	c.deadLetter(sess, msg, DLQKindExhausted, err, 3)
 }
 
 func (c *chatConsumer) processUserStatus(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
@@ -706,6 +973,7 @@ func (c *chatConsumer) processUserStatus(ctx context.Context, sess sarama.Consum
 
 	if err := json.Unmarshal(msg.Value, &userStatus); err != nil {
 		log.Println("Unmarshal error:", err)
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
@@ -730,19 +998,21 @@ func (c *chatConsumer) processUserStatus(ctx context.Context, sess sarama.Consum
 	}
 
 	log.Printf("Update user_status error after 3 retries: %v", err)
-	// ❗ Do not commit -> message will reprocess
+	c.deadLetter(sess, msg, DLQKindExhausted, err, 3)
 }
 
 func (c *chatConsumer) processGroupOut(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
 	var groupOut models.MessageResponse
 	if err := json.Unmarshal(msg.Value, &groupOut); err != nil {
 		log.Println("Kafka Unmarshal error (group-out):", err)
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
 	// Validate mandatory data
 	if groupOut.GroupID.IsZero() || groupOut.SenderID.IsZero() {
 		log.Printf("Invalid group-out payload (missing id): %s\n", string(msg.Value))
+		c.deadLetter(sess, msg, DLQKindInvalid, errors.New("missing group_id or sender_id"), 1)
 		return
 	}
 
@@ -773,14 +1043,15 @@ func (c *chatConsumer) processGroupOut(ctx context.Context, sess sarama.Consumer
 		}
 	}
 	log.Printf("CRITICAL: Failed to remove member %s from group %s after 3 retries: %v", groupOut.SenderID.Hex(), groupOut.GroupID.Hex(), err)
+	c.deadLetter(sess, msg, DLQKindExhausted, err, 3)
 }
 
 func (c *chatConsumer) processDeleteMessageForMe(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
 	var delMsg models.DeleteMessageForMe
 	if err := json.Unmarshal(msg.Value, &delMsg); err != nil {
 		log.Printf("[delete-message-for-me] Unmarshal error: %v | payload: %s", err, string(msg.Value))
-		// Incorrect data -> cannot process -> commit to avoid infinite loop
-		sess.MarkMessage(msg, "")
+		// Incorrect data -> cannot process -> DLQ to avoid infinite loop
+		c.deadLetter(sess, msg, DLQKindUnmarshal, err, 1)
 		return
 	}
 
@@ -788,7 +1059,7 @@ func (c *chatConsumer) processDeleteMessageForMe(ctx context.Context, sess saram
 	userID, err := primitive.ObjectIDFromHex(delMsg.UserID)
 	if err != nil {
 		log.Printf("[delete-message-for-me] Invalid UserID: %s | err: %v", delMsg.UserID, err)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindInvalid, fmt.Errorf("invalid user_id: %w", err), 1)
 		return
 	}
 
@@ -808,7 +1079,7 @@ func (c *chatConsumer) processDeleteMessageForMe(ctx context.Context, sess saram
 	// If all IDs are errors or none are valid -> commit immediately
 	if hasInvalid && len(messageIDs) == 0 {
 		log.Printf("[delete-message-for-me] No valid MessageIDs from user %s", delMsg.UserID)
-		sess.MarkMessage(msg, "")
+		c.deadLetter(sess, msg, DLQKindInvalid, errors.New("no valid message_ids"), 1)
 		return
 	}
 
@@ -838,9 +1109,9 @@ func (c *chatConsumer) processDeleteMessageForMe(ctx context.Context, sess saram
 		}
 	}
 
-	// After 3 failed attempts -> DO NOT commit -> Kafka will re-deliver
-	log.Printf("[delete-message-for-me] CRITICAL: User %s failed to delete messages after 3 retries -> will be reprocessed", delMsg.UserID)
-	// No MarkMessage -> message will be re-consumed on next rebalance
+	// After 3 failed attempts -> DLQ, replay khi DB ổn định lại
+	log.Printf("[delete-message-for-me] CRITICAL: User %s failed to delete messages after 3 retries -> dead-lettered", delMsg.UserID)
+	c.deadLetter(sess, msg, DLQKindExhausted, deleteErr, 3)
 }
 
 func (c *chatConsumer) updateStatusWithRetry(ctx context.Context, statusMsg *models.MessageStatusRequest) error {
@@ -869,8 +1140,13 @@ func (c *chatConsumer) updateStatusWithRetry(ctx context.Context, statusMsg *mod
 
 func (c *chatConsumer) Shutdown() {
 	log.Println(" Shutting down consumer...")
-	c.wg.Wait()              // Wait for all workers
+	c.wg.Wait() // Wait for all workers
+	c.lanes.close()
 	c.batchProcessor.Close() // Wait for batch processor
 	close(c.commitQueue)     // Close commit queue
+	<-c.commitDone
+	if err := c.deadLetters.Close(); err != nil {
+		log.Printf(" Close dead-letter producer: %v", err)
+	}
 	log.Println("Consumer shutdown gracefully")
 }
@@ -0,0 +1,181 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// DeadLetterSuffix - mỗi topic có một DLQ riêng: chat-topic -> chat-topic.dlq
+const DeadLetterSuffix = ".dlq"
+
+// Header ghi lý do và số lần thử của message bị chuyển sang DLQ
+const (
+	HeaderDLQOriginalTopic     = "dlq-original-topic"
+	HeaderDLQOriginalPartition = "dlq-original-partition"
+	HeaderDLQOriginalOffset    = "dlq-original-offset"
+	HeaderDLQKind              = "dlq-kind"
+	HeaderDLQReason            = "dlq-reason"
+	HeaderDLQAttempts          = "dlq-attempts"
+	HeaderDLQFailedAt          = "dlq-failed-at"
+	HeaderDLQReplayCount       = "dlq-replay-count"
+)
+
+// Phân loại lỗi để lọc khi xem / replay
+const (
+	DLQKindUnmarshal = "unmarshal"         // payload không đọc được
+	DLQKindInvalid   = "invalid"           // payload thiếu / sai dữ liệu bắt buộc
+	DLQKindExhausted = "retries_exhausted" // lỗi xử lý sau khi đã retry
+)
+
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterSuffix
+}
+
+// IsDeadLetterTopic cho biết topic có phải DLQ không, tránh replay nhầm DLQ vào chính nó
+func IsDeadLetterTopic(topic string) bool {
+	return strings.HasSuffix(topic, DeadLetterSuffix)
+}
+
+// DeadLetter - một bản ghi trong DLQ kèm metadata lỗi
+type DeadLetter struct {
+	Topic             string    `json:"topic"`
+	Partition         int32     `json:"partition"`
+	Offset            int64     `json:"offset"`
+	OriginalTopic     string    `json:"original_topic"`
+	OriginalPartition int32     `json:"original_partition"`
+	OriginalOffset    int64     `json:"original_offset"`
+	Key               string    `json:"key"`
+	Value             string    `json:"value"`
+	Kind              string    `json:"kind"`
+	Reason            string    `json:"reason"`
+	Attempts          int       `json:"attempts"`
+	ReplayCount       int       `json:"replay_count"`
+	FailedAt          time.Time `json:"failed_at"`
+
+	headers []*sarama.RecordHeader
+}
+
+// messageSender - phần của sarama.SyncProducer mà DLQ cần, bus nội bộ cũng cung cấp được (busProducer)
+type messageSender interface {
+	SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+	Close() error
+}
+
+// DeadLetterPublisher ghi DLQ bằng producer đồng bộ: chỉ commit message gốc khi DLQ đã nhận
+type DeadLetterPublisher struct {
+	producer messageSender
+}
+
+func NewDeadLetterPublisher(brokers []string) (*DeadLetterPublisher, error) {
+	producer, err := newSyncProducer(brokers)
+	if err != nil {
+		return nil, fmt.Errorf("create dead-letter producer: %w", err)
+	}
+	return &DeadLetterPublisher{producer: producer}, nil
+}
+
+// newDeadLetterPublisherFor - Kafka dùng producer đồng bộ riêng, bus nội bộ ghi DLQ vào chính bus
+func newDeadLetterPublisherFor(b EventBus) (*DeadLetterPublisher, error) {
+	if kb, ok := b.(*kafkaBus); ok {
+		return NewDeadLetterPublisher(kb.brokers)
+	}
+	return &DeadLetterPublisher{producer: busProducer{bus: b}}, nil
+}
+
+func newSyncProducer(brokers []string) (sarama.SyncProducer, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Retry.Backoff = 200 * time.Millisecond
+	config.Producer.MaxMessageBytes = 10 * 1024 * 1024
+	return sarama.NewSyncProducer(brokers, config)
+}
+
+// Publish chuyển message lỗi sang DLQ của topic gốc, giữ nguyên key và header cũ
+func (p *DeadLetterPublisher) Publish(msg *sarama.ConsumerMessage, kind string, reason error, attempts int) error {
+	reasonText := ""
+	if reason != nil {
+		reasonText = reason.Error()
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+7)
+	for _, h := range msg.Headers {
+		// Metadata lỗi cũ (nếu message từng vào DLQ) được thay bằng lần lỗi này, chỉ giữ số lần replay
+		if h == nil || (strings.HasPrefix(string(h.Key), "dlq-") && string(h.Key) != HeaderDLQReplayCount) {
+			continue
+		}
+		headers = append(headers, *h)
+	}
+	headers = append(headers,
+		header(HeaderDLQOriginalTopic, msg.Topic),
+		header(HeaderDLQOriginalPartition, strconv.FormatInt(int64(msg.Partition), 10)),
+		header(HeaderDLQOriginalOffset, strconv.FormatInt(msg.Offset, 10)),
+		header(HeaderDLQKind, kind),
+		header(HeaderDLQReason, reasonText),
+		header(HeaderDLQAttempts, strconv.Itoa(attempts)),
+		header(HeaderDLQFailedAt, time.Now().UTC().Format(time.RFC3339Nano)),
+	)
+
+	_, _, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   DeadLetterTopic(msg.Topic),
+		Key:     sarama.ByteEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	})
+	return err
+}
+
+func (p *DeadLetterPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// ParseDeadLetter đọc metadata từ header của một message trong DLQ
+func ParseDeadLetter(msg *sarama.ConsumerMessage) DeadLetter {
+	dl := DeadLetter{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Value:     string(msg.Value),
+		headers:   msg.Headers,
+	}
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		value := string(h.Value)
+		switch string(h.Key) {
+		case HeaderDLQOriginalTopic:
+			dl.OriginalTopic = value
+		case HeaderDLQOriginalPartition:
+			p, _ := strconv.ParseInt(value, 10, 32)
+			dl.OriginalPartition = int32(p)
+		case HeaderDLQOriginalOffset:
+			dl.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderDLQKind:
+			dl.Kind = value
+		case HeaderDLQReason:
+			dl.Reason = value
+		case HeaderDLQAttempts:
+			dl.Attempts, _ = strconv.Atoi(value)
+		case HeaderDLQReplayCount:
+			dl.ReplayCount, _ = strconv.Atoi(value)
+		case HeaderDLQFailedAt:
+			dl.FailedAt, _ = time.Parse(time.RFC3339Nano, value)
+		}
+	}
+	if dl.OriginalTopic == "" {
+		dl.OriginalTopic = strings.TrimSuffix(msg.Topic, DeadLetterSuffix)
+	}
+	return dl
+}
+
+func header(key, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+}
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterAdmin đọc DLQ để xem / sửa / replay về topic gốc (dùng cho cmd/dlq)
+type DeadLetterAdmin struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+	producer sarama.SyncProducer
+}
+
+func NewDeadLetterAdmin(brokers []string) (*DeadLetterAdmin, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Return.Errors = true
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka client: %w", err)
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("create kafka consumer: %w", err)
+	}
+	producer, err := newSyncProducer(brokers)
+	if err != nil {
+		consumer.Close()
+		client.Close()
+		return nil, fmt.Errorf("create kafka producer: %w", err)
+	}
+	return &DeadLetterAdmin{client: client, consumer: consumer, producer: producer}, nil
+}
+
+// List đọc DLQ của topic (nhận cả tên topic gốc lẫn tên DLQ) từ đầu đến offset hiện tại
+func (a *DeadLetterAdmin) List(topic string, match func(DeadLetter) bool) ([]DeadLetter, error) {
+	dlqTopic := dlqTopicName(topic)
+	partitions, err := a.client.Partitions(dlqTopic)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions of %s: %w", dlqTopic, err)
+	}
+
+	var letters []DeadLetter
+	for _, partition := range partitions {
+		oldest, err := a.client.GetOffset(dlqTopic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, err
+		}
+		newest, err := a.client.GetOffset(dlqTopic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+		if newest <= oldest {
+			continue
+		}
+
+		err = a.readPartition(dlqTopic, partition, oldest, newest, func(msg *sarama.ConsumerMessage) {
+			dl := ParseDeadLetter(msg)
+			if match == nil || match(dl) {
+				letters = append(letters, dl)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return letters, nil
+}
+
+// Get đọc một bản ghi DLQ theo partition/offset
+func (a *DeadLetterAdmin) Get(topic string, partition int32, offset int64) (*DeadLetter, error) {
+	dlqTopic := dlqTopicName(topic)
+	newest, err := a.client.GetOffset(dlqTopic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset >= newest {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	var found *DeadLetter
+	err = a.readPartition(dlqTopic, partition, offset, offset+1, func(msg *sarama.ConsumerMessage) {
+		if msg.Offset == offset {
+			dl := ParseDeadLetter(msg)
+			found = &dl
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	return found, nil
+}
+
+// Replay gửi lại bản ghi về topic gốc với cùng key (giữ thứ tự theo partition).
+// value khác nil là payload đã sửa; header dlq-replay-count tăng lên để biết message bị lỗi lặp lại.
+func (a *DeadLetterAdmin) Replay(dl *DeadLetter, value []byte) error {
+	if dl.OriginalTopic == "" || IsDeadLetterTopic(dl.OriginalTopic) {
+		return fmt.Errorf("invalid original topic %q", dl.OriginalTopic)
+	}
+	if value == nil {
+		value = []byte(dl.Value)
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(dl.headers)+1)
+	for _, h := range dl.headers {
+		if h == nil || strings.HasPrefix(string(h.Key), "dlq-") {
+			continue
+		}
+		headers = append(headers, *h)
+	}
+	headers = append(headers, header(HeaderDLQReplayCount, strconv.Itoa(dl.ReplayCount+1)))
+
+	msg := &sarama.ProducerMessage{
+		Topic:   dl.OriginalTopic,
+		Value:   sarama.ByteEncoder(value),
+		Headers: headers,
+	}
+	if dl.Key != "" {
+		msg.Key = sarama.StringEncoder(dl.Key)
+	}
+	_, _, err := a.producer.SendMessage(msg)
+	return err
+}
+
+// Send gửi một message thô, dùng khi replay file spool
+func (a *DeadLetterAdmin) Send(topic, key, value string) error {
+	_, _, err := a.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.StringEncoder(value),
+	})
+	return err
+}
+
+func (a *DeadLetterAdmin) Close() {
+	_ = a.producer.Close()
+	_ = a.consumer.Close()
+	_ = a.client.Close()
+}
+
+// readPartition đọc [from, to) của một partition; dừng nếu broker không trả thêm message trong 10s
+func (a *DeadLetterAdmin) readPartition(topic string, partition int32, from, to int64, fn func(*sarama.ConsumerMessage)) error {
+	pc, err := a.consumer.ConsumePartition(topic, partition, from)
+	if err != nil {
+		return fmt.Errorf("consume %s/%d: %w", topic, partition, err)
+	}
+	defer pc.Close()
+
+	for {
+		select {
+		case msg := <-pc.Messages():
+			fn(msg)
+			if msg.Offset >= to-1 {
+				return nil
+			}
+		case err := <-pc.Errors():
+			return err
+		case <-time.After(10 * time.Second):
+			return fmt.Errorf("timeout reading %s/%d", topic, partition)
+		}
+	}
+}
+
+func dlqTopicName(topic string) string {
+	if IsDeadLetterTopic(topic) {
+		return topic
+	}
+	return DeadLetterTopic(topic)
+}
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log"
+	"my-app/common/outbox"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const processedEventsCollection = "processed_events"
+
+// Lượt xử lý giữ key quá thời gian này coi như worker đã chết, message giao lại được xử lý tiếp
+const eventClaimStale = 5 * time.Minute
+
+// eventTracker bảo đảm mỗi idempotency key chỉ được xử lý thành công một lần:
+// nhận key trước khi xử lý, commit thì đánh dấu done, chuyển DLQ / lỗi thì nhả key để replay được.
+type eventTracker struct {
+	col    *mongo.Collection
+	claims sync.Map // *sarama.ConsumerMessage -> idempotency key
+}
+
+func newEventTracker(db *mongo.Database) *eventTracker {
+	return &eventTracker{col: db.Collection(processedEventsCollection)}
+}
+
+func idempotencyKey(msg *sarama.ConsumerMessage) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == outbox.HeaderIdempotencyKey {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+type claimState int
+
+const (
+	claimAcquired claimState = iota // được xử lý
+	claimDone                       // key đã xử lý xong, commit và bỏ qua
+	claimInFlight                   // key đang được xử lý ở nơi khác, chưa được commit
+)
+
+// Không đọc được trạng thái key đang giữ thì thử lại sau khoảng này
+const eventClaimRetry = 10 * time.Second
+
+// claim nhận key trước khi xử lý. Key đang được xử lý ở nơi khác (claimInFlight) trả kèm thời gian
+// tới khi lượt giữ đó hết hạn, caller thử lại sau khoảng đó thay vì commit offset.
+func (t *eventTracker) claim(ctx context.Context, msg *sarama.ConsumerMessage, key string) (claimState, time.Duration) {
+	if key == "" {
+		return claimAcquired, 0
+	}
+
+	now := time.Now()
+	_, err := t.col.UpdateOne(ctx, bson.M{
+		"_id":        key,
+		"status":     "processing",
+		"claimed_at": bson.M{"$lt": now.Add(-eventClaimStale)},
+	}, bson.M{"$set": bson.M{
+		"status":     "processing",
+		"topic":      msg.Topic,
+		"claimed_at": now,
+	}}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return t.holder(ctx, key, now)
+	}
+	if err != nil {
+		// Không kiểm tra được thì vẫn xử lý (at-least-once), handler nghiệp vụ chịu được giao lại
+		log.Printf("⚠️ [Consumer] Claim idempotency key %s lỗi: %v", key, err)
+		return claimAcquired, 0
+	}
+
+	t.claims.Store(msg, key)
+	return claimAcquired, 0
+}
+
+// holder đọc trạng thái key đang tồn tại: đã done thì bỏ qua, còn processing thì chờ tới khi lượt giữ hết hạn
+func (t *eventTracker) holder(ctx context.Context, key string, now time.Time) (claimState, time.Duration) {
+	var doc struct {
+		Status    string    `bson:"status"`
+		ClaimedAt time.Time `bson:"claimed_at"`
+	}
+	err := t.col.FindOne(ctx, bson.M{"_id": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Key vừa được nhả (DLQ lỗi), thử lại ngay lượt sau
+		return claimInFlight, time.Second
+	}
+	if err != nil {
+		log.Printf("⚠️ [Consumer] Đọc idempotency key %s lỗi: %v", key, err)
+		return claimInFlight, eventClaimRetry
+	}
+	if doc.Status == "done" {
+		return claimDone, 0
+	}
+	return claimInFlight, max(doc.ClaimedAt.Add(eventClaimStale).Sub(now), time.Second)
+}
+
+// release nhả key của message không được commit (chưa ghi được DLQ) để lần giao lại xử lý được
+func (t *eventTracker) release(msg *sarama.ConsumerMessage) {
+	key, ok := t.claims.LoadAndDelete(msg)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := t.col.DeleteOne(ctx, bson.M{"_id": key, "status": "processing"}); err != nil {
+		log.Printf("⚠️ [Consumer] Release idempotency key %v lỗi: %v", key, err)
+	}
+}
+
+// settle chạy trước khi commit offset: key của message thành công thành done, message vào DLQ thì nhả key
+func (t *eventTracker) settle(tasks []*commitTask) {
+	var done, released []string
+	for _, task := range tasks {
+		key, ok := t.claims.LoadAndDelete(task.message)
+		if !ok {
+			continue
+		}
+		if task.deadLettered {
+			released = append(released, key.(string))
+		} else {
+			done = append(done, key.(string))
+		}
+	}
+	if len(done) == 0 && len(released) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if len(done) > 0 {
+		if _, err := t.col.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": done}}, bson.M{
+			"$set": bson.M{"status": "done", "done_at": time.Now()},
+		}); err != nil {
+			log.Printf("⚠️ [Consumer] Mark %d idempotency keys done lỗi: %v", len(done), err)
+		}
+	}
+	if len(released) > 0 {
+		if _, err := t.col.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": released}}); err != nil {
+			log.Printf("⚠️ [Consumer] Release %d idempotency keys lỗi: %v", len(released), err)
+		}
+	}
+}
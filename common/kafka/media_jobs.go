@@ -0,0 +1,17 @@
+package kafka
+
+import (
+	"encoding/json"
+	"log"
+	"my-app/modules/chat/models"
+)
+
+// EnqueueMediaProcessing gửi job tạo thumbnail / WebP / poster cho consumer "media-processing-topic"
+func EnqueueMediaProcessing(mediaID string) {
+	job, _ := json.Marshal(models.MediaProcessingJob{MediaID: mediaID})
+	go func() {
+		if err := SendMessageAsync("media-processing-topic", mediaID, string(job)); err != nil {
+			log.Printf("⚠️ Không gửi được job xử lý media %s: %v", mediaID, err)
+		}
+	}()
+}
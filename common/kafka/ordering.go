@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// laneKey - khóa thứ tự của message: cùng topic + partition key thì xử lý tuần tự.
+// Message không có key chỉ giữ thứ tự theo partition.
+func laneKey(msg *sarama.ConsumerMessage) string {
+	if len(msg.Key) > 0 {
+		return msg.Topic + "/k/" + string(msg.Key)
+	}
+	return msg.Topic + "/p/" + strconv.Itoa(int(msg.Partition))
+}
+
+type laneTask struct {
+	session sarama.ConsumerGroupSession
+	message *sarama.ConsumerMessage
+}
+
+// keyedLanes chia message vào các lane cố định theo hash của laneKey. Mỗi lane là một goroutine
+// xử lý tuần tự, nên message cùng key giữ đúng thứ tự offset; các key khác nhau chạy song song.
+type keyedLanes struct {
+	lanes  []chan laneTask
+	handle func(sarama.ConsumerGroupSession, *sarama.ConsumerMessage)
+	wg     sync.WaitGroup
+}
+
+func newKeyedLanes(count, buffer int, handle func(sarama.ConsumerGroupSession, *sarama.ConsumerMessage)) *keyedLanes {
+	l := &keyedLanes{
+		lanes:  make([]chan laneTask, max(1, count)),
+		handle: handle,
+	}
+	for i := range l.lanes {
+		l.lanes[i] = make(chan laneTask, buffer)
+		l.wg.Add(1)
+		go l.run(l.lanes[i])
+	}
+	return l
+}
+
+func (l *keyedLanes) run(lane chan laneTask) {
+	defer l.wg.Done()
+	for task := range lane {
+		l.handle(task.session, task.message)
+	}
+}
+
+func (l *keyedLanes) laneOf(msg *sarama.ConsumerMessage) int {
+	h := fnv.New32a()
+	h.Write([]byte(laneKey(msg)))
+	return int(h.Sum32() % uint32(len(l.lanes)))
+}
+
+// dispatch chặn khi lane đầy (backpressure lên ConsumeClaim)
+func (l *keyedLanes) dispatch(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+	l.lanes[l.laneOf(msg)] <- laneTask{session: sess, message: msg}
+}
+
+// close chờ các lane xử lý hết message đã nhận
+func (l *keyedLanes) close() {
+	for _, lane := range l.lanes {
+		close(lane)
+	}
+	l.wg.Wait()
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets - các offset đã giao cho lane theo thứ tự, và offset nào đã xử lý xong
+type partitionOffsets struct {
+	session sarama.ConsumerGroupSession
+	pending []int64
+	done    map[int64]*sarama.ConsumerMessage
+}
+
+// offsetTracker chỉ cho commit offset liên tục: message offset 10 xong trước offset 5 (khác key)
+// thì phải chờ offset 5 xong mới được mark, tránh mất offset 5 khi crash / rebalance.
+// Message không được commit (chưa ghi được DLQ) giữ partition ở offset đó tới khi Kafka giao lại.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[topicPartition]*partitionOffsets)}
+}
+
+// track ghi nhận message theo thứ tự nhận từ claim; session mới (sau rebalance) thì bỏ trạng thái cũ
+func (t *offsetTracker) track(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tp := topicPartition{msg.Topic, msg.Partition}
+	p := t.partitions[tp]
+	if p == nil || p.session != sess {
+		p = &partitionOffsets{session: sess, done: make(map[int64]*sarama.ConsumerMessage)}
+		t.partitions[tp] = p
+	}
+	p.pending = append(p.pending, msg.Offset)
+}
+
+// complete đánh dấu message đã xử lý xong. Trả về message có offset cao nhất mà mọi offset trước nó
+// cũng đã xong (nil nếu chưa tiến được) cùng session hiện tại của partition.
+func (t *offsetTracker) complete(msg *sarama.ConsumerMessage) (sarama.ConsumerGroupSession, *sarama.ConsumerMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.partitions[topicPartition{msg.Topic, msg.Partition}]
+	if p == nil || len(p.pending) == 0 || msg.Offset < p.pending[0] {
+		// Message của session cũ hoặc đã commit
+		return nil, nil
+	}
+	p.done[msg.Offset] = msg
+
+	var last *sarama.ConsumerMessage
+	for len(p.pending) > 0 {
+		m, ok := p.done[p.pending[0]]
+		if !ok {
+			break
+		}
+		delete(p.done, p.pending[0])
+		p.pending = p.pending[1:]
+		last = m
+	}
+	if last == nil {
+		return nil, nil
+	}
+	return p.session, last
+}
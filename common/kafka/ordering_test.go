@@ -0,0 +1,281 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeSession ghi lại các offset được mark / commit thay cho session thật của consumer group
+type fakeSession struct {
+	mu      sync.Mutex
+	marked  map[topicPartition][]int64
+	commits int
+
+	// onMark chạy khi MarkMessage, dùng để kiểm tra bất biến tại thời điểm mark
+	onMark func(msg *sarama.ConsumerMessage)
+}
+
+func newFakeSession() *fakeSession {
+	return &fakeSession{marked: make(map[topicPartition][]int64)}
+}
+
+func (s *fakeSession) Claims() map[string][]int32 { return nil }
+func (s *fakeSession) MemberID() string           { return "fake-member" }
+func (s *fakeSession) GenerationID() int32        { return 1 }
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+}
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+}
+func (s *fakeSession) Context() context.Context { return context.Background() }
+
+func (s *fakeSession) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commits++
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	if s.onMark != nil {
+		s.onMark(msg)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tp := topicPartition{msg.Topic, msg.Partition}
+	s.marked[tp] = append(s.marked[tp], msg.Offset)
+}
+
+func (s *fakeSession) lastMarked(topic string, partition int32) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets := s.marked[topicPartition{topic, partition}]
+	if len(offsets) == 0 {
+		return 0, false
+	}
+	return offsets[len(offsets)-1], true
+}
+
+type fakeClaim struct {
+	topic     string
+	partition int32
+	messages  chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return c.topic }
+func (c *fakeClaim) Partition() int32                         { return c.partition }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return int64(cap(c.messages)) }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func newFakeClaim(topic string, partition int32, keys []string) *fakeClaim {
+	claim := &fakeClaim{topic: topic, partition: partition, messages: make(chan *sarama.ConsumerMessage, len(keys))}
+	for i, key := range keys {
+		claim.messages <- &sarama.ConsumerMessage{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    int64(i),
+			Key:       []byte(key),
+		}
+	}
+	close(claim.messages)
+	return claim
+}
+
+func TestOffsetTracker_MarksOnlyContiguousOffsets(t *testing.T) {
+	sess := newFakeSession()
+	tracker := newOffsetTracker()
+
+	msgs := make([]*sarama.ConsumerMessage, 5)
+	for i := range msgs {
+		msgs[i] = &sarama.ConsumerMessage{Topic: "chat-topic", Partition: 0, Offset: int64(i)}
+		tracker.track(sess, msgs[i])
+	}
+
+	steps := []struct {
+		complete int
+		want     int64 // -1: chưa được mark
+	}{
+		{2, -1},
+		{0, 0},
+		{1, 2},
+		{4, -1},
+		{3, 4},
+	}
+	for _, step := range steps {
+		gotSess, got := tracker.complete(msgs[step.complete])
+		if step.want < 0 {
+			if got != nil {
+				t.Fatalf("complete(%d): expected nothing to mark, got offset %d", step.complete, got.Offset)
+			}
+			continue
+		}
+		if got == nil || got.Offset != step.want {
+			t.Fatalf("complete(%d): expected offset %d, got %v", step.complete, step.want, got)
+		}
+		if gotSess != sess {
+			t.Fatalf("complete(%d): expected tracked session", step.complete)
+		}
+	}
+}
+
+func TestOffsetTracker_NewSessionDropsOldState(t *testing.T) {
+	oldSess, newSess := newFakeSession(), newFakeSession()
+	tracker := newOffsetTracker()
+
+	stuck := &sarama.ConsumerMessage{Topic: "chat-topic", Partition: 0, Offset: 10}
+	tracker.track(oldSess, stuck)
+	tracker.track(oldSess, &sarama.ConsumerMessage{Topic: "chat-topic", Partition: 0, Offset: 11})
+
+	// Sau rebalance Kafka giao lại từ offset 10 cho session mới
+	redelivered := &sarama.ConsumerMessage{Topic: "chat-topic", Partition: 0, Offset: 10}
+	tracker.track(newSess, redelivered)
+
+	sess, got := tracker.complete(redelivered)
+	if got == nil || got.Offset != 10 || sess != newSess {
+		t.Fatalf("expected offset 10 marked on new session, got %v", got)
+	}
+}
+
+func TestGroupByLane_KeepsOrderWithinKey(t *testing.T) {
+	keys := []string{"a", "b", "a", "c", "b", "a"}
+	var messages []messageWithCommit
+	for i, key := range keys {
+		messages = append(messages, messageWithCommit{kafkaMsg: &sarama.ConsumerMessage{
+			Topic: "chat-topic", Offset: int64(i), Key: []byte(key),
+		}})
+	}
+
+	groups := groupByLane(messages)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+
+	want := map[string][]int64{"a": {0, 2, 5}, "b": {1, 4}, "c": {3}}
+	for _, group := range groups {
+		key := string(group[0].kafkaMsg.Key)
+		if len(group) != len(want[key]) {
+			t.Fatalf("key %s: expected %d messages, got %d", key, len(want[key]), len(group))
+		}
+		for i, mwc := range group {
+			if string(mwc.kafkaMsg.Key) != key || mwc.kafkaMsg.Offset != want[key][i] {
+				t.Fatalf("key %s: unexpected message at %d: %s@%d", key, i, mwc.kafkaMsg.Key, mwc.kafkaMsg.Offset)
+			}
+		}
+	}
+}
+
+// TestConsumeClaim_PreservesPerKeyOrder chạy ConsumeClaim thật qua keyedLanes và commitTasks với session giả.
+// Handler làm message lẻ chậm hơn để các key hoàn thành lệch nhau; kết quả không phụ thuộc lịch chạy goroutine.
+func TestConsumeClaim_PreservesPerKeyOrder(t *testing.T) {
+	const partitions = 3
+	const perPartition = 300
+	keysPerPartition := []string{"conv-1", "conv-2", "conv-3", "conv-4", "conv-5"}
+
+	c := &chatConsumer{
+		offsets:     newOffsetTracker(),
+		events:      &eventTracker{},
+		commitQueue: make(chan *commitTask, partitions*perPartition),
+	}
+
+	var mu sync.Mutex
+	processed := make(map[string][]int64)       // laneKey -> offsets theo thứ tự xử lý
+	completed := make(map[topicPartition]int64) // số message đã xử lý của partition theo offset liên tục
+	done := make(map[topicPartition]map[int64]bool)
+
+	c.lanes = newKeyedLanes(4, 8, func(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+		defer c.wg.Done()
+		if msg.Offset%2 == 1 {
+			time.Sleep(200 * time.Microsecond)
+		}
+
+		mu.Lock()
+		key := fmt.Sprintf("%d/%s", msg.Partition, laneKey(msg))
+		processed[key] = append(processed[key], msg.Offset)
+		tp := topicPartition{msg.Topic, msg.Partition}
+		if done[tp] == nil {
+			done[tp] = make(map[int64]bool)
+		}
+		done[tp][msg.Offset] = true
+		for done[tp][completed[tp]] {
+			completed[tp]++
+		}
+		mu.Unlock()
+
+		c.commitQueue <- &commitTask{session: sess, message: msg}
+	})
+
+	sess := newFakeSession()
+	sess.onMark = func(msg *sarama.ConsumerMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Mọi offset trước offset được mark phải đã xử lý xong
+		if got := completed[topicPartition{msg.Topic, msg.Partition}]; msg.Offset >= got {
+			t.Errorf("partition %d: marked offset %d while only %d contiguous offsets completed", msg.Partition, msg.Offset, got)
+		}
+	}
+
+	// commitWorker gom task theo lô nhỏ, chạy song song với lane để offset được mark trong lúc còn xử lý
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		var batch []*commitTask
+		for task := range c.commitQueue {
+			batch = append(batch, task)
+			if len(batch) == 17 {
+				c.commitTasks(batch)
+				batch = batch[:0]
+			}
+		}
+		c.commitTasks(batch)
+	}()
+
+	var claims sync.WaitGroup
+	for p := int32(0); p < partitions; p++ {
+		keys := make([]string, perPartition)
+		for i := range keys {
+			keys[i] = keysPerPartition[(i*7+int(p))%len(keysPerPartition)]
+		}
+		claim := newFakeClaim("chat-topic", p, keys)
+
+		claims.Add(1)
+		go func() {
+			defer claims.Done()
+			if err := c.ConsumeClaim(sess, claim); err != nil {
+				t.Errorf("ConsumeClaim: %v", err)
+			}
+		}()
+	}
+	claims.Wait()
+	c.wg.Wait()
+	c.lanes.close()
+	close(c.commitQueue)
+	<-committed
+
+	for key, offsets := range processed {
+		for i := 1; i < len(offsets); i++ {
+			if offsets[i] <= offsets[i-1] {
+				t.Fatalf("key %s processed out of order: %v", key, offsets)
+			}
+		}
+	}
+
+	for p := int32(0); p < partitions; p++ {
+		last, ok := sess.lastMarked("chat-topic", p)
+		if !ok || last != perPartition-1 {
+			t.Fatalf("partition %d: expected last marked offset %d, got %d (ok=%v)", p, perPartition-1, last, ok)
+		}
+		offsets := sess.marked[topicPartition{"chat-topic", p}]
+		for i := 1; i < len(offsets); i++ {
+			if offsets[i] <= offsets[i-1] {
+				t.Fatalf("partition %d: marks not increasing: %v", p, offsets)
+			}
+		}
+	}
+	if sess.commits == 0 {
+		t.Fatal("expected at least one commit")
+	}
+}
@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"my-app/common/outbox"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// outboxPublisher gửi sự kiện outbox bằng producer đồng bộ, kèm idempotency key trong header
+type outboxPublisher struct {
+	producer sarama.SyncProducer
+}
+
+func (p *outboxPublisher) Publish(_ context.Context, e *outbox.Event) error {
+	_, _, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: e.Topic,
+		Key:   sarama.StringEncoder(e.Key),
+		Value: sarama.StringEncoder(e.Payload),
+		Headers: []sarama.RecordHeader{
+			header(outbox.HeaderIdempotencyKey, e.IdempotencyKey),
+		},
+	})
+	return err
+}
+
+// busPublisher gửi sự kiện outbox vào bus nội bộ (ghi xong mới trả về)
+type busPublisher struct {
+	bus EventBus
+}
+
+func (p *busPublisher) Publish(_ context.Context, e *outbox.Event) error {
+	return p.bus.Publish(e.Topic, e.Key, []byte(e.Payload), header(outbox.HeaderIdempotencyKey, e.IdempotencyKey))
+}
+
+// RunOutboxRelay chạy relay outbox trên event bus tới khi ctx bị hủy.
+// Với Kafka, relay dùng producer đồng bộ riêng (thử kết nối lại tới khi được) để chỉ đánh dấu published khi broker đã ack.
+func RunOutboxRelay(ctx context.Context, bus EventBus, store *outbox.Store, interval time.Duration) {
+	kb, ok := bus.(*kafkaBus)
+	if !ok {
+		log.Println("✅ Outbox relay started")
+		outbox.RunRelay(ctx, store, &busPublisher{bus: bus}, interval)
+		return
+	}
+	brokers := kb.brokers
+
+	var producer sarama.SyncProducer
+	for {
+		var err error
+		producer, err = newSyncProducer(brokers)
+		if err == nil {
+			break
+		}
+		log.Printf("⚠️ [Outbox] Chưa kết nối được Kafka, thử lại sau: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+	defer producer.Close()
+
+	log.Println("✅ Outbox relay started")
+	outbox.RunRelay(ctx, store, &outboxPublisher{producer: producer}, interval)
+}
@@ -1,12 +1,16 @@
 package kafka
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"my-app/common/telemetry"
+
 	"github.com/IBM/sarama"
 )
 
@@ -75,7 +79,7 @@ func handleSuccess() {
 	for msg := range AsyncProducer.Successes() {
 		atomic.AddInt64(&metrics.sentCount, 1)
 		atomic.AddInt64(&metrics.pendingCount, -1)
-		_ = msg
+		telemetry.KafkaProducedMessages.WithLabelValues(msg.Topic, "sent").Inc()
 	}
 }
 
@@ -84,8 +88,11 @@ func handleErrors() {
 	for err := range AsyncProducer.Errors() {
 		atomic.AddInt64(&metrics.errorCount, 1)
 		atomic.AddInt64(&metrics.pendingCount, -1)
+		telemetry.KafkaProducedMessages.WithLabelValues(err.Msg.Topic, "error").Inc()
 		log.Printf("❌ Kafka error: topic=%s, partition=%d, offset=%d, err=%v",
 			err.Msg.Topic, err.Msg.Partition, err.Msg.Offset, err.Err)
+		// Producer đã hết retry: lưu ra spool để replay bằng cmd/dlq thay vì mất message
+		spoolProducerMessage(err.Msg, err.Err)
 	}
 }
 
@@ -107,19 +114,22 @@ func monitorMetrics() {
 	}
 }
 
-// ======================== FIX #4: Retry logic với timeout dài hơn ========================
+// SendMessageAsync publish qua event bus đang dùng (Kafka hoặc bus nội bộ khi EVENT_BUS=memory/mongo)
 func SendMessageAsync(topic, key, value string) error {
+	return currentBus().Publish(topic, key, []byte(value))
+}
+
+// ======================== FIX #4: Retry logic với timeout dài hơn ========================
+func sendAsync(msg *sarama.ProducerMessage) error {
+	if AsyncProducer == nil {
+		return errors.New("kafka producer chưa được khởi tạo")
+	}
+
 	pending := atomic.LoadInt64(&metrics.pendingCount)
 	if pending > 20000 {
 		return fmt.Errorf("backpressure: too many pending messages (%d)", pending)
 	}
 
-	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Key:   sarama.StringEncoder(key),
-		Value: sarama.StringEncoder(value),
-	}
-
 	atomic.AddInt64(&metrics.pendingCount, 1)
 
 	// ✅ FIX: Tăng timeout lên 30s và retry
@@ -150,6 +160,14 @@ func GetMetrics() (sent, errors, pending int64) {
 
 // ======================== FIX #5: Graceful shutdown với flush đồng bộ ========================
 func CloseProducer() error {
+	// Đợi tối đa 60s để gửi hết message
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	return CloseProducerContext(ctx)
+}
+
+// CloseProducerContext đợi các message đang chờ được ack tới khi ctx hết hạn rồi đóng producer
+func CloseProducerContext(ctx context.Context) error {
 	var closeErr error
 	shutdownOnce.Do(func() {
 		if AsyncProducer != nil {
@@ -159,8 +177,6 @@ func CloseProducer() error {
 			startPending := atomic.LoadInt64(&metrics.pendingCount)
 			log.Printf("⏳ Waiting for %d pending messages to be sent...", startPending)
 
-			// Đợi tối đa 60s để gửi hết message
-			timeout := time.After(60 * time.Second)
 			ticker := time.NewTicker(1 * time.Second)
 			defer ticker.Stop()
 
@@ -174,7 +190,7 @@ func CloseProducer() error {
 				select {
 				case <-ticker.C:
 					log.Printf("⏳ Still waiting: %d pending messages...", pending)
-				case <-timeout:
+				case <-ctx.Done():
 					log.Printf("⚠️ Timeout: %d messages still pending", pending)
 					break waitLoop
 				}
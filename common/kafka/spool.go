@@ -0,0 +1,136 @@
+package kafka
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// SpooledMessage - message không gửi được lên Kafka (broker lỗi / backpressure), lưu ra file để replay sau
+type SpooledMessage struct {
+	Topic    string    `json:"topic"`
+	Key      string    `json:"key"`
+	Value    string    `json:"value"`
+	Reason   string    `json:"reason"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+var spool = struct {
+	mu   sync.Mutex
+	path string
+}{path: "./data/kafka-spool.jsonl"}
+
+func SetSpoolPath(path string) {
+	spool.mu.Lock()
+	defer spool.mu.Unlock()
+	if path != "" {
+		spool.path = path
+	}
+}
+
+// SpoolMessage ghi thêm message vào file spool (JSON lines)
+func SpoolMessage(topic, key, value string, reason error) error {
+	entry := SpooledMessage{Topic: topic, Key: key, Value: value, FailedAt: time.Now().UTC()}
+	if reason != nil {
+		entry.Reason = reason.Error()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	spool.mu.Lock()
+	defer spool.mu.Unlock()
+	return appendLines(spool.path, [][]byte{line})
+}
+
+// spoolProducerMessage lưu message producer báo lỗi sau khi đã hết retry
+func spoolProducerMessage(msg *sarama.ProducerMessage, reason error) {
+	var key, value []byte
+	if msg.Key != nil {
+		key, _ = msg.Key.Encode()
+	}
+	if msg.Value != nil {
+		value, _ = msg.Value.Encode()
+	}
+	if err := SpoolMessage(msg.Topic, string(key), string(value), reason); err != nil {
+		log.Printf("❌ Không ghi được message vào spool: topic=%s, err=%v", msg.Topic, err)
+	}
+}
+
+// ReplaySpool gửi lại toàn bộ message trong file spool. File được đổi tên trước khi đọc để server vẫn ghi tiếp được;
+// message gửi lỗi được ghi lại vào spool.
+func ReplaySpool(path string, send func(SpooledMessage) error) (replayed, failed int, err error) {
+	processing := fmt.Sprintf("%s.%d.replay", path, time.Now().UnixNano())
+	if err := os.Rename(path, processing); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	f, err := os.Open(processing)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var retry [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		var entry SpooledMessage
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Printf("⚠️ Bỏ qua dòng spool hỏng: %v", err)
+			continue
+		}
+		if err := send(entry); err != nil {
+			log.Printf("⚠️ Replay spool lỗi: topic=%s, key=%s, err=%v", entry.Topic, entry.Key, err)
+			retry = append(retry, line)
+			failed++
+			continue
+		}
+		replayed++
+	}
+	if err := scanner.Err(); err != nil {
+		return replayed, failed, err
+	}
+
+	if len(retry) > 0 {
+		spool.mu.Lock()
+		err := appendLines(path, retry)
+		spool.mu.Unlock()
+		if err != nil {
+			// Giữ file đang xử lý để không mất message chưa gửi được
+			return replayed, failed, fmt.Errorf("write back %d failed messages (kept %s): %w", len(retry), processing, err)
+		}
+	}
+	return replayed, failed, os.Remove(processing)
+}
+
+func appendLines(path string, lines [][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, line := range lines {
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"context"
+
+	"my-app/common/telemetry"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// producerCarrier ghi trace context (traceparent) vào header của message gửi đi
+type producerCarrier struct {
+	headers *[]sarama.RecordHeader
+}
+
+func (c producerCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c producerCarrier) Set(key, value string) {
+	*c.headers = append(*c.headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c producerCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
+
+// consumerCarrier đọc trace context từ header của message nhận được
+type consumerCarrier []*sarama.RecordHeader
+
+func (c consumerCarrier) Get(key string) string {
+	for _, h := range c {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c consumerCarrier) Set(string, string) {}
+
+func (c consumerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for _, h := range c {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
+
+// SendMessageAsyncContext như SendMessageAsync nhưng mang theo trace của ctx qua header,
+// consumer nối tiếp trace đó khi xử lý message
+func SendMessageAsyncContext(ctx context.Context, topic, key, value string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "kafka.publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.destination.name", topic)),
+	)
+	defer span.End()
+
+	var headers []sarama.RecordHeader
+	telemetry.Propagator().Inject(ctx, producerCarrier{headers: &headers})
+
+	err := currentBus().Publish(topic, key, []byte(value), headers...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// messageContext trả về ctx mang trace context của producer (nếu message có traceparent)
+func messageContext(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	return telemetry.Propagator().Extract(ctx, consumerCarrier(msg.Headers))
+}
+
+// startConsumeSpan mở span xử lý message, là con của span publish phía producer
+func startConsumeSpan(ctx context.Context, msg *sarama.ConsumerMessage, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	opts = append(opts,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int64("messaging.kafka.partition", int64(msg.Partition)),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		),
+	)
+	return telemetry.Tracer().Start(messageContext(ctx, msg), name, opts...)
+}
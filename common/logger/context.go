@@ -0,0 +1,35 @@
+package logger
+
+import "context"
+
+type fieldsKey struct{}
+
+type fields struct {
+	requestID      string
+	userID         string
+	conversationID string
+}
+
+func withFields(ctx context.Context, update func(*fields)) context.Context {
+	f, _ := ctx.Value(fieldsKey{}).(fields)
+	update(&f)
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return withFields(ctx, func(f *fields) { f.requestID = id })
+}
+
+func WithUserID(ctx context.Context, id string) context.Context {
+	return withFields(ctx, func(f *fields) { f.userID = id })
+}
+
+func WithConversationID(ctx context.Context, id string) context.Context {
+	return withFields(ctx, func(f *fields) { f.conversationID = id })
+}
+
+// RequestID trả về request id đã gắn vào ctx (rỗng nếu không có)
+func RequestID(ctx context.Context) string {
+	f, _ := ctx.Value(fieldsKey{}).(fields)
+	return f.requestID
+}
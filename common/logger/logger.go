@@ -0,0 +1,154 @@
+// Package logger - logger có cấu trúc dùng chung (zerolog): mỗi dòng log là JSON mang module, request_id,
+// user_id, conversation_id, trace_id lấy từ context; mọi dòng đều đi qua lớp che dữ liệu nhạy cảm (xem redact.go).
+// log.Printf cũ được chuyển vào logger này (module "app") nên cũng được che và có định dạng thống nhất.
+package logger
+
+import (
+	"context"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options cấu hình logger
+type Options struct {
+	Level   string // mức mặc định: trace, debug, info, warn, error
+	Modules string // mức riêng theo module, VD: "kafka=debug,websocket=warn"
+	Format  string // "json" (mặc định) hoặc "console" cho dev
+	Output  io.Writer
+}
+
+var (
+	mu           sync.RWMutex
+	base         = zerolog.New(RedactWriter(os.Stdout)).With().Timestamp().Logger()
+	defaultLevel = zerolog.InfoLevel
+	moduleLevels = map[string]zerolog.Level{}
+	modules      = map[string]*zerolog.Logger{}
+)
+
+// Init cài logger toàn cục và chuyển package log chuẩn sang logger này
+func Init(opts Options) {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	if opts.Format == "console" {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.DateTime}
+	}
+
+	zerolog.SetGlobalLevel(zerolog.TraceLevel) // lọc theo từng module trong L
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+
+	mu.Lock()
+	base = zerolog.New(RedactWriter(out)).With().Timestamp().Logger()
+	defaultLevel = parseLevel(opts.Level, zerolog.InfoLevel)
+	moduleLevels = parseModuleLevels(opts.Modules)
+	modules = map[string]*zerolog.Logger{}
+	mu.Unlock()
+
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(stdWriter{})
+}
+
+// L trả về logger của module, đã áp mức log riêng của module
+func L(module string) *zerolog.Logger {
+	mu.RLock()
+	l, ok := modules[module]
+	mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if l, ok := modules[module]; ok {
+		return l
+	}
+	level, ok := moduleLevels[module]
+	if !ok {
+		level = defaultLevel
+	}
+	created := base.Level(level).With().Str("module", module).Logger()
+	modules[module] = &created
+	return &created
+}
+
+// Ctx trả về logger của module kèm các id trong ctx (request, user, conversation, trace)
+func Ctx(ctx context.Context, module string) *zerolog.Logger {
+	return With(ctx, L(module))
+}
+
+// With gắn các id trong ctx vào một logger có sẵn (VD: logger ghi file riêng của middleware HTTP)
+func With(ctx context.Context, l *zerolog.Logger) *zerolog.Logger {
+	if ctx == nil {
+		return l
+	}
+
+	f, _ := ctx.Value(fieldsKey{}).(fields)
+	sc := trace.SpanContextFromContext(ctx)
+	if f == (fields{}) && !sc.IsValid() {
+		return l
+	}
+
+	c := l.With()
+	if f.requestID != "" {
+		c = c.Str("request_id", f.requestID)
+	}
+	if f.userID != "" {
+		c = c.Str("user_id", f.userID)
+	}
+	if f.conversationID != "" {
+		c = c.Str("conversation_id", f.conversationID)
+	}
+	if sc.IsValid() {
+		c = c.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+	}
+	withFields := c.Logger()
+	return &withFields
+}
+
+func parseLevel(s string, fallback zerolog.Level) zerolog.Level {
+	level, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(s)))
+	if err != nil || s == "" {
+		return fallback
+	}
+	return level
+}
+
+func parseModuleLevels(s string) map[string]zerolog.Level {
+	levels := map[string]zerolog.Level{}
+	for _, part := range strings.Split(s, ",") {
+		module, level, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		parsed, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(level)))
+		if err != nil {
+			continue
+		}
+		levels[strings.TrimSpace(module)] = parsed
+	}
+	return levels
+}
+
+// stdWriter nhận output của package log chuẩn; mức log đoán theo biểu tượng đầu dòng quen dùng trong repo
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	level := zerolog.InfoLevel
+	switch {
+	case strings.Contains(line, "❌"), strings.Contains(line, "CRITICAL"):
+		level = zerolog.ErrorLevel
+	case strings.Contains(line, "⚠️"):
+		level = zerolog.WarnLevel
+	}
+	L("app").WithLevel(level).Msg(line)
+	return len(p), nil
+}
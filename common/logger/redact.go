@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Trường luôn bị che toàn bộ giá trị (so khớp không phân biệt hoa thường, bỏ "_" và "-")
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"setcookie":     true,
+	"apikey":        true,
+	"xapikey":       true,
+	"otp":           true,
+	"email":         true,
+	"phone":         true,
+	"phonenumber":   true,
+	// Nội dung tin nhắn
+	"content":        true,
+	"lastmessage":    true,
+	"messagecontent": true,
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	phonePattern  = regexp.MustCompile(`(?:\+84|\b0)\d{6,7}(\d{3})\b`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._\-]+`)
+	// Payload JSON bị in nguyên vào chuỗi log (log.Printf("... %s", data))
+	jsonFieldPattern = regexp.MustCompile(`(?i)"([a-z_]*password|[a-z_]*token|[a-z_]*secret|authorization|email|phone|phone_number|content|last_message)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+)
+
+func isSensitiveKey(key string) bool {
+	k := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if sensitiveKeys[k] {
+		return true
+	}
+	return strings.HasSuffix(k, "password") || strings.HasSuffix(k, "token") || strings.HasSuffix(k, "secret")
+}
+
+// Redact che email, số điện thoại, token trong một chuỗi tự do
+func Redact(s string) string {
+	s = jsonFieldPattern.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = emailPattern.ReplaceAllString(s, "$1***@$2")
+	s = phonePattern.ReplaceAllString(s, "*******$1")
+	return s
+}
+
+func redactValue(key string, v any) any {
+	if key != "" && isSensitiveKey(key) {
+		if v == nil || v == "" {
+			return v
+		}
+		return redacted
+	}
+	switch val := v.(type) {
+	case string:
+		return Redact(val)
+	case map[string]any:
+		for k, child := range val {
+			val[k] = redactValue(k, child)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = redactValue("", child)
+		}
+		return val
+	}
+	return v
+}
+
+type redactWriter struct {
+	next io.Writer
+}
+
+// RedactWriter bọc output của zerolog: mỗi dòng JSON được che theo tên trường và theo mẫu (email, SĐT, token)
+func RedactWriter(next io.Writer) io.Writer {
+	return redactWriter{next: next}
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	var entry map[string]any
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&entry); err != nil {
+		// Không phải JSON: vẫn che theo mẫu
+		if _, err := w.next.Write([]byte(Redact(string(p)))); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	for k, v := range entry {
+		entry[k] = redactValue(k, v)
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.next.Write(append(out, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
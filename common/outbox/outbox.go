@@ -0,0 +1,143 @@
+// Package outbox ghi sự kiện Kafka cùng transaction với thay đổi dữ liệu nghiệp vụ;
+// relay đọc outbox và publish lên Kafka, consumer bỏ qua bản trùng theo idempotency key.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"my-app/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const Collection = "outbox"
+
+// HeaderIdempotencyKey - header Kafka mang idempotency key, chatConsumer xử lý mỗi key đúng một lần
+const HeaderIdempotencyKey = "idempotency-key"
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusPublished Status = "published"
+)
+
+type Event struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Topic          string             `bson:"topic" json:"topic"`
+	Key            string             `bson:"key" json:"key"`
+	Payload        string             `bson:"payload" json:"payload"`
+	IdempotencyKey string             `bson:"idempotency_key" json:"idempotency_key"`
+	Status         Status             `bson:"status" json:"status"`
+	Attempts       int                `bson:"attempts" json:"attempts"`
+	LastError      string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt  time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil    *time.Time         `bson:"locked_until,omitempty" json:"-"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	PublishedAt    *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
+}
+
+// NewEvent tạo sự kiện chờ publish; idempotency key mặc định là ID của sự kiện
+func NewEvent(topic, key string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	now := time.Now()
+	id := primitive.NewObjectID()
+	return Event{
+		ID:             id,
+		Topic:          topic,
+		Key:            key,
+		Payload:        string(data),
+		IdempotencyKey: id.Hex(),
+		Status:         StatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}, nil
+}
+
+// WithIdempotencyKey dùng key nghiệp vụ khi cùng một sự kiện có thể đến từ nhiều đường (VD: REST và websocket)
+func (e Event) WithIdempotencyKey(key string) Event {
+	e.IdempotencyKey = key
+	return e
+}
+
+// Store - outbox trên Mongo, dùng chung transaction với storage nghiệp vụ
+type Store struct {
+	db *mongo.Database
+}
+
+func NewStore(db *mongo.Database) *Store {
+	return &Store{db: db}
+}
+
+// WithTransaction chạy fn trong transaction; Enqueue với ctx của fn được commit cùng dữ liệu nghiệp vụ
+func (s *Store) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return database.WithTransaction(ctx, s.db, fn)
+}
+
+// Enqueue ghi sự kiện vào outbox; gọi với ctx của WithTransaction để ghi nguyên tử cùng thay đổi nghiệp vụ
+func (s *Store) Enqueue(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(events))
+	for _, e := range events {
+		docs = append(docs, e)
+	}
+	_, err := s.db.Collection(Collection).InsertMany(ctx, docs)
+	return err
+}
+
+// Claim nhận sự kiện chờ publish cũ nhất và khóa trong lockFor để relay khác không gửi trùng. Trả về nil nếu outbox trống.
+func (s *Store) Claim(ctx context.Context, lockFor time.Duration) (*Event, error) {
+	now := time.Now()
+	lockedUntil := now.Add(lockFor)
+
+	var e Event
+	err := s.db.Collection(Collection).FindOneAndUpdate(ctx, bson.M{
+		"status":          StatusPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}, bson.M{"$set": bson.M{"locked_until": lockedUntil}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&e)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Store) MarkPublished(ctx context.Context, ID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := s.db.Collection(Collection).UpdateOne(ctx, bson.M{"_id": ID}, bson.M{
+		"$set":   bson.M{"status": StatusPublished, "published_at": now},
+		"$unset": bson.M{"locked_until": "", "last_error": ""},
+		"$inc":   bson.M{"attempts": 1},
+	})
+	return err
+}
+
+// MarkFailed mở khóa sự kiện và hẹn lần gửi lại
+func (s *Store) MarkFailed(ctx context.Context, ID primitive.ObjectID, errMsg string, nextAttemptAt time.Time) error {
+	_, err := s.db.Collection(Collection).UpdateOne(ctx, bson.M{"_id": ID}, bson.M{
+		"$set":   bson.M{"last_error": errMsg, "next_attempt_at": nextAttemptAt},
+		"$unset": bson.M{"locked_until": ""},
+		"$inc":   bson.M{"attempts": 1},
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Publisher gửi một sự kiện lên Kafka và chỉ trả về nil khi broker đã ghi nhận
+type Publisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
+
+const relayLock = 30 * time.Second
+
+// RunRelay publish outbox theo thứ tự ghi. Sự kiện chỉ được đánh dấu published sau khi Kafka ack;
+// nếu relay chết giữa hai bước, sự kiện được gửi lại và consumer bỏ bản trùng theo idempotency key.
+func RunRelay(ctx context.Context, store *Store, publisher Publisher, interval time.Duration) {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for relayNext(ctx, store, publisher) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayNext gửi một sự kiện, trả về false khi outbox trống hoặc có lỗi để chờ lượt sau
+func relayNext(ctx context.Context, store *Store, publisher Publisher) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	e, err := store.Claim(ctx, relayLock)
+	if err != nil {
+		log.Printf("⚠️ [Outbox] Claim lỗi: %v", err)
+		return false
+	}
+	if e == nil {
+		return false
+	}
+
+	if err := publisher.Publish(ctx, e); err != nil {
+		next := time.Now().Add(retryBackoff(e.Attempts))
+		log.Printf("⚠️ [Outbox] Publish %s -> %s lỗi (lần %d): %v", e.ID.Hex(), e.Topic, e.Attempts+1, err)
+		if merr := store.MarkFailed(ctx, e.ID, err.Error(), next); merr != nil {
+			log.Printf("⚠️ [Outbox] Không cập nhật được sự kiện %s: %v", e.ID.Hex(), merr)
+		}
+		return false
+	}
+
+	if err := store.MarkPublished(ctx, e.ID); err != nil {
+		// Khóa hết hạn thì sự kiện được gửi lại, consumer bỏ qua nhờ idempotency key
+		log.Printf("⚠️ [Outbox] Không đánh dấu published %s: %v", e.ID.Hex(), err)
+	}
+	return true
+}
+
+// retryBackoff: 1s, 2s, 4s... tối đa 1 phút
+func retryBackoff(attempts int) time.Duration {
+	if attempts > 6 {
+		return time.Minute
+	}
+	return time.Duration(1<<uint(attempts)) * time.Second
+}
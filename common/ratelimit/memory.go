@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryLimiter - bucket trong RAM; mỗi instance đếm riêng nên giới hạn thực tế nhân theo số instance
+type memoryLimiter struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+}
+
+func NewMemory() Limiter {
+	l := &memoryLimiter{tats: make(map[string]time.Time)}
+	go l.cleanup()
+	return l
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tat, res := gcra(time.Now(), l.tats[key], limit)
+	l.tats[key] = tat
+	return res, nil
+}
+
+// cleanup bỏ các key đã đầy lại bucket (tat đã qua), giữ map không phình theo số IP / user từng gặp
+func (l *memoryLimiter) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		l.mu.Lock()
+		for key, tat := range l.tats {
+			if tat.Before(now) {
+				delete(l.tats, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
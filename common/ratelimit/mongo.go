@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection lưu mốc tat của từng key; expires_at có TTL index (internal/indexer) để key hết hạn tự xóa
+const Collection = "rate_limits"
+
+type mongoLimiter struct {
+	col *mongo.Collection
+}
+
+func NewMongo(db *mongo.Database) Limiter {
+	return &mongoLimiter{col: db.Collection(Collection)}
+}
+
+// Allow chạy gcra ngay trên Mongo bằng update pipeline (thời gian tính bằng mili giây), nên các instance
+// cùng trừ token của một key không ghi đè lẫn nhau
+func (l *mongoLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := float64(time.Now().UnixNano()) / float64(time.Millisecond)
+	interval := float64(limit.interval()) / float64(time.Millisecond)
+	burst := float64(limit.Burst) * interval
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "tat", Value: bson.D{{Key: "$max", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$tat", now}}}, now}}}},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "allowed", Value: bson.D{{Key: "$lte", Value: bson.A{
+				bson.D{{Key: "$subtract", Value: bson.A{bson.D{{Key: "$add", Value: bson.A{"$tat", interval}}}, burst}}},
+				now,
+			}}}},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "tat", Value: bson.D{{Key: "$cond", Value: bson.A{"$allowed", bson.D{{Key: "$add", Value: bson.A{"$tat", interval}}}, "$tat"}}}},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "expires_at", Value: bson.D{{Key: "$toDate", Value: bson.D{{Key: "$toLong", Value: "$tat"}}}}},
+		}}},
+	}
+
+	var doc struct {
+		Tat     float64 `bson:"tat"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := l.col.FindOneAndUpdate(ctx, bson.M{"_id": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if !doc.Allowed {
+		wait := doc.Tat + interval - burst - now
+		return Result{RetryAfter: time.Duration(wait * float64(time.Millisecond))}, nil
+	}
+	return Result{Allowed: true, Remaining: int(math.Floor((now + burst - doc.Tat) / interval))}, nil
+}
@@ -0,0 +1,131 @@
+// Package ratelimit - giới hạn tần suất theo token bucket (thuật toán GCRA: mỗi key chỉ lưu một mốc thời gian).
+// Backend "memory" đếm trong process; backend "mongo" dùng chung giữa các instance, mỗi lần kiểm tra là
+// một lệnh findOneAndUpdate nguyên tử nên không cần khóa phân tán.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendMongo  = "mongo"
+)
+
+// Timeout - thời gian tối đa chờ backend cho một lần kiểm tra. Backend chậm hoặc lỗi thì caller cho request
+// đi qua, không để rate limit làm nghẽn API / WebSocket
+const Timeout = 200 * time.Millisecond
+
+// Limit - Rate token mỗi giây, tối đa Burst request dồn cùng lúc
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // chỉ có nghĩa khi Allowed = false
+}
+
+// Limiter trừ một token của key theo limit
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func New(backend string, db *mongo.Database) (Limiter, error) {
+	switch backend {
+	case "", BackendMemory:
+		return NewMemory(), nil
+	case BackendMongo:
+		return NewMongo(db), nil
+	}
+	return nil, fmt.Errorf("RATE_LIMIT_BACKEND không hợp lệ: %q (memory, mongo)", backend)
+}
+
+// gcra tính mốc tat (theoretical arrival time) mới sau khi nhận một request lúc now.
+// Bucket đầy khi tat <= now; mỗi request đẩy tat thêm một interval; từ chối khi tat vượt now quá Burst interval.
+func gcra(now, tat time.Time, limit Limit) (time.Time, Result) {
+	interval := limit.interval()
+	burst := time.Duration(limit.Burst) * interval
+
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	if allowAt := next.Add(-burst); now.Before(allowAt) {
+		return tat, Result{RetryAfter: allowAt.Sub(now)}
+	}
+	return next, Result{Allowed: true, Remaining: int((burst - next.Sub(now)) / interval)}
+}
+
+// ParseLimit đọc giới hạn dạng "<số>/<s|m|h>[:burst]", VD: "20/s:40", "10/m". Không ghi burst thì burst = số request.
+func ParseLimit(s string) (Limit, error) {
+	spec, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	countStr, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("giới hạn %q sai định dạng, VD: 20/s:40", s)
+	}
+	count, err := strconv.ParseFloat(countStr, 64)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("giới hạn %q: số request không hợp lệ", s)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("giới hạn %q: đơn vị phải là s, m hoặc h", s)
+	}
+
+	limit := Limit{Rate: count / per.Seconds(), Burst: int(math.Ceil(count))}
+	if hasBurst {
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("giới hạn %q: burst không hợp lệ", s)
+		}
+		limit.Burst = burst
+	}
+	return limit, nil
+}
+
+// ParsePolicies đọc danh sách "tên=giới hạn" cách nhau bởi dấu phẩy; giới hạn "off" thì policy không giới hạn
+func ParsePolicies(s string) (map[string]Limit, error) {
+	policies := make(map[string]Limit)
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("policy %q sai định dạng, VD: api=30/s:60", part)
+		}
+		if strings.TrimSpace(spec) == "off" {
+			delete(policies, name)
+			continue
+		}
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", name, err)
+		}
+		policies[name] = limit
+	}
+	return policies, nil
+}
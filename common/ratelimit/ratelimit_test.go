@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCRA_BurstThenRefill(t *testing.T) {
+	limit := Limit{Rate: 10, Burst: 3} // 1 token mỗi 100ms
+	now := time.Unix(1700000000, 0)
+	var tat time.Time
+
+	for i, wantRemaining := range []int{2, 1, 0} {
+		var res Result
+		tat, res = gcra(now, tat, limit)
+		if !res.Allowed || res.Remaining != wantRemaining {
+			t.Fatalf("request %d: %+v, muốn allowed với remaining %d", i+1, res, wantRemaining)
+		}
+	}
+
+	tat, res := gcra(now, tat, limit)
+	if res.Allowed || res.RetryAfter != 100*time.Millisecond {
+		t.Fatalf("request vượt burst: %+v, muốn bị từ chối và chờ 100ms", res)
+	}
+
+	if _, res = gcra(now.Add(100*time.Millisecond), tat, limit); !res.Allowed {
+		t.Fatalf("sau 100ms phải có lại 1 token: %+v", res)
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("api=30/s:60, auth=10/m, api=off, ws=1.5/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := policies["api"]; ok {
+		t.Fatalf("api=off phải bỏ giới hạn: %+v", policies)
+	}
+	if got := policies["auth"]; got.Burst != 10 || got.Rate != 10.0/60 {
+		t.Fatalf("auth = %+v", got)
+	}
+	if got := policies["ws"]; got.Burst != 2 || got.Rate != 1.5 {
+		t.Fatalf("ws = %+v", got)
+	}
+
+	for _, bad := range []string{"api", "api=30", "api=30/d", "api=0/s", "api=30/s:x"} {
+		if _, err := ParsePolicies(bad); err == nil {
+			t.Fatalf("%q phải lỗi", bad)
+		}
+	}
+}
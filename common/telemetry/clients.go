@@ -0,0 +1,45 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// MongoMonitor đo thời gian từng lệnh MongoDB (find, insert, aggregate...)
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			MongoCommandDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			MongoCommandDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
+
+type esTransport struct {
+	next http.RoundTripper
+}
+
+// ESTransport bọc transport của client Elasticsearch: đo thời gian từng request và tạo span con
+// từ context của request (esapi truyền ctx của lời gọi xuống đây)
+func ESTransport(next http.RoundTripper) http.RoundTripper {
+	return &esTransport{next: otelhttp.NewTransport(next)}
+}
+
+func (t *esTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	ESRequestDuration.WithLabelValues(req.Method, status).Observe(time.Since(start).Seconds())
+	return resp, err
+}
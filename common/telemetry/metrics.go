@@ -0,0 +1,100 @@
+// Package telemetry gom metrics Prometheus (GET /metrics) và tracing OpenTelemetry của toàn hệ thống.
+// Collector đăng ký một lần ở đây; các module chỉ gọi Observe / Inc, không tự tạo registry riêng.
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "unichat"
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Thời gian xử lý request HTTP theo route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	WSActiveSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_active_sessions",
+		Help:      "Số phiên WebSocket đang kết nối vào Hub.",
+	})
+
+	WSDroppedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_dropped_messages_total",
+		Help:      "Số frame bị bỏ vì buffer gửi của client đã đầy.",
+	}, []string{"event"})
+
+	KafkaProducedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_producer_messages_total",
+		Help:      "Số message producer đã gửi xong, theo kết quả (sent / error).",
+	}, []string{"topic", "result"})
+
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Số message còn sau message vừa nhận trên partition (high water mark - offset - 1).",
+	}, []string{"topic", "partition"})
+
+	KafkaBatchFlushDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_batch_flush_duration_seconds",
+		Help:      "Thời gian lưu một batch tin nhắn của BatchProcessor.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"topic"})
+
+	KafkaBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_batch_size",
+		Help:      "Số tin nhắn trong mỗi batch được flush.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	MongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_command_duration_seconds",
+		Help:      "Thời gian thực thi lệnh MongoDB.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"command", "status"})
+
+	ESRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "elasticsearch_request_duration_seconds",
+		Help:      "Thời gian gọi Elasticsearch.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "status"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Số request HTTP / frame WebSocket bị từ chối do vượt giới hạn, theo policy.",
+	}, []string{"policy"})
+)
+
+// RegisterGaugeFunc đăng ký gauge đọc giá trị lúc scrape (độ dài hàng đợi, số message chờ gửi...).
+// Chỉ gọi một lần cho mỗi tên, thường là lúc khởi động app.
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+}
+
+// DroppedMessage ghi nhận một frame WebSocket bị bỏ do buffer đầy
+func DroppedMessage(event string) {
+	WSDroppedMessages.WithLabelValues(event).Inc()
+}
+
+// Handler trả về endpoint /metrics theo định dạng Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
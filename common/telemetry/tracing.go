@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "my-app"
+
+// Options cấu hình tracing
+type Options struct {
+	ServiceName  string
+	OTLPEndpoint string  // VD: http://otel-collector:4318; rỗng thì không xuất trace
+	SampleRatio  float64 // tỉ lệ trace gốc được lấy mẫu, trace con theo quyết định của cha
+}
+
+// InitTracing cài propagator W3C (traceparent) và exporter OTLP/HTTP.
+// Không có endpoint thì chỉ cài propagator: span là no-op nhưng trace context vẫn được chuyển tiếp qua Kafka / HTTP.
+func InitTracing(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if opts.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.OTLPEndpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", opts.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Printf("✅ Tracing: OTLP -> %s (service=%s, sample=%.2f)", opts.OTLPEndpoint, opts.ServiceName, opts.SampleRatio)
+	return provider.Shutdown, nil
+}
+
+// Tracer dùng chung cho mọi span của ứng dụng
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Propagator trả về propagator toàn cục (đã cài bởi InitTracing)
+func Propagator() propagation.TextMapPropagator {
+	return otel.GetTextMapPropagator()
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +17,86 @@ type (
 		Static        StaticConfig
 		Elasticsearch ESConfig
 		LiveKit       LiveKitConfig
+		Privacy       PrivacyConfig
+		Group         GroupConfig
+		Poll          PollConfig
+		Upload        UploadConfig
+		MediaAccess   MediaAccessConfig
+		Outbox        OutboxConfig
+		EventBus      EventBusConfig
+		Telemetry     TelemetryConfig
+		Log           LogConfig
+		Shutdown      ShutdownConfig
+		RateLimit     RateLimitConfig
+	}
+
+	// RateLimitConfig cấu hình giới hạn tần suất (common/ratelimit). Giới hạn ghi dạng "tên=<số>/<s|m|h>[:burst]"
+	RateLimitConfig struct {
+		Backend    string // "memory" (mỗi instance đếm riêng) hoặc "mongo" (dùng chung giữa các instance)
+		Policies   string // theo nhóm route: public, auth (đăng nhập), api (đã xác thực, theo user), upload
+		WSPolicies string // theo loại frame WebSocket của mỗi user, VD: chat, send-reaction, edit-message
+	}
+
+	// ShutdownConfig cấu hình dừng server: đóng socket, lưu nốt batch của consumer, đợi producer gửi hết
+	ShutdownConfig struct {
+		Timeout       time.Duration // tổng thời gian tối đa cho toàn bộ quá trình dừng
+		ReconnectHint time.Duration // client được gợi ý kết nối lại sau [hint, 2*hint) để tránh dồn cùng lúc
+	}
+
+	// LogConfig cấu hình logger có cấu trúc (common/logger)
+	LogConfig struct {
+		Level   string // mức mặc định: debug, info, warn, error
+		Modules string // mức riêng theo module, VD: "kafka=debug,websocket=warn,http=info"
+		Format  string // "json" hoặc "console"
+	}
+
+	// TelemetryConfig cấu hình tracing OpenTelemetry; metrics Prometheus luôn bật tại GET /metrics
+	TelemetryConfig struct {
+		ServiceName  string
+		OTLPEndpoint string  // collector OTLP/HTTP, rỗng thì không xuất trace
+		SampleRatio  float64 // tỉ lệ lấy mẫu trace gốc (0..1)
+	}
+
+	// EventBusConfig chọn nơi publish / consume sự kiện: Kafka, hoặc chạy không cần Kafka (dev, triển khai nhỏ)
+	EventBusConfig struct {
+		Driver       string        // "kafka" (mặc định), "memory" (mất sự kiện chưa xử lý khi restart) hoặc "mongo"
+		PollInterval time.Duration // driver mongo: chu kỳ đọc sự kiện mới do process khác ghi
+	}
+
+	// OutboxConfig cấu hình relay đẩy sự kiện từ outbox (Mongo) lên Kafka
+	OutboxConfig struct {
+		RelayInterval time.Duration // chu kỳ kiểm tra outbox khi không còn sự kiện chờ
+	}
+
+	// PrivacyConfig cấu hình xóa tài khoản vĩnh viễn
+	PrivacyConfig struct {
+		DeletionGrace  time.Duration // thời gian chờ trước khi xóa, user có thể hủy trong thời gian này
+		WorkerInterval time.Duration // chu kỳ quét các yêu cầu xóa đến hạn
+	}
+
+	// GroupConfig cấu hình thời gian giữ nhóm đã giải tán trước khi xóa hẳn
+	GroupConfig struct {
+		RestoreRetention time.Duration // admin hệ thống có thể khôi phục nhóm trong thời gian này
+		PurgeInterval    time.Duration // chu kỳ quét các nhóm quá hạn để xóa vĩnh viễn
+	}
+
+	// PollConfig cấu hình worker tự đóng bình chọn khi tới hạn
+	PollConfig struct {
+		CloseInterval time.Duration // chu kỳ quét bình chọn đã hết hạn
+	}
+
+	// UploadConfig cấu hình upload trực tiếp lên MinIO (presigned / chunked, có thể resume)
+	UploadConfig struct {
+		PartSize        int64         // kích thước mỗi part khi upload nhiều phần (tối thiểu 5MB theo S3)
+		PresignExpiry   time.Duration // thời hạn của presigned URL
+		SessionTTL      time.Duration // phiên upload chưa hoàn tất sau thời gian này sẽ bị hủy
+		CleanupInterval time.Duration // chu kỳ dọn các phiên upload quá hạn
+	}
+
+	// MediaAccessConfig cấu hình URL có chữ ký để xem media
+	MediaAccessConfig struct {
+		URLSecret string        // khóa HMAC ký URL
+		URLTTL    time.Duration // URL sống từ URLTTL đến 2*URLTTL để trình duyệt cache được
 	}
 
 	LiveKitConfig struct {
@@ -33,12 +114,15 @@ type (
 	MongoConfig struct {
 		URI  string
 		Name string
+		// Không khởi động khi Mongo không hỗ trợ transaction (standalone), thay vì ghi outbox không nguyên tử
+		RequireTransactions bool
 	}
 
 	KafkaConfig struct {
-		Brokers []string
-		GroupID string
-		Topics  []string
+		Brokers   []string
+		GroupID   string
+		Topics    []string
+		SpoolPath string // file lưu message không gửi được lên Kafka, replay bằng cmd/dlq
 	}
 
 	StaticConfig struct {
@@ -57,6 +141,8 @@ func LoadAppConfig() AppConfig {
 		Mongo: MongoConfig{
 			URI:  getEnv("MONGO_URI", "mongodb://127.0.0.1:27017"),
 			Name: getEnv("MONGO_DB", "unichat"),
+
+			RequireTransactions: getEnv("MONGO_REQUIRE_TRANSACTIONS", "false") == "true",
 		},
 		Kafka: KafkaConfig{
 			Brokers: splitAndTrim(getEnv("KAFKA_BROKERS", "127.0.0.1:9092")),
@@ -73,8 +159,10 @@ func LoadAppConfig() AppConfig {
 					"un-pinned-message-topic",
 					"add-group-member",
 					"chat-notification-all",
+					"media-processing-topic",
 				}, ",")),
 			),
+			SpoolPath: getEnv("KAFKA_SPOOL_PATH", "./data/kafka-spool.jsonl"),
 		},
 		Static: StaticConfig{
 			RootDir:   getEnv("SPA_ROOT", "./website/dist"),
@@ -91,6 +179,53 @@ func LoadAppConfig() AppConfig {
 			APISecret: getEnv("LIVEKIT_API_SECRET", "secret"),
 			URL:       getEnv("LIVEKIT_URL", "http://127.0.0.1:7880"),
 		},
+		Privacy: PrivacyConfig{
+			DeletionGrace:  DurationEnv("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+			WorkerInterval: DurationEnv("ACCOUNT_DELETION_INTERVAL", time.Hour),
+		},
+		Group: GroupConfig{
+			RestoreRetention: DurationEnv("GROUP_RESTORE_RETENTION", 30*24*time.Hour),
+			PurgeInterval:    DurationEnv("GROUP_PURGE_INTERVAL", time.Hour),
+		},
+		Poll: PollConfig{
+			CloseInterval: DurationEnv("POLL_CLOSE_INTERVAL", 30*time.Second),
+		},
+		Upload: UploadConfig{
+			PartSize:        int64(IntEnv("UPLOAD_PART_SIZE_MB", 8)) * 1024 * 1024,
+			PresignExpiry:   DurationEnv("UPLOAD_PRESIGN_EXPIRY", 15*time.Minute),
+			SessionTTL:      DurationEnv("UPLOAD_SESSION_TTL", 24*time.Hour),
+			CleanupInterval: DurationEnv("UPLOAD_CLEANUP_INTERVAL", time.Hour),
+		},
+		MediaAccess: MediaAccessConfig{
+			URLSecret: getEnv("MEDIA_URL_SECRET", getEnv("JWT_SECRET", "your-secret-key")),
+			URLTTL:    DurationEnv("MEDIA_URL_TTL", 10*time.Minute),
+		},
+		Outbox: OutboxConfig{
+			RelayInterval: DurationEnv("OUTBOX_RELAY_INTERVAL", 500*time.Millisecond),
+		},
+		EventBus: EventBusConfig{
+			Driver:       strings.ToLower(getEnv("EVENT_BUS", "kafka")),
+			PollInterval: DurationEnv("EVENT_BUS_POLL_INTERVAL", 200*time.Millisecond),
+		},
+		Telemetry: TelemetryConfig{
+			ServiceName:  getEnv("OTEL_SERVICE_NAME", "unichat-api"),
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			SampleRatio:  FloatEnv("OTEL_TRACES_SAMPLER_ARG", 0.1),
+		},
+		Log: LogConfig{
+			Level:   getEnv("LOG_LEVEL", "info"),
+			Modules: getEnv("LOG_LEVELS", ""),
+			Format:  strings.ToLower(getEnv("LOG_FORMAT", "json")),
+		},
+		Shutdown: ShutdownConfig{
+			Timeout:       DurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
+			ReconnectHint: DurationEnv("WS_RECONNECT_HINT", 2*time.Second),
+		},
+		RateLimit: RateLimitConfig{
+			Backend:    strings.ToLower(getEnv("RATE_LIMIT_BACKEND", "memory")),
+			Policies:   getEnv("RATE_LIMIT_POLICIES", "public=20/s:40,auth=10/m:10,api=30/s:60,upload=5/s:10"),
+			WSPolicies: getEnv("WS_RATE_LIMITS", "chat=10/s:20,send-reaction=5/s:10,edit-message=2/s:5"),
+		},
 	}
 }
 
@@ -121,3 +256,23 @@ func DurationEnv(key string, fallback time.Duration) time.Duration {
 	}
 	return fallback
 }
+
+// IntEnv returns an integer parsed from the given environment variable.
+func IntEnv(key string, fallback int) int {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// FloatEnv returns a float parsed from the given environment variable.
+func FloatEnv(key string, fallback float64) float64 {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"crypto/tls"
 	"log"
+	"my-app/common/telemetry"
 	"net/http"
 	"time"
 
@@ -18,7 +19,7 @@ func (c AppConfig) NewESClient() *elasticsearch.Client {
 		Addresses: c.Elasticsearch.Addresses,
 		Username:  c.Elasticsearch.Username,
 		Password:  c.Elasticsearch.Password,
-		Transport: tr, // gán transport vào đây
+		Transport: telemetry.ESTransport(tr), // gán transport vào đây
 	}
 
 		log.Println("[DEBUG] ES_ADDRESSES:", c.Elasticsearch.Addresses)
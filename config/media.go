@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// MediaProcessingConfig cấu hình pipeline xử lý media sau upload (cần ffmpeg/ffprobe trên máy chạy consumer)
+type MediaProcessingConfig struct {
+	FFmpegPath  string
+	FFprobePath string
+	WorkDir     string        // thư mục tạm chứa file gốc và biến thể trong lúc xử lý
+	Timeout     time.Duration // thời gian tối đa cho một media
+	Concurrency int           // số media xử lý song song trên một consumer
+}
+
+func LoadMediaProcessing() MediaProcessingConfig {
+	return MediaProcessingConfig{
+		FFmpegPath:  getEnv("FFMPEG_PATH", "ffmpeg"),
+		FFprobePath: getEnv("FFPROBE_PATH", "ffprobe"),
+		WorkDir:     getEnv("MEDIA_WORK_DIR", os.TempDir()),
+		Timeout:     DurationEnv("MEDIA_PROCESSING_TIMEOUT", 2*time.Minute),
+		Concurrency: IntEnv("MEDIA_PROCESSING_CONCURRENCY", 2),
+	}
+}
+
+// MediaScanConfig cấu hình bước quét mã độc file upload
+type MediaScanConfig struct {
+	Scanner      string        // "clamd" (mặc định) hoặc "fake" khi chạy local không có ClamAV
+	ClamdNetwork string        // "tcp" hoặc "unix"
+	ClamdAddress string        // host:port hoặc đường dẫn socket
+	Timeout      time.Duration // thời gian tối đa quét một file
+	MaxBytes     int64         // 0 = theo dung lượng upload tối đa; không được nhỏ hơn giới hạn upload, StreamMaxLength của clamd phải >= giá trị này
+	Interval     time.Duration // chu kỳ worker kiểm tra media chờ quét
+	Concurrency  int
+}
+
+func LoadMediaScan() MediaScanConfig {
+	return MediaScanConfig{
+		Scanner:      getEnv("MEDIA_SCANNER", "clamd"),
+		ClamdNetwork: getEnv("CLAMD_NETWORK", "tcp"),
+		ClamdAddress: getEnv("CLAMD_ADDRESS", "127.0.0.1:3310"),
+		Timeout:      DurationEnv("MEDIA_SCAN_TIMEOUT", 10*time.Minute), // đủ cho file video 2GB
+		MaxBytes:     int64(IntEnv("MEDIA_SCAN_MAX_MB", 0)) * 1024 * 1024,
+		Interval:     DurationEnv("MEDIA_SCAN_INTERVAL", 5*time.Second),
+		Concurrency:  IntEnv("MEDIA_SCAN_CONCURRENCY", 2),
+	}
+}
@@ -10,6 +10,13 @@ import (
 
 var MinioClient *minio.Client
 
+// QuarantineBucket chứa file bị phát hiện mã độc, tách khỏi bucket phục vụ người dùng
+const QuarantineBucket = "unichat-quarantine"
+
+// MinioPresignClient ký presigned URL theo endpoint public mà client truy cập được
+// (chữ ký S3 gắn với host nên không thể ký bằng endpoint nội bộ rồi thay host).
+var MinioPresignClient *minio.Client
+
 func InitMinio() {
 	endpoint := getEnv("MINIO_ENDPOINT", "localhost:9000")
 	accessKey := getEnv("MINIO_ROOT_USER", "dthuadmin")
@@ -29,17 +36,33 @@ func InitMinio() {
 
 	log.Println("Kết nối minio thành công!", endpoint)
 
+	MinioPresignClient = minioClient
+	if publicEndpoint := getEnv("MINIO_PUBLIC_ENDPOINT", ""); publicEndpoint != "" && publicEndpoint != endpoint {
+		// Khai báo region để minio-go ký URL offline, không gọi lên endpoint public
+		presignClient, err := minio.New(publicEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+			Secure: getEnv("MINIO_PUBLIC_USE_SSL", getEnv("MINIO_USE_SSL", "false")) == "true",
+			Region: "us-east-1",
+		})
+		if err != nil {
+			log.Fatalln(" Cấu hình Minio public endpoint lỗi:", err)
+		}
+		MinioPresignClient = presignClient
+	}
+
 	// Tạo bucket nếu chưa có
 	ctx := context.Background()
 	bucketName := "unichat"
 	location := "us-east-1"
 
-	exists, err := MinioClient.BucketExists(ctx, bucketName)
-	if err == nil && !exists {
-		err = MinioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: location})
-		if err != nil {
-			log.Fatalln("Can't create bucket:", err)
+	for _, name := range []string{bucketName, QuarantineBucket} {
+		exists, err := MinioClient.BucketExists(ctx, name)
+		if err == nil && !exists {
+			err = MinioClient.MakeBucket(ctx, name, minio.MakeBucketOptions{Region: location})
+			if err != nil {
+				log.Fatalln("Can't create bucket:", err)
+			}
+			log.Println("Created bucket:", name)
 		}
-		log.Println("Created bucket:", bucketName)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"my-app/common/telemetry"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -21,7 +23,7 @@ func ConnectMongo(ctx context.Context, uri, dbName string) (*mongo.Database, err
 		return nil, errors.New("mongo database name is required")
 	}
 
-	client, err := mongo.NewClient(options.Client().ApplyURI(uri).SetMaxPoolSize(500))
+	client, err := mongo.NewClient(options.Client().ApplyURI(uri).SetMaxPoolSize(500).SetMonitor(telemetry.MongoMonitor()))
 	if err != nil {
 		return nil, fmt.Errorf("cannot create mongo client: %w", err)
 	}
@@ -37,6 +39,6 @@ func ConnectMongo(ctx context.Context, uri, dbName string) (*mongo.Database, err
 		return nil, fmt.Errorf("mongo ping failed: %w", err)
 	}
 
-	fmt.Println("Connected to MongoDB")
+	log.Println("Connected to MongoDB")
 	return client.Database(dbName), nil
 }
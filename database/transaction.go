@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	txSupportOnce sync.Once
+	txSupported   bool
+)
+
+// WithTransaction chạy fn trong một Mongo transaction: mọi thao tác dùng ctx truyền vào fn cùng commit hoặc cùng rollback.
+// Mongo chạy standalone (không phải replica set / mongos) không hỗ trợ transaction, khi đó fn chạy trực tiếp.
+func WithTransaction(ctx context.Context, db *mongo.Database, fn func(ctx context.Context) error) error {
+	if !supportsTransactions(db) {
+		return fn(ctx)
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// TransactionsSupported kiểm tra topology một lần, gọi lúc khởi động để báo sớm khi Mongo chạy standalone
+func TransactionsSupported(db *mongo.Database) bool {
+	return supportsTransactions(db)
+}
+
+func supportsTransactions(db *mongo.Database) bool {
+	txSupportOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var hello struct {
+			SetName string `bson:"setName"`
+			Msg     string `bson:"msg"`
+		}
+		if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+			log.Printf("⚠️ Không kiểm tra được topology MongoDB, tắt transaction: %v", err)
+			return
+		}
+		txSupported = hello.SetName != "" || hello.Msg == "isdbgrid"
+		if !txSupported {
+			log.Println("⚠️⚠️⚠️ MongoDB chạy standalone: transaction bị tắt, outbox và dữ liệu nghiệp vụ được ghi KHÔNG nguyên tử, sự kiện có thể mất khi lỗi giữa chừng. Hãy chạy replica set (docker-compose đã cấu hình rs0) hoặc đặt MONGO_REQUIRE_TRANSACTIONS=true để chặn khởi động.")
+		}
+	})
+	return txSupported
+}
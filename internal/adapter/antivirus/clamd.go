@@ -0,0 +1,147 @@
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"my-app/modules/chat/models"
+	"net"
+	"strings"
+	"time"
+)
+
+// Kích thước mỗi chunk INSTREAM gửi cho clamd
+const clamdChunkSize = 64 * 1024
+
+// Clamd - quét file qua giao thức clamd (lệnh INSTREAM), không cần chia sẻ filesystem với ClamAV
+type Clamd struct {
+	network  string
+	address  string
+	maxBytes int64
+}
+
+func NewClamd(network, address string, maxBytes int64) *Clamd {
+	return &Clamd{network: network, address: address, maxBytes: maxBytes}
+}
+
+// Scan gửi nội dung cho clamd. File lớn hơn maxBytes không được quét một phần mà trả về models.ErrScanTooLarge,
+// vì phần đuôi không quét có thể chứa mã độc.
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*models.ScanResult, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: không kết nối được: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("clamd: gửi lệnh lỗi: %w", err)
+	}
+
+	if err := writeInstream(conn, r, c.maxBytes); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("clamd: đọc kết quả lỗi: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// writeInstream gửi r theo framing INSTREAM: các chunk [độ dài uint32 big-endian][dữ liệu], kết thúc bằng chunk độ dài 0.
+// Đọc quá maxBytes (> 0) thì dừng và trả về models.ErrScanTooLarge, không gửi chunk kết thúc.
+func writeInstream(w io.Writer, r io.Reader, maxBytes int64) error {
+	if maxBytes > 0 {
+		// Đọc dư 1 byte để phân biệt file vừa đúng giới hạn với file lớn hơn
+		r = io.LimitReader(r, maxBytes+1)
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	var sent int64
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			sent += int64(n)
+			if maxBytes > 0 && sent > maxBytes {
+				return models.ErrScanTooLarge
+			}
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := w.Write(size); err != nil {
+				return fmt.Errorf("clamd: gửi dữ liệu lỗi: %w", err)
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return fmt.Errorf("clamd: gửi dữ liệu lỗi: %w", err)
+			}
+		}
+		if errors.Is(rerr, io.EOF) {
+			break
+		}
+		if rerr != nil {
+			return fmt.Errorf("clamd: đọc file lỗi: %w", rerr)
+		}
+	}
+
+	// chunk độ dài 0 báo kết thúc stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := w.Write(size); err != nil {
+		return fmt.Errorf("clamd: gửi dữ liệu lỗi: %w", err)
+	}
+	return nil
+}
+
+// Ping kiểm tra clamd còn sống
+func (c *Clamd) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if strings.TrimRight(reply, "\x00\n") != "PONG" {
+		return fmt.Errorf("clamd: phản hồi PING không hợp lệ: %q", reply)
+	}
+	return nil
+}
+
+// parseClamdReply đọc các dạng "stream: OK", "stream: <tên> FOUND", "<lý do> ERROR"
+func parseClamdReply(reply string) (*models.ScanResult, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &models.ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &models.ScanResult{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasPrefix(reply, "INSTREAM size limit exceeded"):
+		// StreamMaxLength của clamd nhỏ hơn MEDIA_SCAN_MAX_MB
+		return nil, fmt.Errorf("clamd: StreamMaxLength nhỏ hơn giới hạn quét: %w", models.ErrScanTooLarge)
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("clamd: phản hồi không hợp lệ: %q", reply)
+	}
+}
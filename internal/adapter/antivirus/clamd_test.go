@@ -0,0 +1,237 @@
+package antivirus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"my-app/modules/chat/models"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		infected  bool
+		signature string
+		wantErr   error // nil: chỉ cần có lỗi khi errText khác rỗng
+		errText   string
+	}{
+		{name: "ok", reply: "stream: OK\x00"},
+		{name: "ok không prefix", reply: "OK\n"},
+		{name: "found", reply: "stream: Eicar-Test-Signature FOUND\x00", infected: true, signature: "Eicar-Test-Signature"},
+		{name: "error", reply: "Can't allocate memory ERROR\x00", errText: "Can't allocate memory"},
+		{name: "vượt StreamMaxLength", reply: "INSTREAM size limit exceeded. ERROR\x00", wantErr: models.ErrScanTooLarge},
+		{name: "không hợp lệ", reply: "UNKNOWN COMMAND\x00", errText: "không hợp lệ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := parseClamdReply(tt.reply)
+			if tt.wantErr != nil || tt.errText != "" {
+				if err == nil {
+					t.Fatalf("muốn lỗi, nhận %+v", res)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("lỗi = %v, muốn %v", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("lỗi = %v, muốn chứa %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lỗi không mong muốn: %v", err)
+			}
+			if res.Infected != tt.infected || res.Signature != tt.signature {
+				t.Fatalf("kết quả = %+v, muốn infected=%v signature=%q", res, tt.infected, tt.signature)
+			}
+		})
+	}
+}
+
+// readInstream tách các chunk INSTREAM, trả về dữ liệu ghép lại, kích thước từng chunk và có gặp chunk kết thúc không
+func readInstream(t *testing.T, raw []byte) (data []byte, sizes []int, terminated bool) {
+	t.Helper()
+	r := bytes.NewReader(raw)
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			if errors.Is(err, io.EOF) {
+				return data, sizes, false
+			}
+			t.Fatalf("đọc độ dài chunk lỗi: %v", err)
+		}
+		if size == 0 {
+			if r.Len() != 0 {
+				t.Fatalf("còn %d byte sau chunk kết thúc", r.Len())
+			}
+			return data, sizes, true
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			t.Fatalf("chunk thiếu dữ liệu: %v", err)
+		}
+		data = append(data, chunk...)
+		sizes = append(sizes, int(size))
+	}
+}
+
+func TestWriteInstream(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		maxBytes   int64
+		wantChunks []int
+		wantErr    error
+	}{
+		{name: "file rỗng", size: 0, wantChunks: nil},
+		{name: "nhỏ hơn 1 chunk", size: 10, wantChunks: []int{10}},
+		{name: "nhiều chunk", size: 2*clamdChunkSize + 5, wantChunks: []int{clamdChunkSize, clamdChunkSize, 5}},
+		{name: "vừa đúng giới hạn", size: 1000, maxBytes: 1000, wantChunks: []int{1000}},
+		{name: "vượt giới hạn", size: 1001, maxBytes: 1000, wantErr: models.ErrScanTooLarge},
+		{name: "vượt giới hạn ở chunk sau", size: clamdChunkSize + 1, maxBytes: clamdChunkSize, wantErr: models.ErrScanTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := bytes.Repeat([]byte("a"), tt.size)
+			var out bytes.Buffer
+
+			err := writeInstream(&out, bytes.NewReader(content), tt.maxBytes)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("lỗi = %v, muốn %v", err, tt.wantErr)
+				}
+				// Không được gửi chunk kết thúc, clamd sẽ không trả kết quả cho stream dở dang
+				if _, _, terminated := readInstream(t, out.Bytes()); terminated {
+					t.Fatal("file vượt giới hạn vẫn gửi chunk kết thúc")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lỗi không mong muốn: %v", err)
+			}
+
+			data, sizes, terminated := readInstream(t, out.Bytes())
+			if !terminated {
+				t.Fatal("thiếu chunk kết thúc")
+			}
+			if !bytes.Equal(data, content) {
+				t.Fatalf("dữ liệu gửi đi %d byte, muốn %d", len(data), len(content))
+			}
+			if len(sizes) != len(tt.wantChunks) {
+				t.Fatalf("chunk = %v, muốn %v", sizes, tt.wantChunks)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.wantChunks[i] {
+					t.Fatalf("chunk = %v, muốn %v", sizes, tt.wantChunks)
+				}
+			}
+		})
+	}
+}
+
+func TestClamdScan(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("không mở được cổng: %v", err)
+	}
+	defer ln.Close()
+
+	// clamd giả: đọc lệnh và stream rồi trả FOUND nếu có chuỗi EICAR
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				cmd := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+					return
+				}
+				var data []byte
+				for {
+					var size uint32
+					if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					if _, err := io.ReadFull(conn, chunk); err != nil {
+						return
+					}
+					data = append(data, chunk...)
+				}
+				reply := "stream: OK\x00"
+				if bytes.Contains(data, []byte(EICARSignature)) {
+					reply = "stream: Eicar-Test-Signature FOUND\x00"
+				}
+				conn.Write([]byte(reply))
+			}(conn)
+		}
+	}()
+
+	c := NewClamd("tcp", ln.Addr().String(), 1024)
+	ctx := context.Background()
+
+	res, err := c.Scan(ctx, strings.NewReader("hello"))
+	if err != nil || res.Infected {
+		t.Fatalf("file sạch: res=%+v err=%v", res, err)
+	}
+
+	res, err = c.Scan(ctx, strings.NewReader("prefix "+EICARSignature))
+	if err != nil || !res.Infected || res.Signature != "Eicar-Test-Signature" {
+		t.Fatalf("file EICAR: res=%+v err=%v", res, err)
+	}
+
+	// Mã độc nằm sau giới hạn quét: không được báo sạch
+	tail := strings.Repeat("a", 2048) + EICARSignature
+	if res, err := c.Scan(ctx, strings.NewReader(tail)); !errors.Is(err, models.ErrScanTooLarge) {
+		t.Fatalf("file vượt giới hạn: res=%+v err=%v, muốn ErrScanTooLarge", res, err)
+	}
+}
+
+func TestFakeScan(t *testing.T) {
+	scanErr := errors.New("scanner down")
+
+	tests := []struct {
+		name      string
+		scanner   *Fake
+		content   string
+		infected  bool
+		signature string
+		wantErr   error
+	}{
+		{name: "sạch", scanner: NewFake(), content: "hello"},
+		{name: "EICAR", scanner: NewFake(), content: "x" + EICARSignature + "y", infected: true, signature: "Eicar-Test-Signature"},
+		{name: "mẫu tự thêm", scanner: &Fake{Signatures: map[string]string{"BAD": "Test.Bad"}}, content: "this is BAD", infected: true, signature: "Test.Bad"},
+		{name: "bộ quét lỗi", scanner: &Fake{Err: scanErr}, content: "hello", wantErr: scanErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.scanner.Scan(context.Background(), strings.NewReader(tt.content))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("lỗi = %v, muốn %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lỗi không mong muốn: %v", err)
+			}
+			if res.Infected != tt.infected || res.Signature != tt.signature {
+				t.Fatalf("kết quả = %+v, muốn infected=%v signature=%q", res, tt.infected, tt.signature)
+			}
+		})
+	}
+}
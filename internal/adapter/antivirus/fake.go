@@ -0,0 +1,39 @@
+package antivirus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"my-app/modules/chat/models"
+)
+
+// EICARSignature - chuỗi kiểm thử chuẩn, mọi bộ quét đều báo là mã độc
+const EICARSignature = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake - bộ quét giả cho môi trường dev/test không có ClamAV.
+// Báo nhiễm khi nội dung chứa một trong các mẫu trong Signatures, Err dùng để giả lập bộ quét lỗi.
+type Fake struct {
+	Signatures map[string]string // mẫu byte -> tên mã độc
+	Err        error
+}
+
+func NewFake() *Fake {
+	return &Fake{Signatures: map[string]string{EICARSignature: "Eicar-Test-Signature"}}
+}
+
+func (f *Fake) Scan(_ context.Context, r io.Reader) (*models.ScanResult, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	for pattern, name := range f.Signatures {
+		if bytes.Contains(data, []byte(pattern)) {
+			return &models.ScanResult{Infected: true, Signature: name}, nil
+		}
+	}
+	return &models.ScanResult{}, nil
+}
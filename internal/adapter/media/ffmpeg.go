@@ -0,0 +1,101 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"my-app/modules/chat/models"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FFmpeg - đọc thông tin, resize ảnh, tạo WebP và trích khung hình video bằng ffmpeg/ffprobe
+type FFmpeg struct {
+	ffmpegPath  string
+	ffprobePath string
+}
+
+func NewFFmpeg(ffmpegPath, ffprobePath string) *FFmpeg {
+	return &FFmpeg{ffmpegPath: ffmpegPath, ffprobePath: ffprobePath}
+}
+
+// Probe đọc kích thước luồng hình đầu tiên và thời lượng (nếu là video)
+func (f *FFmpeg) Probe(ctx context.Context, src string) (*models.MediaProbe, error) {
+	out, err := f.run(ctx, f.ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		src,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("ffprobe: không đọc được kết quả: %w", err)
+	}
+	if len(parsed.Streams) == 0 {
+		return nil, fmt.Errorf("ffprobe: không tìm thấy luồng hình trong %s", filepath.Base(src))
+	}
+
+	probe := &models.MediaProbe{Width: parsed.Streams[0].Width, Height: parsed.Streams[0].Height}
+	if d, err := strconv.ParseFloat(parsed.Format.Duration, 64); err == nil {
+		probe.Duration = d
+	}
+	return probe, nil
+}
+
+// ResizeImage thu nhỏ về cạnh dài tối đa maxEdge (không phóng to), định dạng theo đuôi file dst
+func (f *FFmpeg) ResizeImage(ctx context.Context, src, dst string, maxEdge int) error {
+	scale := fmt.Sprintf("scale='min(iw,%d)':'min(ih,%d)':force_original_aspect_ratio=decrease", maxEdge, maxEdge)
+	args := []string{"-y", "-v", "error", "-i", src, "-frames:v", "1", "-vf", scale}
+	args = append(args, encodeArgs(dst)...)
+	args = append(args, dst)
+
+	_, err := f.run(ctx, f.ffmpegPath, args...)
+	return err
+}
+
+// ExtractFrame lấy một khung hình tại giây thứ at, giữ kích thước gốc
+func (f *FFmpeg) ExtractFrame(ctx context.Context, src, dst string, at float64) error {
+	args := []string{"-y", "-v", "error", "-ss", strconv.FormatFloat(at, 'f', 3, 64), "-i", src, "-frames:v", "1"}
+	args = append(args, encodeArgs(dst)...)
+	args = append(args, dst)
+
+	_, err := f.run(ctx, f.ffmpegPath, args...)
+	return err
+}
+
+func encodeArgs(dst string) []string {
+	switch strings.ToLower(filepath.Ext(dst)) {
+	case ".webp":
+		return []string{"-c:v", "libwebp", "-quality", "80"}
+	default:
+		return []string{"-q:v", "3"}
+	}
+}
+
+func (f *FFmpeg) run(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", filepath.Base(bin), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -3,17 +3,31 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"my-app/common/kafka"
+	"my-app/common/outbox"
+	"my-app/common/ratelimit"
+	"my-app/common/telemetry"
 	"my-app/config"
 	"my-app/database"
+	"my-app/internal/adapter/antivirus"
+	"my-app/internal/health"
 	"my-app/internal/indexer"
 	"my-app/internal/seeder"
+	"my-app/middleware"
+	chatBiz "my-app/modules/chat/biz"
+	chatModels "my-app/modules/chat/models"
+	chatStorage "my-app/modules/chat/storage"
 	chatws "my-app/modules/chat/transport/websocket"
+	groupBiz "my-app/modules/group/biz"
+	groupStorage "my-app/modules/group/storage"
 	"my-app/modules/loadtest"
+	privacyBiz "my-app/modules/privacy/biz"
+	privacyStorage "my-app/modules/privacy/storage"
 	"my-app/utils"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -24,7 +38,15 @@ type Application struct {
 	server     *http.Server
 	kafkaStop  context.CancelFunc
 	kafkaErrCh chan error
+	kafkaDone  chan struct{} // đóng khi consumer đã lưu nốt batch và commit offset
 	ESClient   *elasticsearch.Client
+	workerStop context.CancelFunc
+
+	hub     *chatws.Hub
+	bus     kafka.EventBus
+	checker *health.Checker
+
+	shutdownTracing func(context.Context) error
 }
 
 func New(ctx context.Context, cfg config.AppConfig) (*Application, error) {
@@ -32,6 +54,15 @@ func New(ctx context.Context, cfg config.AppConfig) (*Application, error) {
 		return nil, err
 	}
 
+	shutdownTracing, err := telemetry.InitTracing(ctx, telemetry.Options{
+		ServiceName:  cfg.Telemetry.ServiceName,
+		OTLPEndpoint: cfg.Telemetry.OTLPEndpoint,
+		SampleRatio:  cfg.Telemetry.SampleRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	config.InitCloudinary()
 	config.InitMinio()
 	esClient := cfg.NewESClient()
@@ -42,6 +73,10 @@ func New(ctx context.Context, cfg config.AppConfig) (*Application, error) {
 	}
 	loadtest.SetDB(db)
 
+	if !database.TransactionsSupported(db) && cfg.Mongo.RequireTransactions {
+		return nil, errors.New("MongoDB không hỗ trợ transaction (standalone) trong khi MONGO_REQUIRE_TRANSACTIONS=true")
+	}
+
 	// Auto-seed data if not exists
 	go func() {
 		log.Println("Checking database seeding...")
@@ -50,23 +85,127 @@ func New(ctx context.Context, cfg config.AppConfig) (*Application, error) {
 		indexer.Execute(db)
 	}()
 
-	if err := kafka.InitAsyncProducer(cfg.Kafka.Brokers); err != nil {
+	scanCfg := config.LoadMediaScan()
+	if scanCfg.MaxBytes <= 0 {
+		scanCfg.MaxBytes = utils.MaxUploadSize()
+	}
+	// File lớn hơn giới hạn quét bị đánh dấu too_large và không bao giờ tải được, nên không cho cấu hình thấp hơn giới hạn upload
+	if scanCfg.MaxBytes < utils.MaxUploadSize() {
+		return nil, fmt.Errorf("MEDIA_SCAN_MAX_MB=%d nhỏ hơn dung lượng upload tối đa %dMB", scanCfg.MaxBytes/(1024*1024), utils.MaxUploadSize()/(1024*1024))
+	}
+	if scanCfg.Scanner != "fake" {
+		log.Printf("ℹ️ Quét mã độc file tới %dMB, StreamMaxLength của clamd phải >= %dM", scanCfg.MaxBytes/(1024*1024), scanCfg.MaxBytes/(1024*1024))
+	}
+
+	policies, err := ratelimit.ParsePolicies(cfg.RateLimit.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_POLICIES: %w", err)
+	}
+	frameLimits, err := ratelimit.ParsePolicies(cfg.RateLimit.WSPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("WS_RATE_LIMITS: %w", err)
+	}
+	limiter, err := ratelimit.New(cfg.RateLimit.Backend, db)
+	if err != nil {
+		return nil, err
+	}
+	middleware.InitRateLimit(limiter, policies)
+
+	kafka.SetSpoolPath(cfg.Kafka.SpoolPath)
+	bus, err := kafka.NewEventBus(cfg.EventBus, cfg.Kafka.Brokers, db)
+	if bus == nil {
+		return nil, err
+	}
+	if err != nil {
 		log.Printf("❌ Failed to init async producer: %v", err)
 	}
 
 	consumerCtx, cancel := context.WithCancel(context.Background())
 	kafkaErrCh := make(chan error, 1)
+	kafkaDone := make(chan struct{})
 	go func() {
-		if err := kafka.StartConsumer(consumerCtx, cfg.Kafka.Brokers, cfg.Kafka.GroupID, cfg.Kafka.Topics, db, esClient); err != nil &&
+		defer close(kafkaDone)
+		if err := kafka.StartConsumer(consumerCtx, bus, cfg.Kafka.GroupID, cfg.Kafka.Topics, db, esClient); err != nil &&
 			!errors.Is(err, context.Canceled) {
 			kafkaErrCh <- err
 		}
 	}()
 
 	hub := chatws.NewHub(db)
+	// Frame luôn đếm trong RAM: frame của một kết nối chỉ đến instance giữ socket, và mỗi frame chat
+	// thêm một lệnh Mongo sẽ nhân đôi tải ghi của chat
+	hub.SetFrameLimits(ratelimit.NewMemory(), frameLimits)
 	go hub.Run()
 
-	router := buildRouter(cfg, db, hub)
+	telemetry.RegisterGaugeFunc("hub_broadcast_queue_depth", "Số sự kiện đang chờ trong Hub.Broadcast.", func() float64 {
+		return float64(len(hub.Broadcast))
+	})
+	telemetry.RegisterGaugeFunc("kafka_producer_pending_messages", "Số message producer đã nhận nhưng Kafka chưa ack.", func() float64 {
+		_, _, pending := kafka.GetMetrics()
+		return float64(pending)
+	})
+
+	// Worker xóa tài khoản đến hạn & dọn file export
+	workerCtx, workerStop := context.WithCancel(context.Background())
+	privacyStore := privacyStorage.NewMongoStore(db, esClient)
+	deletion := privacyBiz.NewDeletionBiz(privacyStore, cfg.Privacy.DeletionGrace)
+	deletion.OnDeleted = func(userID string) {
+		hub.Broadcast <- chatws.HubEvent{Type: "account_deleted", Payload: userID}
+	}
+	go privacyBiz.RunWorker(workerCtx, cfg.Privacy.WorkerInterval, deletion, privacyBiz.NewExportBiz(privacyStore))
+
+	// Worker xóa vĩnh viễn nhóm đã giải tán quá thời gian khôi phục
+	groupLifecycle := groupBiz.NewGroupLifecycleBiz(groupStorage.NewMongoStoreGroup(db), cfg.Group.RestoreRetention)
+	go groupBiz.RunPurgeWorker(workerCtx, cfg.Group.PurgeInterval, groupLifecycle)
+
+	// Worker tự đóng bình chọn khi tới hạn và gửi tin nhắn tổng kết
+	pollBiz := chatBiz.NewPollBiz(chatStorage.NewMongoChatStore(db))
+	go chatBiz.RunPollCloseWorker(workerCtx, cfg.Poll.CloseInterval, pollBiz, hub.PublishPollClosed)
+
+	// Worker hủy phiên upload trực tiếp quá hạn và dọn multipart dở dang trên MinIO
+	uploadSessions := chatBiz.NewUploadSessionBiz(chatStorage.NewMongoChatStore(db), chatBiz.UploadSessionOptions{
+		PartSize:      cfg.Upload.PartSize,
+		PresignExpiry: cfg.Upload.PresignExpiry,
+		SessionTTL:    cfg.Upload.SessionTTL,
+	})
+	go chatBiz.RunUploadCleanupWorker(workerCtx, cfg.Upload.CleanupInterval, uploadSessions)
+
+	// Worker quét mã độc file upload, file sạch mới được đưa sang pipeline xử lý media
+	mediaScans := chatBiz.NewMediaScanBiz(chatStorage.NewMongoChatStore(db), newMalwareScanner(scanCfg), scanCfg.Timeout)
+	go chatBiz.RunMediaScanWorker(workerCtx, scanCfg.Interval, scanCfg.Concurrency, mediaScans, func(media *chatModels.Media) {
+		if media.ScanStatus == chatModels.ScanClean && media.ProcessingStatus == chatModels.ProcessingPending {
+			kafka.EnqueueMediaProcessing(media.ID.Hex())
+		}
+		hub.PublishMediaScanned(media)
+	})
+
+	// Relay outbox: publish sự kiện đã ghi cùng transaction nghiệp vụ lên Kafka
+	go kafka.RunOutboxRelay(workerCtx, bus, outbox.NewStore(db), cfg.Outbox.RelayInterval)
+
+	checker := health.NewChecker(
+		health.MongoCheck(db),
+		health.ElasticsearchCheck(esClient),
+		health.MinioCheck(config.MinioClient, "unichat"),
+		health.EventBusCheck(bus),
+		health.LiveKitCheck(cfg.LiveKit),
+	)
+	diagnostics := health.NewDiagnostics(checker,
+		health.Section{Name: "hub", Collect: func(context.Context) (any, error) {
+			return hub.Stats(), nil
+		}},
+		health.Section{Name: "producer", Collect: func(context.Context) (any, error) {
+			sent, failed, pending := kafka.GetMetrics()
+			return map[string]any{"sent": sent, "errors": failed, "pending": pending, "healthy": kafka.IsHealthy()}, nil
+		}},
+		health.Section{Name: "consumer_lag", Timeout: 10 * time.Second, Collect: func(ctx context.Context) (any, error) {
+			return bus.Lag(ctx, cfg.Kafka.GroupID, cfg.Kafka.Topics)
+		}},
+		health.Section{Name: "indexes", Collect: func(context.Context) (any, error) {
+			return indexer.Status(), nil
+		}},
+	)
+
+	router := buildRouter(cfg, db, hub, checker, diagnostics)
 	server := &http.Server{
 		Addr:              cfg.HTTPAddress,
 		Handler:           router,
@@ -78,7 +217,15 @@ func New(ctx context.Context, cfg config.AppConfig) (*Application, error) {
 		server:     server,
 		kafkaStop:  cancel,
 		kafkaErrCh: kafkaErrCh,
+		kafkaDone:  kafkaDone,
 		ESClient:   esClient,
+		workerStop: workerStop,
+
+		hub:     hub,
+		bus:     bus,
+		checker: checker,
+
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
@@ -92,35 +239,75 @@ func (a *Application) Run(ctx context.Context) error {
 		errCh <- nil
 	}()
 
-	select {
-	case <-ctx.Done():
-		return a.shutdown(context.Background())
-	case err := <-errCh:
-		if err != nil {
-			_ = a.shutdown(context.Background())
-			return err
-		}
-	case err := <-a.kafkaErrCh:
-		if err != nil {
-			log.Printf("⚠️ Kafka Consumer Error (Running without Kafka): %v", err)
-			// Do not shutdown
-			// _ = a.shutdown(context.Background())
-			// return err
+	kafkaErrCh := a.kafkaErrCh
+	for {
+		select {
+		case <-ctx.Done():
+			return a.shutdown(context.Background())
+		case err := <-errCh:
+			if err != nil {
+				_ = a.shutdown(context.Background())
+				return err
+			}
+			return nil
+		case err := <-kafkaErrCh:
+			// Server vẫn chạy nhưng tin nhắn không còn được lưu; chạy không cần Kafka thì đặt EVENT_BUS=memory hoặc mongo
+			log.Printf("⚠️ Event bus consumer stopped, chat persistence is DISABLED: %v (set EVENT_BUS=memory|mongo to run without Kafka)", err)
+			kafkaErrCh = nil
 		}
 	}
-
-	return nil
 }
 
+// shutdown dừng theo thứ tự để không mất tin nhắn: ngừng nhận kết nối -> đưa client WebSocket sang instance
+// khác -> consumer lưu nốt batch và commit offset -> producer gửi hết message đang chờ. Tất cả trong SHUTDOWN_TIMEOUT.
 func (a *Application) shutdown(ctx context.Context) error {
+	shutdownCtx, cancel := context.WithTimeout(ctx, a.cfg.Shutdown.Timeout)
+	defer cancel()
+	log.Printf("🛑 Shutting down (deadline %s)...", a.cfg.Shutdown.Timeout)
+
+	// /readyz trả 503 để load balancer ngừng chuyển request tới
+	a.checker.SetDraining()
+
+	// Server.Shutdown đóng listener và đợi request thường; kết nối WebSocket đã hijack do Hub tự đóng
+	serverErrCh := make(chan error, 1)
+	go func() {
+		serverErrCh <- a.server.Shutdown(shutdownCtx)
+	}()
+	if err := a.hub.Shutdown(shutdownCtx, a.cfg.Shutdown.ReconnectHint); err != nil {
+		log.Printf("⚠️ WebSocket drain: %v", err)
+	}
+
+	a.workerStop()
 	a.kafkaStop()
+	select {
+	case <-a.kafkaDone:
+	case <-shutdownCtx.Done():
+		log.Println("⚠️ Consumer did not finish its last batch before the shutdown deadline")
+	}
 
-	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	// Producer đóng sau consumer: consumer còn gửi DLQ trong lúc xử lý batch cuối
+	if err := a.bus.Close(shutdownCtx); err != nil {
+		log.Printf("⚠️ Event bus close: %v", err)
+	}
+
+	serverErr := <-serverErrCh
 
-	if err := a.server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return err
+	// Đẩy nốt các span còn trong bộ đệm của exporter
+	if err := a.shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("⚠️ Tracing shutdown: %v", err)
 	}
 
+	if serverErr != nil && !errors.Is(serverErr, http.ErrServerClosed) {
+		return serverErr
+	}
+	log.Println("✅ Shutdown complete")
 	return nil
 }
+
+func newMalwareScanner(cfg config.MediaScanConfig) chatBiz.MalwareScanner {
+	if cfg.Scanner == "fake" {
+		log.Println("⚠️ MEDIA_SCANNER=fake: chỉ phát hiện file EICAR, không dùng cho production")
+		return antivirus.NewFake()
+	}
+	return antivirus.NewClamd(cfg.ClamdNetwork, cfg.ClamdAddress, cfg.MaxBytes)
+}
@@ -4,7 +4,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"my-app/common/telemetry"
 	"my-app/config"
+	"my-app/internal/health"
 	"my-app/middleware"
 	"my-app/modules/chat/transport/websocket"
 	"my-app/routes"
@@ -13,15 +15,21 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func buildRouter(cfg config.AppConfig, db *mongo.Database, hub *websocket.Hub) *gin.Engine {
+func buildRouter(cfg config.AppConfig, db *mongo.Database, hub *websocket.Hub, checker *health.Checker, diagnostics *health.Diagnostics) *gin.Engine {
 	router := gin.New()
 	router.Use(
-		gin.Logger(),
+		middleware.RequestContextMiddleware(),
+		// Đứng trước Recovery để request panic vẫn được ghi nhận là 500
+		middleware.MetricsMiddleware(),
 		gin.Recovery(),
 		middleware.CORSMiddleware(),
 	)
 
-	routes.InitRouter(router, db, hub, cfg.NewESClient(), cfg)
+	router.GET("/metrics", gin.WrapH(telemetry.Handler()))
+	router.GET("/healthz", health.LivenessHandler())
+	router.GET("/readyz", health.ReadinessHandler(checker))
+
+	routes.InitRouter(router, db, hub, cfg.NewESClient(), cfg, diagnostics)
 
 	if cfg.Static.AssetsDir != "" {
 		router.Static("/assets", cfg.Static.AssetsDir)
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"my-app/common/kafka"
+	"my-app/config"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// MongoCheck - Mongo là nơi lưu mọi dữ liệu, mất Mongo thì instance không phục vụ được
+func MongoCheck(db *mongo.Database) Check {
+	return Check{
+		Name:     "mongo",
+		Critical: true,
+		Impact:   "toàn bộ API",
+		Run: func(ctx context.Context) error {
+			return db.Client().Ping(ctx, readpref.Primary())
+		},
+	}
+}
+
+func ElasticsearchCheck(es *elasticsearch.Client) Check {
+	return Check{
+		Name:   "elasticsearch",
+		Impact: "tìm kiếm tin nhắn và người dùng",
+		Run: func(ctx context.Context) error {
+			if es == nil {
+				return errors.New("chưa khởi tạo client")
+			}
+			res, err := es.Ping(es.Ping.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			defer res.Body.Close()
+			if res.IsError() {
+				return fmt.Errorf("elasticsearch trả về %s", res.Status())
+			}
+			return nil
+		},
+	}
+}
+
+func MinioCheck(client *minio.Client, bucket string) Check {
+	return Check{
+		Name:   "minio",
+		Impact: "upload và tải file đính kèm",
+		Run: func(ctx context.Context) error {
+			if client == nil {
+				return errors.New("chưa khởi tạo client")
+			}
+			ok, err := client.BucketExists(ctx, bucket)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("bucket %s không tồn tại", bucket)
+			}
+			return nil
+		},
+	}
+}
+
+// EventBusCheck - bus lỗi thì tin nhắn vẫn được nhận (ghi spool / outbox) nhưng lưu và giao trễ
+func EventBusCheck(bus kafka.EventBus) Check {
+	return Check{
+		Name:   "event_bus",
+		Impact: "lưu tin nhắn và giao sự kiện bị trễ",
+		Run:    bus.Ping,
+	}
+}
+
+// LiveKitCheck chỉ kiểm tra cấu hình: server không giữ kết nối tới LiveKit, chỉ ký token cho client
+func LiveKitCheck(cfg config.LiveKitConfig) Check {
+	return Check{
+		Name:   "livekit",
+		Impact: "gọi thoại / video",
+		Run: func(context.Context) error {
+			if cfg.APIKey == "" || cfg.APISecret == "" {
+				return errors.New("thiếu LIVEKIT_API_KEY hoặc LIVEKIT_API_SECRET")
+			}
+			u, err := url.Parse(cfg.URL)
+			if err != nil || u.Host == "" {
+				return fmt.Errorf("LIVEKIT_URL không hợp lệ: %q", cfg.URL)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"my-app/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LivenessHandler - /healthz: process còn phục vụ HTTP, không phụ thuộc gì bên ngoài
+// (phụ thuộc lỗi không được làm orchestrator restart instance)
+func LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+	}
+}
+
+// ReadinessHandler - /readyz: 503 khi có check critical lỗi, 200 khi ok hoặc degraded
+func ReadinessHandler(checker *Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Run(c.Request.Context())
+
+		code := http.StatusOK
+		if report.Status == StatusDown {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, report)
+	}
+}
+
+// Section - một nhóm số liệu trên trang chẩn đoán (Hub, producer, lag của consumer, index...)
+type Section struct {
+	Name    string
+	Timeout time.Duration
+	Collect func(ctx context.Context) (any, error)
+}
+
+type Diagnostics struct {
+	checker  *Checker
+	sections []Section
+}
+
+func NewDiagnostics(checker *Checker, sections ...Section) *Diagnostics {
+	return &Diagnostics{checker: checker, sections: sections}
+}
+
+// Collect chạy readiness và thu số liệu các section song song; section lỗi trả về {"error": ...}
+func (d *Diagnostics) Collect(ctx context.Context) map[string]any {
+	out := make(map[string]any, len(d.sections)+1)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		report := d.checker.Run(ctx)
+		mu.Lock()
+		out["readiness"] = report
+		mu.Unlock()
+	}()
+
+	for _, section := range d.sections {
+		wg.Add(1)
+		go func(section Section) {
+			defer wg.Done()
+			timeout := section.Timeout
+			if timeout <= 0 {
+				timeout = 5 * time.Second
+			}
+			sectionCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			var value any
+			err := call(sectionCtx, func(ctx context.Context) error {
+				var err error
+				value, err = section.Collect(ctx)
+				return err
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				out[section.Name] = gin.H{"error": err.Error()}
+				return
+			}
+			out[section.Name] = value
+		}(section)
+	}
+	wg.Wait()
+	return out
+}
+
+// DiagnosticsHandler - trang chẩn đoán cho admin hệ thống
+func DiagnosticsHandler(d *Diagnostics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy thông tin chẩn đoán hệ thống thành công", d.Collect(c.Request.Context())))
+	}
+}
@@ -0,0 +1,140 @@
+// Package health - kiểm tra sức khỏe các phụ thuộc cho /healthz, /readyz và trang chẩn đoán của admin.
+// Check critical lỗi thì instance chưa sẵn sàng (503); check không critical lỗi chỉ làm trạng thái
+// "degraded": tính năng liên quan tạm ngưng nhưng chat vẫn chạy (VD: ES chết thì mất tìm kiếm).
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// Check - một phụ thuộc cần kiểm tra
+type Check struct {
+	Name     string
+	Critical bool
+	Impact   string // tính năng bị ảnh hưởng khi check lỗi, hiển thị trong báo cáo
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Result struct {
+	Status    Status `json:"status"`
+	Critical  bool   `json:"critical"`
+	Impact    string `json:"impact,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+type Report struct {
+	Status    Status            `json:"status"`
+	Draining  bool              `json:"draining,omitempty"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+type Checker struct {
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// SetDraining đánh dấu instance đang dừng: /readyz trả 503 để load balancer ngừng chuyển request tới
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Run chạy song song mọi check, mỗi check bị giới hạn bởi Timeout của nó
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]Result, len(c.checks)),
+		CheckedAt: time.Now(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			switch {
+			case result.Status == StatusOK:
+			case check.Critical:
+				report.Status = StatusDown
+			case report.Status == StatusOK:
+				report.Status = StatusDegraded
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDown
+		report.Draining = true
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := call(ctx, check.Run)
+	result := Result{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Impact = check.Impact
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// call trả về khi fn xong hoặc ctx hết hạn: một số client (sarama, minio) không dừng ngay theo ctx
+func call(ctx context.Context, fn func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("quá thời gian chờ: %w", ctx.Err())
+	}
+}
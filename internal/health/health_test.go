@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker_Status(t *testing.T) {
+	ok := Check{Name: "mongo", Critical: true, Run: func(context.Context) error { return nil }}
+	esDown := Check{Name: "elasticsearch", Run: func(context.Context) error { return errors.New("connection refused") }}
+	hang := Check{Name: "mongo", Critical: true, Timeout: 50 * time.Millisecond, Run: func(context.Context) error {
+		time.Sleep(time.Second) // client không tôn trọng ctx
+		return nil
+	}}
+
+	cases := []struct {
+		name   string
+		checks []Check
+		want   Status
+	}{
+		{"tất cả ok", []Check{ok}, StatusOK},
+		{"phụ thuộc không critical lỗi", []Check{ok, esDown}, StatusDegraded},
+		{"phụ thuộc critical quá hạn", []Check{hang, esDown}, StatusDown},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			report := NewChecker(tc.checks...).Run(context.Background())
+			if report.Status != tc.want {
+				t.Fatalf("status = %s, muốn %s: %+v", report.Status, tc.want, report.Checks)
+			}
+			if time.Since(start) > 500*time.Millisecond {
+				t.Fatalf("Run không tôn trọng timeout của check")
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package indexer
 import (
 	"context"
 	"log"
+	"my-app/modules/chat/models"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,6 +15,9 @@ func Execute(db *mongo.Database) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	begin()
+	defer finish()
+
 	// 1. Collection "messages"
 	messages := db.Collection("messages")
 
@@ -107,6 +111,20 @@ func Execute(db *mongo.Database) {
 		{Key: "user_id", Value: 1},
 		{Key: "friend_id", Value: 1},
 	}, false)
+	createIndex(ctx, friendShip, "idx_friend_status", bson.D{
+		{Key: "friend_id", Value: 1},
+		{Key: "status", Value: 1},
+	}, false)
+	createIndex(ctx, friendShip, "idx_user_status", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "status", Value: 1},
+	}, false)
+
+	// Gợi ý kết bạn đã bị bỏ qua
+	createIndex(ctx, db.Collection("friend_suggestion_dismissed"), "idx_user_dismissed_unique", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "dismissed_user_id", Value: 1},
+	}, true)
 
 	// 6. Collection "user_roles"
 	userRoles := db.Collection("user_roles")
@@ -140,6 +158,139 @@ func Execute(db *mongo.Database) {
 		{Key: "permission_id", Value: 1},
 	}, false)
 
+	// 11. Xuất dữ liệu & xóa tài khoản
+	createIndex(ctx, db.Collection("data_export_jobs"), "idx_export_user_status", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "status", Value: 1},
+	}, false)
+	accountDeletions := db.Collection("account_deletions")
+	createIndex(ctx, accountDeletions, "idx_deletion_user_status", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "status", Value: 1},
+	}, false)
+	createIndex(ctx, accountDeletions, "idx_deletion_due", bson.D{
+		{Key: "status", Value: 1},
+		{Key: "scheduled_at", Value: 1},
+	}, false)
+
+	// 12. Link mời & yêu cầu tham gia nhóm
+	groupInvites := db.Collection("group_invites")
+	createIndex(ctx, groupInvites, "idx_invite_code_unique", bson.D{
+		{Key: "code", Value: 1},
+	}, true)
+	createIndex(ctx, groupInvites, "idx_invite_group", bson.D{
+		{Key: "group_id", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, false)
+	createIndex(ctx, db.Collection("group_join_requests"), "idx_join_request_group_status", bson.D{
+		{Key: "group_id", Value: 1},
+		{Key: "status", Value: 1},
+		{Key: "user_id", Value: 1},
+	}, false)
+
+	// 13. Nhóm đã giải tán (khôi phục & xóa vĩnh viễn)
+	createIndex(ctx, db.Collection("group"), "idx_group_dissolved_at", bson.D{
+		{Key: "dissolved_at", Value: 1},
+	}, false)
+
+	// 14. Kênh trong cộng đồng
+	createIndex(ctx, db.Collection("group"), "idx_group_parent", bson.D{
+		{Key: "parent_id", Value: 1},
+		{Key: "created_at", Value: 1},
+	}, false)
+
+	// 15. Hộp thư nhắc đến (unique chống trùng khi Kafka giao lại)
+	createIndex(ctx, db.Collection("mentions"), "idx_mention_inbox", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "read_at", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, false)
+	createIndex(ctx, db.Collection("mentions"), "uniq_mention_user_message", bson.D{
+		{Key: "user_id", Value: 1},
+		{Key: "message_id", Value: 1},
+	}, true)
+
+	// 16. Tìm tin nhắn theo người được nhắc
+	createIndex(ctx, db.Collection("messages"), "idx_message_mentioned_users", bson.D{
+		{Key: "mentioned_user_ids", Value: 1},
+		{Key: "created_at", Value: -1},
+	}, false)
+
+	// 17. Bình chọn: mỗi user một phiếu, worker quét theo hạn chót
+	createIndex(ctx, db.Collection("poll_votes"), "uniq_poll_vote_user", bson.D{
+		{Key: "poll_id", Value: 1},
+		{Key: "user_id", Value: 1},
+	}, true)
+	createIndex(ctx, db.Collection("polls"), "idx_poll_open_deadline", bson.D{
+		{Key: "closed_at", Value: 1},
+		{Key: "deadline", Value: 1},
+	}, false)
+
+	// 18. Phiên upload trực tiếp: worker quét phiên quá hạn
+	createIndex(ctx, db.Collection("upload_sessions"), "idx_upload_session_status_expires", bson.D{
+		{Key: "status", Value: 1},
+		{Key: "expires_at", Value: 1},
+	}, false)
+
+	// 19. Media: worker quét mã độc lấy theo thứ tự upload, route tải file tra theo object key
+	createIndex(ctx, db.Collection("medias"), "idx_media_scan_queue", bson.D{
+		{Key: "scan_status", Value: 1},
+		{Key: "created_at", Value: 1},
+	}, false)
+	createIndex(ctx, db.Collection("medias"), "idx_media_url", bson.D{
+		{Key: "url", Value: 1},
+	}, false)
+
+	// 20. Quyền xem media: gỡ link khi thu hồi tin nhắn, tra tin nhắn chứa media cũ chưa có links
+	createIndex(ctx, db.Collection("medias"), "idx_media_link_message", bson.D{
+		{Key: "links.message_id", Value: 1},
+	}, false)
+	createPartialIndex(ctx, messages, "idx_message_media_ids_partial", bson.D{
+		{Key: "media_ids", Value: 1},
+	}, bson.M{
+		"media_ids": bson.M{"$exists": true},
+	})
+
+	// 21. DLQ: mỗi bản ghi DLQ chỉ replay một lần (trừ khi ép bằng -force)
+	createIndex(ctx, db.Collection("dead_letter_replays"), "uniq_dead_letter_replay", bson.D{
+		{Key: "dlq_topic", Value: 1},
+		{Key: "partition", Value: 1},
+		{Key: "offset", Value: 1},
+	}, true)
+
+	// 22. Outbox: relay lấy sự kiện chờ gửi theo thứ tự ghi; sự kiện đã gửi và idempotency key đã xử lý tự xóa
+	createIndex(ctx, db.Collection("outbox"), "idx_outbox_pending", bson.D{
+		{Key: "status", Value: 1},
+		{Key: "next_attempt_at", Value: 1},
+		{Key: "_id", Value: 1},
+	}, false)
+	createTTLIndex(ctx, db.Collection("outbox"), "ttl_outbox_published", "published_at", 3*24*time.Hour)
+	createTTLIndex(ctx, db.Collection("processed_events"), "ttl_processed_events", "claimed_at", 7*24*time.Hour)
+
+	// 23. Client message ID: client gửi lại (retry, reconnect) cùng một ID thì chỉ lưu một tin nhắn mỗi cuộc trò chuyện
+	createUniquePartialIndex(ctx, messages, models.ClientMessageIndex, bson.D{
+		{Key: "sender_id", Value: 1},
+		{Key: "receiver_id", Value: 1},
+		{Key: "group_id", Value: 1},
+		{Key: "client_message_id", Value: 1},
+	}, bson.M{
+		"client_message_id": bson.M{"$exists": true},
+	})
+
+	// 24. Event bus nội bộ (EVENT_BUS=mongo): đọc theo topic + offset, sự kiện cũ tự xóa
+	createIndex(ctx, db.Collection("event_log"), "uniq_event_log_topic_offset", bson.D{
+		{Key: "topic", Value: 1},
+		{Key: "offset", Value: 1},
+	}, true)
+	createTTLIndex(ctx, db.Collection("event_log"), "ttl_event_log", "created_at", 7*24*time.Hour)
+
+	// 25. Rate limit dùng chung (RATE_LIMIT_BACKEND=mongo): key tự xóa khi bucket đã đầy lại
+	createTTLIndex(ctx, db.Collection("rate_limits"), "ttl_rate_limits", "expires_at", 0)
+
+	if failed := len(Status().Failed); failed > 0 {
+		log.Printf("⚠️ Index creation finished with %d failure(s)", failed)
+		return
+	}
 	log.Println("✅ All indexes created successfully.")
 }
 
@@ -149,6 +300,7 @@ func createIndex(ctx context.Context, col *mongo.Collection, name string, keys b
 		Options: options.Index().SetName(name).SetUnique(unique),
 	}
 	_, err := col.Indexes().CreateOne(ctx, indexModel)
+	record(col.Name(), name, err)
 	if err != nil {
 		log.Printf("⚠️ Could not create index %s on %s: %v", name, col.Name(), err)
 	} else {
@@ -164,9 +316,43 @@ func createPartialIndex(ctx context.Context, col *mongo.Collection, name string,
 			SetPartialFilterExpression(filter),
 	}
 	_, err := col.Indexes().CreateOne(ctx, indexModel)
+	record(col.Name(), name, err)
 	if err != nil {
 		log.Printf("⚠️ Could not create partial index %s on %s: %v", name, col.Name(), err)
 	} else {
 		log.Printf("🚀 Created partial index %s on %s", name, col.Name())
 	}
 }
+
+func createUniquePartialIndex(ctx context.Context, col *mongo.Collection, name string, keys bson.D, filter bson.M) {
+	indexModel := mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName(name).
+			SetUnique(true).
+			SetPartialFilterExpression(filter),
+	}
+	_, err := col.Indexes().CreateOne(ctx, indexModel)
+	record(col.Name(), name, err)
+	if err != nil {
+		log.Printf("⚠️ Could not create unique partial index %s on %s: %v", name, col.Name(), err)
+	} else {
+		log.Printf("🚀 Created unique partial index %s on %s", name, col.Name())
+	}
+}
+
+func createTTLIndex(ctx context.Context, col *mongo.Collection, name, field string, ttl time.Duration) {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: field, Value: 1}},
+		Options: options.Index().
+			SetName(name).
+			SetExpireAfterSeconds(int32(ttl.Seconds())),
+	}
+	_, err := col.Indexes().CreateOne(ctx, indexModel)
+	record(col.Name(), name, err)
+	if err != nil {
+		log.Printf("⚠️ Could not create TTL index %s on %s: %v", name, col.Name(), err)
+	} else {
+		log.Printf("🚀 Created TTL index %s on %s", name, col.Name())
+	}
+}
@@ -0,0 +1,67 @@
+package indexer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StatePending = "pending"
+	StateRunning = "running"
+	StateDone    = "done"
+)
+
+// IndexResult - kết quả tạo một index ở lần Execute gần nhất
+type IndexResult struct {
+	Collection string `json:"collection"`
+	Name       string `json:"name"`
+	Error      string `json:"error,omitempty"`
+}
+
+// StatusReport - trạng thái lần đảm bảo index gần nhất, hiển thị ở trang chẩn đoán của admin
+type StatusReport struct {
+	State      string        `json:"state"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Created    int           `json:"created"`
+	Failed     []IndexResult `json:"failed,omitempty"`
+}
+
+var (
+	statusMu sync.RWMutex
+	status   = StatusReport{State: StatePending}
+)
+
+// Status trả về bản sao trạng thái index hiện tại
+func Status() StatusReport {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	report := status
+	report.Failed = append([]IndexResult(nil), status.Failed...)
+	return report
+}
+
+func begin() {
+	now := time.Now()
+	statusMu.Lock()
+	status = StatusReport{State: StateRunning, StartedAt: &now}
+	statusMu.Unlock()
+}
+
+func record(collection, name string, err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	if err != nil {
+		status.Failed = append(status.Failed, IndexResult{Collection: collection, Name: name, Error: err.Error()})
+		return
+	}
+	status.Created++
+}
+
+func finish() {
+	now := time.Now()
+	statusMu.Lock()
+	status.State = StateDone
+	status.FinishedAt = &now
+	statusMu.Unlock()
+}
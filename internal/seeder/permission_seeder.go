@@ -55,6 +55,7 @@ func SeedPermissions(db *mongo.Database) {
 		// {Code: "system:group:force_delete", Name: "Xóa nhóm vĩnh viễn", Desc: "Xóa vĩnh viễn bất kỳ nhóm nào khỏi hệ thống", Module: "system_group"},
 		{Code: "system:group:resolve_report", Name: "Giải quyết báo cáo", Desc: "Xử lý báo cáo vi phạm từ các nhóm", Module: "system_group"},
 		{Code: "system:group:change_owner", Name: "Thay đổi chủ sở hữu nhóm", Desc: "Thay đổi chủ sở hữu của bất kỳ nhóm nào", Module: "system_group"},
+		{Code: "system:group:restore", Name: "Khôi phục nhóm", Desc: "Khôi phục nhóm đã giải tán trong thời gian lưu giữ", Module: "system_group"},
 		// {Code: "system:group:lock", Name: "Khóa nhóm", Desc: "Khóa hoạt động của nhóm", Module: "system_group"},
 
 		// System Log (module: system_log)
@@ -36,7 +36,7 @@ func SeedRolePermissions(db *mongo.Database) {
 	matrix := map[string][]string{
 		"system_admin": {
 			"system:user:view_all", "system:user:create", "system:user:update_global", "system:user:delete", "system:user:view_details",
-			"system:group:view_all", "system:group:view_details", "system:group:resolve_report", "system:group:change_owner", "system:setting:view", "system:setting:config", "system:moderator:assign",
+			"system:group:view_all", "system:group:view_details", "system:group:resolve_report", "system:group:change_owner", "system:group:restore", "system:setting:view", "system:setting:config", "system:moderator:assign",
 			"system:content:delete_any", "system:message:delete_any", "system:group:lock",
 		},  
 		"clinic_admin": {
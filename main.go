@@ -6,12 +6,15 @@ import (
 	"os/signal"
 	"syscall"
 
+	"my-app/common/logger"
 	"my-app/config"
 	"my-app/internal/app"
 )
 
 func main() {
 	cfg := config.LoadAppConfig()
+	logger.Init(logger.Options{Level: cfg.Log.Level, Modules: cfg.Log.Modules, Format: cfg.Log.Format})
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
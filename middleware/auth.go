@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"my-app/common"
+	"my-app/common/logger"
 	"my-app/utils"
 	"net/http"
 	"strings"
@@ -38,6 +39,10 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Lưu UserID vào context
 		ctx.Set("userID", claims.UserID)
 		ctx.Set("roles", claims.Roles)
+		if claims.IssuedAt != nil {
+			ctx.Set("authTime", claims.IssuedAt.Time)
+		}
+		ctx.Request = ctx.Request.WithContext(logger.WithUserID(ctx.Request.Context(), claims.UserID))
 		ctx.Next()
 	}
 }
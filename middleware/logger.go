@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"my-app/common/logger"
 	"net"
 	"net/http"
 	"net/url"
@@ -72,14 +72,15 @@ func LoggerMiddleware() gin.HandlerFunc {
 	// }
 
 	// Cách 2 dùng thư viện lumberjack
-	logger := zerolog.New(&lumberjack.Logger{
+	// Body / header được che mật khẩu, token, email, SĐT, nội dung tin nhắn trước khi ghi file
+	fileLogger := zerolog.New(logger.RedactWriter(&lumberjack.Logger{
 		Filename:   logPath,
 		MaxSize:    1, // megabytes
 		MaxBackups: 5,
 		MaxAge:     5,    //days Bao lâu thì xóa file này
 		Compress:   true, // bạn có muốn nén file hay không
 		LocalTime:  true,
-	}).With().Timestamp().Logger()
+	})).With().Timestamp().Str("module", "http").Logger()
 
 	return func(ctx *gin.Context) {
 
@@ -116,13 +117,12 @@ func LoggerMiddleware() gin.HandlerFunc {
 					requestBody["form_files"] = formFiles
 				}
 			}
-			log.Println("multipart/form-data")
 		} else {
 			// dành cho Content-Type: json || x-www-form-urlencoded
 			//io.ReadAll(ctx.Request.Body) khi dùng io để đọc hết thì sau khi đọc xong thì nó sẽ bỏ hết dữ lệu
 			bodyBytes, err := io.ReadAll(ctx.Request.Body)
 			if err != nil {
-				logger.Error().Err(err).Msg("Lỗi khi đọc body trả về")
+				logger.With(ctx.Request.Context(), &fileLogger).Error().Err(err).Msg("Lỗi khi đọc body trả về")
 			}
 
 			// sau khi đọc xong rồi thì nạp dữ liệu lại cho body
@@ -175,12 +175,13 @@ func LoggerMiddleware() gin.HandlerFunc {
 			responseBodyParsed = responseBodyRaw
 		}
 
-		logEvent := logger.Info()
+		reqLogger := logger.With(ctx.Request.Context(), &fileLogger)
+		logEvent := reqLogger.Info()
 
 		if static_code >= 500 {
-			logEvent = logger.Error()
+			logEvent = reqLogger.Error()
 		} else if static_code >= 400 {
-			logEvent = logger.Warn()
+			logEvent = reqLogger.Warn()
 		}
 
 		logEvent.
@@ -1,26 +1,30 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"math"
 	"my-app/common"
+	"my-app/common/logger"
+	"my-app/common/ratelimit"
+	"my-app/common/telemetry"
 	"net/http"
-	"sync"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
-type Client struct {
-	LimitTer *rate.Limiter
-	lastSeen time.Time
-}
-
 var (
-	mu      sync.Mutex
-	clients = make(map[string]*Client)
+	rateLimiter ratelimit.Limiter
+	rateLimits  map[string]ratelimit.Limit
 )
 
+// InitRateLimit đặt backend và bảng policy cho RateLimit, gọi một lần trước khi dựng router
+func InitRateLimit(limiter ratelimit.Limiter, policies map[string]ratelimit.Limit) {
+	rateLimiter = limiter
+	rateLimits = policies
+}
+
 func getClientIP(ctx *gin.Context) string {
 	ip := ctx.ClientIP() // Lấy địa chỉ IP của gười dùng
 
@@ -31,55 +35,36 @@ func getClientIP(ctx *gin.Context) string {
 	return ip
 }
 
-//// hey -n 20 -c 1 -H "X-API-Key: becfce45-9237-4c6d-a7c5-f3be786249a5" http://localhost:3000/v1/items/get-all
-
-func getRateLimiter(ip string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock() // tự đóng ở cuối cùng
-	client, exists := clients[ip]
-
-	//nếu chưa có ip thì tạo mới
-	if !exists {
-		limiter := rate.NewLimiter(50, 100) // bucket nghĩa là nhận 5 request / s , tức là mỗi giây sẽ cấp thêm 5 request
-		// 	 brust là số lượng request tối đa có thể xử lý ngay cùng 1 lúc
-		// có nghĩa là nếu mà cùng lúc có 11 request thì request sẽ phải đợi bucket sinh ra token mớisẽ là khoản 200ms
-
-		newClient := &Client{limiter, time.Now()}
-
-		clients[ip] = newClient
-		log.Printf("A clients[%s] - {limiter: %v+v, lastSeen: %s}", ip, newClient.LimitTer, newClient.lastSeen)
-		return limiter
-	}
-	log.Printf("A clients[%s] - {limiter: %v+v, lastSeen: %s}", ip, client.LimitTer, client.lastSeen)
-
-	client.lastSeen = time.Now()
-	return client.LimitTer
-}
-
-func CleanUpClient() {
-	for {
-		time.Sleep(time.Minute)
-		mu.Lock()
-
-		for ip, client := range clients {
-			if time.Since(client.lastSeen) > 3*time.Minute { // nếu thời gian sài hơn 3p rồi thì xóa
-				delete(clients, ip)
-			}
+// RateLimit giới hạn request theo policy (xem RATE_LIMIT_POLICIES): đặt sau AuthMiddleware thì đếm theo user,
+// chưa xác thực thì đếm theo IP. Policy không được cấu hình hoặc để "off" thì không giới hạn.
+func RateLimit(policy string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, ok := rateLimits[policy]
+		if !ok || rateLimiter == nil {
+			ctx.Next()
+			return
 		}
 
-		mu.Unlock()
-	}
-}
-
-// hey -n 2000 -c 1 -H "X-API-Key: becfce45-9237-4c6d-a7c5-f3be786249a5" http://localhost:3000/v1/items/get-all
-func RateLimitingMiddleware() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		ip := getClientIP(ctx)
+		key := policy + ":ip:" + getClientIP(ctx)
+		if userID := ctx.GetString("userID"); userID != "" {
+			key = policy + ":user:" + userID
+		}
 
-		limiter := getRateLimiter(ip)
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), ratelimit.Timeout)
+		res, err := rateLimiter.Allow(reqCtx, key, limit)
+		cancel()
+		if err != nil {
+			logger.Ctx(ctx.Request.Context(), "ratelimit").Warn().Err(err).Str("policy", policy).Msg("Rate limit backend unavailable, request allowed")
+			ctx.Next()
+			return
+		}
 
-		if !limiter.Allow() {
-			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, common.ErrRequest)
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			telemetry.RateLimited.WithLabelValues(policy).Inc()
+			ctx.Header("Retry-After", strconv.Itoa(max(1, int(math.Ceil(res.RetryAfter.Seconds())))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, common.ErrRequest(fmt.Errorf("rate limit %s exceeded", policy)))
 			return
 		}
 
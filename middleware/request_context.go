@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"time"
+
+	"my-app/common/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// HeaderRequestID - client / gateway có thể gửi sẵn, không có thì server tự sinh và trả lại trong response
+const HeaderRequestID = "X-Request-ID"
+
+// RequestContextMiddleware gắn request id vào context của request (logger.Ctx đọc ra) và ghi access log có cấu trúc.
+// Đặt đầu chuỗi middleware để user_id (AuthMiddleware) và trace_id (MetricsMiddleware) gắn sau vẫn có trong access log.
+func RequestContextMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		c.Header(HeaderRequestID, requestID)
+		c.Set("requestID", requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := zerolog.InfoLevel
+		if status >= 500 {
+			level = zerolog.ErrorLevel
+		} else if status >= 400 {
+			level = zerolog.WarnLevel
+		}
+
+		logger.Ctx(c.Request.Context(), "http").WithLevel(level).
+			Str("method", c.Request.Method).
+			Str("route", c.FullPath()).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("duration", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Int("response_size", c.Writer.Size()).
+			Msg("HTTP request")
+	}
+}
@@ -15,12 +15,27 @@ import (
 
 type ChatStorage interface {
 	SaveMessage(ctx context.Context, msg *models.Message) error
+	FindMessageByClientID(ctx context.Context, senderID, receiverID, groupID primitive.ObjectID, clientMessageID string) (*models.Message, error)
 	CheckUserExists(ctx context.Context, userID string) (bool, error)
 	CheckGroupExists(ctx context.Context, groupID string) (bool, error)
 	IsUserInGroup(ctx context.Context, userID, groupID primitive.ObjectID) (bool, error)
 	GetUserById(ctx context.Context, userID primitive.ObjectID) (*ModelUser.User, error)
+	IsBlocked(ctx context.Context, userA, userB string) (bool, error)
+	GetGroupSettings(ctx context.Context, groupID primitive.ObjectID) (*models.GroupSettings, error)
+	GetGroupRoleCode(ctx context.Context, groupID, userID primitive.ObjectID) (string, error)
+	GetLastGroupMessageTime(ctx context.Context, groupID, senderID primitive.ObjectID, before time.Time, excludeID primitive.ObjectID) (*time.Time, error)
+
+	MentionStore
+	SaveMentionInbox(ctx context.Context, items []models.MentionInboxItem) error
+
+	mediaAccessChecker
+	GetMediasByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Media, error)
+	AddMediaLinks(ctx context.Context, mediaIDs []primitive.ObjectID, link models.MediaLink) error
 }
 
+// ErrUserBlocked trả về khi 2 người trong chat 1-1 đang chặn nhau
+var ErrUserBlocked = errors.New("người dùng đã bị chặn")
+
 type ChatESIndexer interface {
 	IndexMessage(ctx context.Context, msg *models.Message, senderName string, senderAvatar string) error
 }
@@ -52,6 +67,9 @@ func (biz *ChatBiz) HandleMessage(
 	replyTo models.ReplyMessageMini,
 	task *models.Task,
 	parentID string,
+	clientMessageID string,
+	mentions []models.Mention,
+	mentionTargets []models.MentionTarget,
 ) (*models.Message, error) {
 
 	senderID, _ := primitive.ObjectIDFromHex(sender)
@@ -85,6 +103,7 @@ func (biz *ChatBiz) HandleMessage(
 	}
 
 	msg.ID = message_id
+	msg.ClientMessageID = models.NormalizeClientMessageID(clientMessageID)
 
 	// 1. Kiểm tra sender (Sử dụng cache)
 	cacheKey := "u:" + sender
@@ -120,6 +139,15 @@ func (biz *ChatBiz) HandleMessage(
 				return nil, errors.New("không tìm thấy người nhận")
 			}
 		}
+
+		// Không cache trạng thái chặn vì có thể thay đổi bất kỳ lúc nào
+		blocked, err := biz.store.IsBlocked(ctx, sender, receiver)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrUserBlocked
+		}
 	}
 
 	// 3. Kiểm tra group - Sử dụng cache
@@ -156,11 +184,51 @@ func (biz *ChatBiz) HandleMessage(
 				return nil, errors.New("người gửi không thuộc nhóm")
 			}
 		}
+
+		// Cài đặt nhóm (chỉ admin gửi, chế độ thông báo, chế độ chậm) - không cache vì admin có thể đổi bất kỳ lúc nào
+		if types != "system" {
+			if err := biz.checkGroupPolicy(ctx, msg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Đính kèm: chỉ gửi được media mình có quyền xem, rồi gắn media vào cuộc trò chuyện này
+	attachIDs := mediaIDs
+	if task != nil {
+		attachIDs = append(append([]primitive.ObjectID{}, mediaIDs...), task.AttachmentIDs...)
+	}
+	if len(attachIDs) > 0 {
+		if err := biz.linkMedia(ctx, msg, attachIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Mention đã được hub phân giải lúc gửi (cùng danh sách đã nhận thông báo), ở đây chỉ lưu lại
+	var mentionItems []models.MentionInboxItem
+	if types != "system" {
+		mentionItems = ApplyMentions(msg, mentions, mentionTargets)
 	}
 
 	// 4. Lưu MongoDB
 	if err := biz.store.SaveMessage(ctx, msg); err != nil {
-		return nil, err
+		if !errors.Is(err, models.ErrDuplicateClientMessage) {
+			return nil, err
+		}
+		// Client gửi lại tin nhắn đã lưu (retry, reconnect sang instance khác) hoặc bản khác vừa được lưu song song:
+		// unique index uniq_msg_client_id chặn bản trùng, dùng bản đã lưu làm bản gốc
+		existing, findErr := biz.store.FindMessageByClientID(ctx, senderID, receiverID, groupID, msg.ClientMessageID)
+		if findErr != nil || existing == nil {
+			return nil, err
+		}
+		log.Printf("⚠️ Tin nhắn %s trùng client_message_id %s với %s, bỏ qua", msg.ID.Hex(), msg.ClientMessageID, existing.ID.Hex())
+		return existing, nil
+	}
+
+	if len(mentionItems) > 0 {
+		if err := biz.store.SaveMentionInbox(ctx, mentionItems); err != nil {
+			log.Printf("⚠️ Lỗi lưu hộp thư nhắc đến cho tin nhắn %s: %v", msg.ID.Hex(), err)
+		}
 	}
 
 	// 3. Index Elasticsearch (ASYNCHRONOUS)
@@ -183,3 +251,62 @@ func (biz *ChatBiz) HandleMessage(
 
 	return msg, nil
 }
+
+func (biz *ChatBiz) linkMedia(ctx context.Context, msg *models.Message, mediaIDs []primitive.ObjectID) error {
+	medias, err := biz.store.GetMediasByIDs(ctx, mediaIDs)
+	if err != nil {
+		return err
+	}
+	for i := range medias {
+		ok, err := canAccessMedia(ctx, biz.store, &medias[i], msg.SenderID)
+		if err != nil {
+			return err
+		}
+		if !ok || medias[i].RevokedAt != nil {
+			return ErrMediaAttachDenied
+		}
+	}
+
+	return biz.store.AddMediaLinks(ctx, mediaIDs, models.MediaLink{
+		MessageID:  msg.ID,
+		SenderID:   msg.SenderID,
+		ReceiverID: msg.ReceiverID,
+		GroupID:    msg.GroupID,
+	})
+}
+
+func (biz *ChatBiz) checkGroupPolicy(ctx context.Context, msg *models.Message) error {
+	return checkGroupPostPolicy(ctx, biz.store, msg)
+}
+
+type groupPostStore interface {
+	GetGroupSettings(ctx context.Context, groupID primitive.ObjectID) (*models.GroupSettings, error)
+	GetGroupRoleCode(ctx context.Context, groupID, userID primitive.ObjectID) (string, error)
+	GetLastGroupMessageTime(ctx context.Context, groupID, senderID primitive.ObjectID, before time.Time, excludeID primitive.ObjectID) (*time.Time, error)
+}
+
+// checkGroupPostPolicy áp dụng cài đặt nhóm cho tin nhắn sắp lưu (dùng chung cho chat thường và bình chọn)
+func checkGroupPostPolicy(ctx context.Context, store groupPostStore, msg *models.Message) error {
+	settings, err := store.GetGroupSettings(ctx, msg.GroupID)
+	if err != nil {
+		return err
+	}
+	if !settings.HasPostRestriction() {
+		return nil
+	}
+
+	role, err := store.GetGroupRoleCode(ctx, msg.GroupID, msg.SenderID)
+	if err != nil {
+		return err
+	}
+
+	var lastSentAt *time.Time
+	if settings.SlowModeSeconds > 0 && !models.IsGroupAdminRole(role) {
+		lastSentAt, err = store.GetLastGroupMessageTime(ctx, msg.GroupID, msg.SenderID, msg.CreatedAt, msg.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return CheckGroupPost(settings, role, msg.ParentMessageID != nil, lastSentAt, msg.CreatedAt)
+}
@@ -2,7 +2,6 @@ package biz
 
 import (
 	"context"
-	"fmt"
 	"my-app/modules/chat/models"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -22,7 +21,6 @@ func NewCreateMediaBiz(store CreateMediaStorage) *CreateMediaStorageBiz {
 
 func (biz *CreateMediaStorageBiz) UploadMedia(ctx context.Context, media *models.Media) (*models.Media, error) {
 	media.ID = primitive.NewObjectID()
-	fmt.Println("media", media)
 
 	return biz.store.UploadMedia(ctx, media)
 }
@@ -3,6 +3,7 @@ package biz
 import (
 	"context"
 	"fmt"
+	"my-app/common/outbox"
 	"my-app/modules/chat/models"
 	"my-app/modules/chat/storage"
 	"time"
@@ -10,16 +11,23 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// EventOutbox - ghi sự kiện Kafka trong cùng transaction với dữ liệu nghiệp vụ
+type EventOutbox interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	Enqueue(ctx context.Context, events ...outbox.Event) error
+}
+
 type CreateTaskBiz struct {
 	taskStorage    *storage.TaskStorage
 	messageStorage *storage.MongoChatStore
+	outbox         EventOutbox
 	socket         interface {
 		SendMessage(msg interface{})
 	} // Simple interface for socket, or we use the existing mechanism
 }
 
-func NewCreateTaskBiz(taskStorage *storage.TaskStorage, messageStorage *storage.MongoChatStore) *CreateTaskBiz {
-	return &CreateTaskBiz{taskStorage: taskStorage, messageStorage: messageStorage}
+func NewCreateTaskBiz(taskStorage *storage.TaskStorage, messageStorage *storage.MongoChatStore, outbox EventOutbox) *CreateTaskBiz {
+	return &CreateTaskBiz{taskStorage: taskStorage, messageStorage: messageStorage, outbox: outbox}
 }
 
 // NOTE: We might need to inject the real Socket manager or reuse the existing logic.
@@ -167,7 +175,14 @@ func (biz *CreateTaskBiz) CreateTasksBulk(ctx context.Context, req *models.Creat
 			Status:        models.TaskStatusPendingAcceptance,
 		}
 
-		if err := biz.taskStorage.CreateTask(ctx, groupTask); err != nil {
+		// Task và tin nhắn giao việc (qua outbox) được ghi cùng transaction
+		err := biz.outbox.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := biz.taskStorage.CreateTask(ctx, groupTask); err != nil {
+				return err
+			}
+			return biz.enqueueTaskMessages(ctx, groupTask, req.CreatorName)
+		})
+		if err != nil {
 			return nil, fmt.Errorf("tạo công việc nhóm thất bại: %w", err)
 		}
 
@@ -202,8 +217,19 @@ func (biz *CreateTaskBiz) CreateTasksBulk(ctx context.Context, req *models.Creat
 		})
 	}
 
-	// --- Atomic bulk insert (InsertMany: all-or-nothing) ---
-	if err := biz.taskStorage.BulkCreateTasks(ctx, tasks); err != nil {
+	// --- Atomic bulk insert + tin nhắn giao việc qua outbox trong cùng transaction ---
+	err = biz.outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := biz.taskStorage.BulkCreateTasks(ctx, tasks); err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			if err := biz.enqueueTaskMessages(ctx, t, req.CreatorName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, fmt.Errorf("tạo công việc thất bại, vui lòng thử lại: %w", err)
 	}
 
@@ -218,3 +244,44 @@ func (biz *CreateTaskBiz) CreateTasksBulk(ctx context.Context, req *models.Creat
 
 	return tasks, nil
 }
+
+// enqueueTaskMessages ghi tin nhắn giao việc vào outbox, cùng đích với tin client gửi qua websocket:
+// task có group -> 1 tin vào nhóm; group task ngoài nhóm -> 1 tin cho mỗi người nhận; còn lại -> tin 1-1 cho người nhận.
+// Idempotency key theo task + đích nên consumer chỉ lưu một bản dù cả hai đường đều gửi.
+func (biz *CreateTaskBiz) enqueueTaskMessages(ctx context.Context, task *models.Task, creatorName string) error {
+	var receivers []primitive.ObjectID
+	switch {
+	case !task.GroupID.IsZero():
+		receivers = []primitive.ObjectID{primitive.NilObjectID}
+	case len(task.Assignees) > 0:
+		for _, a := range task.Assignees {
+			receivers = append(receivers, a.AssigneeID)
+		}
+	default:
+		receivers = []primitive.ObjectID{task.AssigneeID}
+	}
+
+	now := time.Now()
+	events := make([]outbox.Event, 0, len(receivers))
+	for _, receiverID := range receivers {
+		msg := &models.MessageResponse{
+			ID:         primitive.NewObjectID(),
+			SenderID:   task.CreatorID,
+			SenderName: creatorName,
+			ReceiverID: receiverID,
+			GroupID:    task.GroupID,
+			Content:    "Đã tạo công việc: " + task.Title,
+			Type:       models.MediaTypeTask,
+			Task:       task,
+			Status:     models.StatusSent,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		e, err := outbox.NewEvent("chat-topic", task.CreatorID.Hex(), msg)
+		if err != nil {
+			return err
+		}
+		events = append(events, e.WithIdempotencyKey(models.TaskMessageKey(task.ID, receiverID, task.GroupID)))
+	}
+	return biz.outbox.Enqueue(ctx, events...)
+}
@@ -0,0 +1,72 @@
+package biz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"my-app/modules/chat/models"
+	"time"
+)
+
+// ErrGroupRestricted - tin nhắn bị từ chối do cài đặt của nhóm, không cần retry
+var ErrGroupRestricted = errors.New("nhóm đang giới hạn thao tác này")
+
+var (
+	ErrOnlyAdminsCanPost = fmt.Errorf("%w: chỉ quản trị viên được gửi tin nhắn trong nhóm", ErrGroupRestricted)
+	ErrAnnouncementOnly  = fmt.Errorf("%w: nhóm đang ở chế độ thông báo, chỉ quản trị viên được đăng bài", ErrGroupRestricted)
+	ErrOnlyAdminsCanPin  = fmt.Errorf("%w: chỉ quản trị viên được ghim tin nhắn", ErrGroupRestricted)
+	ErrOnlyAdminsCanAdd  = fmt.Errorf("%w: chỉ quản trị viên được thêm thành viên", ErrGroupRestricted)
+)
+
+// SlowModeError - thành viên gửi tin nhắn quá nhanh khi nhóm bật chế độ chậm
+type SlowModeError struct {
+	Remaining time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("Chế độ chậm đang bật, vui lòng chờ %d giây", int(math.Ceil(e.Remaining.Seconds())))
+}
+
+func (e *SlowModeError) Is(target error) bool {
+	return target == ErrGroupRestricted
+}
+
+// CheckGroupPost kiểm tra quyền gửi tin nhắn theo cài đặt nhóm.
+// isReply = true với bình luận trong thread: chế độ "chỉ admin gửi" vẫn cho phép bình luận,
+// còn chế độ thông báo thì chặn tất cả. lastSentAt là lần gửi trước của thành viên (nil nếu chưa có).
+func CheckGroupPost(settings *models.GroupSettings, role string, isReply bool, lastSentAt *time.Time, now time.Time) error {
+	if !settings.HasPostRestriction() || models.IsGroupAdminRole(role) {
+		return nil
+	}
+
+	if settings.AnnouncementMode {
+		return ErrAnnouncementOnly
+	}
+	if settings.OnlyAdminsCanPost && !isReply {
+		return ErrOnlyAdminsCanPost
+	}
+
+	if settings.SlowModeSeconds > 0 && lastSentAt != nil {
+		wait := time.Duration(settings.SlowModeSeconds) * time.Second
+		if elapsed := now.Sub(*lastSentAt); elapsed < wait {
+			return &SlowModeError{Remaining: wait - elapsed}
+		}
+	}
+	return nil
+}
+
+// CheckGroupPin kiểm tra quyền ghim / bỏ ghim tin nhắn
+func CheckGroupPin(settings *models.GroupSettings, role string) error {
+	if settings != nil && settings.OnlyAdminsCanPin && !models.IsGroupAdminRole(role) {
+		return ErrOnlyAdminsCanPin
+	}
+	return nil
+}
+
+// CheckGroupMemberAdd kiểm tra quyền thêm thành viên
+func CheckGroupMemberAdd(settings *models.GroupSettings, role string) error {
+	if settings != nil && settings.MemberAddPermission == models.MemberAddAdmins && !models.IsGroupAdminRole(role) {
+		return ErrOnlyAdminsCanAdd
+	}
+	return nil
+}
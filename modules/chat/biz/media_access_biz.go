@@ -0,0 +1,266 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"my-app/modules/chat/models"
+	"my-app/utils"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrMediaNotFound     = errors.New("không tìm thấy media")
+	ErrMediaAccessDenied = errors.New("bạn không có quyền xem media này")
+	ErrMediaRevoked      = errors.New("media đã bị xóa")
+	ErrInvalidMediaURL   = errors.New("đường dẫn media không hợp lệ hoặc đã hết hạn")
+	ErrNotMediaOwner     = errors.New("chỉ người upload được xóa media")
+	ErrMediaAttachDenied = errors.New("không thể đính kèm media của người khác")
+)
+
+// mediaAccessChecker - phần storage cần để kiểm tra quyền xem media
+type mediaAccessChecker interface {
+	IsUserInGroup(ctx context.Context, userID, groupID primitive.ObjectID) (bool, error)
+	FindMessageMediaLinks(ctx context.Context, mediaID primitive.ObjectID) ([]models.MediaLink, error)
+}
+
+// mediaLinks trả về link của media; media cũ chưa có links thì dựng từ các tin nhắn chứa nó
+func mediaLinks(ctx context.Context, store mediaAccessChecker, media *models.Media) ([]models.MediaLink, error) {
+	if len(media.Links) > 0 {
+		return media.Links, nil
+	}
+	return store.FindMessageMediaLinks(ctx, media.ID)
+}
+
+// canAccessMedia: người upload, người gửi/nhận (1-1) hoặc thành viên nhóm của một tin nhắn chứa media
+func canAccessMedia(ctx context.Context, store mediaAccessChecker, media *models.Media, userID primitive.ObjectID) (bool, error) {
+	if media.UploadedBy != nil && *media.UploadedBy == userID {
+		return true, nil
+	}
+
+	links, err := mediaLinks(ctx, store, media)
+	if err != nil {
+		return false, err
+	}
+
+	var groups []primitive.ObjectID
+	for _, l := range links {
+		if !l.GroupID.IsZero() {
+			groups = append(groups, l.GroupID)
+			continue
+		}
+		if l.SenderID == userID || l.ReceiverID == userID {
+			return true, nil
+		}
+	}
+
+	for _, groupID := range groups {
+		inGroup, err := store.IsUserInGroup(ctx, userID, groupID)
+		if err != nil {
+			return false, err
+		}
+		if inGroup {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type MediaAccessStorage interface {
+	mediaAccessChecker
+	FindMediaByID(ctx context.Context, ID primitive.ObjectID) (*models.Media, error)
+	FindMediaByURL(ctx context.Context, key string) (*models.Media, error)
+	RevokeMedia(ctx context.Context, ID primitive.ObjectID, revokedAt time.Time) (bool, error)
+	RemoveMediaObject(ctx context.Context, key string) error
+}
+
+// SignedMediaParams - tham số chữ ký trên URL xem media
+type SignedMediaParams struct {
+	UserID    string
+	Size      string
+	Expires   string
+	Signature string
+}
+
+type MediaAccessBiz struct {
+	store  MediaAccessStorage
+	signer *utils.URLSigner
+	ttl    time.Duration
+}
+
+func NewMediaAccessBiz(store MediaAccessStorage, secret string, ttl time.Duration) *MediaAccessBiz {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &MediaAccessBiz{store: store, signer: utils.NewURLSigner(secret), ttl: ttl}
+}
+
+// Sign cấp URL có chữ ký cho các media user được xem, media không có quyền bị bỏ qua.
+// Hạn dùng làm tròn theo ttl để cùng một media trả cùng URL trong một khoảng, trình duyệt cache được thumbnail.
+func (biz *MediaAccessBiz) Sign(ctx context.Context, userID primitive.ObjectID, mediaIDs []string, size, basePath string) ([]models.SignedMediaURL, error) {
+	expiresAt := time.Now().Truncate(biz.ttl).Add(2 * biz.ttl)
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	urls := make([]models.SignedMediaURL, 0, len(mediaIDs))
+	for _, id := range mediaIDs {
+		mediaID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		media, err := biz.find(ctx, mediaID)
+		if err != nil {
+			if errors.Is(err, ErrMediaNotFound) || errors.Is(err, ErrMediaRevoked) {
+				continue
+			}
+			return nil, err
+		}
+		ok, err := canAccessMedia(ctx, biz.store, media, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		q := url.Values{}
+		if size != "" {
+			q.Set("size", size)
+		}
+		q.Set("u", userID.Hex())
+		q.Set("exp", exp)
+		q.Set("sig", biz.signer.Sign(media.ID.Hex(), size, userID.Hex(), exp))
+
+		urls = append(urls, models.SignedMediaURL{
+			MediaID:   media.ID.Hex(),
+			URL:       fmt.Sprintf("%s/%s?%s", basePath, media.ID.Hex(), q.Encode()),
+			ExpiresAt: expiresAt,
+		})
+	}
+	return urls, nil
+}
+
+// Authorize kiểm tra chữ ký, hạn dùng và quyền hiện tại (tin nhắn bị thu hồi / media bị xóa có hiệu lực ngay)
+func (biz *MediaAccessBiz) Authorize(ctx context.Context, media *models.Media, params SignedMediaParams) error {
+	if media.RevokedAt != nil {
+		return ErrMediaRevoked
+	}
+
+	exp, err := strconv.ParseInt(params.Expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidMediaURL
+	}
+	if !biz.signer.Verify(params.Signature, media.ID.Hex(), params.Size, params.UserID, params.Expires) {
+		return ErrInvalidMediaURL
+	}
+
+	userID, err := primitive.ObjectIDFromHex(params.UserID)
+	if err != nil {
+		return ErrInvalidMediaURL
+	}
+	ok, err := canAccessMedia(ctx, biz.store, media, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMediaAccessDenied
+	}
+	return nil
+}
+
+// AuthorizeByID dùng cho route stream theo media ID
+func (biz *MediaAccessBiz) AuthorizeByID(ctx context.Context, ID string, params SignedMediaParams) error {
+	mediaID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return ErrMediaNotFound
+	}
+	media, err := biz.find(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	return biz.Authorize(ctx, media, params)
+}
+
+// AuthorizeObject dùng cho route tải theo tên object. Object không thuộc media nào (avatar, logo...) vẫn công khai.
+func (biz *MediaAccessBiz) AuthorizeObject(ctx context.Context, key string, params SignedMediaParams) error {
+	media, err := biz.store.FindMediaByURL(ctx, key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+	if err := CheckMediaScan(media); err != nil {
+		return err
+	}
+	return biz.Authorize(ctx, media, params)
+}
+
+// Revoke xóa media của người upload: URL đã cấp hết hiệu lực ngay, file trên MinIO bị xóa
+func (biz *MediaAccessBiz) Revoke(ctx context.Context, userID primitive.ObjectID, ID string) error {
+	mediaID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return ErrMediaNotFound
+	}
+	media, err := biz.find(ctx, mediaID)
+	if err != nil {
+		if errors.Is(err, ErrMediaRevoked) {
+			return nil
+		}
+		return err
+	}
+
+	owner, err := biz.isOwner(ctx, media, userID)
+	if err != nil {
+		return err
+	}
+	if !owner {
+		return ErrNotMediaOwner
+	}
+
+	if _, err := biz.store.RevokeMedia(ctx, media.ID, time.Now()); err != nil {
+		return err
+	}
+
+	// Xóa file gốc và các biến thể, lỗi chỉ làm sót file rác vì bản ghi đã bị thu hồi
+	_ = biz.store.RemoveMediaObject(ctx, media.URL)
+	for _, v := range media.Variants {
+		_ = biz.store.RemoveMediaObject(ctx, v.URL)
+	}
+	return nil
+}
+
+// isOwner: người upload; media cũ chưa lưu người upload thì là người đã gửi nó
+func (biz *MediaAccessBiz) isOwner(ctx context.Context, media *models.Media, userID primitive.ObjectID) (bool, error) {
+	if media.UploadedBy != nil {
+		return *media.UploadedBy == userID, nil
+	}
+	links, err := mediaLinks(ctx, biz.store, media)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range links {
+		if l.SenderID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (biz *MediaAccessBiz) find(ctx context.Context, mediaID primitive.ObjectID) (*models.Media, error) {
+	media, err := biz.store.FindMediaByID(ctx, mediaID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+	if media.RevokedAt != nil {
+		return nil, ErrMediaRevoked
+	}
+	return media, nil
+}
@@ -2,15 +2,26 @@ package biz
 
 import (
 	"context"
+	"errors"
 	"io"
+	"mime"
 	"my-app/modules/chat/models"
+	"path/filepath"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	ErrMediaVariantNotReady = errors.New("ảnh xem trước chưa sẵn sàng")
+	ErrMediaNotScanned      = errors.New("file đang được kiểm tra mã độc, vui lòng thử lại sau")
+	ErrMediaInfected        = errors.New("file chứa mã độc và đã bị cách ly")
+	ErrMediaScanFailed      = errors.New("không kiểm tra được mã độc cho file này")
+)
+
 type MediaReader interface {
-	OpenMedia(ctx context.Context, ID primitive.ObjectID) (io.ReadSeeker, int64, time.Time, models.MediaType, error)
+	FindMediaByID(ctx context.Context, ID primitive.ObjectID) (*models.Media, error)
+	OpenMediaObject(ctx context.Context, key string) (io.ReadSeeker, int64, time.Time, error)
 }
 
 type MediaBiz struct {
@@ -21,8 +32,92 @@ func NewMediaBiz(store MediaReader) *MediaBiz {
 	return &MediaBiz{store: store}
 }
 
-func (biz *MediaBiz) GetMedia(ctx context.Context, ID string) (io.ReadSeeker, int64, time.Time, models.MediaType, error) {
-	mediaID, _ := primitive.ObjectIDFromHex(ID)
+// GetMedia mở file gốc hoặc biến thể theo size (thumb/small/medium/large/poster).
+// Biến thể chưa có (chưa xử lý xong, hoặc ảnh gốc nhỏ hơn size yêu cầu) thì trả file gốc; riêng poster của video thì báo lỗi.
+func (biz *MediaBiz) GetMedia(ctx context.Context, ID string, size string, preferWebP bool) (*models.MediaStream, error) {
+	mediaID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := biz.store.FindMediaByID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckMediaScan(media); err != nil {
+		return nil, err
+	}
+
+	key := media.URL
+	contentType := originalContentType(media)
+	served := models.MediaSizeOriginal
+
+	if size != "" && size != models.MediaSizeOriginal {
+		format := models.MediaFormatJPEG
+		if preferWebP {
+			format = models.MediaFormatWebP
+		}
+
+		if v := pickVariant(media, size, format); v != nil {
+			key, contentType, served = v.URL, v.ContentType, v.Size
+		} else if media.Type == models.TypeVideo {
+			return nil, ErrMediaVariantNotReady
+		}
+	}
+
+	reader, length, modTime, err := biz.store.OpenMediaObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MediaStream{
+		Reader:      reader,
+		Size:        length,
+		ModTime:     modTime,
+		Type:        media.Type,
+		ContentType: contentType,
+		Variant:     served,
+	}, nil
+}
+
+// CheckMediaScan chặn tải file chưa quét xong, bị nhiễm mã độc hoặc không quét được
+func CheckMediaScan(media *models.Media) error {
+	switch media.ScanStatus {
+	case models.ScanPending:
+		return ErrMediaNotScanned
+	case models.ScanInfected:
+		return ErrMediaInfected
+	case models.ScanTooLarge, models.ScanFailed:
+		return ErrMediaScanFailed
+	}
+	return nil
+}
+
+// pickVariant chọn biến thể theo size. Pipeline không phóng to nên thiếu size nghĩa là bản gốc
+// (ảnh) hoặc poster (video) đã nhỏ hơn size yêu cầu.
+func pickVariant(media *models.Media, size, format string) *models.MediaVariant {
+	if v := media.FindVariant(size, format); v != nil {
+		return v
+	}
+	if media.Type == models.TypeVideo && size != models.MediaSizePoster {
+		return media.FindVariant(models.MediaSizePoster, format)
+	}
+	return nil
+}
 
-	return biz.store.OpenMedia(ctx, mediaID)
+func originalContentType(media *models.Media) string {
+	if media.ContentType != "" {
+		return media.ContentType
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(media.URL)); ct != "" {
+		return ct
+	}
+	switch media.Type {
+	case models.TypeImage:
+		return "image/jpeg"
+	case models.TypeVideo:
+		return "video/mp4"
+	default:
+		return "application/octet-stream"
+	}
 }
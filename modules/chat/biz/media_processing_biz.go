@@ -0,0 +1,203 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"my-app/modules/chat/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MediaTranscoder interface {
+	Probe(ctx context.Context, src string) (*models.MediaProbe, error)
+	ResizeImage(ctx context.Context, src, dst string, maxEdge int) error
+	ExtractFrame(ctx context.Context, src, dst string, at float64) error
+}
+
+type MediaProcessingStorage interface {
+	FindMediaByID(ctx context.Context, ID primitive.ObjectID) (*models.Media, error)
+	ClaimMediaProcessing(ctx context.Context, ID primitive.ObjectID, staleBefore time.Time) (bool, error)
+	SaveMediaProcessingResult(ctx context.Context, ID primitive.ObjectID, result *models.MediaProcessingResult) error
+	DownloadMediaObject(ctx context.Context, key, dst string) error
+	UploadMediaObject(ctx context.Context, key, src, contentType string) (int64, error)
+}
+
+type MediaProcessingBiz struct {
+	store      MediaProcessingStorage
+	transcoder MediaTranscoder
+	workDir    string
+	timeout    time.Duration
+}
+
+func NewMediaProcessingBiz(store MediaProcessingStorage, transcoder MediaTranscoder, workDir string, timeout time.Duration) *MediaProcessingBiz {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return &MediaProcessingBiz{store: store, transcoder: transcoder, workDir: workDir, timeout: timeout}
+}
+
+var mediaFormats = []struct {
+	format      string
+	ext         string
+	contentType string
+}{
+	{models.MediaFormatJPEG, ".jpg", "image/jpeg"},
+	{models.MediaFormatWebP, ".webp", "image/webp"},
+}
+
+// Process tạo biến thể cho một media: ảnh → các size JPEG + WebP; video → poster, thời lượng và các size của poster.
+// Gọi lại với media đã xử lý xong là no-op (Kafka có thể giao lại).
+func (biz *MediaProcessingBiz) Process(ctx context.Context, mediaID primitive.ObjectID) error {
+	media, err := biz.store.FindMediaByID(ctx, mediaID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil // media đã bị xóa
+		}
+		return err
+	}
+
+	// Chưa quét mã độc xong thì chưa xử lý, worker quét sẽ gửi lại job khi file sạch
+	if !media.IsScanClean() {
+		return nil
+	}
+
+	if media.Type != models.TypeImage && media.Type != models.TypeVideo {
+		if media.ProcessingStatus == "" || media.ProcessingStatus == models.ProcessingPending {
+			return biz.store.SaveMediaProcessingResult(ctx, mediaID, &models.MediaProcessingResult{Status: models.ProcessingSkipped})
+		}
+		return nil
+	}
+
+	claimed, err := biz.store.ClaimMediaProcessing(ctx, mediaID, time.Now().Add(-2*biz.timeout))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil // đã xong hoặc consumer khác đang xử lý
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, biz.timeout)
+	defer cancel()
+
+	result, err := biz.process(ctx, media)
+	if err != nil {
+		log.Printf("⚠️ Xử lý media %s lỗi: %v", mediaID.Hex(), err)
+		result = &models.MediaProcessingResult{Status: models.ProcessingFailed, Error: err.Error()}
+	}
+
+	// Dùng context riêng để vẫn ghi được trạng thái khi ctx xử lý đã hết hạn
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	if saveErr := biz.store.SaveMediaProcessingResult(saveCtx, mediaID, result); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+func (biz *MediaProcessingBiz) process(ctx context.Context, media *models.Media) (*models.MediaProcessingResult, error) {
+	dir, err := os.MkdirTemp(biz.workDir, "media-"+media.ID.Hex()+"-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "original"+strings.ToLower(filepath.Ext(media.URL)))
+	if err := biz.store.DownloadMediaObject(ctx, media.URL, src); err != nil {
+		return nil, fmt.Errorf("tải file gốc: %w", err)
+	}
+
+	probe, err := biz.transcoder.Probe(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.MediaProcessingResult{
+		Status: models.ProcessingReady,
+		Width:  probe.Width,
+		Height: probe.Height,
+	}
+
+	// Nguồn để resize: ảnh gốc, hoặc khung hình poster với video
+	frame := src
+	if media.Type == models.TypeVideo {
+		result.Duration = math.Round(probe.Duration*100) / 100
+
+		at := 1.0
+		if probe.Duration > 0 && probe.Duration < 2 {
+			at = probe.Duration / 2
+		}
+		frame = filepath.Join(dir, "poster.png")
+		if err := biz.transcoder.ExtractFrame(ctx, src, frame, at); err != nil {
+			return nil, fmt.Errorf("trích poster: %w", err)
+		}
+
+		posterEdge := max(probe.Width, probe.Height)
+		variants, err := biz.makeVariants(ctx, media, dir, frame, models.MediaSizePoster, posterEdge, probe)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variants...)
+	}
+
+	longest := max(probe.Width, probe.Height)
+	for _, spec := range models.MediaImageSizes {
+		// Không phóng to: chỉ tạo size nhỏ hơn bản gốc, riêng thumb luôn có để làm ảnh xem trước
+		if spec.MaxEdge >= longest && spec.Name != models.MediaSizeThumb {
+			continue
+		}
+		variants, err := biz.makeVariants(ctx, media, dir, frame, spec.Name, spec.MaxEdge, probe)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variants...)
+	}
+
+	return result, nil
+}
+
+func (biz *MediaProcessingBiz) makeVariants(ctx context.Context, media *models.Media, dir, src, size string, maxEdge int, probe *models.MediaProbe) ([]models.MediaVariant, error) {
+	width, height := fitWithin(probe.Width, probe.Height, maxEdge)
+
+	variants := make([]models.MediaVariant, 0, len(mediaFormats))
+	for _, f := range mediaFormats {
+		dst := filepath.Join(dir, size+f.ext)
+		if err := biz.transcoder.ResizeImage(ctx, src, dst, maxEdge); err != nil {
+			return nil, fmt.Errorf("tạo %s/%s: %w", size, f.format, err)
+		}
+
+		key := fmt.Sprintf("variants/%s/%s%s", media.ID.Hex(), size, f.ext)
+		n, err := biz.store.UploadMediaObject(ctx, key, dst, f.contentType)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", key, err)
+		}
+
+		variants = append(variants, models.MediaVariant{
+			Size:        size,
+			Format:      f.format,
+			URL:         key,
+			ContentType: f.contentType,
+			Width:       width,
+			Height:      height,
+			Bytes:       n,
+		})
+	}
+	return variants, nil
+}
+
+// fitWithin tính kích thước sau khi thu nhỏ về cạnh dài maxEdge, giữ tỉ lệ
+func fitWithin(width, height, maxEdge int) (int, int) {
+	longest := max(width, height)
+	if longest <= maxEdge || longest == 0 {
+		return width, height
+	}
+	ratio := float64(maxEdge) / float64(longest)
+	return max(1, int(math.Round(float64(width)*ratio))), max(1, int(math.Round(float64(height)*ratio)))
+}
@@ -0,0 +1,129 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"my-app/modules/chat/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bộ quét lỗi thì thử lại với backoff tăng dần, quá maxScanAttempts lần thì chuyển sang scan_failed
+// để media lỗi không chiếm hàng chờ mãi
+const (
+	maxScanAttempts  = 5
+	scanRetryBackoff = 30 * time.Second
+	maxScanRetryWait = 30 * time.Minute
+)
+
+// MalwareScanner - bộ quét mã độc (clamd, hoặc bản giả khi dev/test)
+type MalwareScanner interface {
+	Scan(ctx context.Context, r io.Reader) (*models.ScanResult, error)
+}
+
+type MediaScanStorage interface {
+	ClaimMediaScan(ctx context.Context, staleBefore time.Time) (*models.Media, error)
+	SaveMediaScanResult(ctx context.Context, ID primitive.ObjectID, status models.ScanStatus, signature string, scannedAt time.Time) error
+	ReleaseMediaScan(ctx context.Context, ID primitive.ObjectID, errMsg string, retryAt time.Time) error
+	FailMediaScan(ctx context.Context, ID primitive.ObjectID, errMsg string) error
+	ReadMediaObject(ctx context.Context, key string) (io.ReadCloser, error)
+	QuarantineMediaObject(ctx context.Context, key string) error
+}
+
+type MediaScanBiz struct {
+	store   MediaScanStorage
+	scanner MalwareScanner
+	timeout time.Duration
+}
+
+func NewMediaScanBiz(store MediaScanStorage, scanner MalwareScanner, timeout time.Duration) *MediaScanBiz {
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	return &MediaScanBiz{store: store, scanner: scanner, timeout: timeout}
+}
+
+// ScanNext quét một media đang chờ, trả về nil nếu hàng chờ trống.
+// File nhiễm được chuyển sang bucket cách ly, file vượt giới hạn quét bị đánh dấu too_large;
+// lỗi bộ quét thì media giữ trạng thái pending và được thử lại sau, hết lượt thử thì thành scan_failed.
+func (biz *MediaScanBiz) ScanNext(ctx context.Context) (*models.Media, error) {
+	// Lượt quét bị bỏ dở quá 2 lần timeout coi như worker đã chết
+	media, err := biz.store.ClaimMediaScan(ctx, time.Now().Add(-2*biz.timeout))
+	if err != nil || media == nil {
+		return nil, err
+	}
+
+	result, err := biz.scan(ctx, media)
+	if errors.Is(err, models.ErrScanTooLarge) {
+		log.Printf("⚠️ Media %s vượt giới hạn quét (%d bytes): %v", media.ID.Hex(), media.Size, err)
+		return biz.saveResult(ctx, media, models.ScanTooLarge, "")
+	}
+	if err != nil {
+		return biz.retryLater(ctx, media, fmt.Errorf("quét media %s lỗi: %w", media.ID.Hex(), err))
+	}
+
+	status := models.ScanClean
+	if result.Infected {
+		status = models.ScanInfected
+		if err := biz.store.QuarantineMediaObject(ctx, media.URL); err != nil {
+			// Chưa cách ly được thì không ghi kết quả, lượt sau quét lại và cách ly tiếp
+			return biz.retryLater(ctx, media, fmt.Errorf("cách ly media %s lỗi: %w", media.ID.Hex(), err))
+		}
+	}
+	return biz.saveResult(ctx, media, status, result.Signature)
+}
+
+func (biz *MediaScanBiz) saveResult(ctx context.Context, media *models.Media, status models.ScanStatus, signature string) (*models.Media, error) {
+	now := time.Now()
+	if err := biz.store.SaveMediaScanResult(ctx, media.ID, status, signature, now); err != nil {
+		return nil, err
+	}
+
+	media.ScanStatus = status
+	media.ScanSignature = signature
+	media.ScannedAt = &now
+	return media, nil
+}
+
+// retryLater trả media về hàng chờ với backoff 30s, 1m, 2m... hoặc chuyển sang scan_failed khi hết lượt thử.
+// Media scan_failed được trả về để báo cho người upload.
+func (biz *MediaScanBiz) retryLater(ctx context.Context, media *models.Media, cause error) (*models.Media, error) {
+	if media.ScanAttempts >= maxScanAttempts {
+		if err := biz.store.FailMediaScan(ctx, media.ID, cause.Error()); err != nil {
+			return nil, err
+		}
+		log.Printf("❌ Media %s quét lỗi %d lần, chuyển sang %s: %v", media.ID.Hex(), media.ScanAttempts, models.ScanFailed, cause)
+		media.ScanStatus = models.ScanFailed
+		return media, nil
+	}
+
+	if err := biz.store.ReleaseMediaScan(ctx, media.ID, cause.Error(), time.Now().Add(scanRetryDelay(media.ScanAttempts))); err != nil {
+		log.Printf("⚠️ Không trả media %s về hàng chờ quét: %v", media.ID.Hex(), err)
+	}
+	return nil, cause
+}
+
+func scanRetryDelay(attempts int) time.Duration {
+	delay := scanRetryBackoff
+	for i := 1; i < attempts && delay < maxScanRetryWait; i++ {
+		delay *= 2
+	}
+	return min(delay, maxScanRetryWait)
+}
+
+func (biz *MediaScanBiz) scan(ctx context.Context, media *models.Media) (*models.ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, biz.timeout)
+	defer cancel()
+
+	obj, err := biz.store.ReadMediaObject(ctx, media.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return biz.scanner.Scan(ctx, obj)
+}
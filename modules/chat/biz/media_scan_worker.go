@@ -0,0 +1,47 @@
+package biz
+
+import (
+	"context"
+	"log"
+	"my-app/modules/chat/models"
+	"time"
+)
+
+// RunMediaScanWorker quét lần lượt các media chờ quét với concurrency luồng, onScanned dùng để báo kết quả cho người upload
+func RunMediaScanWorker(ctx context.Context, interval time.Duration, concurrency int, scans *MediaScanBiz, onScanned func(*models.Media)) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				// Quét liên tục tới khi hàng chờ trống rồi mới nghỉ
+				media, err := scans.ScanNext(ctx)
+				if err != nil {
+					log.Printf("⚠️ Media scan worker: %v", err)
+				}
+				if media != nil {
+					if onScanned != nil {
+						onScanned(media)
+					}
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+}
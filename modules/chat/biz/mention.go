@@ -0,0 +1,198 @@
+package biz
+
+import (
+	"context"
+	"my-app/modules/chat/models"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// Editor chèn mention dạng <span data-mention-id="..."> (hoặc data-user-id)
+	mentionAttrRe = regexp.MustCompile(`data-(?:mention-id|user-id)="([0-9a-fA-F]{24})"`)
+	// @username / @here / @all trong nội dung thuần
+	mentionTextRe = regexp.MustCompile(`(?:^|[\s(>])@([\p{L}\p{N}_.\-]+)`)
+	htmlTagRe     = regexp.MustCompile(`<[^>]*>`)
+)
+
+type MentionStore interface {
+	GetGroupMembers(ctx context.Context, groupID primitive.ObjectID) ([]primitive.ObjectID, error)
+	FindUserIDsByUsernames(ctx context.Context, usernames []string) (map[string]primitive.ObjectID, error)
+	FilterOnlineUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]primitive.ObjectID, error)
+}
+
+type MentionTarget = models.MentionTarget
+
+type parsedMentions struct {
+	userIDs   []primitive.ObjectID
+	usernames []string
+	here      bool
+	all       bool
+}
+
+func parseMentions(content string) parsedMentions {
+	var p parsedMentions
+	seenID := map[primitive.ObjectID]bool{}
+	seenName := map[string]bool{}
+
+	for _, m := range mentionAttrRe.FindAllStringSubmatch(content, -1) {
+		if oid, err := primitive.ObjectIDFromHex(m[1]); err == nil && !seenID[oid] {
+			seenID[oid] = true
+			p.userIDs = append(p.userIDs, oid)
+		}
+	}
+
+	// Bỏ phần mention dạng thẻ đã xử lý ở trên rồi mới quét text, tránh đếm trùng tên hiển thị
+	text := htmlTagRe.ReplaceAllString(content, " ")
+	for _, m := range mentionTextRe.FindAllStringSubmatch(text, -1) {
+		token := strings.TrimRight(m[1], ".-")
+		switch strings.ToLower(token) {
+		case "here":
+			p.here = true
+		case "all", "everyone":
+			p.all = true
+		case "":
+		default:
+			name := strings.ToLower(token)
+			if !seenName[name] {
+				seenName[name] = true
+				p.usernames = append(p.usernames, name)
+			}
+		}
+	}
+	return p
+}
+
+// HasMentionCandidate kiểm tra nhanh trước khi phải truy vấn DB
+func HasMentionCandidate(content string) bool {
+	return strings.Contains(content, "@") || strings.Contains(content, "data-mention-id") || strings.Contains(content, "data-user-id")
+}
+
+// ResolveMentions phân tích nội dung và trả về các mention hợp lệ cùng danh sách người được nhắc.
+// Trong nhóm chỉ nhắc được thành viên; chat 1-1 chỉ nhắc được người nhận. Người gửi không tự nhận thông báo.
+func ResolveMentions(ctx context.Context, store MentionStore, content string, senderID, receiverID, groupID primitive.ObjectID) ([]models.Mention, []MentionTarget, error) {
+	if !HasMentionCandidate(content) {
+		return nil, nil, nil
+	}
+
+	p := parseMentions(content)
+	if len(p.userIDs) == 0 && len(p.usernames) == 0 && !p.here && !p.all {
+		return nil, nil, nil
+	}
+
+	userIDs := p.userIDs
+	if len(p.usernames) > 0 {
+		byName, err := store.FindUserIDsByUsernames(ctx, p.usernames)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range p.usernames {
+			if id, ok := byName[name]; ok {
+				userIDs = append(userIDs, id)
+			}
+		}
+	}
+
+	var members []primitive.ObjectID
+	if groupID.IsZero() {
+		members = []primitive.ObjectID{receiverID}
+		p.here, p.all = false, false
+	} else {
+		var err error
+		if members, err = store.GetGroupMembers(ctx, groupID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	isMember := make(map[primitive.ObjectID]bool, len(members))
+	for _, id := range members {
+		isMember[id] = true
+	}
+
+	var mentions []models.Mention
+	targets := map[primitive.ObjectID]string{}
+	order := []primitive.ObjectID{}
+	addTarget := func(id primitive.ObjectID, t string) {
+		if id == senderID || !isMember[id] {
+			return
+		}
+		if _, ok := targets[id]; !ok {
+			targets[id] = t
+			order = append(order, id)
+		}
+	}
+
+	added := map[primitive.ObjectID]bool{}
+	for _, id := range userIDs {
+		if !isMember[id] || added[id] {
+			continue
+		}
+		added[id] = true
+		uid := id
+		mentions = append(mentions, models.Mention{Type: models.MentionUser, UserID: &uid})
+		addTarget(id, models.MentionUser)
+	}
+
+	if p.here {
+		online, err := store.FilterOnlineUsers(ctx, members)
+		if err != nil {
+			return nil, nil, err
+		}
+		mentions = append(mentions, models.Mention{Type: models.MentionHere})
+		for _, id := range online {
+			addTarget(id, models.MentionHere)
+		}
+	}
+
+	if p.all {
+		mentions = append(mentions, models.Mention{Type: models.MentionAll})
+		for _, id := range members {
+			addTarget(id, models.MentionAll)
+		}
+	}
+
+	result := make([]MentionTarget, 0, len(order))
+	for _, id := range order {
+		result = append(result, MentionTarget{UserID: id, Type: targets[id]})
+	}
+	return mentions, result, nil
+}
+
+// MentionPreview - đoạn nội dung (bỏ thẻ HTML, tối đa 200 ký tự) lưu trong hộp thư nhắc đến
+func MentionPreview(content string) string {
+	preview := strings.TrimSpace(htmlTagRe.ReplaceAllString(content, ""))
+	if r := []rune(preview); len(r) > 200 {
+		preview = string(r[:200]) + "…"
+	}
+	return preview
+}
+
+// ApplyMentions gán mention vào message và trả về các bản ghi hộp thư nhắc đến
+func ApplyMentions(msg *models.Message, mentions []models.Mention, targets []MentionTarget) []models.MentionInboxItem {
+	msg.Mentions = mentions
+	if len(targets) == 0 {
+		return nil
+	}
+
+	preview := MentionPreview(msg.Content)
+
+	items := make([]models.MentionInboxItem, 0, len(targets))
+	msg.MentionedUserIDs = make([]primitive.ObjectID, 0, len(targets))
+	for _, t := range targets {
+		msg.MentionedUserIDs = append(msg.MentionedUserIDs, t.UserID)
+		items = append(items, models.MentionInboxItem{
+			ID:          primitive.NewObjectID(),
+			UserID:      t.UserID,
+			MessageID:   msg.ID,
+			SenderID:    msg.SenderID,
+			GroupID:     msg.GroupID,
+			ReceiverID:  msg.ReceiverID,
+			MentionType: t.Type,
+			Content:     preview,
+			CreatedAt:   msg.CreatedAt,
+		})
+	}
+	return items
+}
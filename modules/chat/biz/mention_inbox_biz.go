@@ -0,0 +1,71 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/chat/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrEmptyMentionSelection = errors.New("cần truyền ids hoặc all=true")
+
+type MentionInboxStorage interface {
+	ListMentionInbox(ctx context.Context, userID primitive.ObjectID, page, limit int, unreadOnly bool) ([]models.MentionInboxItem, int64, error)
+	CountUnreadMentions(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	MarkMentionsRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, groupID *primitive.ObjectID) (int64, error)
+}
+
+type MentionInboxBiz struct {
+	store MentionInboxStorage
+}
+
+func NewMentionInboxBiz(store MentionInboxStorage) *MentionInboxBiz {
+	return &MentionInboxBiz{store: store}
+}
+
+func (biz *MentionInboxBiz) List(ctx context.Context, userID primitive.ObjectID, req *models.MentionInboxRequest) ([]models.MentionInboxItem, int64, int64, error) {
+	items, total, err := biz.store.ListMentionInbox(ctx, userID, req.Page, req.Limit, req.UnreadOnly)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	unread, err := biz.store.CountUnreadMentions(ctx, userID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return items, total, unread, nil
+}
+
+func (biz *MentionInboxBiz) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return biz.store.CountUnreadMentions(ctx, userID)
+}
+
+// MarkRead đánh dấu đã đọc, trả về số bản ghi được cập nhật
+func (biz *MentionInboxBiz) MarkRead(ctx context.Context, userID primitive.ObjectID, req *models.MarkMentionsReadRequest) (int64, error) {
+	if len(req.IDs) == 0 && !req.All {
+		return 0, ErrEmptyMentionSelection
+	}
+
+	var ids []primitive.ObjectID
+	if !req.All {
+		for _, raw := range req.IDs {
+			oid, err := primitive.ObjectIDFromHex(raw)
+			if err != nil {
+				return 0, errors.New("id không hợp lệ: " + raw)
+			}
+			ids = append(ids, oid)
+		}
+	}
+
+	var groupID *primitive.ObjectID
+	if req.GroupID != "" {
+		oid, err := primitive.ObjectIDFromHex(req.GroupID)
+		if err != nil {
+			return 0, errors.New("group_id không hợp lệ")
+		}
+		groupID = &oid
+	}
+
+	return biz.store.MarkMentionsRead(ctx, userID, ids, groupID)
+}
@@ -0,0 +1,445 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"my-app/modules/chat/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	ModelUser "my-app/modules/user/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrPollNotFound          = errors.New("không tìm thấy bình chọn")
+	ErrPollClosed            = errors.New("bình chọn đã kết thúc")
+	ErrNotPollParticipant    = errors.New("bạn không thuộc cuộc trò chuyện của bình chọn này")
+	ErrInvalidPollOption     = errors.New("lựa chọn không hợp lệ")
+	ErrPollSingleChoice      = errors.New("bình chọn này chỉ cho phép chọn một đáp án")
+	ErrPollAddOptionDisabled = errors.New("bình chọn không cho phép thêm lựa chọn")
+	ErrNotPollCreator        = errors.New("chỉ người tạo hoặc quản trị viên nhóm được đóng bình chọn")
+)
+
+// SystemActionPollClosed - system_action của tin nhắn tổng kết khi bình chọn kết thúc
+const SystemActionPollClosed = "poll_closed"
+
+type PollStorage interface {
+	groupPostStore
+	CheckUserExists(ctx context.Context, userID string) (bool, error)
+	IsUserInGroup(ctx context.Context, userID, groupID primitive.ObjectID) (bool, error)
+	IsBlocked(ctx context.Context, userA, userB string) (bool, error)
+	GetUserById(ctx context.Context, userID primitive.ObjectID) (*ModelUser.User, error)
+	SaveMessage(ctx context.Context, msg *models.Message) error
+
+	CreatePoll(ctx context.Context, poll *models.Poll) error
+	FindPoll(ctx context.Context, pollID primitive.ObjectID) (*models.Poll, error)
+	SetPollVote(ctx context.Context, pollID, userID primitive.ObjectID, optionIDs []string) error
+	AddPollOption(ctx context.Context, pollID primitive.ObjectID, option models.PollOption) error
+	ClosePoll(ctx context.Context, pollID primitive.ObjectID, closedBy *primitive.ObjectID, closedAt time.Time) (bool, error)
+	ListPollVotes(ctx context.Context, pollID primitive.ObjectID) ([]models.PollVote, error)
+	ListDuePolls(ctx context.Context, now time.Time, limit int64) ([]models.Poll, error)
+}
+
+type PollBiz struct {
+	store PollStorage
+}
+
+func NewPollBiz(store PollStorage) *PollBiz {
+	return &PollBiz{store: store}
+}
+
+// Create tạo bình chọn và tin nhắn type "poll" tương ứng (đã lưu DB)
+func (biz *PollBiz) Create(ctx context.Context, creatorID primitive.ObjectID, req *models.CreatePollRequest) (*models.Poll, *models.Message, error) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, nil, errors.New("câu hỏi không được để trống")
+	}
+
+	options, err := normalizePollOptions(req.Options, creatorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if req.Deadline != nil && !req.Deadline.After(now) {
+		return nil, nil, errors.New("hạn chót phải ở tương lai")
+	}
+
+	var groupID, receiverID primitive.ObjectID
+	if req.GroupID != "" {
+		if groupID, err = primitive.ObjectIDFromHex(req.GroupID); err != nil {
+			return nil, nil, errors.New("group_id không hợp lệ")
+		}
+	} else if req.ReceiverID != "" {
+		if receiverID, err = primitive.ObjectIDFromHex(req.ReceiverID); err != nil {
+			return nil, nil, errors.New("receiver_id không hợp lệ")
+		}
+		if receiverID == creatorID {
+			return nil, nil, errors.New("không thể tạo bình chọn với chính mình")
+		}
+	} else {
+		return nil, nil, errors.New("cần group_id hoặc receiver_id")
+	}
+
+	creator, err := biz.store.GetUserById(ctx, creatorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg := &models.Message{
+		ID:         primitive.NewObjectID(),
+		SenderID:   creatorID,
+		GroupID:    groupID,
+		ReceiverID: receiverID,
+		Content:    question,
+		Type:       models.MediaTypePoll,
+		CreatedAt:  now,
+		Status:     models.StatusSent,
+	}
+
+	if !groupID.IsZero() {
+		inGroup, err := biz.store.IsUserInGroup(ctx, creatorID, groupID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !inGroup {
+			return nil, nil, ErrNotPollParticipant
+		}
+		if err := checkGroupPostPolicy(ctx, biz.store, msg); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		exists, err := biz.store.CheckUserExists(ctx, receiverID.Hex())
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			return nil, nil, errors.New("không tìm thấy người nhận")
+		}
+		blocked, err := biz.store.IsBlocked(ctx, creatorID.Hex(), receiverID.Hex())
+		if err != nil {
+			return nil, nil, err
+		}
+		if blocked {
+			return nil, nil, ErrUserBlocked
+		}
+	}
+
+	poll := &models.Poll{
+		ID:             primitive.NewObjectID(),
+		MessageID:      msg.ID,
+		CreatorID:      creatorID,
+		CreatorName:    creator.DisplayName,
+		GroupID:        groupID,
+		ReceiverID:     receiverID,
+		Question:       question,
+		Options:        options,
+		MultipleChoice: req.MultipleChoice,
+		Anonymous:      req.Anonymous,
+		AllowAddOption: req.AllowAddOption,
+		Deadline:       req.Deadline,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	msg.Poll = poll
+
+	if err := biz.store.CreatePoll(ctx, poll); err != nil {
+		return nil, nil, err
+	}
+	if err := biz.store.SaveMessage(ctx, msg); err != nil {
+		return nil, nil, err
+	}
+
+	return poll, msg, nil
+}
+
+// Get trả về bình chọn kèm kết quả hiện tại theo góc nhìn của viewerID
+func (biz *PollBiz) Get(ctx context.Context, viewerID, pollID primitive.ObjectID) (*models.Poll, error) {
+	poll, err := biz.findForParticipant(ctx, viewerID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	return poll, biz.fillResults(ctx, poll, viewerID)
+}
+
+// Vote ghi nhận lựa chọn của user (ghi đè lần chọn trước). optionIDs rỗng = rút phiếu
+func (biz *PollBiz) Vote(ctx context.Context, userID, pollID primitive.ObjectID, optionIDs []string) (*models.Poll, error) {
+	poll, err := biz.findForParticipant(ctx, userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.IsClosed(time.Now()) {
+		return nil, ErrPollClosed
+	}
+
+	seen := make(map[string]bool, len(optionIDs))
+	chosen := make([]string, 0, len(optionIDs))
+	for _, id := range optionIDs {
+		if !poll.HasOption(id) {
+			return nil, ErrInvalidPollOption
+		}
+		if !seen[id] {
+			seen[id] = true
+			chosen = append(chosen, id)
+		}
+	}
+	if !poll.MultipleChoice && len(chosen) > 1 {
+		return nil, ErrPollSingleChoice
+	}
+
+	if err := biz.store.SetPollVote(ctx, poll.ID, userID, chosen); err != nil {
+		return nil, err
+	}
+	return poll, biz.fillResults(ctx, poll, userID)
+}
+
+// AddOption thêm lựa chọn mới khi bình chọn bật "cho phép thêm lựa chọn"
+func (biz *PollBiz) AddOption(ctx context.Context, userID, pollID primitive.ObjectID, text string) (*models.Poll, error) {
+	poll, err := biz.findForParticipant(ctx, userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.IsClosed(time.Now()) {
+		return nil, ErrPollClosed
+	}
+	if !poll.AllowAddOption && poll.CreatorID != userID {
+		return nil, ErrPollAddOptionDisabled
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > models.PollMaxOptionText {
+		return nil, fmt.Errorf("lựa chọn phải từ 1 đến %d ký tự", models.PollMaxOptionText)
+	}
+	if len(poll.Options) >= models.PollMaxOptions {
+		return nil, fmt.Errorf("bình chọn tối đa %d lựa chọn", models.PollMaxOptions)
+	}
+	for _, o := range poll.Options {
+		if strings.EqualFold(o.Text, text) {
+			return nil, errors.New("lựa chọn đã tồn tại")
+		}
+	}
+
+	option := models.PollOption{ID: primitive.NewObjectID().Hex(), Text: text, AddedBy: userID}
+	if err := biz.store.AddPollOption(ctx, poll.ID, option); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPollClosed
+		}
+		return nil, err
+	}
+	poll.Options = append(poll.Options, option)
+
+	return poll, biz.fillResults(ctx, poll, userID)
+}
+
+// Close đóng bình chọn thủ công (người tạo hoặc owner/admin nhóm), trả về kết quả và tin nhắn tổng kết
+func (biz *PollBiz) Close(ctx context.Context, userID, pollID primitive.ObjectID) (*models.Poll, *models.Message, error) {
+	poll, err := biz.findForParticipant(ctx, userID, pollID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if poll.ClosedAt != nil {
+		return nil, nil, ErrPollClosed
+	}
+
+	if poll.CreatorID != userID {
+		if poll.GroupID.IsZero() {
+			return nil, nil, ErrNotPollCreator
+		}
+		role, err := biz.store.GetGroupRoleCode(ctx, poll.GroupID, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !models.IsGroupAdminRole(role) {
+			return nil, nil, ErrNotPollCreator
+		}
+	}
+
+	return biz.close(ctx, poll, &userID)
+}
+
+// PollClosedEvent - bình chọn vừa được worker đóng, dùng để bắn event realtime
+type PollClosedEvent struct {
+	Poll    *models.Poll
+	Summary *models.Message
+}
+
+// CloseDue đóng các bình chọn đã hết hạn
+func (biz *PollBiz) CloseDue(ctx context.Context) ([]PollClosedEvent, error) {
+	polls, err := biz.store.ListDuePolls(ctx, time.Now(), 100)
+	if err != nil {
+		return nil, err
+	}
+
+	var closed []PollClosedEvent
+	for i := range polls {
+		poll, summary, err := biz.close(ctx, &polls[i], nil)
+		if err != nil {
+			if errors.Is(err, ErrPollClosed) {
+				continue
+			}
+			return closed, err
+		}
+		closed = append(closed, PollClosedEvent{Poll: poll, Summary: summary})
+	}
+	return closed, nil
+}
+
+func (biz *PollBiz) close(ctx context.Context, poll *models.Poll, closedBy *primitive.ObjectID) (*models.Poll, *models.Message, error) {
+	now := time.Now()
+	ok, err := biz.store.ClosePoll(ctx, poll.ID, closedBy, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrPollClosed
+	}
+	poll.ClosedAt = &now
+	poll.ClosedBy = closedBy
+
+	if err := biz.fillResults(ctx, poll, primitive.NilObjectID); err != nil {
+		return nil, nil, err
+	}
+
+	actorID := poll.CreatorID
+	if closedBy != nil {
+		actorID = *closedBy
+	}
+	summary := &models.Message{
+		ID:           primitive.NewObjectID(),
+		SenderID:     actorID,
+		GroupID:      poll.GroupID,
+		ReceiverID:   poll.ReceiverID,
+		Content:      BuildPollSummary(poll),
+		Type:         "system",
+		CreatedAt:    now,
+		Status:       models.StatusSent,
+		SystemAction: SystemActionPollClosed,
+	}
+	if err := biz.store.SaveMessage(ctx, summary); err != nil {
+		return nil, nil, err
+	}
+	return poll, summary, nil
+}
+
+func (biz *PollBiz) findForParticipant(ctx context.Context, userID, pollID primitive.ObjectID) (*models.Poll, error) {
+	poll, err := biz.store.FindPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPollNotFound
+		}
+		return nil, err
+	}
+
+	if !poll.GroupID.IsZero() {
+		inGroup, err := biz.store.IsUserInGroup(ctx, userID, poll.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		if !inGroup {
+			return nil, ErrNotPollParticipant
+		}
+	} else if userID != poll.CreatorID && userID != poll.ReceiverID {
+		return nil, ErrNotPollParticipant
+	}
+	return poll, nil
+}
+
+// fillResults đếm phiếu cho từng lựa chọn; danh sách người chọn chỉ có với bình chọn công khai
+func (biz *PollBiz) fillResults(ctx context.Context, poll *models.Poll, viewerID primitive.ObjectID) error {
+	votes, err := biz.store.ListPollVotes(ctx, poll.ID)
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]int, len(poll.Options))
+	for i := range poll.Options {
+		poll.Options[i].VoteCount = 0
+		poll.Options[i].Voters = nil
+		index[poll.Options[i].ID] = i
+	}
+
+	poll.TotalVoters = 0
+	poll.MyVotes = nil
+	for _, v := range votes {
+		if len(v.OptionIDs) == 0 {
+			continue
+		}
+		poll.TotalVoters++
+		if v.UserID == viewerID {
+			poll.MyVotes = v.OptionIDs
+		}
+		for _, id := range v.OptionIDs {
+			i, ok := index[id]
+			if !ok {
+				continue
+			}
+			poll.Options[i].VoteCount++
+			if !poll.Anonymous {
+				poll.Options[i].Voters = append(poll.Options[i].Voters, models.PollVoter{
+					UserID: v.UserID,
+					Name:   v.UserName,
+					Avatar: v.UserAvatar,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// BuildPollSummary tạo nội dung tin nhắn hệ thống tổng kết kết quả
+func BuildPollSummary(poll *models.Poll) string {
+	if poll.TotalVoters == 0 {
+		return fmt.Sprintf("Bình chọn \"%s\" đã kết thúc, không có ai bình chọn", poll.Question)
+	}
+
+	maxVotes := 0
+	for _, o := range poll.Options {
+		if o.VoteCount > maxVotes {
+			maxVotes = o.VoteCount
+		}
+	}
+
+	var winners, parts []string
+	for _, o := range poll.Options {
+		parts = append(parts, fmt.Sprintf("%s: %d", o.Text, o.VoteCount))
+		if o.VoteCount == maxVotes {
+			winners = append(winners, o.Text)
+		}
+	}
+
+	return fmt.Sprintf("Bình chọn \"%s\" đã kết thúc với %d người tham gia. Dẫn đầu: %s (%d phiếu). Kết quả: %s",
+		poll.Question, poll.TotalVoters, strings.Join(winners, ", "), maxVotes, strings.Join(parts, "; "))
+}
+
+func normalizePollOptions(raw []string, creatorID primitive.ObjectID) ([]models.PollOption, error) {
+	seen := make(map[string]bool, len(raw))
+	options := make([]models.PollOption, 0, len(raw))
+	for _, text := range raw {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if utf8.RuneCountInString(text) > models.PollMaxOptionText {
+			return nil, fmt.Errorf("lựa chọn tối đa %d ký tự", models.PollMaxOptionText)
+		}
+		key := strings.ToLower(text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		options = append(options, models.PollOption{ID: primitive.NewObjectID().Hex(), Text: text, AddedBy: creatorID})
+	}
+
+	if len(options) < 2 {
+		return nil, errors.New("bình chọn cần ít nhất 2 lựa chọn khác nhau")
+	}
+	if len(options) > models.PollMaxOptions {
+		return nil, fmt.Errorf("bình chọn tối đa %d lựa chọn", models.PollMaxOptions)
+	}
+	return options, nil
+}
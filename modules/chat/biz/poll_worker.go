@@ -0,0 +1,35 @@
+package biz
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunPollCloseWorker định kỳ đóng các bình chọn đã tới hạn, onClosed dùng để bắn event realtime
+func RunPollCloseWorker(ctx context.Context, interval time.Duration, polls *PollBiz, onClosed func(PollClosedEvent)) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		closed, err := polls.CloseDue(ctx)
+		if err != nil {
+			log.Printf("⚠️ Poll worker: đóng bình chọn hết hạn lỗi: %v", err)
+		}
+		for _, ev := range closed {
+			if onClosed != nil {
+				onClosed(ev)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -14,6 +14,12 @@ type RecallMessageStore interface {
 
 	// Cập nhật trạng thái thu hồi
 	UpdateMessageRecall(ctx context.Context, id primitive.ObjectID, recalledBy primitive.ObjectID) error
+
+	// Gỡ media khỏi cuộc trò chuyện của tin nhắn bị thu hồi
+	RemoveMediaLinks(ctx context.Context, messageID primitive.ObjectID) error
+
+	// Xoá nội dung xem trước trong hộp thư nhắc đến của người khác
+	UpdateMentionPreview(ctx context.Context, messageID primitive.ObjectID, preview string) error
 }
 
 type ESChatRecallStore interface {
@@ -48,6 +54,14 @@ func (biz *RecallMessageBiz) RecallMessage(ctx context.Context, msgID, userID pr
 		return errors.New("you do not have permission to recall this message")
 	}
 
+	// Thu hồi quyền xem media trước, lần retry sau vẫn gỡ tiếp được nếu bước này lỗi
+	if err := biz.store.RemoveMediaLinks(ctx, msgID); err != nil {
+		return err
+	}
+	if err := biz.store.UpdateMentionPreview(ctx, msgID, ""); err != nil {
+		return err
+	}
+
 	// Nếu đã thu hồi rồi thì bỏ qua
 	if msg.RecalledAt != nil {
 		return nil
@@ -7,24 +7,35 @@ import (
 
 type ChatSearchStore interface {
 	// Trả về messages, nextCursor, error
-	SearchMessages(ctx context.Context, content string, senderID, receiverID, groupID string, limit int, cursorTime string, startTime, endTime string) ([]models.ESMessage, string, error)
+	SearchMessages(ctx context.Context, content string, senderID, receiverID, groupID string, limit int, cursorTime string, startTime, endTime string, excludeSenderIDs []string, mentionedOnly bool) ([]models.ESMessage, string, error)
+}
+
+type BlockedUserStore interface {
+	GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error)
 }
 
 type ChatSearchBiz struct {
-	store ChatSearchStore
+	store      ChatSearchStore
+	blockStore BlockedUserStore
 }
 
-func NewChatSearchBiz(store ChatSearchStore) *ChatSearchBiz {
-	return &ChatSearchBiz{store: store}
+func NewChatSearchBiz(store ChatSearchStore, blockStore BlockedUserStore) *ChatSearchBiz {
+	return &ChatSearchBiz{store: store, blockStore: blockStore}
 }
 
 // Search tin nhắn theo content, trả về messages và nextCursor (nếu có)
-func (biz *ChatSearchBiz) Search(ctx context.Context, content string, senderID, receiverID, groupID string, limit int, cursorTime string, startTime, endTime string) ([]models.ESMessage, string, error) {
+func (biz *ChatSearchBiz) Search(ctx context.Context, content string, senderID, receiverID, groupID string, limit int, cursorTime string, startTime, endTime string, mentionedOnly bool) ([]models.ESMessage, string, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 
-	messages, nextCursor, err := biz.store.SearchMessages(ctx, content, senderID, receiverID, groupID, limit, cursorTime, startTime, endTime)
+	// Ẩn tin nhắn của những người đang chặn/bị chặn
+	blockedIDs, err := biz.blockStore.GetBlockedUserIDs(ctx, senderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	messages, nextCursor, err := biz.store.SearchMessages(ctx, content, senderID, receiverID, groupID, limit, cursorTime, startTime, endTime, blockedIDs, mentionedOnly)
 	if err != nil {
 		return nil, "", err
 	}
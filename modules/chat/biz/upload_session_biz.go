@@ -0,0 +1,458 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"my-app/modules/chat/models"
+	"my-app/utils"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrUploadSessionNotFound = errors.New("không tìm thấy phiên upload")
+	ErrUploadSessionClosed   = errors.New("phiên upload đã kết thúc")
+	ErrUploadSessionExpired  = errors.New("phiên upload đã hết hạn")
+	ErrUploadNotMultipart    = errors.New("phiên upload không chia part")
+	ErrInvalidUploadPart     = errors.New("part upload không hợp lệ")
+	ErrUploadIncomplete      = errors.New("file chưa được upload đủ")
+)
+
+// UploadVerificationError - file đã upload nhưng không khớp khai báo (dung lượng, checksum, định dạng).
+// Object bị xóa và phiên chuyển sang failed, client phải tạo phiên mới.
+type UploadVerificationError struct {
+	Reason string
+}
+
+func (e *UploadVerificationError) Error() string {
+	return "file upload không hợp lệ: " + e.Reason
+}
+
+type UploadSessionStorage interface {
+	UploadMedia(ctx context.Context, media *models.Media) (*models.Media, error)
+	FindMediaByID(ctx context.Context, ID primitive.ObjectID) (*models.Media, error)
+
+	CreateUploadSession(ctx context.Context, session *models.UploadSession) error
+	FindUploadSession(ctx context.Context, ID primitive.ObjectID) (*models.UploadSession, error)
+	TransitionUploadSession(ctx context.Context, ID primitive.ObjectID, from, to models.UploadSessionStatus, mediaID *primitive.ObjectID, errMsg string) (bool, error)
+	ListExpiredUploadSessions(ctx context.Context, now, completingBefore time.Time, limit int64) ([]models.UploadSession, error)
+
+	NewMultipartUpload(ctx context.Context, key, contentType string) (string, error)
+	PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error)
+	PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int, expiry time.Duration) (string, error)
+	PutUploadPart(ctx context.Context, key, uploadID string, partNumber int, data io.Reader, size int64) (*models.UploadedPart, error)
+	ListUploadedParts(ctx context.Context, key, uploadID string) ([]models.UploadedPart, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []models.UploadedPart) error
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+	StatMediaObject(ctx context.Context, key string) (int64, error)
+	ReadMediaObjectHead(ctx context.Context, key string, n int64) ([]byte, error)
+	HashMediaObject(ctx context.Context, key string) (string, error)
+	RemoveMediaObject(ctx context.Context, key string) error
+}
+
+type UploadSessionOptions struct {
+	PartSize      int64
+	PresignExpiry time.Duration
+	SessionTTL    time.Duration
+}
+
+// Số presigned URL tối đa cấp trong một lần gọi, client xin thêm khi cần
+const uploadPresignBatch = 100
+
+// Thời gian tối đa để ghép và kiểm tra file (hash SHA-256 file tới 2GB). Phiên kẹt ở completing lâu hơn
+// uploadCompletingStale (process chết giữa chừng) được CleanupExpired thu hồi.
+const (
+	uploadFinalizeTimeout = 15 * time.Minute
+	uploadCompletingStale = 2 * uploadFinalizeTimeout
+)
+
+type UploadSessionBiz struct {
+	store UploadSessionStorage
+	opts  UploadSessionOptions
+}
+
+func NewUploadSessionBiz(store UploadSessionStorage, opts UploadSessionOptions) *UploadSessionBiz {
+	if opts.PartSize < models.UploadMinPartSize {
+		opts.PartSize = models.UploadMinPartSize
+	}
+	if opts.PresignExpiry <= 0 {
+		opts.PresignExpiry = 15 * time.Minute
+	}
+	if opts.SessionTTL <= 0 {
+		opts.SessionTTL = 24 * time.Hour
+	}
+	return &UploadSessionBiz{store: store, opts: opts}
+}
+
+// Create mở phiên upload: file nhỏ dùng 1 presigned PUT, file lớn dùng multipart để upload song song và resume
+func (biz *UploadSessionBiz) Create(ctx context.Context, userID primitive.ObjectID, req *models.CreateUploadSessionRequest) (*models.UploadSessionResponse, error) {
+	filename := filepath.Base(strings.TrimSpace(req.Filename))
+	if filename == "." || filename == "/" {
+		return nil, errors.New("tên file không hợp lệ")
+	}
+	if err := utils.ValidateUploadExt(filename); err != nil {
+		return nil, err
+	}
+	if max := utils.MaxUploadSizeForExt(filename); req.Size > max {
+		return nil, fmt.Errorf("file vượt quá dung lượng cho phép (tối đa %.1fMB)", float64(max)/(1024*1024))
+	}
+
+	now := time.Now()
+	session := &models.UploadSession{
+		UserID:         userID,
+		Filename:       filename,
+		ObjectKey:      fmt.Sprintf("%s-%s", uuid.New().String(), filename),
+		Size:           req.Size,
+		ContentType:    req.ContentType,
+		ChecksumSHA256: strings.ToLower(req.ChecksumSHA256),
+		Mode:           models.UploadModeSingle,
+		Status:         models.UploadSessionPending,
+		ExpiresAt:      now.Add(biz.opts.SessionTTL),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if req.Size > biz.opts.PartSize {
+		partSize := biz.opts.PartSize
+		if (req.Size+partSize-1)/partSize > models.UploadMaxParts {
+			partSize = (req.Size + models.UploadMaxParts - 1) / models.UploadMaxParts
+		}
+
+		contentType := req.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		uploadID, err := biz.store.NewMultipartUpload(ctx, session.ObjectKey, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("không khởi tạo được multipart upload: %w", err)
+		}
+
+		session.Mode = models.UploadModeMultipart
+		session.UploadID = uploadID
+		session.PartSize = partSize
+		session.TotalParts = int((req.Size + partSize - 1) / partSize)
+	}
+
+	if err := biz.store.CreateUploadSession(ctx, session); err != nil {
+		if session.Mode == models.UploadModeMultipart {
+			_ = biz.store.AbortMultipartUpload(ctx, session.ObjectKey, session.UploadID)
+		}
+		return nil, err
+	}
+
+	return biz.buildResponse(ctx, session, nil)
+}
+
+// Get trả về trạng thái phiên kèm các part đã upload để client resume
+func (biz *UploadSessionBiz) Get(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.UploadSessionResponse, error) {
+	session, err := biz.findOwned(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UploadSessionPending {
+		return &models.UploadSessionResponse{Session: session}, nil
+	}
+
+	var uploaded []models.UploadedPart
+	if session.Mode == models.UploadModeMultipart {
+		if uploaded, err = biz.store.ListUploadedParts(ctx, session.ObjectKey, session.UploadID); err != nil {
+			return nil, err
+		}
+	}
+	return biz.buildResponse(ctx, session, uploaded)
+}
+
+// PresignParts cấp presigned URL cho các part được yêu cầu
+func (biz *UploadSessionBiz) PresignParts(ctx context.Context, userID, sessionID primitive.ObjectID, partNumbers []int) ([]models.PresignedPart, error) {
+	session, err := biz.findPending(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Mode != models.UploadModeMultipart {
+		return nil, ErrUploadNotMultipart
+	}
+
+	parts := make([]models.PresignedPart, 0, len(partNumbers))
+	for _, n := range partNumbers {
+		if n < 1 || n > session.TotalParts {
+			return nil, fmt.Errorf("%w: part %d nằm ngoài 1..%d", ErrInvalidUploadPart, n, session.TotalParts)
+		}
+		u, err := biz.store.PresignUploadPart(ctx, session.ObjectKey, session.UploadID, n, biz.opts.PresignExpiry)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, models.PresignedPart{PartNumber: n, URL: u})
+	}
+	return parts, nil
+}
+
+// UploadPart nhận một part qua API rồi đẩy lên MinIO, upload lại cùng số part sẽ ghi đè
+func (biz *UploadSessionBiz) UploadPart(ctx context.Context, userID, sessionID primitive.ObjectID, partNumber int, data io.Reader, size int64) (*models.UploadedPart, error) {
+	session, err := biz.findPending(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Mode != models.UploadModeMultipart {
+		return nil, ErrUploadNotMultipart
+	}
+	if partNumber < 1 || partNumber > session.TotalParts {
+		return nil, fmt.Errorf("%w: part %d nằm ngoài 1..%d", ErrInvalidUploadPart, partNumber, session.TotalParts)
+	}
+	if expected := session.PartLength(partNumber); size != expected {
+		return nil, fmt.Errorf("%w: part %d phải có %d byte, nhận %d", ErrInvalidUploadPart, partNumber, expected, size)
+	}
+
+	return biz.store.PutUploadPart(ctx, session.ObjectKey, session.UploadID, partNumber, data, size)
+}
+
+// Complete ghép file, kiểm tra dung lượng / checksum / MIME type thật rồi tạo bản ghi Media.
+// Gọi lại sau khi đã hoàn tất sẽ trả về Media cũ.
+func (biz *UploadSessionBiz) Complete(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.Media, error) {
+	session, err := biz.findOwned(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.UploadSessionCompleted && session.MediaID != nil {
+		return biz.store.FindMediaByID(ctx, *session.MediaID)
+	}
+	if session.Status != models.UploadSessionPending {
+		return nil, ErrUploadSessionClosed
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadSessionExpired
+	}
+
+	claimed, err := biz.store.TransitionUploadSession(ctx, session.ID, models.UploadSessionPending, models.UploadSessionCompleting, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrUploadSessionClosed
+	}
+
+	// Client ngắt kết nối không được làm dở việc ghép file hay bỏ phiên kẹt ở completing
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), uploadFinalizeTimeout)
+	defer cancel()
+
+	media, err := biz.finalize(ctx, session)
+	if err != nil {
+		var verifyErr *UploadVerificationError
+		if errors.As(err, &verifyErr) {
+			biz.discard(ctx, session)
+			if _, terr := biz.store.TransitionUploadSession(ctx, session.ID, models.UploadSessionCompleting, models.UploadSessionFailed, nil, verifyErr.Reason); terr != nil {
+				log.Printf("⚠️ Không cập nhật được phiên upload %s: %v", session.ID.Hex(), terr)
+			}
+			return nil, err
+		}
+
+		// Lỗi tạm thời hoặc thiếu part: trả phiên về pending để client upload tiếp / thử lại
+		if _, terr := biz.store.TransitionUploadSession(ctx, session.ID, models.UploadSessionCompleting, models.UploadSessionPending, nil, ""); terr != nil {
+			log.Printf("⚠️ Không cập nhật được phiên upload %s: %v", session.ID.Hex(), terr)
+		}
+		return nil, err
+	}
+
+	if _, err := biz.store.TransitionUploadSession(ctx, session.ID, models.UploadSessionCompleting, models.UploadSessionCompleted, &media.ID, ""); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+func (biz *UploadSessionBiz) finalize(ctx context.Context, session *models.UploadSession) (*models.Media, error) {
+	if session.Mode == models.UploadModeMultipart {
+		parts, err := biz.store.ListUploadedParts(ctx, session.ObjectKey, session.UploadID)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != session.TotalParts {
+			return nil, fmt.Errorf("%w: đã nhận %d/%d part", ErrUploadIncomplete, len(parts), session.TotalParts)
+		}
+		for i, p := range parts {
+			if p.PartNumber != i+1 {
+				return nil, fmt.Errorf("%w: thiếu part %d", ErrUploadIncomplete, i+1)
+			}
+			if p.Size != session.PartLength(p.PartNumber) {
+				return nil, &UploadVerificationError{Reason: fmt.Sprintf("part %d có %d byte, mong đợi %d", p.PartNumber, p.Size, session.PartLength(p.PartNumber))}
+			}
+		}
+		if err := biz.store.CompleteMultipartUpload(ctx, session.ObjectKey, session.UploadID, parts); err != nil {
+			return nil, fmt.Errorf("không ghép được file: %w", err)
+		}
+	}
+
+	size, err := biz.store.StatMediaObject(ctx, session.ObjectKey)
+	if err != nil {
+		return nil, ErrUploadIncomplete
+	}
+	if size != session.Size {
+		return nil, &UploadVerificationError{Reason: fmt.Sprintf("dung lượng %d byte không khớp khai báo %d byte", size, session.Size)}
+	}
+
+	if session.ChecksumSHA256 != "" {
+		sum, err := biz.store.HashMediaObject(ctx, session.ObjectKey)
+		if err != nil {
+			return nil, err
+		}
+		if sum != session.ChecksumSHA256 {
+			return nil, &UploadVerificationError{Reason: "checksum SHA-256 không khớp"}
+		}
+	}
+
+	// Không tin Content-Type client gửi lên, đoán lại từ nội dung file
+	head, err := biz.store.ReadMediaObjectHead(ctx, session.ObjectKey, 512)
+	if err != nil {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head)
+	if err := utils.ValidateSniffedFile(session.Filename, contentType, size, utils.MaxResumableVideoSize); err != nil {
+		return nil, &UploadVerificationError{Reason: err.Error()}
+	}
+
+	now := time.Now()
+	mediaType := utils.DetectMediaType(contentType)
+	media := &models.Media{
+		Type:             mediaType,
+		Filename:         session.Filename,
+		Size:             size,
+		URL:              session.ObjectKey,
+		ContentType:      contentType,
+		ProcessingStatus: models.ProcessingSkipped,
+		UploadedBy:       &session.UserID,
+		ScanStatus:       models.ScanPending,
+	}
+	media.ID = primitive.NewObjectID()
+	media.CreatedAt = now
+	media.UpdatedAt = now
+	if mediaType == models.TypeImage || mediaType == models.TypeVideo {
+		media.ProcessingStatus = models.ProcessingPending
+	}
+
+	return biz.store.UploadMedia(ctx, media)
+}
+
+// Abort hủy phiên và xóa dữ liệu đã upload
+func (biz *UploadSessionBiz) Abort(ctx context.Context, userID, sessionID primitive.ObjectID) error {
+	session, err := biz.findOwned(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	aborted, err := biz.store.TransitionUploadSession(ctx, session.ID, models.UploadSessionPending, models.UploadSessionAborted, nil, "")
+	if err != nil {
+		return err
+	}
+	if !aborted {
+		return ErrUploadSessionClosed
+	}
+
+	biz.discard(ctx, session)
+	return nil
+}
+
+// CleanupExpired hủy các phiên quá hạn chưa hoàn tất và thu hồi phiên kẹt ở completing, trả về số phiên đã dọn
+func (biz *UploadSessionBiz) CleanupExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	sessions, err := biz.store.ListExpiredUploadSessions(ctx, now, now.Add(-uploadCompletingStale), 200)
+	if err != nil {
+		return 0, err
+	}
+
+	cleaned := 0
+	for i := range sessions {
+		session := &sessions[i]
+
+		// Phiên còn hạn bị kẹt ở completing: trả về pending để client gọi complete lại
+		if session.Status == models.UploadSessionCompleting && now.Before(session.ExpiresAt) {
+			if _, err := biz.store.TransitionUploadSession(ctx, session.ID, models.UploadSessionCompleting, models.UploadSessionPending, nil, ""); err != nil {
+				log.Printf("⚠️ Không trả phiên upload %s về pending: %v", session.ID.Hex(), err)
+			}
+			continue
+		}
+
+		aborted, err := biz.store.TransitionUploadSession(ctx, session.ID, session.Status, models.UploadSessionAborted, nil, "hết hạn")
+		if err != nil {
+			log.Printf("⚠️ Không hủy được phiên upload %s: %v", session.ID.Hex(), err)
+			continue
+		}
+		if aborted {
+			biz.discard(ctx, session)
+			cleaned++
+		}
+	}
+	return cleaned, nil
+}
+
+// discard xóa dữ liệu trên MinIO của phiên, bỏ qua lỗi vì object có thể chưa tồn tại
+func (biz *UploadSessionBiz) discard(ctx context.Context, session *models.UploadSession) {
+	if session.Mode == models.UploadModeMultipart {
+		_ = biz.store.AbortMultipartUpload(ctx, session.ObjectKey, session.UploadID)
+	}
+	_ = biz.store.RemoveMediaObject(ctx, session.ObjectKey)
+}
+
+func (biz *UploadSessionBiz) buildResponse(ctx context.Context, session *models.UploadSession, uploaded []models.UploadedPart) (*models.UploadSessionResponse, error) {
+	resp := &models.UploadSessionResponse{Session: session, UploadedParts: uploaded}
+
+	if session.Mode == models.UploadModeSingle {
+		u, err := biz.store.PresignPutObject(ctx, session.ObjectKey, biz.opts.PresignExpiry)
+		if err != nil {
+			return nil, err
+		}
+		resp.UploadURL = u
+		return resp, nil
+	}
+
+	// Cấp sẵn URL cho các part còn thiếu (tối đa uploadPresignBatch)
+	done := make(map[int]bool, len(uploaded))
+	for _, p := range uploaded {
+		done[p.PartNumber] = true
+	}
+	for n := 1; n <= session.TotalParts && len(resp.Parts) < uploadPresignBatch; n++ {
+		if done[n] {
+			continue
+		}
+		u, err := biz.store.PresignUploadPart(ctx, session.ObjectKey, session.UploadID, n, biz.opts.PresignExpiry)
+		if err != nil {
+			return nil, err
+		}
+		resp.Parts = append(resp.Parts, models.PresignedPart{PartNumber: n, URL: u})
+	}
+	return resp, nil
+}
+
+func (biz *UploadSessionBiz) findOwned(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.UploadSession, error) {
+	session, err := biz.store.FindUploadSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUploadSessionNotFound
+		}
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrUploadSessionNotFound
+	}
+	return session, nil
+}
+
+func (biz *UploadSessionBiz) findPending(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.UploadSession, error) {
+	session, err := biz.findOwned(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UploadSessionPending {
+		return nil, ErrUploadSessionClosed
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadSessionExpired
+	}
+	return session, nil
+}
@@ -0,0 +1,32 @@
+package biz
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunUploadCleanupWorker định kỳ hủy các phiên upload quá hạn và xóa phần dữ liệu dở dang trên MinIO
+func RunUploadCleanupWorker(ctx context.Context, interval time.Duration, uploads *UploadSessionBiz) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cleaned, err := uploads.CleanupExpired(ctx)
+		if err != nil {
+			log.Printf("⚠️ Upload worker: dọn phiên upload quá hạn lỗi: %v", err)
+		} else if cleaned > 0 {
+			log.Printf("🧹 Upload worker: đã hủy %d phiên upload quá hạn", cleaned)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
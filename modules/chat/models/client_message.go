@@ -0,0 +1,36 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ClientMessageIndex - unique index (sender, receiver, group, client_message_id) trên messages
+const ClientMessageIndex = "uniq_msg_client_id"
+
+// ErrDuplicateClientMessage trả về khi client_message_id đã có tin nhắn trong cuộc trò chuyện
+var ErrDuplicateClientMessage = errors.New("tin nhắn đã được gửi trước đó")
+
+// NormalizeClientMessageID chuẩn hóa UUID client gửi lên; chuỗi rỗng hoặc không phải UUID thì bỏ qua (trả về "")
+func NormalizeClientMessageID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return ""
+	}
+	return parsed.String()
+}
+
+// ClientMessageKey - idempotency key của tin nhắn theo người gửi, cuộc trò chuyện và client_message_id
+func ClientMessageKey(senderID, receiverID, groupID primitive.ObjectID, clientMessageID string) string {
+	if !groupID.IsZero() {
+		return "client-msg:" + senderID.Hex() + ":g:" + groupID.Hex() + ":" + clientMessageID
+	}
+	return "client-msg:" + senderID.Hex() + ":u:" + receiverID.Hex() + ":" + clientMessageID
+}
@@ -18,6 +18,7 @@ type ConversationPreview struct {
 	LastDate        time.Time           `json:"last_date"`
 	UnreadCount     int                 `json:"unread_count"`
 	Type            string              `json:"type"`
+	Kind            string              `json:"kind,omitempty"`           // "community" khi là cộng đồng (gộp các kênh)
 	Status          string              `json:"status,omitempty"`         // online/offline
 	UpdatedAt       time.Time           `json:"updated_at,omitempty"`     // thời gian offline
 	IsMuted         bool                `bson:"is_muted" json:"is_muted"` // đã tắt thông báo chưa
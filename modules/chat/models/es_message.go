@@ -22,4 +22,7 @@ type ESMessage struct {
 	Status     MessageStatus `json:"status"`
 	DeletedFor []string      `json:"deleted_for,omitempty"`
 	RecalledAt *time.Time    `json:"recalled_at,omitempty"`
+
+	MentionedUserIDs []string `json:"mentioned_user_ids,omitempty"`
+	MentionTypes     []string `json:"mention_types,omitempty"`
 }
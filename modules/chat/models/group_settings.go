@@ -0,0 +1,26 @@
+package models
+
+// Ai được thêm thành viên vào nhóm
+const (
+	MemberAddAll    = "all"    // mọi thành viên
+	MemberAddAdmins = "admins" // chỉ trưởng nhóm / quản trị viên
+)
+
+// GroupSettings - cấu hình quyền trong nhóm, lưu trong document "group" (field settings)
+type GroupSettings struct {
+	OnlyAdminsCanPost   bool   `json:"only_admins_can_post" bson:"only_admins_can_post"`
+	OnlyAdminsCanPin    bool   `json:"only_admins_can_pin" bson:"only_admins_can_pin"`
+	MemberAddPermission string `json:"member_add_permission" bson:"member_add_permission"` // all | admins, rỗng = all
+	SlowModeSeconds     int    `json:"slow_mode_seconds" bson:"slow_mode_seconds"`         // 0 = tắt
+	AnnouncementMode    bool   `json:"announcement_mode" bson:"announcement_mode"`         // chỉ admin đăng bài, thành viên chỉ đọc
+}
+
+// HasPostRestriction = true nếu cần kiểm tra quyền khi gửi tin nhắn
+func (s *GroupSettings) HasPostRestriction() bool {
+	return s != nil && (s.OnlyAdminsCanPost || s.AnnouncementMode || s.SlowModeSeconds > 0)
+}
+
+// IsGroupAdminRole - owner và admin được bỏ qua các giới hạn của nhóm
+func IsGroupAdminRole(role string) bool {
+	return role == "owner" || role == "admin"
+}
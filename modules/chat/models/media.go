@@ -1,7 +1,10 @@
 package models
 
 import (
+	"errors"
+	"io"
 	"my-app/common"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -21,6 +24,171 @@ type Media struct {
 	Filename          string           `bson:"filename" json:"filename"` // Tên gốc
 	Size              int64            `bson:"size" json:"size"`
 	URL               string           `bson:"url" json:"url"`
+	ContentType       string           `bson:"content_type,omitempty" json:"content_type,omitempty"`
+
+	// Điền bởi pipeline xử lý media sau khi upload
+	Width            int              `bson:"width,omitempty" json:"width,omitempty"`
+	Height           int              `bson:"height,omitempty" json:"height,omitempty"`
+	Duration         float64          `bson:"duration,omitempty" json:"duration,omitempty"` // giây, chỉ có với video
+	ProcessingStatus ProcessingStatus `bson:"processing_status,omitempty" json:"processing_status,omitempty"`
+	ProcessingError  string           `bson:"processing_error,omitempty" json:"-"`
+	Variants         []MediaVariant   `bson:"variants,omitempty" json:"variants,omitempty"`
+
+	// Quét mã độc trước khi cho phép tải về
+	UploadedBy    *primitive.ObjectID `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"`
+	ScanStatus    ScanStatus          `bson:"scan_status,omitempty" json:"scan_status,omitempty"`
+	ScanSignature string              `bson:"scan_signature,omitempty" json:"scan_signature,omitempty"` // tên mã độc phát hiện được
+	ScannedAt     *time.Time          `bson:"scanned_at,omitempty" json:"scanned_at,omitempty"`
+	ScanError     string              `bson:"scan_error,omitempty" json:"-"`
+	ScanAttempts  int                 `bson:"scan_attempts,omitempty" json:"-"`
+	ScanRetryAt   *time.Time          `bson:"scan_retry_at,omitempty" json:"-"` // lỗi bộ quét: chưa quét lại trước thời điểm này
+
+	// Các cuộc trò chuyện media được gửi vào, dùng để kiểm tra quyền xem
+	Links     []MediaLink `bson:"links,omitempty" json:"-"`
+	RevokedAt *time.Time  `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"` // người upload đã xóa
+}
+
+// MediaLink - tin nhắn chứa media; người gửi/nhận (1-1) hoặc thành viên nhóm được xem
+type MediaLink struct {
+	MessageID  primitive.ObjectID `bson:"message_id"`
+	SenderID   primitive.ObjectID `bson:"sender_id"`
+	ReceiverID primitive.ObjectID `bson:"receiver_id,omitempty"`
+	GroupID    primitive.ObjectID `bson:"group_id,omitempty"`
+}
+
+type SignMediaRequest struct {
+	MediaIDs []string `json:"media_ids" binding:"required,min=1,max=100,dive,len=24,hexadecimal"`
+	Size     string   `json:"size"` // thumb|small|medium|large|poster, rỗng là file gốc
+}
+
+// SignedMediaURL - URL có chữ ký HMAC, hết hạn sau ExpiresAt
+type SignedMediaURL struct {
+	MediaID   string    `json:"media_id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ScanStatus string
+
+const (
+	ScanPending  ScanStatus = "pending"
+	ScanClean    ScanStatus = "clean"
+	ScanInfected ScanStatus = "infected"
+	ScanTooLarge ScanStatus = "too_large"   // vượt giới hạn quét, không quét được toàn bộ nên không cho tải
+	ScanFailed   ScanStatus = "scan_failed" // bộ quét lỗi quá số lần thử
+)
+
+// ErrScanTooLarge - file lớn hơn giới hạn của bộ quét, không được coi là sạch
+var ErrScanTooLarge = errors.New("file vượt quá giới hạn quét mã độc")
+
+// IsScanClean - media upload trước khi có bước quét (không có scan_status) được coi là sạch
+func (m *Media) IsScanClean() bool {
+	return m.ScanStatus == "" || m.ScanStatus == ScanClean
+}
+
+// ScanResult - kết quả trả về từ bộ quét mã độc
+type ScanResult struct {
+	Infected  bool
+	Signature string
+}
+
+type ProcessingStatus string
+
+const (
+	ProcessingPending    ProcessingStatus = "pending"
+	ProcessingProcessing ProcessingStatus = "processing"
+	ProcessingReady      ProcessingStatus = "ready"
+	ProcessingFailed     ProcessingStatus = "failed"
+	ProcessingSkipped    ProcessingStatus = "skipped" // file thường, không cần xử lý
+)
+
+// Kích thước biến thể, tính theo cạnh dài nhất
+const (
+	MediaSizeOriginal = "original"
+	MediaSizeThumb    = "thumb"
+	MediaSizeSmall    = "small"
+	MediaSizeMedium   = "medium"
+	MediaSizeLarge    = "large"
+	MediaSizePoster   = "poster" // khung hình đại diện của video, giữ kích thước gốc
+)
+
+type MediaSizeSpec struct {
+	Name    string
+	MaxEdge int
+}
+
+// MediaImageSizes - thứ tự từ nhỏ đến lớn, stream dùng để chọn biến thể gần nhất
+var MediaImageSizes = []MediaSizeSpec{
+	{Name: MediaSizeThumb, MaxEdge: 160},
+	{Name: MediaSizeSmall, MaxEdge: 480},
+	{Name: MediaSizeMedium, MaxEdge: 1080},
+	{Name: MediaSizeLarge, MaxEdge: 2048},
+}
+
+const (
+	MediaFormatJPEG = "jpeg"
+	MediaFormatWebP = "webp"
+)
+
+// MediaVariant - bản đã xử lý (resize / webp / poster) lưu trên MinIO
+type MediaVariant struct {
+	Size        string `bson:"size" json:"size"`
+	Format      string `bson:"format" json:"format"`
+	URL         string `bson:"url" json:"url"` // object key trên MinIO
+	ContentType string `bson:"content_type" json:"content_type"`
+	Width       int    `bson:"width" json:"width"`
+	Height      int    `bson:"height" json:"height"`
+	Bytes       int64  `bson:"bytes" json:"bytes"`
+}
+
+// FindVariant tìm biến thể đúng size, ưu tiên format yêu cầu
+func (m *Media) FindVariant(size, format string) *MediaVariant {
+	var fallback *MediaVariant
+	for i := range m.Variants {
+		v := &m.Variants[i]
+		if v.Size != size {
+			continue
+		}
+		if v.Format == format {
+			return v
+		}
+		if fallback == nil {
+			fallback = v
+		}
+	}
+	return fallback
+}
+
+// MediaProbe - thông tin đọc được từ file gốc
+type MediaProbe struct {
+	Width    int
+	Height   int
+	Duration float64
+}
+
+// MediaProcessingResult - kết quả pipeline ghi lại vào medias
+type MediaProcessingResult struct {
+	Status   ProcessingStatus
+	Error    string
+	Width    int
+	Height   int
+	Duration float64
+	Variants []MediaVariant
+}
+
+// MediaProcessingJob - payload Kafka topic media-processing-topic
+type MediaProcessingJob struct {
+	MediaID string `json:"media_id"`
+}
+
+// MediaStream - nội dung trả về cho StreamMediaHandler
+type MediaStream struct {
+	Reader      io.ReadSeeker
+	Size        int64
+	ModTime     time.Time
+	Type        MediaType
+	ContentType string
+	Variant     string // size đã phục vụ, "original" nếu là file gốc
 }
 
 type MediaPagination struct {
@@ -41,6 +209,9 @@ type MediaItemResponse struct {
 	Filename string    `json:"filename"`
 	Size     int64     `json:"size"`
 	URL      string    `json:"url"`
+	Width    int       `json:"width,omitempty"`
+	Height   int       `json:"height,omitempty"`
+	Duration float64   `json:"duration,omitempty"`
 
 	// Thông tin bổ sung từ message (nếu cần mở rộng sau)
 	Content string `json:"content,omitempty"`
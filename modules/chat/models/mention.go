@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MentionUser = "user" // @user
+	MentionHere = "here" // @here - thành viên đang online
+	MentionAll  = "all"  // @all - toàn bộ thành viên
+)
+
+// Mention - một lượt nhắc trong nội dung tin nhắn
+type Mention struct {
+	Type   string              `bson:"type" json:"type"`
+	UserID *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+}
+
+// MentionTarget - người nhận thông báo nhắc đến và kiểu nhắc (ưu tiên user > here > all)
+type MentionTarget struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	Type   string             `json:"type"`
+}
+
+// MentionInboxItem - hộp thư nhắc đến, mỗi người được nhắc một bản ghi (collection "mentions")
+type MentionInboxItem struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	MessageID   primitive.ObjectID `bson:"message_id" json:"message_id"`
+	SenderID    primitive.ObjectID `bson:"sender_id" json:"sender_id"`
+	GroupID     primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	ReceiverID  primitive.ObjectID `bson:"receiver_id,omitempty" json:"receiver_id,omitempty"`
+	MentionType string             `bson:"mention_type" json:"mention_type"`
+	Content     string             `bson:"content" json:"content"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ReadAt      *time.Time         `bson:"read_at,omitempty" json:"read_at,omitempty"`
+
+	// Thông tin hiển thị, lấy qua $lookup
+	SenderName   string `bson:"sender_name,omitempty" json:"sender_name,omitempty"`
+	SenderAvatar string `bson:"sender_avatar,omitempty" json:"sender_avatar,omitempty"`
+	GroupName    string `bson:"group_name,omitempty" json:"group_name,omitempty"`
+}
+
+type MentionInboxRequest struct {
+	Page       int  `form:"page,default=1" binding:"min=1"`
+	Limit      int  `form:"limit,default=20" binding:"min=1,max=100"`
+	UnreadOnly bool `form:"unread_only"`
+}
+
+// MarkMentionsReadRequest - truyền ids hoặc all=true (có thể giới hạn theo group_id)
+type MarkMentionsReadRequest struct {
+	IDs     []string `json:"ids"`
+	All     bool     `json:"all"`
+	GroupID string   `json:"group_id"`
+}
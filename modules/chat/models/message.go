@@ -27,6 +27,12 @@ const (
 	MediaTypeTask MediaType = "task"
 )
 
+// Người gửi của tin nhắn hệ thống không do thành viên nào thực hiện (VD: admin hệ thống khôi phục nhóm)
+var (
+	SystemSenderID   = primitive.NilObjectID
+	SystemSenderName = "Hệ thống"
+)
+
 type ReplyMessageMini struct {
 	ID       primitive.ObjectID `bson:"id" json:"id"`
 	Sender   string             `bson:"sender" json:"sender"`
@@ -49,6 +55,7 @@ type Message struct {
 	DeletedFor []primitive.ObjectID `bson:"deleted_for,omitempty" json:"deleted_for"` // lưu user nào đã xóa
 	Reply      ReplyMessageMini     `bson:"reply,omitempty" json:"reply,omitempty"`
 	Task       *Task                `bson:"task,omitempty" json:"task,omitempty"` // Embed task details
+	Poll       *Poll                `bson:"poll,omitempty" json:"poll,omitempty"` // Câu hỏi & lựa chọn lúc tạo, kết quả lấy qua /polls/:id
 
 	RecalledAt *time.Time          `bson:"recalled_at,omitempty" json:"recalled_at,omitempty"`
 	RecalledBy *primitive.ObjectID `bson:"recalled_by,omitempty" json:"recalled_by,omitempty"`
@@ -64,6 +71,13 @@ type Message struct {
 	ThreadDepth     int                 `bson:"thread_depth,omitempty" json:"thread_depth,omitempty"`           // cấp độ (0 = message gốc)
 
 	EditedAt *time.Time `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+
+	// @mention: token trong nội dung và danh sách user thực sự được nhắc (đã mở rộng @here/@all)
+	Mentions         []Mention            `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	MentionedUserIDs []primitive.ObjectID `bson:"mentioned_user_ids,omitempty" json:"mentioned_user_ids,omitempty"`
+
+	// UUID do client sinh cho mỗi tin nhắn, duy nhất trong cuộc trò chuyện (index uniq_msg_client_id)
+	ClientMessageID string `bson:"client_message_id,omitempty" json:"client_message_id,omitempty"`
 }
 
 type Reaction struct {
@@ -128,6 +142,7 @@ type MessageResponse struct {
 	Type         MediaType          `bson:"type" json:"type"` // "image", "video", "file"
 	Reply        ReplyMessageMini   `bson:"reply,omitempty" json:"reply,omitempty"`
 	Task         *Task              `bson:"task,omitempty" json:"task,omitempty"`
+	Poll         *Poll              `bson:"poll,omitempty" json:"poll,omitempty"`
 
 	DisplayName     string    `json:"display_name"`
 	Avatar          string    `json:"avatar"`
@@ -150,6 +165,13 @@ type MessageResponse struct {
 	ParentID     string     `json:"parent_id,omitempty"`
 	CommentCount int        `json:"comment_count"`
 	EditedAt     *time.Time `json:"edited_at,omitempty"`
+
+	Mentions []Mention `json:"mentions,omitempty"`
+	// Người được nhắc do hub phân giải lúc gửi, consumer lưu đúng danh sách đã thông báo thay vì phân giải lại
+	MentionTargets []MentionTarget `json:"mention_targets,omitempty"`
+
+	// UUID client sinh cho tin nhắn; gửi lại cùng ID thì server trả về tin nhắn đã lưu thay vì tạo mới
+	ClientMessageID string `json:"client_message_id,omitempty"`
 }
 
 type MessageStatusRequest struct {
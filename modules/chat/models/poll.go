@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MediaTypePoll MediaType = "poll"
+)
+
+const (
+	PollMaxOptions    = 20
+	PollMaxOptionText = 200
+)
+
+type PollOption struct {
+	ID      string             `bson:"id" json:"id"`
+	Text    string             `bson:"text" json:"text"`
+	AddedBy primitive.ObjectID `bson:"added_by" json:"added_by"`
+
+	// Kết quả, tính từ poll_votes khi trả về
+	VoteCount int         `bson:"-" json:"vote_count"`
+	Voters    []PollVoter `bson:"-" json:"voters,omitempty"` // rỗng với bình chọn ẩn danh
+}
+
+type PollVoter struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	Name   string             `json:"name"`
+	Avatar string             `json:"avatar,omitempty"`
+}
+
+// Poll - bình chọn gắn với một tin nhắn type "poll" (collection "polls")
+type Poll struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MessageID   primitive.ObjectID `bson:"message_id" json:"message_id"`
+	CreatorID   primitive.ObjectID `bson:"creator_id" json:"creator_id"`
+	CreatorName string             `bson:"creator_name" json:"creator_name"`
+	GroupID     primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	ReceiverID  primitive.ObjectID `bson:"receiver_id,omitempty" json:"receiver_id,omitempty"`
+
+	Question       string       `bson:"question" json:"question"`
+	Options        []PollOption `bson:"options" json:"options"`
+	MultipleChoice bool         `bson:"multiple_choice" json:"multiple_choice"`
+	Anonymous      bool         `bson:"anonymous" json:"anonymous"`
+	AllowAddOption bool         `bson:"allow_add_option" json:"allow_add_option"`
+	Deadline       *time.Time   `bson:"deadline,omitempty" json:"deadline,omitempty"`
+
+	ClosedAt *time.Time          `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	ClosedBy *primitive.ObjectID `bson:"closed_by,omitempty" json:"closed_by,omitempty"` // nil khi tự đóng lúc hết hạn
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+
+	TotalVoters int      `bson:"-" json:"total_voters"`
+	MyVotes     []string `bson:"-" json:"my_votes,omitempty"` // lựa chọn của người đang xem
+}
+
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.Deadline != nil && !now.Before(*p.Deadline))
+}
+
+func (p *Poll) HasOption(id string) bool {
+	for _, o := range p.Options {
+		if o.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// PollVote - lựa chọn của một user trong một bình chọn (collection "poll_votes")
+type PollVote struct {
+	PollID    primitive.ObjectID `bson:"poll_id" json:"poll_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	OptionIDs []string           `bson:"option_ids" json:"option_ids"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Lấy qua $lookup
+	UserName   string `bson:"user_name,omitempty" json:"-"`
+	UserAvatar string `bson:"user_avatar,omitempty" json:"-"`
+}
+
+type CreatePollRequest struct {
+	GroupID        string     `json:"group_id"`
+	ReceiverID     string     `json:"receiver_id"`
+	Question       string     `json:"question" binding:"required,max=500"`
+	Options        []string   `json:"options" binding:"required,min=2"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous"`
+	AllowAddOption bool       `json:"allow_add_option"`
+	Deadline       *time.Time `json:"deadline"`
+}
+
+// VotePollRequest - option_ids rỗng nghĩa là rút lại phiếu
+type VotePollRequest struct {
+	PollID    string   `json:"poll_id"`
+	OptionIDs []string `json:"option_ids"`
+}
+
+type AddPollOptionRequest struct {
+	Text string `json:"text" binding:"required"`
+}
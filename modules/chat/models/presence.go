@@ -0,0 +1,6 @@
+package models
+
+// PresenceUpdate client gửi khi user chuyển sang / thoát khỏi trạng thái idle
+type PresenceUpdate struct {
+	Idle bool `json:"idle"`
+}
@@ -142,3 +142,13 @@ type CreateTaskCommentRequest struct {
 	ReplyContent  string               `json:"reply_to_content,omitempty"`
 	ReplyUsername string               `json:"reply_to_username,omitempty"`
 }
+
+// TaskMessageKey - idempotency key của tin nhắn giao việc gửi tới một người / một nhóm.
+// Tin nhắn này đến từ cả outbox (khi tạo task) lẫn websocket của client, consumer chỉ lưu một bản.
+func TaskMessageKey(taskID, receiverID, groupID primitive.ObjectID) string {
+	target := receiverID
+	if !groupID.IsZero() {
+		target = groupID
+	}
+	return "task-message:" + taskID.Hex() + ":" + target.Hex()
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UploadMode string
+
+const (
+	UploadModeSingle    UploadMode = "single"    // 1 presigned PUT
+	UploadModeMultipart UploadMode = "multipart" // S3 multipart, upload từng part và resume được
+)
+
+type UploadSessionStatus string
+
+const (
+	UploadSessionPending    UploadSessionStatus = "pending"
+	UploadSessionCompleting UploadSessionStatus = "completing" // đang kiểm tra, chặn gọi complete 2 lần
+	UploadSessionCompleted  UploadSessionStatus = "completed"
+	UploadSessionAborted    UploadSessionStatus = "aborted"
+	UploadSessionFailed     UploadSessionStatus = "failed" // sai dung lượng / checksum / định dạng
+)
+
+// S3 multipart: tối đa 10000 part, part (trừ part cuối) tối thiểu 5MB
+const (
+	UploadMinPartSize = 5 * 1024 * 1024
+	UploadMaxParts    = 10000
+)
+
+// UploadSession - phiên upload trực tiếp lên MinIO (collection "upload_sessions")
+type UploadSession struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+
+	Filename       string `bson:"filename" json:"filename"`
+	ObjectKey      string `bson:"object_key" json:"-"`
+	Size           int64  `bson:"size" json:"size"`                                           // dung lượng client khai báo
+	ContentType    string `bson:"content_type,omitempty" json:"content_type,omitempty"`       // client khai báo, chỉ để tham khảo
+	ChecksumSHA256 string `bson:"checksum_sha256,omitempty" json:"checksum_sha256,omitempty"` // hex
+
+	Mode       UploadMode `bson:"mode" json:"mode"`
+	UploadID   string     `bson:"upload_id,omitempty" json:"upload_id,omitempty"` // multipart upload id của MinIO
+	PartSize   int64      `bson:"part_size,omitempty" json:"part_size,omitempty"`
+	TotalParts int        `bson:"total_parts,omitempty" json:"total_parts,omitempty"`
+
+	Status  UploadSessionStatus `bson:"status" json:"status"`
+	Error   string              `bson:"error,omitempty" json:"error,omitempty"`
+	MediaID *primitive.ObjectID `bson:"media_id,omitempty" json:"media_id,omitempty"`
+
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// PartLength - dung lượng mong đợi của part thứ n (part cuối có thể nhỏ hơn)
+func (s *UploadSession) PartLength(partNumber int) int64 {
+	if partNumber < 1 || partNumber > s.TotalParts {
+		return 0
+	}
+	if partNumber == s.TotalParts {
+		return s.Size - int64(s.TotalParts-1)*s.PartSize
+	}
+	return s.PartSize
+}
+
+type UploadedPart struct {
+	PartNumber int    `json:"part_number"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+}
+
+type PresignedPart struct {
+	PartNumber int    `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+type CreateUploadSessionRequest struct {
+	Filename       string `json:"filename" binding:"required"`
+	Size           int64  `json:"size" binding:"required,min=1"`
+	ContentType    string `json:"content_type"`
+	ChecksumSHA256 string `json:"checksum_sha256" binding:"omitempty,len=64,hexadecimal"`
+}
+
+type PresignUploadPartsRequest struct {
+	PartNumbers []int `json:"part_numbers" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+type UploadSessionResponse struct {
+	Session       *UploadSession  `json:"session"`
+	UploadURL     string          `json:"upload_url,omitempty"`     // mode single: PUT thẳng lên MinIO
+	Parts         []PresignedPart `json:"parts,omitempty"`          // mode multipart: URL cho các part được yêu cầu
+	UploadedParts []UploadedPart  `json:"uploaded_parts,omitempty"` // để client resume
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+)
+
+// BlockReader là phần truy vấn quan hệ chặn mà chat cần, do friend storage cài đặt
+type BlockReader interface {
+	IsBlocked(ctx context.Context, userA, userB string) (bool, error)
+	GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error)
+}
+
+// IsBlocked kiểm tra 2 user có đang chặn nhau (bất kể ai là người chặn)
+func (s *MongoChatStore) IsBlocked(ctx context.Context, userA, userB string) (bool, error) {
+	return s.blocks.IsBlocked(ctx, userA, userB)
+}
+
+// GetBlockedUserIDs trả về danh sách user đang chặn hoặc bị chặn bởi userID
+func (s *MongoChatStore) GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	return s.blocks.GetBlockedUserIDs(ctx, userID)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha1"
 	"my-app/modules/chat/models"
+	friendStorage "my-app/modules/friend/storage"
 	ModelUser "my-app/modules/user/models"
 	"sort"
 
@@ -22,11 +23,12 @@ func NewESChatStore(client *elasticsearch.Client) *ESChatStore {
 }
 
 type MongoChatStore struct {
-	db *mongo.Database
+	db     *mongo.Database
+	blocks BlockReader // quan hệ chặn thuộc module friend
 }
 
 func NewMongoChatStore(db *mongo.Database) *MongoChatStore {
-	return &MongoChatStore{db: db}
+	return &MongoChatStore{db: db, blocks: friendStorage.NewMongoStoreFriend(db)}
 }
 
 func (s *MongoChatStore) CheckUserExists(ctx context.Context, userID string) (bool, error) {
@@ -67,7 +69,7 @@ func (s *MongoChatStore) CheckGroupExists(ctx context.Context, groupID string) (
 		return false, err
 	}
 
-	filter := bson.M{"_id": oid}
+	filter := bson.M{"_id": oid, "dissolved_at": bson.M{"$exists": false}}
 	err = s.db.Collection("group").FindOne(ctx, filter).Err()
 	if err == mongo.ErrNoDocuments {
 		return false, nil
@@ -120,6 +122,14 @@ func (s *MongoChatStore) GetByConversation(
 	return result, nil
 }
 
+// ConversationIDOf trả về id hội thoại của tin nhắn: id nhóm, hoặc id ghép từ hai user với tin nhắn 1-1
+func ConversationIDOf(senderID, receiverID, groupID primitive.ObjectID) primitive.ObjectID {
+	if !groupID.IsZero() {
+		return groupID
+	}
+	return GetConversationID(senderID, receiverID)
+}
+
 // ConversationID sẽ luôn giống nhau cho 2 người bất kể ai gửi
 // Tạo ID cố định từ 2 ObjectID (A và B)
 func GetConversationID(a, b primitive.ObjectID) primitive.ObjectID {
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/chat/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FindMessageByClientID tìm tin nhắn đã lưu theo client_message_id trong cuộc trò chuyện, không có thì trả về nil
+func (s *MongoChatStore) FindMessageByClientID(ctx context.Context, senderID, receiverID, groupID primitive.ObjectID, clientMessageID string) (*models.Message, error) {
+	var msg models.Message
+	err := s.db.Collection("messages").FindOne(ctx, bson.M{
+		"sender_id":         senderID,
+		"receiver_id":       optionalObjectID(receiverID),
+		"group_id":          optionalObjectID(groupID),
+		"client_message_id": clientMessageID,
+	}).Decode(&msg)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// optionalObjectID: receiver_id / group_id rỗng không được lưu (omitempty), so với null để khớp cả field thiếu
+func optionalObjectID(id primitive.ObjectID) interface{} {
+	if id.IsZero() {
+		return nil
+	}
+	return id
+}
+
+// isClientMessageConflict: lỗi trùng key đến từ index client_message_id (không phải trùng _id)
+func isClientMessageConflict(err error) bool {
+	var we mongo.WriteException
+	if !errors.As(err, &we) {
+		return false
+	}
+	for _, e := range we.WriteErrors {
+		if e.Code == 11000 && strings.Contains(e.Message, models.ClientMessageIndex) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,6 @@ package storage
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"my-app/modules/chat/models"
 	"sort"
@@ -23,8 +22,10 @@ type temp struct {
 }
 
 type groupInfo struct {
-	Name  string `bson:"name"`
-	Image string `bson:"image"`
+	Name     string              `bson:"name"`
+	Image    string              `bson:"image"`
+	Kind     string              `bson:"kind"`
+	ParentID *primitive.ObjectID `bson:"parent_id,omitempty"`
 }
 
 type groupTemp struct {
@@ -65,7 +66,7 @@ func (s *MongoChatStore) GetConversations(ctx context.Context, userID string, pa
 	if convType != "user" {
 		groupResults, err = s.getGroupConversations(ctx, userObjectID, 1, 1000, keyword, filterIDs)
 		if err != nil {
-			fmt.Println("Lỗi khi lấy group:", err)
+			log.Println("Lỗi khi lấy group:", err)
 		}
 	}
 
@@ -107,12 +108,13 @@ func (s *MongoChatStore) GetConversations(ctx context.Context, userID string, pa
 	}
 
 	// Gộp group conversations
-	for _, g := range groupResults {
+	for _, g := range aggregateCommunityChannels(groupResults) {
 		preview := models.ConversationPreview{
 			GroupID:        g.GroupID.Hex(),
 			DisplayName:    g.GroupInfo.Name,
 			Avatar:         g.GroupInfo.Image,
 			Type:           "group",
+			Kind:           g.GroupInfo.Kind,
 			ConversationID: g.GroupID.Hex(),
 		}
 		if g.LastMessage != nil {
@@ -174,6 +176,38 @@ func (s *MongoChatStore) GetConversations(ctx context.Context, userID string, pa
 	return results[start:end], int64(len(results)), nil
 }
 
+// aggregateCommunityChannels gộp các kênh vào cộng đồng chứa nó: cộng dồn số chưa đọc
+// và lấy tin nhắn mới nhất. Kênh không có cộng đồng trong danh sách thì giữ nguyên.
+func aggregateCommunityChannels(groups []groupTemp) []groupTemp {
+	index := make(map[primitive.ObjectID]int, len(groups))
+	result := make([]groupTemp, 0, len(groups))
+	for _, g := range groups {
+		if g.GroupInfo.ParentID == nil {
+			index[g.GroupID] = len(result)
+			result = append(result, g)
+		}
+	}
+
+	for _, g := range groups {
+		if g.GroupInfo.ParentID == nil {
+			continue
+		}
+
+		i, ok := index[*g.GroupInfo.ParentID]
+		if !ok {
+			result = append(result, g)
+			continue
+		}
+
+		parent := &result[i]
+		parent.UnreadCount += g.UnreadCount
+		if g.LastMessage != nil && (parent.LastMessage == nil || g.LastMessage.CreatedAt.After(parent.LastMessage.CreatedAt)) {
+			parent.LastMessage = g.LastMessage
+		}
+	}
+	return result
+}
+
 func convertToObjectID(id string) primitive.ObjectID {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
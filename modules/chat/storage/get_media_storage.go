@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"log"
 	"my-app/modules/chat/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -66,7 +67,6 @@ func (s *MongoChatStore) GetMediaList(
 			"$match": bson.M{"media_items.type": mediaType},
 		})
 	} else {
-		fmt.Println(">>> NO mediaType filter applied")
 	}
 
 	// Project
@@ -83,6 +83,9 @@ func (s *MongoChatStore) GetMediaList(
 			"filename": "$media_items.filename",
 			"size":     "$media_items.size",
 			"url":      "$media_items.url",
+			"width":    "$media_items.width",
+			"height":   "$media_items.height",
+			"duration": "$media_items.duration",
 		},
 	})
 
@@ -107,6 +110,9 @@ func (s *MongoChatStore) GetMediaList(
 			Filename string             `bson:"filename"`
 			Size     int64              `bson:"size"`
 			URL      string             `bson:"url"`
+			Width    int                `bson:"width"`
+			Height   int                `bson:"height"`
+			Duration float64            `bson:"duration"`
 		}
 
 		if err := cursor.Decode(&item); err != nil {
@@ -122,6 +128,9 @@ func (s *MongoChatStore) GetMediaList(
 			Filename:  item.Filename,
 			Size:      item.Size,
 			URL:       item.URL,
+			Width:     item.Width,
+			Height:    item.Height,
+			Duration:  item.Duration,
 			Content:   item.Content,
 			IsRead:    item.IsRead,
 		})
@@ -153,7 +162,7 @@ func (s *MongoChatStore) GetMediaList(
 
 	countCursor, err := s.db.Collection("messages").Aggregate(ctx, countPipeline)
 	if err != nil {
-		fmt.Println("❌ Count aggregate ERROR:", err)
+		log.Println("❌ Count aggregate ERROR:", err)
 		return nil, err
 	}
 	defer countCursor.Close(ctx)
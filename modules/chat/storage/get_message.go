@@ -184,6 +184,7 @@ func (s *MongoChatStore) GetMessage(ctx context.Context, SenderID, ReceiverID, G
 			Status:       msg.Status,
 			IsRead:       msg.IsRead,
 			Type:         msg.Type,
+			Poll:         msg.Poll,
 			RecalledAt:   msg.RecalledAt,
 			RecalledBy:   msg.RecalledBy,
 			Task:         msg.Task,
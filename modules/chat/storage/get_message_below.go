@@ -176,6 +176,7 @@ func (s *MongoChatStore) GetMessageBelow(
 			Status:       msg.Status,
 			IsRead:       msg.IsRead,
 			Type:         msg.Type,
+			Poll:         msg.Poll,
 			Reactions:    msg.Reactions,
 			EditedAt:     msg.EditedAt,
 			CommentCount: commentCounts[msg.ID],
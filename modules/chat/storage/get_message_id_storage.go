@@ -232,6 +232,7 @@ func (s *MongoChatStore) GetMessageByID(
 			Status:       msg.Status,
 			IsRead:       msg.IsRead,
 			Type:         msg.Type,
+			Poll:         msg.Poll,
 			RecalledAt:   msg.RecalledAt,
 			RecalledBy:   msg.RecalledBy,
 			Reactions:    msg.Reactions,
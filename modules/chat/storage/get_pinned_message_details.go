@@ -2,7 +2,6 @@ package storage
 
 import (
 	"context"
-	"fmt"
 	"my-app/modules/chat/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,7 +13,6 @@ func (s *MongoChatStore) GetPinnedMessageDetails(
 	ctx context.Context,
 	conversationID primitive.ObjectID,
 ) ([]models.PinnedMessageDetail, error) {
-	fmt.Println("conversationID", conversationID)
 	pipeline := mongo.Pipeline{
 		{{"$match", bson.M{
 			"conversation_id": conversationID,
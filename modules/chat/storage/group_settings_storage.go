@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/chat/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetGroupSettings trả về cài đặt mặc định (không giới hạn) nếu nhóm chưa cấu hình
+func (s *MongoChatStore) GetGroupSettings(ctx context.Context, groupID primitive.ObjectID) (*models.GroupSettings, error) {
+	var group struct {
+		Settings models.GroupSettings `bson:"settings"`
+	}
+	err := s.db.Collection("group").FindOne(ctx,
+		bson.M{"_id": groupID},
+		options.FindOne().SetProjection(bson.M{"settings": 1}),
+	).Decode(&group)
+	if err != nil {
+		return nil, err
+	}
+	return &group.Settings, nil
+}
+
+// GetGroupRoleCode lấy mã vai trò (owner/admin/member) của user trong nhóm, rỗng nếu không thuộc nhóm
+func (s *MongoChatStore) GetGroupRoleCode(ctx context.Context, groupID, userID primitive.ObjectID) (string, error) {
+	var gur struct {
+		RoleID string `bson:"role_id"`
+	}
+	err := s.db.Collection("group_user_roles").FindOne(ctx, bson.M{
+		"group_id":   groupID.Hex(),
+		"user_id":    userID.Hex(),
+		"is_deleted": bson.M{"$ne": true},
+	}).Decode(&gur)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	roleID, err := primitive.ObjectIDFromHex(gur.RoleID)
+	if err != nil {
+		return "", nil
+	}
+
+	var role struct {
+		Code string `bson:"code"`
+	}
+	err = s.db.Collection("roles").FindOne(ctx, bson.M{"_id": roleID}).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	return role.Code, err
+}
+
+// GetLastGroupMessageTime lấy thời điểm tin nhắn gần nhất của sender trong nhóm trước mốc before
+// (bỏ qua excludeID để không tự so với chính tin nhắn khi Kafka giao lại)
+func (s *MongoChatStore) GetLastGroupMessageTime(ctx context.Context, groupID, senderID primitive.ObjectID, before time.Time, excludeID primitive.ObjectID) (*time.Time, error) {
+	var msg struct {
+		CreatedAt time.Time `bson:"created_at"`
+	}
+	err := s.db.Collection("messages").FindOne(ctx, bson.M{
+		"group_id":   groupID,
+		"sender_id":  senderID,
+		"_id":        bson.M{"$ne": excludeID},
+		"type":       bson.M{"$ne": "system"},
+		"created_at": bson.M{"$lte": before},
+	}, options.FindOne().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"created_at": 1}),
+	).Decode(&msg)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg.CreatedAt, nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/chat/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AddMediaLinks gắn media vào tin nhắn vừa gửi, gửi lại (Kafka giao lại) không tạo link trùng
+func (s *MongoChatStore) AddMediaLinks(ctx context.Context, mediaIDs []primitive.ObjectID, link models.MediaLink) error {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+	_, err := s.db.Collection("medias").UpdateMany(ctx, bson.M{
+		"_id": bson.M{"$in": mediaIDs},
+	}, bson.M{
+		"$addToSet": bson.M{"links": link},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+	return err
+}
+
+// RemoveMediaLinks gỡ link của tin nhắn bị thu hồi, người trong cuộc trò chuyện đó không xem được nữa
+func (s *MongoChatStore) RemoveMediaLinks(ctx context.Context, messageID primitive.ObjectID) error {
+	_, err := s.db.Collection("medias").UpdateMany(ctx, bson.M{
+		"links.message_id": messageID,
+	}, bson.M{
+		"$pull": bson.M{"links": bson.M{"message_id": messageID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	return err
+}
+
+// FindMessageMediaLinks dựng link từ các tin nhắn (chưa thu hồi) chứa media, cho media upload trước khi có links
+func (s *MongoChatStore) FindMessageMediaLinks(ctx context.Context, mediaID primitive.ObjectID) ([]models.MediaLink, error) {
+	cursor, err := s.db.Collection("messages").Find(ctx, bson.M{
+		"$or": []bson.M{
+			{"media_ids": mediaID},
+			{"task.attachment_ids": mediaID},
+		},
+		"recalled_at": bson.M{"$exists": false},
+	}, options.Find().
+		SetProjection(bson.M{"_id": 1, "sender_id": 1, "receiver_id": 1, "group_id": 1}).
+		SetLimit(50))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var msgs []struct {
+		ID         primitive.ObjectID `bson:"_id"`
+		SenderID   primitive.ObjectID `bson:"sender_id"`
+		ReceiverID primitive.ObjectID `bson:"receiver_id"`
+		GroupID    primitive.ObjectID `bson:"group_id"`
+	}
+	if err := cursor.All(ctx, &msgs); err != nil {
+		return nil, err
+	}
+
+	links := make([]models.MediaLink, 0, len(msgs))
+	for _, m := range msgs {
+		links = append(links, models.MediaLink{
+			MessageID:  m.ID,
+			SenderID:   m.SenderID,
+			ReceiverID: m.ReceiverID,
+			GroupID:    m.GroupID,
+		})
+	}
+	return links, nil
+}
+
+// RevokeMedia đánh dấu media đã bị xóa; giữ bản ghi để URL đã cấp và route tải theo tên object đều bị từ chối
+func (s *MongoChatStore) RevokeMedia(ctx context.Context, ID primitive.ObjectID, revokedAt time.Time) (bool, error) {
+	res, err := s.db.Collection("medias").UpdateOne(ctx, bson.M{
+		"_id":        ID,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{
+		"revoked_at": revokedAt,
+		"updated_at": revokedAt,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
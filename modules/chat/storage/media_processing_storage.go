@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"my-app/config"
+	"my-app/modules/chat/models"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *MongoChatStore) FindMediaByID(ctx context.Context, ID primitive.ObjectID) (*models.Media, error) {
+	var media models.Media
+	if err := s.db.Collection("medias").FindOne(ctx, bson.M{"_id": ID}).Decode(&media); err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// ClaimMediaProcessing chuyển media sang "processing"; media đang xử lý quá staleBefore (consumer chết giữa chừng) được nhận lại
+func (s *MongoChatStore) ClaimMediaProcessing(ctx context.Context, ID primitive.ObjectID, staleBefore time.Time) (bool, error) {
+	res, err := s.db.Collection("medias").UpdateOne(ctx, bson.M{
+		"_id": ID,
+		"$or": []bson.M{
+			{"processing_status": bson.M{"$in": []models.ProcessingStatus{models.ProcessingPending, models.ProcessingFailed}}},
+			{"processing_status": models.ProcessingProcessing, "updated_at": bson.M{"$lt": staleBefore}},
+		},
+	}, bson.M{"$set": bson.M{
+		"processing_status": models.ProcessingProcessing,
+		"updated_at":        time.Now(),
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (s *MongoChatStore) SaveMediaProcessingResult(ctx context.Context, ID primitive.ObjectID, result *models.MediaProcessingResult) error {
+	set := bson.M{
+		"processing_status": result.Status,
+		"updated_at":        time.Now(),
+	}
+	unset := bson.M{}
+
+	if result.Error != "" {
+		set["processing_error"] = result.Error
+	} else {
+		unset["processing_error"] = ""
+	}
+	if result.Status == models.ProcessingReady {
+		set["width"] = result.Width
+		set["height"] = result.Height
+		set["duration"] = result.Duration
+		set["variants"] = result.Variants
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := s.db.Collection("medias").UpdateOne(ctx, bson.M{"_id": ID}, update)
+	return err
+}
+
+func (s *MongoChatStore) DownloadMediaObject(ctx context.Context, key, dst string) error {
+	return config.MinioClient.FGetObject(ctx, "unichat", key, dst, minio.GetObjectOptions{})
+}
+
+func (s *MongoChatStore) UploadMediaObject(ctx context.Context, key, src, contentType string) (int64, error) {
+	info, err := config.MinioClient.FPutObject(ctx, "unichat", key, src, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// OpenMediaObject mở object trên MinIO để stream (hỗ trợ Seek cho Range request)
+func (s *MongoChatStore) OpenMediaObject(ctx context.Context, key string) (io.ReadSeeker, int64, time.Time, error) {
+	obj, err := config.MinioClient.GetObject(ctx, "unichat", key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, time.Time{}, err
+	}
+	return obj, stat.Size, stat.LastModified, nil
+}
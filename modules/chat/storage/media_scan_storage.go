@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"my-app/config"
+	"my-app/modules/chat/models"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClaimMediaScan nhận media chờ quét cũ nhất đã tới lượt thử lại; media đang quét quá staleBefore
+// (worker chết giữa chừng) được nhận lại. Mỗi lần nhận tăng scan_attempts. Trả về nil nếu không còn media nào.
+func (s *MongoChatStore) ClaimMediaScan(ctx context.Context, staleBefore time.Time) (*models.Media, error) {
+	now := time.Now()
+	var media models.Media
+	err := s.db.Collection("medias").FindOneAndUpdate(ctx, bson.M{
+		"scan_status": models.ScanPending,
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"scan_started_at": bson.M{"$exists": false}},
+				{"scan_started_at": bson.M{"$lt": staleBefore}},
+			}},
+			{"$or": []bson.M{
+				{"scan_retry_at": bson.M{"$exists": false}},
+				{"scan_retry_at": bson.M{"$lte": now}},
+			}},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"scan_started_at": now,
+			"updated_at":      now,
+		},
+		"$inc": bson.M{"scan_attempts": 1},
+	}, options.FindOneAndUpdate().
+		SetSort(bson.M{"created_at": 1}).
+		SetReturnDocument(options.After),
+	).Decode(&media)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+func (s *MongoChatStore) SaveMediaScanResult(ctx context.Context, ID primitive.ObjectID, status models.ScanStatus, signature string, scannedAt time.Time) error {
+	set := bson.M{
+		"scan_status": status,
+		"scanned_at":  scannedAt,
+		"updated_at":  scannedAt,
+	}
+	if signature != "" {
+		set["scan_signature"] = signature
+	}
+
+	_, err := s.db.Collection("medias").UpdateOne(ctx, bson.M{"_id": ID}, bson.M{
+		"$set":   set,
+		"$unset": bson.M{"scan_started_at": "", "scan_error": "", "scan_retry_at": ""},
+	})
+	return err
+}
+
+// ReleaseMediaScan trả media về hàng chờ khi bộ quét lỗi, worker chỉ nhận lại sau retryAt
+func (s *MongoChatStore) ReleaseMediaScan(ctx context.Context, ID primitive.ObjectID, errMsg string, retryAt time.Time) error {
+	_, err := s.db.Collection("medias").UpdateOne(ctx, bson.M{"_id": ID}, bson.M{
+		"$set":   bson.M{"scan_error": errMsg, "scan_retry_at": retryAt, "updated_at": time.Now()},
+		"$unset": bson.M{"scan_started_at": ""},
+	})
+	return err
+}
+
+// FailMediaScan đánh dấu media không quét được sau nhiều lần thử, media không được tải về
+func (s *MongoChatStore) FailMediaScan(ctx context.Context, ID primitive.ObjectID, errMsg string) error {
+	now := time.Now()
+	_, err := s.db.Collection("medias").UpdateOne(ctx, bson.M{"_id": ID}, bson.M{
+		"$set":   bson.M{"scan_status": models.ScanFailed, "scan_error": errMsg, "scanned_at": now, "updated_at": now},
+		"$unset": bson.M{"scan_started_at": "", "scan_retry_at": ""},
+	})
+	return err
+}
+
+// FindMediaByURL tìm media theo object key, dùng cho route tải file theo tên object
+func (s *MongoChatStore) FindMediaByURL(ctx context.Context, key string) (*models.Media, error) {
+	var media models.Media
+	if err := s.db.Collection("medias").FindOne(ctx, bson.M{"url": key}).Decode(&media); err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+func (s *MongoChatStore) ReadMediaObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	return config.MinioClient.GetObject(ctx, "unichat", key, minio.GetObjectOptions{})
+}
+
+// QuarantineMediaObject chuyển object sang bucket cách ly rồi xóa khỏi bucket chính
+func (s *MongoChatStore) QuarantineMediaObject(ctx context.Context, key string) error {
+	_, err := config.MinioClient.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: config.QuarantineBucket, Object: key},
+		minio.CopySrcOptions{Bucket: "unichat", Object: key},
+	)
+	if err != nil {
+		return err
+	}
+	return config.MinioClient.RemoveObject(ctx, "unichat", key, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/chat/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindUserIDsByUsernames tra user theo username (không phân biệt hoa thường), key trả về là username viết thường
+func (s *MongoChatStore) FindUserIDsByUsernames(ctx context.Context, usernames []string) (map[string]primitive.ObjectID, error) {
+	result := make(map[string]primitive.ObjectID, len(usernames))
+	if len(usernames) == 0 {
+		return result, nil
+	}
+
+	cursor, err := s.db.Collection("users").Find(ctx,
+		bson.M{"username": bson.M{"$in": usernames}},
+		options.Find().
+			SetProjection(bson.M{"_id": 1, "username": 1}).
+			SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Username string             `bson:"username"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		result[strings.ToLower(u.Username)] = u.ID
+	}
+	return result, nil
+}
+
+// FilterOnlineUsers lọc các user đang kết nối (away/busy/dnd vẫn tính là online)
+func (s *MongoChatStore) FilterOnlineUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := s.db.Collection("user_status").Find(ctx, bson.M{
+		"user_id": bson.M{"$in": userIDs},
+		"status":  bson.M{"$ne": "offline"},
+	}, options.Find().SetProjection(bson.M{"user_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.UserID)
+	}
+	return ids, nil
+}
+
+// SaveMentionInbox lưu hộp thư nhắc đến, bỏ qua bản ghi trùng khi Kafka giao lại tin nhắn
+func (s *MongoChatStore) SaveMentionInbox(ctx context.Context, items []models.MentionInboxItem) error {
+	docs := make([]interface{}, 0, len(items))
+	for _, it := range items {
+		docs = append(docs, it)
+	}
+
+	_, err := s.db.Collection("mentions").InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// UpdateMentionPreview thay bản xem trước trong hộp thư nhắc đến của tin nhắn đã sửa, preview rỗng khi thu hồi
+func (s *MongoChatStore) UpdateMentionPreview(ctx context.Context, messageID primitive.ObjectID, preview string) error {
+	_, err := s.db.Collection("mentions").UpdateMany(ctx,
+		bson.M{"message_id": messageID},
+		bson.M{"$set": bson.M{"content": preview}},
+	)
+	return err
+}
+
+func (s *MongoChatStore) ListMentionInbox(ctx context.Context, userID primitive.ObjectID, page, limit int, unreadOnly bool) ([]models.MentionInboxItem, int64, error) {
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read_at"] = bson.M{"$exists": false}
+	}
+
+	total, err := s.db.Collection("mentions").CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		{{Key: "$skip", Value: int64((page - 1) * limit)}},
+		{{Key: "$limit", Value: int64(limit)}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "sender_id",
+			"foreignField": "_id",
+			"as":           "sender",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$sender", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "group",
+			"localField":   "group_id",
+			"foreignField": "_id",
+			"as":           "group",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$group", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$addFields", Value: bson.M{
+			"sender_name":   "$sender.display_name",
+			"sender_avatar": "$sender.avatar",
+			"group_name":    "$group.name",
+		}}},
+		{{Key: "$project", Value: bson.M{"sender": 0, "group": 0}}},
+	}
+
+	cursor, err := s.db.Collection("mentions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.MentionInboxItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (s *MongoChatStore) CountUnreadMentions(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return s.db.Collection("mentions").CountDocuments(ctx, bson.M{
+		"user_id": userID,
+		"read_at": bson.M{"$exists": false},
+	})
+}
+
+// MarkMentionsRead đánh dấu đã đọc theo danh sách id, hoặc toàn bộ (giới hạn theo nhóm nếu có)
+func (s *MongoChatStore) MarkMentionsRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, groupID *primitive.ObjectID) (int64, error) {
+	filter := bson.M{
+		"user_id": userID,
+		"read_at": bson.M{"$exists": false},
+	}
+	if len(ids) > 0 {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+	if groupID != nil {
+		filter["group_id"] = *groupID
+	}
+
+	res, err := s.db.Collection("mentions").UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{"read_at": time.Now()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/chat/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoChatStore) CreatePoll(ctx context.Context, poll *models.Poll) error {
+	_, err := s.db.Collection("polls").InsertOne(ctx, poll)
+	return err
+}
+
+func (s *MongoChatStore) FindPoll(ctx context.Context, pollID primitive.ObjectID) (*models.Poll, error) {
+	var poll models.Poll
+	if err := s.db.Collection("polls").FindOne(ctx, bson.M{"_id": pollID}).Decode(&poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// SetPollVote ghi đè lựa chọn của user, optionIDs rỗng thì xóa phiếu
+func (s *MongoChatStore) SetPollVote(ctx context.Context, pollID, userID primitive.ObjectID, optionIDs []string) error {
+	filter := bson.M{"poll_id": pollID, "user_id": userID}
+	if len(optionIDs) == 0 {
+		_, err := s.db.Collection("poll_votes").DeleteOne(ctx, filter)
+		return err
+	}
+
+	_, err := s.db.Collection("poll_votes").UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{
+			"option_ids": optionIDs,
+			"updated_at": time.Now(),
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// AddPollOption thêm lựa chọn khi bình chọn còn mở, trả về mongo.ErrNoDocuments nếu đã đóng
+func (s *MongoChatStore) AddPollOption(ctx context.Context, pollID primitive.ObjectID, option models.PollOption) error {
+	res, err := s.db.Collection("polls").UpdateOne(ctx, bson.M{
+		"_id":       pollID,
+		"closed_at": bson.M{"$exists": false},
+	}, bson.M{
+		"$push": bson.M{"options": option},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ClosePoll đóng bình chọn, trả về false nếu đã được đóng trước đó (worker và người tạo có thể đóng cùng lúc)
+func (s *MongoChatStore) ClosePoll(ctx context.Context, pollID primitive.ObjectID, closedBy *primitive.ObjectID, closedAt time.Time) (bool, error) {
+	set := bson.M{"closed_at": closedAt, "updated_at": closedAt}
+	if closedBy != nil {
+		set["closed_by"] = *closedBy
+	}
+
+	res, err := s.db.Collection("polls").UpdateOne(ctx, bson.M{
+		"_id":       pollID,
+		"closed_at": bson.M{"$exists": false},
+	}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// ListPollVotes trả về toàn bộ phiếu kèm tên/ảnh người bình chọn
+func (s *MongoChatStore) ListPollVotes(ctx context.Context, pollID primitive.ObjectID) ([]models.PollVote, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"poll_id": pollID}}},
+		{{Key: "$sort", Value: bson.M{"updated_at": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$addFields", Value: bson.M{
+			"user_name":   "$user.display_name",
+			"user_avatar": "$user.avatar",
+		}}},
+		{{Key: "$project", Value: bson.M{"user": 0}}},
+	}
+
+	cursor, err := s.db.Collection("poll_votes").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var votes []models.PollVote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// ListDuePolls lấy các bình chọn đã tới hạn nhưng chưa đóng
+func (s *MongoChatStore) ListDuePolls(ctx context.Context, now time.Time, limit int64) ([]models.Poll, error) {
+	cursor, err := s.db.Collection("polls").Find(ctx, bson.M{
+		"closed_at": bson.M{"$exists": false},
+		"deadline":  bson.M{"$lte": now},
+	}, options.Find().SetSort(bson.M{"deadline": 1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var polls []models.Poll
+	if err := cursor.All(ctx, &polls); err != nil {
+		return nil, err
+	}
+	return polls, nil
+}
@@ -11,8 +11,12 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// SaveMessage bỏ qua tin nhắn trùng _id (Kafka giao lại); trùng client_message_id với tin nhắn khác thì trả về models.ErrDuplicateClientMessage
 func (s *MongoChatStore) SaveMessage(ctx context.Context, msg *models.Message) error {
 	_, err := s.db.Collection("messages").InsertOne(ctx, msg)
+	if msg.ClientMessageID != "" && isClientMessageConflict(err) {
+		return models.ErrDuplicateClientMessage
+	}
 	if mongo.IsDuplicateKeyError(err) {
 		return nil
 	}
@@ -42,6 +46,11 @@ func (s *ESChatStore) IndexMessage(ctx context.Context, msg *models.Message, sen
 		Status:       msg.Status,
 		DeletedFor:   hexSlice(msg.DeletedFor),
 		RecalledAt:   msg.RecalledAt,
+
+		MentionedUserIDs: hexSlice(msg.MentionedUserIDs),
+	}
+	for _, m := range msg.Mentions {
+		doc.MentionTypes = append(doc.MentionTypes, m.Type)
 	}
 
 	body, err := json.Marshal(doc)
@@ -27,6 +27,8 @@ func (s *ESChatStore) SearchMessages(
 	cursorTime string, // <<< thêm cursor
 	startTime string,
 	endTime string,
+	excludeSenderIDs []string,
+	mentionedOnly bool, // chỉ lấy tin nhắn có nhắc đến myID
 ) ([]models.ESMessage, string, error) {
 
 	if limit <= 0 {
@@ -113,6 +115,22 @@ func (s *ESChatStore) SearchMessages(
 		},
 	}
 
+	if len(excludeSenderIDs) > 0 {
+		boolQuery["must_not"] = append(boolQuery["must_not"].([]interface{}), map[string]interface{}{
+			"terms": map[string]interface{}{
+				"sender_id.keyword": excludeSenderIDs,
+			},
+		})
+	}
+
+	if mentionedOnly {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{
+				"mentioned_user_ids.keyword": myID,
+			},
+		})
+	}
+
 	if len(filters) > 0 {
 		boolQuery["filter"] = filters
 	}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"my-app/config"
+	"my-app/modules/chat/models"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoChatStore) CreateUploadSession(ctx context.Context, session *models.UploadSession) error {
+	res, err := s.db.Collection("upload_sessions").InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+	session.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *MongoChatStore) FindUploadSession(ctx context.Context, ID primitive.ObjectID) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := s.db.Collection("upload_sessions").FindOne(ctx, bson.M{"_id": ID}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TransitionUploadSession chuyển trạng thái khi phiên đang ở trạng thái from, trả về false nếu đã bị đổi trước đó
+func (s *MongoChatStore) TransitionUploadSession(ctx context.Context, ID primitive.ObjectID, from, to models.UploadSessionStatus, mediaID *primitive.ObjectID, errMsg string) (bool, error) {
+	set := bson.M{"status": to, "updated_at": time.Now()}
+	if mediaID != nil {
+		set["media_id"] = *mediaID
+	}
+	if errMsg != "" {
+		set["error"] = errMsg
+	}
+
+	res, err := s.db.Collection("upload_sessions").UpdateOne(ctx, bson.M{
+		"_id":    ID,
+		"status": from,
+	}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// ListExpiredUploadSessions lấy các phiên pending đã quá hạn và phiên completing không cập nhật từ trước completingBefore
+func (s *MongoChatStore) ListExpiredUploadSessions(ctx context.Context, now, completingBefore time.Time, limit int64) ([]models.UploadSession, error) {
+	cursor, err := s.db.Collection("upload_sessions").Find(ctx, bson.M{"$or": []bson.M{
+		{"status": models.UploadSessionPending, "expires_at": bson.M{"$lte": now}},
+		{"status": models.UploadSessionCompleting, "updated_at": bson.M{"$lte": completingBefore}},
+	}}, options.Find().SetSort(bson.M{"expires_at": 1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.UploadSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *MongoChatStore) minioCore() minio.Core {
+	return minio.Core{Client: config.MinioClient}
+}
+
+func (s *MongoChatStore) NewMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	return s.minioCore().NewMultipartUpload(ctx, "unichat", key, minio.PutObjectOptions{ContentType: contentType})
+}
+
+// PresignPutObject tạo URL để client PUT thẳng file lên MinIO
+func (s *MongoChatStore) PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := config.MinioPresignClient.PresignedPutObject(ctx, "unichat", key, expiry)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// PresignUploadPart tạo URL để client PUT một part của multipart upload
+func (s *MongoChatStore) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("partNumber", strconv.Itoa(partNumber))
+	params.Set("uploadId", uploadID)
+
+	u, err := config.MinioPresignClient.Presign(ctx, "PUT", "unichat", key, expiry, params)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// PutUploadPart upload một part qua API (cho client không PUT thẳng lên MinIO được)
+func (s *MongoChatStore) PutUploadPart(ctx context.Context, key, uploadID string, partNumber int, data io.Reader, size int64) (*models.UploadedPart, error) {
+	part, err := s.minioCore().PutObjectPart(ctx, "unichat", key, uploadID, partNumber, data, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &models.UploadedPart{PartNumber: part.PartNumber, Size: part.Size, ETag: part.ETag}, nil
+}
+
+// ListUploadedParts lấy toàn bộ part đã upload, sắp xếp theo số thứ tự
+func (s *MongoChatStore) ListUploadedParts(ctx context.Context, key, uploadID string) ([]models.UploadedPart, error) {
+	var (
+		parts  []models.UploadedPart
+		marker int
+	)
+	for {
+		res, err := s.minioCore().ListObjectParts(ctx, "unichat", key, uploadID, marker, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range res.ObjectParts {
+			parts = append(parts, models.UploadedPart{PartNumber: p.PartNumber, Size: p.Size, ETag: p.ETag})
+		}
+		if !res.IsTruncated {
+			break
+		}
+		marker = res.NextPartNumberMarker
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+func (s *MongoChatStore) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []models.UploadedPart) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, p := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	_, err := s.minioCore().CompleteMultipartUpload(ctx, "unichat", key, uploadID, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *MongoChatStore) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return s.minioCore().AbortMultipartUpload(ctx, "unichat", key, uploadID)
+}
+
+// StatMediaObject trả về dung lượng thật của object trên MinIO
+func (s *MongoChatStore) StatMediaObject(ctx context.Context, key string) (int64, error) {
+	info, err := config.MinioClient.StatObject(ctx, "unichat", key, minio.StatObjectOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// ReadMediaObjectHead đọc tối đa n byte đầu của object (để đoán MIME type)
+func (s *MongoChatStore) ReadMediaObjectHead(ctx context.Context, key string, n int64) ([]byte, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(0, n-1); err != nil {
+		return nil, err
+	}
+
+	obj, err := config.MinioClient.GetObject(ctx, "unichat", key, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(io.LimitReader(obj, n))
+}
+
+// HashMediaObject tính SHA-256 (hex) của toàn bộ object
+func (s *MongoChatStore) HashMediaObject(ctx context.Context, key string) (string, error) {
+	obj, err := config.MinioClient.GetObject(ctx, "unichat", key, minio.GetObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, obj); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *MongoChatStore) RemoveMediaObject(ctx context.Context, key string) error {
+	return config.MinioClient.RemoveObject(ctx, "unichat", key, minio.RemoveObjectOptions{})
+}
@@ -2,15 +2,16 @@ package ginMessage
 
 import (
 	"my-app/common"
-	"my-app/modules/chat/biz"
-	"my-app/modules/chat/storage"
+	"my-app/config"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func DeleteMediaHandler(db *mongo.Database) gin.HandlerFunc {
+// DeleteMediaHandler - người upload xóa media, URL đã cấp hết hiệu lực ngay
+func DeleteMediaHandler(db *mongo.Database, cfg config.MediaAccessConfig) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		mediaID := ctx.Param("mediaID")
 		if mediaID == "" {
@@ -18,11 +19,14 @@ func DeleteMediaHandler(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
-		store := storage.NewMongoChatStore(db)
-		business := biz.NewDeleteMediaBiz(store)
+		userID, err := primitive.ObjectIDFromHex(ctx.GetString("userID"))
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
 
-		if err := business.DeleteMedia(ctx.Request.Context(), mediaID); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Không thể xóa media: " + err.Error()})
+		if err := newMediaAccessBiz(db, cfg).Revoke(ctx.Request.Context(), userID, mediaID); err != nil {
+			writeMediaAccessError(ctx, err)
 			return
 		}
 
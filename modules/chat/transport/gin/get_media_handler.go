@@ -8,9 +8,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func GetMediaHandler() gin.HandlerFunc {
+func GetMediaHandler(db *mongo.Database, cfg config.MediaAccessConfig) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		objectName := ctx.Param("objectName")
 		if objectName == "" {
@@ -18,6 +19,12 @@ func GetMediaHandler() gin.HandlerFunc {
 			return
 		}
 
+		// Object gắn với media (không phải avatar...) cần URL có chữ ký như route stream
+		if err := newMediaAccessBiz(db, cfg).AuthorizeObject(ctx.Request.Context(), objectName, signedMediaParams(ctx)); err != nil {
+			writeMediaAccessError(ctx, err)
+			return
+		}
+
 		bucketName := "unichat"
 
 		// Lấy object từ MinIO
@@ -0,0 +1,88 @@
+package ginMessage
+
+import (
+	"errors"
+	"my-app/common"
+	"my-app/config"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"my-app/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Đường dẫn stream trong URL có chữ ký, tương đối với gốc API
+const signedMediaBasePath = "/v1/upload/media/stream"
+
+func newMediaAccessBiz(db *mongo.Database, cfg config.MediaAccessConfig) *biz.MediaAccessBiz {
+	return biz.NewMediaAccessBiz(storage.NewMongoChatStore(db), cfg.URLSecret, cfg.URLTTL)
+}
+
+// SignMediaHandler cấp URL có chữ ký (hết hạn ngắn) cho các media user được xem
+func SignMediaHandler(db *mongo.Database, cfg config.MediaAccessConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.SignMediaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		urls, err := newMediaAccessBiz(db, cfg).Sign(c.Request.Context(), userID, req.MediaIDs, req.Size, signedMediaBasePath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, common.NewResponse(http.StatusInternalServerError, err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Success", urls))
+	}
+}
+
+func signedMediaParams(c *gin.Context) biz.SignedMediaParams {
+	return biz.SignedMediaParams{
+		UserID:    c.Query("u"),
+		Size:      c.Query("size"),
+		Expires:   c.Query("exp"),
+		Signature: c.Query("sig"),
+	}
+}
+
+// setSignedCacheHeader cho trình duyệt cache riêng tới khi URL hết hạn
+func setSignedCacheHeader(c *gin.Context) {
+	exp, err := strconv.ParseInt(c.Query("exp"), 10, 64)
+	if err != nil {
+		return
+	}
+	if remaining := exp - time.Now().Unix(); remaining > 0 {
+		c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(remaining, 10))
+	}
+}
+
+func writeMediaAccessError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, biz.ErrMediaNotScanned):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, biz.ErrMediaRevoked):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, biz.ErrMediaInfected), errors.Is(err, biz.ErrMediaScanFailed), errors.Is(err, biz.ErrMediaAccessDenied),
+		errors.Is(err, biz.ErrInvalidMediaURL), errors.Is(err, biz.ErrNotMediaOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, biz.ErrMediaNotFound), errors.Is(err, biz.ErrMediaVariantNotReady),
+		errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
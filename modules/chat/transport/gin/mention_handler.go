@@ -0,0 +1,90 @@
+package ginMessage
+
+import (
+	"my-app/common"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"my-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetMentionInbox - danh sách tin nhắn nhắc đến user hiện tại
+func GetMentionInbox(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.MentionInboxRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		business := biz.NewMentionInboxBiz(storage.NewMongoChatStore(db))
+		items, total, unread, err := business.List(c.Request.Context(), userID, &req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách nhắc đến thành công", gin.H{
+			"data":   items,
+			"total":  total,
+			"unread": unread,
+			"page":   req.Page,
+			"limit":  req.Limit,
+		}))
+	}
+}
+
+func CountUnreadMentions(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		business := biz.NewMentionInboxBiz(storage.NewMongoChatStore(db))
+		unread, err := business.CountUnread(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Success", gin.H{"unread": unread}))
+	}
+}
+
+func MarkMentionsRead(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.MarkMentionsReadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		business := biz.NewMentionInboxBiz(storage.NewMongoChatStore(db))
+		updated, err := business.MarkRead(c.Request.Context(), userID, &req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã đánh dấu đã đọc", gin.H{"updated": updated}))
+	}
+}
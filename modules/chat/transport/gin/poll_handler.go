@@ -0,0 +1,185 @@
+package ginMessage
+
+import (
+	"errors"
+	"my-app/common"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreatePollHandler - tạo bình chọn trong nhóm hoặc chat 1-1
+func CreatePollHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.CreatePollRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		business := biz.NewPollBiz(storage.NewMongoChatStore(db))
+		poll, msg, err := business.Create(c.Request.Context(), userID, &req)
+		if err != nil {
+			writePollError(c, err)
+			return
+		}
+
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "chat",
+			Payload: &models.MessageResponse{
+				ID:         msg.ID,
+				SenderID:   msg.SenderID,
+				ReceiverID: msg.ReceiverID,
+				GroupID:    msg.GroupID,
+				Content:    msg.Content,
+				Type:       msg.Type,
+				CreatedAt:  msg.CreatedAt,
+				Status:     msg.Status,
+				Poll:       poll,
+			},
+		}
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "conversations",
+			Payload: &models.ConversationPreview{
+				UserID:          msg.ReceiverID.Hex(),
+				GroupID:         msg.GroupID.Hex(),
+				LastMessage:     msg.Content,
+				LastMessageID:   msg.ID.Hex(),
+				LastMessageType: string(msg.Type),
+				LastDate:        msg.CreatedAt,
+				SenderID:        msg.SenderID.Hex(),
+			},
+		}
+
+		c.JSON(http.StatusCreated, common.NewResponse(http.StatusCreated, "Tạo bình chọn thành công", gin.H{
+			"poll":    poll,
+			"message": msg,
+		}))
+	}
+}
+
+func GetPollHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, pollID, ok := parsePollParams(c)
+		if !ok {
+			return
+		}
+
+		poll, err := biz.NewPollBiz(storage.NewMongoChatStore(db)).Get(c.Request.Context(), userID, pollID)
+		if err != nil {
+			writePollError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Success", poll))
+	}
+}
+
+func VotePollHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, pollID, ok := parsePollParams(c)
+		if !ok {
+			return
+		}
+
+		var req models.VotePollRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		poll, err := biz.NewPollBiz(storage.NewMongoChatStore(db)).Vote(c.Request.Context(), userID, pollID, req.OptionIDs)
+		if err != nil {
+			writePollError(c, err)
+			return
+		}
+
+		hub.PublishPoll("poll_updated", poll)
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Bình chọn thành công", poll))
+	}
+}
+
+func AddPollOptionHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, pollID, ok := parsePollParams(c)
+		if !ok {
+			return
+		}
+
+		var req models.AddPollOptionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		poll, err := biz.NewPollBiz(storage.NewMongoChatStore(db)).AddOption(c.Request.Context(), userID, pollID, req.Text)
+		if err != nil {
+			writePollError(c, err)
+			return
+		}
+
+		hub.PublishPoll("poll_updated", poll)
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã thêm lựa chọn", poll))
+	}
+}
+
+func ClosePollHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, pollID, ok := parsePollParams(c)
+		if !ok {
+			return
+		}
+
+		poll, summary, err := biz.NewPollBiz(storage.NewMongoChatStore(db)).Close(c.Request.Context(), userID, pollID)
+		if err != nil {
+			writePollError(c, err)
+			return
+		}
+
+		hub.PublishPollClosed(biz.PollClosedEvent{Poll: poll, Summary: summary})
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã kết thúc bình chọn", poll))
+	}
+}
+
+func parsePollParams(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	pollID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "poll id không hợp lệ", nil))
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return userID, pollID, true
+}
+
+func writePollError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, biz.ErrPollNotFound):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, biz.ErrNotPollParticipant), errors.Is(err, biz.ErrNotPollCreator),
+		errors.Is(err, biz.ErrPollAddOptionDisabled), errors.Is(err, biz.ErrGroupRestricted),
+		errors.Is(err, biz.ErrUserBlocked):
+		c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, biz.ErrPollClosed):
+		c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
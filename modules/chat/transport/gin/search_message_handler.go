@@ -9,9 +9,10 @@ import (
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func SearchMessages(esClient *elasticsearch.Client) gin.HandlerFunc {
+func SearchMessages(db *mongo.Database, esClient *elasticsearch.Client) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		senderID, exists := ctx.Get("userID")
 		if !exists {
@@ -39,11 +40,12 @@ func SearchMessages(esClient *elasticsearch.Client) gin.HandlerFunc {
 		cursorTime := ctx.Query("cursor_time")
 		startTime := ctx.Query("start_time")
 		endTime := ctx.Query("end_time")
+		mentionedOnly := ctx.Query("mentioned") == "true" // chỉ tin nhắn nhắc đến mình
 
 		store := storage.NewESChatStore(esClient)
-		business := biz.NewChatSearchBiz(store)
+		business := biz.NewChatSearchBiz(store, storage.NewMongoChatStore(db))
 
-		messages, nextCursor, err := business.Search(ctx.Request.Context(), content, senderIDStr, receiverID, groupID, limit, cursorTime, startTime, endTime)
+		messages, nextCursor, err := business.Search(ctx.Request.Context(), content, senderIDStr, receiverID, groupID, limit, cursorTime, startTime, endTime, mentionedOnly)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -3,6 +3,7 @@ package ginMessage
 import (
 	"fmt"
 	"io"
+	"my-app/config"
 	"my-app/modules/chat/biz"
 	"my-app/modules/chat/storage"
 	"net/http"
@@ -13,18 +14,33 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func StreamMediaHandler(db *mongo.Database) gin.HandlerFunc {
+// StreamMediaHandler phục vụ file gốc hoặc biến thể đã xử lý.
+// size=thumb|small|medium|large|poster (mặc định: file gốc), format=webp|jpeg (mặc định theo header Accept)
+func StreamMediaHandler(db *mongo.Database, cfg config.MediaAccessConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		mediaID := c.Param("id")
+		size := c.Query("size")
 
-		store := storage.NewMongoChatStore(db)
-		mediaBiz := biz.NewMediaBiz(store)
+		preferWebP := strings.Contains(c.GetHeader("Accept"), "image/webp")
+		switch c.Query("format") {
+		case "webp":
+			preferWebP = true
+		case "jpeg", "jpg":
+			preferWebP = false
+		}
 
-		file, size, modTime, mediaType, err := mediaBiz.GetMedia(c.Request.Context(), mediaID)
+		// URL phải có chữ ký hợp lệ và người được cấp vẫn còn quyền xem
+		if err := newMediaAccessBiz(db, cfg).AuthorizeByID(c.Request.Context(), mediaID, signedMediaParams(c)); err != nil {
+			writeMediaAccessError(c, err)
+			return
+		}
+
+		media, err := biz.NewMediaBiz(storage.NewMongoChatStore(db)).GetMedia(c.Request.Context(), mediaID, size, preferWebP)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			writeMediaAccessError(c, err)
 			return
 		}
+		file, size64, modTime := media.Reader, media.Size, media.ModTime
 		defer file.(io.Closer).Close()
 
 		disposition := "inline"
@@ -32,21 +48,21 @@ func StreamMediaHandler(db *mongo.Database) gin.HandlerFunc {
 			disposition = "attachment"
 		}
 		c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%s", disposition, mediaID))
+		c.Header("Vary", "Accept")
 
-		var contentType string
-		switch mediaType {
-		case "image":
-			contentType = "image/jpeg" // hoặc png/gif dựa vào định dạng
+		contentType := media.ContentType
+		switch {
+		case strings.HasPrefix(contentType, "image/"):
+			// Ảnh gốc hoặc biến thể (thumbnail, webp, poster video)
 			c.Header("Content-Type", contentType)
-			c.Header("Content-Length", fmt.Sprintf("%d", size))
-			c.Header("Cache-Control", "public, max-age=86400") // cache ảnh
-			io.Copy(c.Writer, file)                            // stream toàn bộ ảnh
+			c.Header("Content-Length", fmt.Sprintf("%d", size64))
+			setSignedCacheHeader(c) // cache ảnh/thumbnail tới khi URL hết hạn
+			io.Copy(c.Writer, file) // stream toàn bộ ảnh
 			return
 
-		case "video":
-			contentType = "video/mp4"
+		case strings.HasPrefix(contentType, "video/"):
 			rangeHeader := c.GetHeader("Range")
-			start, end := int64(0), size-1
+			start, end := int64(0), size64-1
 
 			if rangeHeader != "" && strings.HasPrefix(rangeHeader, "bytes=") {
 				parts := strings.Split(strings.TrimPrefix(rangeHeader, "bytes="), "-")
@@ -56,8 +72,8 @@ func StreamMediaHandler(db *mongo.Database) gin.HandlerFunc {
 				}
 			}
 
-			if start > end || start < 0 || end >= size {
-				c.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+			if start > end || start < 0 || end >= size64 {
+				c.Header("Content-Range", fmt.Sprintf("bytes */%d", size64))
 				c.Status(http.StatusRequestedRangeNotSatisfiable)
 				return
 			}
@@ -65,16 +81,18 @@ func StreamMediaHandler(db *mongo.Database) gin.HandlerFunc {
 			c.Header("Content-Type", contentType)
 			c.Header("Accept-Ranges", "bytes")
 			c.Header("Content-Length", fmt.Sprintf("%d", end-start+1))
-			c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+			c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size64))
 			c.Status(http.StatusPartialContent)
 			file.Seek(start, 0)
 			http.ServeContent(c.Writer, c.Request, mediaID, modTime, file)
 			return
 
 		default: // file download
-			contentType = "application/octet-stream"
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
 			c.Header("Content-Type", contentType)
-			c.Header("Content-Length", fmt.Sprintf("%d", size))
+			c.Header("Content-Length", fmt.Sprintf("%d", size64))
 			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", mediaID))
 			io.Copy(c.Writer, file)
 			return
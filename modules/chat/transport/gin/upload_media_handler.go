@@ -1,7 +1,6 @@
 package ginMessage
 
 import (
-	"fmt"
 	"my-app/common"
 	"my-app/modules/chat/biz"
 	"my-app/modules/chat/models"
@@ -10,20 +9,19 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func UploadMediaHandler(db *mongo.Database) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		form, err := ctx.MultipartForm()
-		fmt.Println("form", form)
 
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Không thể đọc form dữ liệu"})
 			return
 		}
 		files := form.File["files"] // nhận danh sách file (key phải trùng ở client)
-		fmt.Println("files", files)
 
 		if len(files) == 0 {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Không tìm thấy file nào"})
@@ -35,6 +33,12 @@ func UploadMediaHandler(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
+		uploaderID, err := primitive.ObjectIDFromHex(ctx.GetString("userID"))
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
 		var mediaList []models.Media
 		store := storage.NewMongoChatStore(db)
 		business := biz.NewCreateMediaBiz(store)
@@ -59,15 +63,23 @@ func UploadMediaHandler(db *mongo.Database) gin.HandlerFunc {
 				return
 			}
 
-			mediaType := utils.DetectMediaType(fileHeader.Header.Get("Content-Type"))
+			// MIME type đọc từ nội dung, không dùng header client gửi lên
+			contentType := utils.SniffFileContentType(fileHeader)
+			mediaType := utils.DetectMediaType(contentType)
 			media := models.Media{
-				Type:     mediaType,
-				Filename: fileHeader.Filename,
-				Size:     fileHeader.Size,
-				URL:      url,
+				Type:             mediaType,
+				Filename:         fileHeader.Filename,
+				Size:             fileHeader.Size,
+				URL:              url,
+				ContentType:      contentType,
+				ProcessingStatus: models.ProcessingSkipped,
+				UploadedBy:       &uploaderID,
+				ScanStatus:       models.ScanPending, // chưa cho tải về tới khi quét mã độc xong
+			}
+			if mediaType == models.TypeImage || mediaType == models.TypeVideo {
+				media.ProcessingStatus = models.ProcessingPending
 			}
 
-			fmt.Println("media", media)
 			createdMedia, err := business.UploadMedia(ctx.Request.Context(), &media)
 			if err != nil {
 				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Không thể lưu media vào DB"})
@@ -0,0 +1,185 @@
+package ginMessage
+
+import (
+	"errors"
+	"my-app/common"
+	"my-app/config"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"my-app/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newUploadSessionBiz(db *mongo.Database, cfg config.UploadConfig) *biz.UploadSessionBiz {
+	return biz.NewUploadSessionBiz(storage.NewMongoChatStore(db), biz.UploadSessionOptions{
+		PartSize:      cfg.PartSize,
+		PresignExpiry: cfg.PresignExpiry,
+		SessionTTL:    cfg.SessionTTL,
+	})
+}
+
+// CreateUploadSessionHandler mở phiên upload trực tiếp lên MinIO (presigned PUT hoặc multipart)
+func CreateUploadSessionHandler(db *mongo.Database, cfg config.UploadConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.CreateUploadSessionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		resp, err := newUploadSessionBiz(db, cfg).Create(c.Request.Context(), userID, &req)
+		if err != nil {
+			writeUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, common.NewResponse(http.StatusCreated, "Tạo phiên upload thành công", resp))
+	}
+}
+
+// GetUploadSessionHandler trả về trạng thái phiên và các part đã upload để resume
+func GetUploadSessionHandler(db *mongo.Database, cfg config.UploadConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, sessionID, ok := parseUploadSessionParams(c)
+		if !ok {
+			return
+		}
+
+		resp, err := newUploadSessionBiz(db, cfg).Get(c.Request.Context(), userID, sessionID)
+		if err != nil {
+			writeUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Success", resp))
+	}
+}
+
+func PresignUploadPartsHandler(db *mongo.Database, cfg config.UploadConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, sessionID, ok := parseUploadSessionParams(c)
+		if !ok {
+			return
+		}
+
+		var req models.PresignUploadPartsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		parts, err := newUploadSessionBiz(db, cfg).PresignParts(c.Request.Context(), userID, sessionID, req.PartNumbers)
+		if err != nil {
+			writeUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Success", parts))
+	}
+}
+
+// UploadPartHandler nhận body thô của một part, dùng khi client không PUT thẳng lên MinIO được
+func UploadPartHandler(db *mongo.Database, cfg config.UploadConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, sessionID, ok := parseUploadSessionParams(c)
+		if !ok {
+			return
+		}
+
+		partNumber, err := strconv.Atoi(c.Param("number"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "Số thứ tự part không hợp lệ", nil))
+			return
+		}
+		if c.Request.ContentLength <= 0 {
+			c.JSON(http.StatusLengthRequired, common.NewResponse(http.StatusLengthRequired, "Thiếu header Content-Length", nil))
+			return
+		}
+
+		part, err := newUploadSessionBiz(db, cfg).UploadPart(c.Request.Context(), userID, sessionID, partNumber, c.Request.Body, c.Request.ContentLength)
+		if err != nil {
+			writeUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Upload part thành công", part))
+	}
+}
+
+// CompleteUploadSessionHandler kiểm tra file đã upload và tạo bản ghi Media
+func CompleteUploadSessionHandler(db *mongo.Database, cfg config.UploadConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, sessionID, ok := parseUploadSessionParams(c)
+		if !ok {
+			return
+		}
+
+		media, err := newUploadSessionBiz(db, cfg).Complete(c.Request.Context(), userID, sessionID)
+		if err != nil {
+			writeUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Upload dữ liệu thành công", media))
+	}
+}
+
+func AbortUploadSessionHandler(db *mongo.Database, cfg config.UploadConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, sessionID, ok := parseUploadSessionParams(c)
+		if !ok {
+			return
+		}
+
+		if err := newUploadSessionBiz(db, cfg).Abort(c.Request.Context(), userID, sessionID); err != nil {
+			writeUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã hủy phiên upload", nil))
+	}
+}
+
+func parseUploadSessionParams(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "ID phiên upload không hợp lệ", nil))
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, sessionID, true
+}
+
+func writeUploadSessionError(c *gin.Context, err error) {
+	var verifyErr *biz.UploadVerificationError
+	switch {
+	case errors.Is(err, biz.ErrUploadSessionNotFound):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, biz.ErrUploadSessionClosed), errors.Is(err, biz.ErrUploadIncomplete):
+		c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), nil))
+	case errors.Is(err, biz.ErrUploadSessionExpired):
+		c.JSON(http.StatusGone, common.NewResponse(http.StatusGone, err.Error(), nil))
+	case errors.As(err, &verifyErr):
+		c.JSON(http.StatusUnprocessableEntity, common.NewResponse(http.StatusUnprocessableEntity, err.Error(), nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
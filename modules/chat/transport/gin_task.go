@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"my-app/common/outbox"
 	"my-app/modules/chat/biz"
 	"my-app/modules/chat/models"
 	"my-app/modules/chat/storage"
@@ -38,7 +39,7 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 
 	taskStore := storage.NewTaskStorage(h.db)
 	msgStore := storage.NewMongoChatStore(h.db)
-	bizInstance := biz.NewCreateTaskBiz(taskStore, msgStore)
+	bizInstance := biz.NewCreateTaskBiz(taskStore, msgStore, outbox.NewStore(h.db))
 
 	tasks, err := bizInstance.CreateTasksBulk(c.Request.Context(), &req, userID)
 	if err != nil {
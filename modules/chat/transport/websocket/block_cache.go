@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	friendStorage "my-app/modules/friend/storage"
+	"time"
+)
+
+// Danh sách chặn được cache theo user để không query DB cho mỗi tin nhắn/mỗi lần đổi trạng thái.
+// Instance xử lý block/unblock xoá cache ngay (InvalidateBlocks), các instance khác nhận thay đổi sau tối đa TTL.
+const blockCacheTTL = 30 * time.Second
+
+type blockSet struct {
+	ids       map[string]bool
+	expiresAt time.Time
+}
+
+// blockedUsers trả về tập user đang chặn hoặc bị chặn bởi userID, lỗi DB coi như không chặn ai
+func (h *Hub) blockedUsers(userID string) map[string]bool {
+	if h.DB == nil {
+		return nil
+	}
+
+	if val, ok := h.blockSets.Load(userID); ok {
+		set := val.(*blockSet)
+		if time.Now().Before(set.expiresAt) {
+			return set.ids
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	ids, err := friendStorage.NewMongoStoreFriend(h.DB).GetBlockedUserIDs(ctx, userID)
+	if err != nil {
+		log.Printf("Lỗi GetBlockedUserIDs %s: %v", userID, err)
+		return nil
+	}
+
+	set := &blockSet{ids: make(map[string]bool, len(ids)), expiresAt: time.Now().Add(blockCacheTTL)}
+	for _, id := range ids {
+		set.ids[id] = true
+	}
+	h.blockSets.Store(userID, set)
+	return set.ids
+}
+
+// IsBlocked kiểm tra 2 user có chặn nhau không (theo cả 2 chiều) dựa trên cache danh sách chặn
+func (h *Hub) IsBlocked(userA, userB string) bool {
+	return h.blockedUsers(userA)[userB]
+}
+
+// InvalidateBlocks xoá cache danh sách chặn, gọi sau khi chặn/bỏ chặn
+func (h *Hub) InvalidateBlocks(userIDs ...string) {
+	for _, id := range userIDs {
+		h.blockSets.Delete(id)
+	}
+}
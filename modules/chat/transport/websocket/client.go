@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"my-app/common/kafka"
+	"my-app/common/logger"
+	"my-app/common/telemetry"
+	"my-app/modules/chat/biz"
 	"my-app/modules/chat/models"
 	"my-app/modules/chat/storage"
 	"os"
@@ -16,6 +19,7 @@ import (
 	"github.com/gorilla/websocket"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Client struct {
@@ -28,6 +32,9 @@ type Client struct {
 	mu           sync.Mutex
 	closed       bool
 	IsStressUser bool // Đánh dấu nếu là user từ bộ load test
+	Idle         bool // Client báo user không thao tác (auto-away)
+
+	writeDone chan struct{} // đóng khi WritePump kết thúc, Hub.Shutdown đợi để frame cuối được gửi hết
 }
 
 type WSMessage struct {
@@ -42,12 +49,14 @@ type WSMessage struct {
 	CommentTask   *models.TaskComment                 `json:"task_comment,omitempty"`
 	Forward       *models.ForwardMessageRequest       `json:"forward,omitempty"`
 	EditMessage   *models.EditMessageRequest          `json:"edit_message,omitempty"`
+	Presence      *models.PresenceUpdate              `json:"presence,omitempty"`
+	PollVote      *models.VotePollRequest             `json:"poll_vote,omitempty"`
 }
 
 func (c *Client) ReadPump(db *mongo.Database) {
 	defer func() {
 		log.Println(" ReadPump đóng cho user:", c.UserID)
-		c.Hub.Unregister <- c
+		c.Hub.unregister(c)
 		time.Sleep(200 * time.Millisecond)
 		c.Conn.Close()
 	}()
@@ -76,9 +85,18 @@ func (c *Client) ReadPump(db *mongo.Database) {
 		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 		c.LastSeen = time.Now()
 
+		// Mỗi frame là gốc của một trace: tin nhắn chat mang trace này qua Kafka tới consumer và tới người nhận
+		ctx, span := telemetry.Tracer().Start(logger.WithUserID(context.Background(), c.UserID), "ws.receive "+incoming.Type,
+			trace.WithSpanKind(trace.SpanKindServer))
+
+		if !c.allowFrame(ctx, &incoming) {
+			span.End()
+			continue
+		}
+
 		switch incoming.Type {
 		case "chat":
-			c.handleChatMessage(incoming.Message)
+			c.handleChatMessage(ctx, incoming.Message)
 		case "update_seen":
 			c.handleUpdateSeen(incoming.MessageStatus)
 		case "member_left":
@@ -105,7 +123,14 @@ func (c *Client) ReadPump(db *mongo.Database) {
 			c.handleForwardMessage(incoming.Forward)
 		case "edit-message":
 			c.handleEditMessage(incoming.EditMessage)
+		case "poll_vote":
+			c.handlePollVote(incoming.PollVote)
+		case "presence":
+			if incoming.Presence != nil && !c.IsStressUser {
+				c.Hub.SetIdle(c, incoming.Presence.Idle)
+			}
 		}
+		span.End()
 	}
 }
 
@@ -113,7 +138,6 @@ func (c *Client) handleTaskComment(res *models.TaskComment) {
 	if res == nil {
 		return
 	}
-	fmt.Println("res1", res)
 	c.Hub.Broadcast <- HubEvent{
 		Type:    "task_comment",
 		Payload: res,
@@ -145,6 +169,9 @@ func (c *Client) handleEditMessage(req *models.EditMessageRequest) {
 		log.Println("handleEditMessage error:", err)
 		return
 	}
+	if err := store.UpdateMentionPreview(ctx, messageID, biz.MentionPreview(req.Content)); err != nil {
+		log.Println("handleEditMessage: update mention preview error:", err)
+	}
 
 	// Broadcast the update to all clients
 	c.Hub.Broadcast <- HubEvent{
@@ -242,6 +269,13 @@ func (c *Client) handleGroupMember(res *models.GroupMemberRequest) {
 		return
 	}
 
+	if res.Action == "add_member" {
+		if err := c.Hub.CheckGroupMemberAdd(c.UserID, res.GroupID); err != nil {
+			c.sendRejected(res.GroupID, err)
+			return
+		}
+	}
+
 	msg := &models.MessageResponse{
 		ID:        primitive.NewObjectID(),
 		CreatedAt: time.Now(),
@@ -256,8 +290,8 @@ func (c *Client) handleGroupMember(res *models.GroupMemberRequest) {
 
 	msgCopy := msg
 
-	go c.sendToKafkaWithRetry("add-group-member", res.SenderID.Hex(), res)
-	go c.sendToKafkaWithRetry("chat-topic", msg.SenderID.Hex(), msg)
+	go c.sendToKafkaWithRetry(context.Background(), "add-group-member", res.SenderID.Hex(), res)
+	go c.sendToKafkaWithRetry(context.Background(), "chat-topic", msg.SenderID.Hex(), msg)
 
 	c.Hub.Broadcast <- HubEvent{
 		Type:    "chat",
@@ -290,6 +324,13 @@ func (c *Client) handleUnPinnedMessage(msg *models.MessageResponse, res *models.
 		return
 	}
 
+	if msg.GroupID != primitive.NilObjectID {
+		if err := c.Hub.CheckGroupPin(c.UserID, msg.GroupID); err != nil {
+			c.sendRejected(msg.GroupID, err)
+			return
+		}
+	}
+
 	if msg.GroupID != primitive.NilObjectID {
 		res.ConversationID = msg.GroupID.Hex()
 	} else {
@@ -310,10 +351,15 @@ func (c *Client) handleUnPinnedMessage(msg *models.MessageResponse, res *models.
 	res.ReceiverID = msg.ReceiverID.Hex()
 	res.PinnedByID = msg.SenderID.Hex()
 	res.SenderID = msg.SenderID.Hex()
-	// tin nhắn
-	go c.sendToKafkaWithRetry("chat-topic", msg.SenderID.Hex(), msg)
-	// Update DB before broadcast (nếu cần)
-	go c.sendToKafkaWithRetry("un-pinned-message-topic", msg.SenderID.Hex(), res)
+	// Tin nhắn hệ thống và bỏ ghim đi cùng một transaction outbox
+	if err := c.Hub.enqueueOutbox(
+		outboxMessage{topic: "chat-topic", key: msg.SenderID.Hex(), payload: msg},
+		outboxMessage{topic: "un-pinned-message-topic", key: msg.SenderID.Hex(), payload: res},
+	); err != nil {
+		log.Printf("❌ [Outbox] Không lưu được sự kiện bỏ ghim của %s: %v", c.UserID, err)
+		c.sendRejected(msg.GroupID, errEventNotSaved)
+		return
+	}
 
 	c.Hub.Broadcast <- HubEvent{
 		Type:    "chat",
@@ -333,6 +379,13 @@ func (c *Client) handlePinnedMessage(msg *models.MessageResponse, res *models.Me
 		return
 	}
 
+	if msg.GroupID != primitive.NilObjectID {
+		if err := c.Hub.CheckGroupPin(c.UserID, msg.GroupID); err != nil {
+			c.sendRejected(msg.GroupID, err)
+			return
+		}
+	}
+
 	if msg.GroupID != primitive.NilObjectID {
 		res.ConversationID = msg.GroupID.Hex()
 	} else {
@@ -360,11 +413,15 @@ func (c *Client) handlePinnedMessage(msg *models.MessageResponse, res *models.Me
 	res.PinID = res.MessageID
 	res.PinnedAt = now.Format(time.RFC3339)
 
-	// Update DB before broadcast (nếu cần)
-	go c.sendToKafkaWithRetry("pinned-message-topic", msg.SenderID.Hex(), res)
-
-	// tin nhắn
-	go c.sendToKafkaWithRetry("chat-topic", msg.SenderID.Hex(), msg)
+	// Ghim và tin nhắn hệ thống đi cùng một transaction outbox
+	if err := c.Hub.enqueueOutbox(
+		outboxMessage{topic: "pinned-message-topic", key: msg.SenderID.Hex(), payload: res},
+		outboxMessage{topic: "chat-topic", key: msg.SenderID.Hex(), payload: msg},
+	); err != nil {
+		log.Printf("❌ [Outbox] Không lưu được sự kiện ghim của %s: %v", c.UserID, err)
+		c.sendRejected(msg.GroupID, errEventNotSaved)
+		return
+	}
 
 	c.Hub.Broadcast <- HubEvent{
 		Type:    "chat",
@@ -399,7 +456,7 @@ func (c *Client) handleRecallMessage(msg *models.MessageResponse) {
 	msg.RecalledAt = &now
 
 	// Update DB before broadcast (nếu cần)
-	go c.sendToKafkaWithRetry("recall-message-topic", msg.SenderID.Hex(), msg)
+	go c.sendToKafkaWithRetry(context.Background(), "recall-message-topic", msg.SenderID.Hex(), msg)
 
 	// Gửi sự kiện cho mọi client
 
@@ -416,14 +473,13 @@ func (c *Client) handleNotificationMessage(msg *models.MessageNotificationRespon
 	msg.CreatedAt = time.Now()
 
 	msgCopy := msg
-	fmt.Println("msgCopy", msgCopy)
 	switch msg.NotificationType {
 	case models.NotificationTypeSystem:
-		go c.sendToKafkaWithRetry("chat-notification-all", msg.SenderID.Hex(), msg)
+		go c.sendToKafkaWithRetry(context.Background(), "chat-notification-all", msg.SenderID.Hex(), msg)
 	case models.NotificationTypeGroup:
-		go c.sendToKafkaWithRetry("chat-notification-group", msg.SenderID.Hex(), msg)
+		go c.sendToKafkaWithRetry(context.Background(), "chat-notification-group", msg.SenderID.Hex(), msg)
 	case models.NotificationTypePersonal:
-		go c.sendToKafkaWithRetry("chat-notification-personal", msg.SenderID.Hex(), msg)
+		go c.sendToKafkaWithRetry(context.Background(), "chat-notification-personal", msg.SenderID.Hex(), msg)
 	}
 
 	c.Hub.Broadcast <- HubEvent{
@@ -433,11 +489,25 @@ func (c *Client) handleNotificationMessage(msg *models.MessageNotificationRespon
 }
 
 // ======================== FIX #4: Retry logic khi gửi Kafka ========================
-func (c *Client) handleChatMessage(msg *models.MessageResponse) {
+func (c *Client) handleChatMessage(ctx context.Context, msg *models.MessageResponse) {
 	if msg == nil {
 		return
 	}
 	newID := primitive.NewObjectID()
+	ctx = logger.WithConversationID(ctx, storage.ConversationIDOf(msg.SenderID, msg.ReceiverID, msg.GroupID).Hex())
+
+	// Client gửi lại tin nhắn đã nhận (retry, reconnect): trả về bản gốc thay vì tạo tin nhắn mới
+	msg.ClientMessageID = models.NormalizeClientMessageID(msg.ClientMessageID)
+	if c.IsStressUser {
+		msg.ClientMessageID = ""
+	}
+	if msg.ClientMessageID != "" {
+		if canonical := c.Hub.findClientMessage(msg); canonical != nil {
+			c.sendMessageAck(canonical, true)
+			return
+		}
+	}
+
 	// Đừng ghi file và gửi Kafka cho stress users để giảm tải hệ thống
 	if !c.IsStressUser {
 		data, _ := json.Marshal(msg)
@@ -445,25 +515,50 @@ func (c *Client) handleChatMessage(msg *models.MessageResponse) {
 	}
 	// Xử lý group message
 	if msg.GroupID != primitive.NilObjectID {
+		if !c.IsStressUser && msg.Type != "system" {
+			if err := c.Hub.CheckGroupPost(c.UserID, msg.GroupID, msg.ParentID != ""); err != nil {
+				c.sendRejected(msg.GroupID, err)
+				return
+			}
+		}
+
 		msg.CreatedAt = time.Now()
 		msg.UpdatedAt = time.Now()
 		msg.ID = newID
 		msg.Status = models.StatusDelivered
 
+		if !c.acceptClientMessage(msg) {
+			return
+		}
+
 		// FIX: Gửi Kafka với retry logic - CHỈ CHO USER THẬT
 		if !c.IsStressUser {
-			msgCopy := *msg
-			go c.sendToKafkaWithRetry("chat-topic", msgCopy.SenderID.Hex(), msgCopy)
+			go c.publishChatMessage(ctx, *msg)
 		}
 
 		c.Hub.Broadcast <- HubEvent{
 			Type:    "chat",
 			Payload: msg,
+			Ctx:     ctx,
 		}
 	} else {
 		// 1-1 message
 		if msg.SenderID == msg.ReceiverID {
-			log.Println(" Error: Không được gửi tin nhắn cho chính mình")
+			logger.Ctx(ctx, "websocket").Warn().Msg("Không được gửi tin nhắn cho chính mình")
+			return
+		}
+
+		if !c.IsStressUser && c.Hub.IsBlocked(msg.SenderID.Hex(), msg.ReceiverID.Hex()) {
+			data, _ := json.Marshal(map[string]interface{}{
+				"type":        "message_blocked",
+				"receiver_id": msg.ReceiverID.Hex(),
+				"message":     "Không thể gửi tin nhắn vì người dùng đã bị chặn",
+			})
+			select {
+			case c.Send <- data:
+			default:
+				dropFrame(data)
+			}
 			return
 		}
 
@@ -476,15 +571,19 @@ func (c *Client) handleChatMessage(msg *models.MessageResponse) {
 		msg.ID = newID
 		msg.CreatedAt = time.Now()
 
+		if !c.acceptClientMessage(msg) {
+			return
+		}
+
 		// FIX: Gửi Kafka với retry logic - CHỈ CHO USER THẬT
 		if !c.IsStressUser {
-			msgCopy := *msg
-			go c.sendToKafkaWithRetry("chat-topic", msgCopy.SenderID.Hex(), msgCopy)
+			go c.publishChatMessage(ctx, *msg)
 		}
 
 		c.Hub.Broadcast <- HubEvent{
 			Type:    "chat",
 			Payload: msg,
+			Ctx:     ctx,
 		}
 	}
 
@@ -520,7 +619,7 @@ func (c *Client) handleUpdateSeen(msg *models.MessageStatusRequest) {
 	}
 
 	msgCopy := *msg
-	go c.sendToKafkaWithRetry("update-status-message", msgCopy.SenderID, msgCopy)
+	go c.sendToKafkaWithRetry(context.Background(), "update-status-message", msgCopy.SenderID, msgCopy)
 
 	c.Hub.Broadcast <- HubEvent{Type: "update_seen", Payload: msg}
 }
@@ -537,10 +636,12 @@ func (c *Client) handleMemberLeft(msg *models.MessageResponse) {
 	msg.SystemAction = "leave"
 	msg.Status = "seen"
 	msg.IsRead = true
+	// Mention chỉ do hub phân giải, không nhận từ client
+	msg.Mentions, msg.MentionTargets = nil, nil
 
 	msgCopy := *msg
-	go c.sendToKafkaWithRetry("chat-topic", msgCopy.SenderID.Hex(), msgCopy)
-	go c.sendToKafkaWithRetry("group-out", msgCopy.SenderID.Hex(), msgCopy)
+	go c.sendToKafkaWithRetry(context.Background(), "chat-topic", msgCopy.SenderID.Hex(), msgCopy)
+	go c.sendToKafkaWithRetry(context.Background(), "group-out", msgCopy.SenderID.Hex(), msgCopy)
 
 	c.Hub.Broadcast <- HubEvent{
 		Type:    "chat",
@@ -587,7 +688,7 @@ func (c *Client) handleDeleteForMe(delMsg *models.DeleteMessageForMe) {
 		Payload: delMsg,
 	}
 
-	go c.sendToKafkaWithRetry("delete-message-for-me-topic", userID.Hex(), *delMsg)
+	go c.sendToKafkaWithRetry(context.Background(), "delete-message-for-me-topic", userID.Hex(), *delMsg)
 }
 
 func (c *Client) handleForwardMessage(req *models.ForwardMessageRequest) {
@@ -626,7 +727,7 @@ func (c *Client) handleForwardMessage(req *models.ForwardMessageRequest) {
 			Status:    models.StatusDelivered,
 		}
 
-		go c.sendToKafkaWithRetry("chat-topic", req.SenderID, newMsg)
+		go c.sendToKafkaWithRetry(context.Background(), "chat-topic", req.SenderID, newMsg)
 		c.Hub.Broadcast <- HubEvent{Type: "chat", Payload: newMsg}
 	}
 
@@ -637,6 +738,11 @@ func (c *Client) handleForwardMessage(req *models.ForwardMessageRequest) {
 			continue
 		}
 
+		if c.Hub.IsBlocked(req.SenderID, ridStr) {
+			log.Printf(" Forward: %s và %s đang chặn nhau, bỏ qua", req.SenderID, ridStr)
+			continue
+		}
+
 		newMsg := &models.MessageResponse{
 			ID:         primitive.NewObjectID(),
 			SenderID:   senderID,
@@ -655,25 +761,32 @@ func (c *Client) handleForwardMessage(req *models.ForwardMessageRequest) {
 			newMsg.Status = models.StatusSent
 		}
 
-		go c.sendToKafkaWithRetry("chat-topic", newMsg.SenderID.Hex(), newMsg)
+		go c.sendToKafkaWithRetry(context.Background(), "chat-topic", newMsg.SenderID.Hex(), newMsg)
 		c.Hub.Broadcast <- HubEvent{Type: "chat", Payload: newMsg}
 	}
 }
 
 // ======================== Retry logic với exponential backoff ========================
-func (c *Client) sendToKafkaWithRetry(topic, key string, payload interface{}) {
+// publishChatMessage phân giải mention, thông báo người được nhắc rồi gửi tin nhắn kèm kết quả phân giải lên Kafka
+func (c *Client) publishChatMessage(ctx context.Context, msg models.MessageResponse) {
+	c.Hub.resolveMentions(&msg)
+	c.Hub.notifyMentions(msg)
+	c.sendToKafkaWithRetry(ctx, "chat-topic", msg.SenderID.Hex(), msg)
+}
+
+func (c *Client) sendToKafkaWithRetry(ctx context.Context, topic, key string, payload interface{}) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf(" JSON marshal error: %v", err)
+		logger.Ctx(ctx, "websocket").Error().Err(err).Str("topic", topic).Msg("JSON marshal error")
 		return
 	}
 
 	maxRetries := 5
 	for retry := 0; retry < maxRetries; retry++ {
-		err = kafka.SendMessageAsync(topic, key, string(data))
+		err = kafka.SendMessageAsyncContext(ctx, topic, key, string(data))
 		if err == nil {
 			if retry > 0 {
-				log.Printf(" Retry success after %d attempts: topic=%s", retry, topic)
+				logger.Ctx(ctx, "websocket").Info().Int("attempts", retry).Str("topic", topic).Msg("Retry success")
 			}
 			return
 		}
@@ -681,22 +794,23 @@ func (c *Client) sendToKafkaWithRetry(topic, key string, payload interface{}) {
 		// Thất bại → retry với exponential backoff
 		if retry < maxRetries-1 {
 			backoff := time.Duration(1<<uint(retry)) * 100 * time.Millisecond // 100ms, 200ms, 400ms, 800ms, 1.6s
-			log.Printf(" Retry %d/%d after %v: topic=%s, error=%v", retry+1, maxRetries, backoff, topic, err)
+			logger.Ctx(ctx, "websocket").Warn().Err(err).Int("retry", retry+1).Dur("backoff", backoff).Str("topic", topic).Msg("Retry publish")
 			time.Sleep(backoff)
 		}
 	}
 
 	// CRITICAL: Sau 5 lần retry vẫn fail
-	log.Printf("❌ CRITICAL: Failed to send to Kafka after %d retries: topic=%s, key=%s", maxRetries, topic, key)
-	c.logFailedMessage(topic, key, string(data))
+	logger.Ctx(ctx, "websocket").Error().Err(err).Int("retries", maxRetries).Str("topic", topic).Str("key", key).Msg("CRITICAL: Failed to send to Kafka")
+	c.logFailedMessage(topic, key, string(data), err)
 }
 
-// Ghi message thất bại vào file để recovery sau
-func (c *Client) logFailedMessage(topic, key, data string) {
-	logEntry := fmt.Sprintf("[%s] topic=%s key=%s data=%s\n",
-		time.Now().Format(time.RFC3339), topic, key, data)
-
-	log.Printf("💾 Logged failed message: %s", logEntry)
+// Ghi message thất bại vào file spool để replay sau (go run ./cmd/dlq replay-spool)
+func (c *Client) logFailedMessage(topic, key, data string, reason error) {
+	if err := kafka.SpoolMessage(topic, key, data, reason); err != nil {
+		log.Printf("❌ Không ghi được message vào spool: topic=%s key=%s err=%v data=%s", topic, key, err, data)
+		return
+	}
+	log.Printf("💾 Spooled failed message: topic=%s key=%s", topic, key)
 }
 
 func (c *Client) WritePump() {
@@ -704,7 +818,10 @@ func (c *Client) WritePump() {
 
 	defer func() {
 		pingTicker.Stop()
-		c.Hub.Unregister <- c
+		if c.writeDone != nil {
+			close(c.writeDone)
+		}
+		c.Hub.unregister(c)
 		c.Conn.Close()
 	}()
 
@@ -712,6 +829,10 @@ func (c *Client) WritePump() {
 		select {
 		case msg, ok := <-c.Send:
 			if !ok {
+				if c.Hub.Draining() {
+					c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server_going_away"))
+					return
+				}
 				c.Conn.WriteMessage(websocket.CloseMessage, []byte("channel closed"))
 				return
 			}
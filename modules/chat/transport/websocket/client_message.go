@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"time"
+)
+
+// Thời gian hub nhớ client_message_id đã nhận; quá hạn thì tra bản đã lưu trong DB
+const clientMessageTTL = 10 * time.Minute
+
+type clientMessageEntry struct {
+	msg       models.MessageResponse
+	expiresAt time.Time
+}
+
+func clientMessageKey(msg *models.MessageResponse) string {
+	return models.ClientMessageKey(msg.SenderID, msg.ReceiverID, msg.GroupID, msg.ClientMessageID)
+}
+
+// findClientMessage trả về bản gốc của tin nhắn client gửi lại: ưu tiên cache của hub, sau đó tới tin nhắn đã lưu
+func (h *Hub) findClientMessage(msg *models.MessageResponse) *models.MessageResponse {
+	if v, ok := h.clientMessages.Load(clientMessageKey(msg)); ok {
+		entry := v.(*clientMessageEntry)
+		if time.Now().Before(entry.expiresAt) {
+			canonical := entry.msg
+			return &canonical
+		}
+	}
+	if h.DB == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	saved, err := storage.NewMongoChatStore(h.DB).FindMessageByClientID(ctx, msg.SenderID, msg.ReceiverID, msg.GroupID, msg.ClientMessageID)
+	if err != nil {
+		// Lỗi DB thì cho qua, consumer vẫn bỏ bản trùng nhờ unique index
+		log.Printf("⚠️ [Hub] Không tra được client_message_id %s: %v", msg.ClientMessageID, err)
+		return nil
+	}
+	if saved == nil {
+		return nil
+	}
+	return savedClientMessage(saved)
+}
+
+// reserveClientMessage ghi nhận client_message_id với ID server vừa cấp.
+// Trả về false kèm bản gốc nếu một lần gửi khác (session khác của cùng user) đã giữ ID này trước.
+func (h *Hub) reserveClientMessage(msg *models.MessageResponse) (*models.MessageResponse, bool) {
+	key := clientMessageKey(msg)
+	entry := &clientMessageEntry{msg: *msg, expiresAt: time.Now().Add(clientMessageTTL)}
+
+	for {
+		v, loaded := h.clientMessages.LoadOrStore(key, entry)
+		if !loaded {
+			return nil, true
+		}
+		prev := v.(*clientMessageEntry)
+		if time.Now().Before(prev.expiresAt) {
+			canonical := prev.msg
+			return &canonical, false
+		}
+		if h.clientMessages.CompareAndSwap(key, prev, entry) {
+			return nil, true
+		}
+	}
+}
+
+// pruneClientMessages dọn client_message_id hết hạn khỏi cache
+func (h *Hub) pruneClientMessages() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		h.clientMessages.Range(func(key, value interface{}) bool {
+			if now.After(value.(*clientMessageEntry).expiresAt) {
+				h.clientMessages.CompareAndDelete(key, value)
+			}
+			return true
+		})
+	}
+}
+
+// acceptClientMessage chạy sau khi server cấp ID cho tin nhắn có client_message_id.
+// Trả về false nếu là bản gửi lại: client nhận bản gốc qua message_ack, không phát và không gửi Kafka lần nữa.
+func (c *Client) acceptClientMessage(msg *models.MessageResponse) bool {
+	if msg.ClientMessageID == "" {
+		return true
+	}
+	if canonical, ok := c.Hub.reserveClientMessage(msg); !ok {
+		c.sendMessageAck(canonical, true)
+		return false
+	}
+	c.sendMessageAck(msg, false)
+	return true
+}
+
+// sendMessageAck báo cho client ID server của tin nhắn ứng với client_message_id
+func (c *Client) sendMessageAck(msg *models.MessageResponse, duplicate bool) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":              "message_ack",
+		"client_message_id": msg.ClientMessageID,
+		"duplicate":         duplicate,
+		"message":           msg,
+	})
+	select {
+	case c.Send <- data:
+	default:
+		dropFrame(data)
+	}
+}
+
+func savedClientMessage(m *models.Message) *models.MessageResponse {
+	res := &models.MessageResponse{
+		ID:              m.ID,
+		ClientMessageID: m.ClientMessageID,
+		SenderID:        m.SenderID,
+		ReceiverID:      m.ReceiverID,
+		GroupID:         m.GroupID,
+		Content:         m.Content,
+		CreatedAt:       m.CreatedAt,
+		Status:          m.Status,
+		IsRead:          m.IsRead,
+		Type:            m.Type,
+		Reply:           m.Reply,
+		Task:            m.Task,
+		Poll:            m.Poll,
+		RecalledAt:      m.RecalledAt,
+		RecalledBy:      m.RecalledBy,
+		EditedAt:        m.EditedAt,
+		Mentions:        m.Mentions,
+	}
+	if m.ParentMessageID != nil {
+		res.ParentID = m.ParentMessageID.Hex()
+	}
+	return res
+}
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"my-app/modules/chat/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// broadcastGroupEvent gửi thay đổi thông tin/trạng thái nhóm tới toàn bộ thành viên
+func (h *Hub) broadcastGroupEvent(eventType string, payload map[string]interface{}) {
+	groupIDStr, _ := payload["group_id"].(string)
+	groupID, err := primitive.ObjectIDFromHex(groupIDStr)
+	if err != nil {
+		return
+	}
+
+	// Tên/ảnh nhóm được cache khi gửi tin nhắn chat
+	if eventType == "group_updated" || eventType == "group_restored" {
+		h.Cache.Delete("group:" + groupIDStr)
+	}
+
+	members, err := storage.NewMongoChatStore(h.DB).GetGroupMembers(context.Background(), groupID)
+	if err != nil {
+		log.Printf("Lỗi GetGroupMembers trong %s: %v", eventType, err)
+		return
+	}
+
+	// recipients: người nhận thêm ngoài thành viên hiện tại (vd. user vừa bị gỡ khỏi kênh)
+	recipients, _ := payload["recipients"].([]string)
+	delete(payload, "recipients")
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    eventType,
+		"message": payload,
+	})
+
+	sent := make(map[string]bool, len(members)+len(recipients))
+	for _, memberID := range members {
+		sent[memberID.Hex()] = true
+		h.sendToUser(memberID.Hex(), data)
+	}
+	for _, uid := range recipients {
+		if !sent[uid] {
+			sent[uid] = true
+			h.sendToUser(uid, data)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cache cài đặt nhóm và vai trò thành viên có TTL ngắn: instance nhận API cập nhật ngay qua event
+// group_settings_updated, các instance khác thấy thay đổi sau tối đa groupPolicyTTL
+const groupPolicyTTL = 30 * time.Second
+
+type groupSettingsEntry struct {
+	settings  *models.GroupSettings
+	expiresAt time.Time
+}
+
+type groupRoleEntry struct {
+	role      string
+	expiresAt time.Time
+}
+
+// getGroupSettings lấy cài đặt nhóm từ cache, hết hạn hoặc chưa có thì đọc lại DB
+func (h *Hub) getGroupSettings(groupID primitive.ObjectID) *models.GroupSettings {
+	if v, ok := h.groupSettings.Load(groupID.Hex()); ok {
+		entry := v.(*groupSettingsEntry)
+		if time.Now().Before(entry.expiresAt) {
+			return entry.settings
+		}
+	}
+	if h.DB == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	settings, err := storage.NewMongoChatStore(h.DB).GetGroupSettings(ctx, groupID)
+	if err != nil {
+		log.Printf("⚠️ [Hub] Không lấy được cài đặt nhóm %s: %v", groupID.Hex(), err)
+		return nil
+	}
+	h.storeGroupSettings(groupID.Hex(), settings)
+	return settings
+}
+
+func (h *Hub) storeGroupSettings(groupID string, settings *models.GroupSettings) {
+	h.groupSettings.Store(groupID, &groupSettingsEntry{settings: settings, expiresAt: time.Now().Add(groupPolicyTTL)})
+}
+
+// getGroupRole lấy vai trò của user trong nhóm, được cache vì mỗi tin nhắn nhóm có giới hạn đăng bài đều cần
+func (h *Hub) getGroupRole(groupID primitive.ObjectID, userID string) string {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil || h.DB == nil {
+		return ""
+	}
+
+	key := groupID.Hex() + ":" + userID
+	if v, ok := h.groupRoles.Load(key); ok {
+		entry := v.(*groupRoleEntry)
+		if time.Now().Before(entry.expiresAt) {
+			return entry.role
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	role, err := storage.NewMongoChatStore(h.DB).GetGroupRoleCode(ctx, groupID, uid)
+	if err != nil {
+		log.Printf("⚠️ [Hub] Không lấy được vai trò của %s trong nhóm %s: %v", userID, groupID.Hex(), err)
+		return role
+	}
+	h.groupRoles.Store(key, &groupRoleEntry{role: role, expiresAt: time.Now().Add(groupPolicyTTL)})
+	return role
+}
+
+// CheckGroupPost kiểm tra cài đặt nhóm trước khi phát tin nhắn, lỗi DB thì cho qua
+// (ChatBiz.HandleMessage vẫn kiểm tra lại trước khi lưu)
+func (h *Hub) CheckGroupPost(userID string, groupID primitive.ObjectID, isReply bool) error {
+	settings := h.getGroupSettings(groupID)
+	if !settings.HasPostRestriction() {
+		return nil
+	}
+
+	role := h.getGroupRole(groupID, userID)
+	now := time.Now()
+	key := groupID.Hex() + ":" + userID
+
+	var lastSentAt *time.Time
+	if v, ok := h.slowMode.Load(key); ok {
+		t := v.(time.Time)
+		lastSentAt = &t
+	}
+
+	if err := biz.CheckGroupPost(settings, role, isReply, lastSentAt, now); err != nil {
+		return err
+	}
+
+	if settings.SlowModeSeconds > 0 && !models.IsGroupAdminRole(role) {
+		h.slowMode.Store(key, now)
+	}
+	return nil
+}
+
+func (h *Hub) CheckGroupPin(userID string, groupID primitive.ObjectID) error {
+	settings := h.getGroupSettings(groupID)
+	if settings == nil || !settings.OnlyAdminsCanPin {
+		return nil
+	}
+	return biz.CheckGroupPin(settings, h.getGroupRole(groupID, userID))
+}
+
+func (h *Hub) CheckGroupMemberAdd(userID string, groupID primitive.ObjectID) error {
+	settings := h.getGroupSettings(groupID)
+	if settings == nil || settings.MemberAddPermission != models.MemberAddAdmins {
+		return nil
+	}
+	return biz.CheckGroupMemberAdd(settings, h.getGroupRole(groupID, userID))
+}
+
+// sendRejected báo cho client biết thao tác bị từ chối do cài đặt nhóm
+func (c *Client) sendRejected(groupID primitive.ObjectID, err error) {
+	frame := map[string]interface{}{
+		"type":     "message_rejected",
+		"group_id": groupID.Hex(),
+		"message":  err.Error(),
+	}
+
+	var slow *biz.SlowModeError
+	if errors.As(err, &slow) {
+		frame["retry_after"] = int(math.Ceil(slow.Remaining.Seconds()))
+	}
+
+	data, _ := json.Marshal(frame)
+	select {
+	case c.Send <- data:
+	default:
+		dropFrame(data)
+	}
+}
+
+// broadcastGroupSettings cập nhật cache và báo cho thành viên nhóm
+func (h *Hub) broadcastGroupSettings(payload map[string]interface{}) {
+	groupIDStr, _ := payload["group_id"].(string)
+	groupID, err := primitive.ObjectIDFromHex(groupIDStr)
+	if err != nil {
+		return
+	}
+
+	if settings, ok := payload["settings"].(*models.GroupSettings); ok && settings != nil {
+		h.storeGroupSettings(groupIDStr, settings)
+	} else {
+		h.groupSettings.Delete(groupIDStr)
+	}
+
+	members, err := storage.NewMongoChatStore(h.DB).GetGroupMembers(context.Background(), groupID)
+	if err != nil {
+		log.Println("Lỗi GetGroupMembers trong group_settings_updated:", err)
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    "group_settings_updated",
+		"message": payload,
+	})
+	for _, memberID := range members {
+		h.sendToUser(memberID.Hex(), data)
+	}
+}
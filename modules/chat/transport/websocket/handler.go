@@ -22,6 +22,13 @@ func WebSocketHandler(db *mongo.Database, hub *Hub) gin.HandlerFunc {
 			return
 		}
 
+		// Server đang dừng: client kết nối lại vào instance khác
+		if hub.Draining() {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server is shutting down"})
+			return
+		}
+
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			return
@@ -34,8 +41,14 @@ func WebSocketHandler(db *mongo.Database, hub *Hub) gin.HandlerFunc {
 			Send:         make(chan []byte, 1024),
 			UserID:       userID,
 			IsStressUser: strings.HasPrefix(userID, "stress_user"),
+			writeDone:    make(chan struct{}),
+		}
+		if !hub.register(client) {
+			// Hub đã dừng giữa lúc kiểm tra Draining và nâng cấp kết nối
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server_going_away"))
+			conn.Close()
+			return
 		}
-		hub.Register <- client
 
 		// goroutine xử lý đọc / ghi
 		go client.ReadPump(db)
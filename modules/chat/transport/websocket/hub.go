@@ -3,20 +3,25 @@ package websocket
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"my-app/common/kafka"
+	"my-app/common/logger"
+	"my-app/common/ratelimit"
+	"my-app/common/telemetry"
 	"my-app/modules/chat/models"
 	"my-app/modules/chat/storage"
+	friendStorage "my-app/modules/friend/storage"
 	ModelsUser "my-app/modules/user/models"
 	StorageUser "my-app/modules/user/storage"
 	"time"
 
 	"sync"
+	"sync/atomic"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Hub struct {
@@ -27,11 +32,32 @@ type Hub struct {
 	Unregister chan *Client
 	Cache      *sync.Map
 	mu         sync.RWMutex
+
+	presence   sync.Map // userID -> *ModelsUser.UserStatus (trạng thái thủ công, custom status, quyền riêng tư)
+	lastStatus sync.Map // userID -> trạng thái đã broadcast gần nhất
+	blockSets  sync.Map // userID -> *blockSet (xem block_cache.go)
+
+	// Broadcast trạng thái cần đọc DB nên chạy tuần tự ở worker riêng, không chặn Run
+	statusJobs chan func()
+
+	groupSettings sync.Map // groupID -> *groupSettingsEntry (xem group_policy.go)
+	groupRoles    sync.Map // groupID:userID -> *groupRoleEntry
+	slowMode      sync.Map // groupID:userID -> thời điểm gửi tin nhắn gần nhất
+
+	clientMessages sync.Map // models.ClientMessageKey -> *clientMessageEntry
+
+	// Token bucket theo user cho từng loại frame (xem rate_limit.go)
+	frameLimiter ratelimit.Limiter
+	frameLimits  map[string]ratelimit.Limit
+
+	draining atomic.Bool   // đang dừng server: từ chối kết nối mới (xem shutdown.go)
+	stop     chan struct{} // đóng khi Hub dừng hẳn, kết thúc Run và các goroutine định kỳ
 }
 
 type HubEvent struct {
 	Type    string
 	Payload interface{}
+	Ctx     context.Context // trace của request gửi sự kiện (nếu có), span giao tới người nhận nối vào đây
 }
 
 func NewHub(db *mongo.Database) *Hub {
@@ -43,29 +69,74 @@ func NewHub(db *mongo.Database) *Hub {
 		Register:   make(chan *Client, 1024),
 		Unregister: make(chan *Client, 1024),
 		Cache:      &sync.Map{},
+		statusJobs: make(chan func(), 1024),
+		stop:       make(chan struct{}),
 	}
 }
 func (h *Hub) Run() {
 	log.Println("🚀 [Hub] Hub.Run is starting (Version: SSE-V3-FIX)")
 	go h.CheckOfflineTimeout()
+	go h.pruneClientMessages()
+	go h.runStatusJobs()
 
 	for {
 		select {
+		case <-h.stop:
+			// Kết nối đã vào hàng đợi nhưng chưa được nhận: đóng để client kết nối lại vào instance khác
+		drain:
+			for {
+				select {
+				case client := <-h.Register:
+					client.SafeClose()
+				default:
+					break drain
+				}
+			}
+			log.Println("🛑 [Hub] Hub.Run stopped")
+			return
+
 		case client := <-h.Register:
+			if h.Draining() {
+				// Kết nối nâng cấp xong ngay lúc Shutdown bắt đầu: không nhận vào Hub, WritePump gửi close 1012
+				client.SafeClose()
+				continue
+			}
+
 			h.mu.Lock()
 			if h.Clients[client.UserID] == nil {
 				h.Clients[client.UserID] = make(map[string]*Client)
 			}
+			if _, ok := h.Clients[client.UserID][client.SessionID]; !ok {
+				telemetry.WSActiveSessions.Inc()
+			}
 			h.Clients[client.UserID][client.SessionID] = client
 			h.mu.Unlock()
 			client.LastSeen = time.Now()
 
-			h.BroadcastUserStatus(client.UserID, "online")
+			// Nạp presence (đọc DB) ở worker trạng thái, cùng thứ tự với job offline của lần ngắt kết nối trước
+			userID, isStress := client.UserID, client.IsStressUser
+			h.enqueueStatus(func() {
+				if !isStress {
+					h.loadPresence(userID)
+				}
+				h.BroadcastUserStatus(userID, h.effectiveStatus(userID))
+			})
 
 		case client := <-h.Unregister:
+			if h.Draining() {
+				// Session đã được Shutdown tách khỏi Hub; không báo offline vì client sắp kết nối lại vào instance
+				// khác, trạng thái offline gửi muộn sẽ ghi đè trạng thái online mới
+				client.SafeClose()
+				continue
+			}
+
 			h.mu.Lock()
 			sessions := h.Clients[client.UserID]
 			if sessions != nil {
+				// ReadPump và WritePump đều gửi Unregister, chỉ giảm gauge khi session còn trong Hub
+				if _, ok := sessions[client.SessionID]; ok {
+					telemetry.WSActiveSessions.Dec()
+				}
 				delete(sessions, client.SessionID)
 				if len(sessions) == 0 {
 					delete(h.Clients, client.UserID)
@@ -73,9 +144,20 @@ func (h *Hub) Run() {
 			}
 			h.mu.Unlock()
 
-			// Nếu user hết session -> offline
+			userID := client.UserID
 			if sessions == nil || len(sessions) == 0 {
-				h.BroadcastUserStatus(client.UserID, "offline")
+				// Nếu user hết session -> offline, trừ khi đã kết nối lại trước khi worker xử lý
+				h.enqueueStatus(func() {
+					if h.IsUserOnline(userID) {
+						return
+					}
+					h.BroadcastUserStatus(userID, ModelsUser.StatusOffline)
+					h.presence.Delete(userID)
+					h.lastStatus.Delete(userID)
+				})
+			} else {
+				// Session còn lại có thể đều đang idle
+				h.enqueueStatus(func() { h.refreshStatus(userID) })
 			}
 
 			client.SafeClose()
@@ -90,7 +172,7 @@ func (h *Hub) Run() {
 				}
 
 				// FIX: Đưa toàn bộ xử lý DB và Broadcast ra goroutine riêng để tránh nghẽn Hub
-				go h.broadcastChatMessage(msg)
+				go h.broadcastChatMessage(event.Ctx, msg)
 				continue
 			case "update_seen":
 				msg := event.Payload.(*models.MessageStatusRequest)
@@ -100,23 +182,23 @@ func (h *Hub) Run() {
 				})
 				// Chỉ gửi cho receiver
 				if sessions, ok := h.Clients[msg.ReceiverID]; ok {
-					fmt.Println("data_Rêciver", msg)
 					for _, c := range sessions {
 						select {
 						case c.Send <- data:
 						default:
 							log.Printf("Buffer full — dropping update_seen for %s\n", c.UserID)
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
 
 				if sessions, ok := h.Clients[msg.SenderID]; ok {
-					fmt.Println("SenderID", msg)
 					for _, c := range sessions {
 						select {
 						case c.Send <- data:
 						default:
 							log.Printf("Buffer full — dropping update_seen for %s\n", c.UserID)
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
@@ -136,6 +218,7 @@ func (h *Hub) Run() {
 						case c.Send <- data:
 						default:
 							log.Printf("Buffer full — dropping delete_for_me for %s", payload.UserID)
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
@@ -163,6 +246,7 @@ func (h *Hub) Run() {
 									case c.Send <- data:
 									default:
 										log.Printf("Buffer full — dropping edit_message_update for %s\n", c.UserID)
+										telemetry.DroppedMessage(event.Type)
 									}
 								}
 							}
@@ -176,6 +260,7 @@ func (h *Hub) Run() {
 							case c.Send <- data:
 							default:
 								log.Printf("Buffer full — dropping edit_message_update for %s\n", c.UserID)
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -185,6 +270,7 @@ func (h *Hub) Run() {
 							case c.Send <- data:
 							default:
 								log.Printf("Buffer full — dropping edit_message_update for %s\n", c.UserID)
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -213,6 +299,7 @@ func (h *Hub) Run() {
 								case c.Send <- data:
 								default:
 									log.Printf("Buffer full — dropping chat for %s", memberID.Hex())
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -228,6 +315,7 @@ func (h *Hub) Run() {
 						case receiver.Send <- data:
 						default:
 							log.Printf(" Buffer full — dropping message for receiver %s", msg.ReceiverID.Hex())
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
@@ -238,6 +326,7 @@ func (h *Hub) Run() {
 						case sender.Send <- data:
 						default:
 							log.Printf(" Buffer full — dropping message for sender %s", msg.SenderID.Hex())
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
@@ -266,6 +355,7 @@ func (h *Hub) Run() {
 								case c.Send <- dataRecall:
 								default:
 									log.Printf("Buffer full — dropping recall for %s", memberID.Hex())
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -287,6 +377,7 @@ func (h *Hub) Run() {
 							case c.Send <- dataRecall:
 							default:
 								log.Printf("Buffer full — dropping recall for %s", uid.Hex())
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -316,6 +407,7 @@ func (h *Hub) Run() {
 								case c.Send <- dataRecall:
 								default:
 									log.Printf("Buffer full — dropping recall for %s", memberID.Hex())
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -337,6 +429,7 @@ func (h *Hub) Run() {
 							case c.Send <- dataRecall:
 							default:
 								log.Printf("Buffer full — dropping recall for %s", uid.Hex())
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -366,6 +459,7 @@ func (h *Hub) Run() {
 								case c.Send <- dataRecall:
 								default:
 									log.Printf("Buffer full — dropping recall for %s", memberID.Hex())
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -384,6 +478,7 @@ func (h *Hub) Run() {
 							case c.Send <- dataRecall:
 							default:
 								log.Printf("Buffer full — dropping recall for %s", uid.Hex())
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -407,6 +502,13 @@ func (h *Hub) Run() {
 					break
 				}
 
+				// User bật "không làm phiền" vẫn nhận tin nhưng không bị đẩy thông báo (bỏ preview hội thoại bên dưới)
+				dataMsgSilent, _ := json.Marshal(map[string]interface{}{
+					"type":    "chat",
+					"message": resSocket,
+					"silent":  true,
+				})
+
 				// Đếm số user nhận được để log chính xác
 				broadcastCount := 0
 
@@ -416,16 +518,29 @@ func (h *Hub) Run() {
 						continue
 					}
 
+					dnd := h.IsDND(userID)
+					msgData := dataMsg
+					if dnd {
+						msgData = dataMsgSilent
+					}
+
 					for _, client := range sessions {
 						// 1. Gửi tin nhắn chính (hiển thị trong khung chat)
 						select {
-						case client.Send <- dataMsg:
+						case client.Send <- msgData:
 							// thành công
 						default:
 							log.Printf("Buffer full — dropping chat-notification message for user %s", userID)
+							telemetry.DroppedMessage(event.Type)
 							continue // thử client tiếp theo
 						}
 
+						// DND: không đẩy preview hội thoại (thông báo), chỉ giao tin nhắn
+						if dnd {
+							broadcastCount++
+							continue
+						}
+
 						// 2. Tạo và gửi conversation preview riêng cho user này
 						convPreview := &models.ConversationPreview{
 							SenderID:        resSocket.SenderID.Hex(),
@@ -453,6 +568,7 @@ func (h *Hub) Run() {
 							broadcastCount++
 						default:
 							log.Printf("Buffer full — dropping conversation preview for user %s", userID)
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
@@ -473,13 +589,13 @@ func (h *Hub) Run() {
 				}
 
 				if sessions, ok := h.Clients[senderID]; ok {
-					fmt.Println("uid", senderID)
 
 					for _, c := range sessions {
 						select {
 						case c.Send <- data:
 						default:
 							log.Println("buffer full, dropping message for", senderID)
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
@@ -494,6 +610,7 @@ func (h *Hub) Run() {
 							case c.Send <- data:
 							default:
 								log.Println("buffer full, dropping message for", uid)
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -523,6 +640,7 @@ func (h *Hub) Run() {
 								case c.Send <- dataRecall:
 								default:
 									log.Printf("Buffer full — dropping recall for %s", memberID.Hex())
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -542,6 +660,7 @@ func (h *Hub) Run() {
 						case c.Send <- data:
 						default:
 							log.Printf("Buffer full — dropping account_deleted for %s\n", deletedUserID)
+							telemetry.DroppedMessage(event.Type)
 						}
 						// Ta không close ngay lập tức để client nhận được message
 					}
@@ -570,6 +689,7 @@ func (h *Hub) Run() {
 								case c.Send <- dataRecall:
 								default:
 									log.Printf("Buffer full — dropping recall for %s", memberID.Hex())
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -588,6 +708,7 @@ func (h *Hub) Run() {
 							case c.Send <- dataRecall:
 							default:
 								log.Printf("Buffer full — dropping recall for %s", uid.Hex())
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -615,6 +736,7 @@ func (h *Hub) Run() {
 								case c.Send <- data:
 								default:
 									log.Printf("Buffer full — dropping reaction_update for %s\n", c.UserID)
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -641,6 +763,7 @@ func (h *Hub) Run() {
 								case c.Send <- data:
 								default:
 									log.Printf("Buffer full — dropping reaction_update for %s\n", c.UserID)
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -648,9 +771,7 @@ func (h *Hub) Run() {
 				}
 
 			case "video-call":
-				log.Println("[Hub] Received video-call event") // DEBUG
 				payload := event.Payload.(map[string]interface{})
-				log.Printf("[Hub] Payload: %+v\n", payload) // DEBUG
 				data, _ := json.Marshal(map[string]interface{}{
 					"type":    "video-call",
 					"message": payload,
@@ -658,9 +779,14 @@ func (h *Hub) Run() {
 
 				ctx := context.Background()
 
+				// Chỉ log loại sự kiện và ID, không log payload
+				groupID, _ := payload["group_id"].(string)
+				receiverID, _ := payload["receiver_id"].(string)
+				callerID, _ := payload["caller_id"].(string)
+				logger.Ctx(ctx, "websocket").Debug().Str("event", event.Type).Str("group_id", groupID).Str("receiver_id", receiverID).Str("caller_id", callerID).Msg("Video call event")
+
 				// Handle Group Call
-				if groupID, ok := payload["group_id"].(string); ok && groupID != "" {
-					log.Printf("[Hub] Broadcasting group call to group: %s\n", groupID) // DEBUG
+				if groupID != "" {
 					oid, err := primitive.ObjectIDFromHex(groupID)
 					if err == nil {
 						members, err := storage.NewMongoChatStore(h.DB).GetGroupMembers(ctx, oid)
@@ -672,6 +798,7 @@ func (h *Hub) Run() {
 										case c.Send <- data:
 										default:
 											log.Printf("Buffer full — dropping video-call for %s", memberID.Hex())
+											telemetry.DroppedMessage(event.Type)
 										}
 									}
 								}
@@ -681,29 +808,27 @@ func (h *Hub) Run() {
 				}
 
 				// Handle DM Call (User to User)
-				if receiverID, ok := payload["receiver_id"].(string); ok && receiverID != "" {
-					log.Printf("[Hub] Processing DM call for receiver: %s\n", receiverID) // DEBUG
+				if receiverID != "" {
 					// Send to Receiver
 					if sessions, ok := h.Clients[receiverID]; ok {
-						log.Printf("[Hub] Receiver %s is ONLINE. Sending socket message.\n", receiverID) // DEBUG
 						for _, c := range sessions {
 							select {
 							case c.Send <- data:
 							default:
 								log.Printf("Buffer full — dropping video-call for %s", receiverID)
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
-					} else {
-						log.Printf("[Hub] Receiver %s is OFFLINE (not in h.Clients).\n", receiverID) // DEBUG
 					}
 					// Send to Sender (Caller) - though they likely know, it keeps state in sync
-					if callerID, ok := payload["caller_id"].(string); ok && callerID != "" {
+					if callerID != "" {
 						if sessions, ok := h.Clients[callerID]; ok {
 							for _, c := range sessions {
 								select {
 								case c.Send <- data:
 								default:
 									log.Printf("Buffer full — dropping video-call for %s", callerID)
+									telemetry.DroppedMessage(event.Type)
 								}
 							}
 						}
@@ -741,6 +866,7 @@ func (h *Hub) Run() {
 							case c.Send <- data:
 							default:
 								log.Printf("Buffer full — dropping group_dissolved for %s", memberID.Hex())
+								telemetry.DroppedMessage(event.Type)
 							}
 						}
 					}
@@ -749,6 +875,9 @@ func (h *Hub) Run() {
 			case "group_member_removed":
 				payload := event.Payload.(map[string]interface{})
 				targetUserID := payload["user_id"].(string)
+				if groupID, ok := payload["group_id"].(string); ok {
+					h.groupRoles.Delete(groupID + ":" + targetUserID)
+				}
 				data, _ := json.Marshal(map[string]interface{}{
 					"type":    "group_member_removed",
 					"message": payload,
@@ -760,9 +889,37 @@ func (h *Hub) Run() {
 						case c.Send <- data:
 						default:
 							log.Printf("Buffer full — dropping group_member_removed for %s", targetUserID)
+							telemetry.DroppedMessage(event.Type)
 						}
 					}
 				}
+
+			case "group_settings_updated":
+				payload := event.Payload.(map[string]interface{})
+				go h.broadcastGroupSettings(payload)
+
+			case "group_updated", "group_archived", "group_unarchived", "group_restored",
+				"community_channel_created", "channel_members_changed":
+				payload := event.Payload.(map[string]interface{})
+				go h.broadcastGroupEvent(event.Type, payload)
+
+			case "poll_updated", "poll_closed":
+				go h.broadcastPollEvent(event.Type, event.Payload.(*models.Poll))
+
+			// Yêu cầu tham gia nhóm: gửi cho admin (group_join_request) hoặc người xin vào (group_join_request_reviewed)
+			case "group_join_request", "group_join_request_reviewed", "mention", "media_scanned":
+				payload := event.Payload.(map[string]interface{})
+				recipients, _ := payload["recipients"].([]string)
+				delete(payload, "recipients")
+
+				data, _ := json.Marshal(map[string]interface{}{
+					"type":    event.Type,
+					"message": payload,
+				})
+
+				for _, uid := range recipients {
+					h.sendToUser(uid, data)
+				}
 			}
 		}
 	}
@@ -770,13 +927,26 @@ func (h *Hub) Run() {
 
 func (h *Hub) BroadcastUserStatus(userID, status string) {
 	oid, _ := primitive.ObjectIDFromHex(userID)
+	now := time.Now()
 	event := ModelsUser.UserStatus{
 		UserID:    oid,
 		Status:    status,
-		UpdatedAt: time.Now(),
+		UpdatedAt: now,
+		LastSeen:  &now,
+	}
+
+	presence := h.getPresence(userID)
+	if presence.CustomStatus.IsActive(now) {
+		event.CustomStatus = presence.CustomStatus
 	}
 	data, _ := json.Marshal(event)
 
+	// Bản không có last seen cho những người không được phép xem
+	hidden := event
+	hidden.LastSeen = nil
+	hidden.UpdatedAt = time.Time{}
+	hiddenData, _ := json.Marshal(hidden)
+
 	h.mu.RLock()
 	isStress := false
 	if sessions, ok := h.Clients[userID]; ok {
@@ -792,39 +962,105 @@ func (h *Hub) BroadcastUserStatus(userID, status string) {
 	if isStress {
 		return
 	}
+	h.lastStatus.Store(userID, status)
+
+	// Không để người đang chặn/bị chặn thấy trạng thái online
+	blocked := h.blockedUsers(userID)
+	friends := make(map[string]bool)
+	if h.DB != nil && presence.LastSeenPrivacy == ModelsUser.LastSeenFriends {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		friendIDs, err := friendStorage.NewMongoStoreFriend(h.DB).GetFriendIDs(ctx, userID)
+		cancel()
+		if err != nil {
+			log.Printf("Lỗi GetFriendIDs %s: %v", userID, err)
+		}
+		for _, id := range friendIDs {
+			friends[id] = true
+		}
+	}
 
 	h.mu.RLock()
 	// gửi tới tất cả client khác - nhưng bỏ qua stress users (họ không cần xem status)
-	for _, sessions := range h.Clients {
+	for uid, sessions := range h.Clients {
+		if blocked[uid] {
+			continue
+		}
+		payload := data
+		if uid != userID && !presence.CanSeeLastSeen(friends[uid]) {
+			payload = hiddenData
+		}
 		for _, c := range sessions {
 			if c.IsStressUser {
 				continue
 			}
 			select {
-			case c.Send <- data:
+			case c.Send <- payload:
 			default:
 				log.Printf("Buffer full — dropping user_status update for %s\n", c.UserID)
+				telemetry.DroppedMessage("user_status")
 			}
 		}
 	}
 	h.mu.RUnlock()
 
-	// Lưu DB + Kafka - CHỈ CHO USER THẬT
+	// Lưu DB + Kafka - CHỈ CHO USER THẬT. Trạng thái online nhiều và chỉ cần bản mới nhất, không đi qua outbox
 	go kafka.SendMessageAsync("user-status-topic", userID, string(data))
 }
 
+// register / unregister gửi client cho Run; Hub đã dừng thì không chặn mãi. register trả về false khi Hub đã dừng.
+func (h *Hub) register(c *Client) bool {
+	select {
+	case h.Register <- c:
+		return true
+	case <-h.stop:
+		return false
+	}
+}
+
+func (h *Hub) unregister(c *Client) {
+	select {
+	case h.Unregister <- c:
+	case <-h.stop:
+	}
+}
+
+// enqueueStatus đẩy việc broadcast trạng thái sang worker, giữ đúng thứ tự kết nối/ngắt kết nối
+func (h *Hub) enqueueStatus(job func()) {
+	select {
+	case h.statusJobs <- job:
+	case <-h.stop:
+	}
+}
+
+func (h *Hub) runStatusJobs() {
+	for {
+		select {
+		case <-h.stop:
+			return
+		case job := <-h.statusJobs:
+			job()
+		}
+	}
+}
+
 func (h *Hub) CheckOfflineTimeout() {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
 		now := time.Now()
 		h.mu.RLock()
 		for userID, sessions := range h.Clients {
 			for sessionID, c := range sessions {
 				if now.Sub(c.LastSeen) > 60*time.Second {
 					log.Printf("User %s session %s timeout, đánh offline", userID, sessionID)
-					h.Unregister <- c
+					h.unregister(c)
 				}
 			}
 		}
@@ -832,7 +1068,15 @@ func (h *Hub) CheckOfflineTimeout() {
 	}
 }
 
-func (h *Hub) broadcastChatMessage(msg *models.MessageResponse) {
+func (h *Hub) broadcastChatMessage(ctx context.Context, msg *models.MessageResponse) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := telemetry.Tracer().Start(ctx, "hub.deliver chat")
+	defer span.End()
+	recipients := 0
+	defer func() { span.SetAttributes(attribute.Int("chat.recipient_sessions", recipients)) }()
+
 	if h.DB != nil {
 		// 1. Caching: Tránh query DB liên tục mỗi tin nhắn
 		cacheKey := "user:" + msg.SenderID.Hex()
@@ -880,9 +1124,9 @@ func (h *Hub) broadcastChatMessage(msg *models.MessageResponse) {
 
 	// 2. Broadcast logic (Nhóm hoặc 1-1)
 	if msg.GroupID != primitive.NilObjectID {
-		members, err := storage.NewMongoChatStore(h.DB).GetGroupMembers(context.Background(), msg.GroupID)
+		members, err := storage.NewMongoChatStore(h.DB).GetGroupMembers(ctx, msg.GroupID)
 		if err != nil {
-			log.Println("Lỗi GetGroupMembers:", err)
+			logger.Ctx(ctx, "websocket").Error().Err(err).Msg("Lỗi GetGroupMembers")
 			return
 		}
 
@@ -906,12 +1150,15 @@ func (h *Hub) broadcastChatMessage(msg *models.MessageResponse) {
 
 		// Gửi message thật
 		for _, mID := range members {
-			h.sendToUser(mID.Hex(), data)
+			recipients += h.sendToUser(mID.Hex(), data)
 		}
 	} else {
-		// Nhắn 1-1
-		h.sendToUser(msg.ReceiverID.Hex(), data)
-		h.sendToUser(msg.SenderID.Hex(), data)
+		// Nhắn 1-1: bị chặn thì không giao tới người nhận
+		if h.IsBlocked(msg.SenderID.Hex(), msg.ReceiverID.Hex()) {
+			return
+		}
+		recipients += h.sendToUser(msg.ReceiverID.Hex(), data)
+		recipients += h.sendToUser(msg.SenderID.Hex(), data)
 
 		if msg.ParentID == "" {
 			// Sender's preview (viewing the receiver)
@@ -945,22 +1192,35 @@ func (h *Hub) broadcastChatMessage(msg *models.MessageResponse) {
 	}
 }
 
-func (h *Hub) sendToUser(userID string, data []byte) {
+// sendToUser gửi frame tới mọi session của user, trả về số session nhận được
+func (h *Hub) sendToUser(userID string, data []byte) int {
 	h.mu.RLock()
 	sessions, ok := h.Clients[userID]
 	if !ok {
 		h.mu.RUnlock()
-		return
+		return 0
 	}
 
+	sent := 0
 	for _, c := range sessions {
 		select {
 		case c.Send <- data:
+			sent++
 		default:
-			// Buffer full
+			dropFrame(data)
 		}
 	}
 	h.mu.RUnlock()
+	return sent
+}
+
+// dropFrame ghi nhận frame bị bỏ vì buffer gửi của client đầy, nhãn là type của frame
+func dropFrame(data []byte) {
+	var frame struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(data, &frame)
+	telemetry.DroppedMessage(frame.Type)
 }
 
 func (h *Hub) IsUserOnline(userID string) bool {
@@ -969,3 +1229,32 @@ func (h *Hub) IsUserOnline(userID string) bool {
 	_, ok := h.Clients[userID]
 	return ok
 }
+
+// HubStats - số liệu kết nối hiện tại của Hub, dùng cho trang chẩn đoán của admin
+type HubStats struct {
+	Users             int `json:"users"`
+	Sessions          int `json:"sessions"`
+	StressSessions    int `json:"stress_sessions"`
+	BroadcastQueue    int `json:"broadcast_queue"`
+	BroadcastCapacity int `json:"broadcast_capacity"`
+}
+
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := HubStats{
+		Users:             len(h.Clients),
+		BroadcastQueue:    len(h.Broadcast),
+		BroadcastCapacity: cap(h.Broadcast),
+	}
+	for _, sessions := range h.Clients {
+		for _, c := range sessions {
+			stats.Sessions++
+			if c.IsStressUser {
+				stats.StressSessions++
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,24 @@
+package websocket
+
+import (
+	"my-app/modules/chat/models"
+)
+
+// PublishMediaScanned báo kết quả quét mã độc cho người upload (clean: đã xem được, infected: đã bị cách ly,
+// too_large/scan_failed: không quét được nên không cho tải)
+func (h *Hub) PublishMediaScanned(media *models.Media) {
+	if media == nil || media.UploadedBy == nil {
+		return
+	}
+
+	h.Broadcast <- HubEvent{
+		Type: "media_scanned",
+		Payload: map[string]interface{}{
+			"recipients":     []string{media.UploadedBy.Hex()},
+			"media_id":       media.ID.Hex(),
+			"filename":       media.Filename,
+			"scan_status":    media.ScanStatus,
+			"scan_signature": media.ScanSignature,
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"time"
+)
+
+// resolveMentions phân giải mention của tin nhắn vừa gửi một lần duy nhất, ghi đè Mentions/MentionTargets
+// client gửi lên. Kết quả đi theo payload Kafka để consumer lưu hộp thư nhắc đến đúng với thông báo đã gửi.
+func (h *Hub) resolveMentions(msg *models.MessageResponse) {
+	msg.Mentions, msg.MentionTargets = nil, nil
+	if h.DB == nil || msg.Type == "system" || !biz.HasMentionCandidate(msg.Content) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	mentions, targets, err := biz.ResolveMentions(ctx, storage.NewMongoChatStore(h.DB), msg.Content, msg.SenderID, msg.ReceiverID, msg.GroupID)
+	if err != nil {
+		log.Printf("⚠️ Không phân giải được mention của tin nhắn %s: %v", msg.ID.Hex(), err)
+		return
+	}
+	msg.Mentions, msg.MentionTargets = mentions, targets
+}
+
+// notifyMentions bắn event "mention" cho người được nhắc (msg đã qua resolveMentions).
+// Event này tách khỏi "chat" nên vẫn tới được người đã tắt thông báo (IsMuted) cuộc trò chuyện.
+func (h *Hub) notifyMentions(msg models.MessageResponse) {
+	targets := msg.MentionTargets
+	if len(targets) == 0 {
+		return
+	}
+
+	// Gom người nhận theo loại mention để client hiển thị "nhắc đến bạn" / "@all"...
+	byType := make(map[string][]string)
+	for _, t := range targets {
+		byType[t.Type] = append(byType[t.Type], t.UserID.Hex())
+	}
+
+	groupID := ""
+	if !msg.GroupID.IsZero() {
+		groupID = msg.GroupID.Hex()
+	}
+
+	for mentionType, recipients := range byType {
+		h.Broadcast <- HubEvent{
+			Type: "mention",
+			Payload: map[string]interface{}{
+				"recipients":   recipients,
+				"message_id":   msg.ID.Hex(),
+				"group_id":     groupID,
+				"receiver_id":  msg.ReceiverID.Hex(),
+				"sender_id":    msg.SenderID.Hex(),
+				"sender_name":  msg.DisplayName,
+				"avatar":       msg.Avatar,
+				"content":      msg.Content,
+				"mention_type": mentionType,
+				"created_at":   msg.CreatedAt,
+			},
+		}
+	}
+}
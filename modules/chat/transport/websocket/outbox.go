@@ -0,0 +1,38 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"my-app/common/outbox"
+	"time"
+)
+
+var errEventNotSaved = errors.New("không lưu được thao tác, vui lòng thử lại")
+
+// outboxMessage - một sự kiện Kafka chờ ghi vào outbox
+type outboxMessage struct {
+	topic   string
+	key     string
+	payload interface{}
+}
+
+// enqueueOutbox ghi các sự kiện vào outbox trong một transaction, relay sẽ publish lên Kafka.
+// Các sự kiện đi cùng nhau (VD: tin nhắn hệ thống + ghim) không còn bị gửi được một nửa.
+func (h *Hub) enqueueOutbox(messages ...outboxMessage) error {
+	events := make([]outbox.Event, 0, len(messages))
+	for _, m := range messages {
+		e, err := outbox.NewEvent(m.topic, m.key, m.payload)
+		if err != nil {
+			return err
+		}
+		events = append(events, e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := outbox.NewStore(h.DB)
+	return store.WithTransaction(ctx, func(ctx context.Context) error {
+		return store.Enqueue(ctx, events...)
+	})
+}
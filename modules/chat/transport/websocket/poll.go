@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"my-app/modules/chat/biz"
+	"my-app/modules/chat/models"
+	"my-app/modules/chat/storage"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PublishPoll bắn kết quả bình chọn (poll_updated / poll_closed) cho người trong cuộc trò chuyện.
+// my_votes là dữ liệu riêng của từng người nên bị bỏ khỏi bản broadcast.
+func (h *Hub) PublishPoll(eventType string, poll *models.Poll) {
+	if poll == nil {
+		return
+	}
+	shared := *poll
+	shared.MyVotes = nil
+
+	h.Broadcast <- HubEvent{Type: eventType, Payload: &shared}
+}
+
+func (h *Hub) broadcastPollEvent(eventType string, poll *models.Poll) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    eventType,
+		"message": poll,
+	})
+
+	if poll.GroupID.IsZero() {
+		h.sendToUser(poll.CreatorID.Hex(), data)
+		h.sendToUser(poll.ReceiverID.Hex(), data)
+		return
+	}
+
+	members, err := storage.NewMongoChatStore(h.DB).GetGroupMembers(context.Background(), poll.GroupID)
+	if err != nil {
+		log.Printf("Lỗi GetGroupMembers trong %s: %v", eventType, err)
+		return
+	}
+	for _, memberID := range members {
+		h.sendToUser(memberID.Hex(), data)
+	}
+}
+
+// handlePollVote - bình chọn qua WebSocket, kết quả trả về cho người vote kèm my_votes
+func (c *Client) handlePollVote(req *models.VotePollRequest) {
+	if req == nil || c.IsStressUser || c.Hub.DB == nil {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.UserID)
+	if err != nil {
+		return
+	}
+	pollID, err := primitive.ObjectIDFromHex(req.PollID)
+	if err != nil {
+		c.sendPollRejected(req.PollID, biz.ErrPollNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	poll, err := biz.NewPollBiz(storage.NewMongoChatStore(c.Hub.DB)).Vote(ctx, userID, pollID, req.OptionIDs)
+	if err != nil {
+		c.sendPollRejected(req.PollID, err)
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    "poll_voted",
+		"message": poll,
+	})
+	select {
+	case c.Send <- data:
+	default:
+		dropFrame(data)
+	}
+
+	c.Hub.PublishPoll("poll_updated", poll)
+}
+
+func (c *Client) sendPollRejected(pollID string, err error) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":    "poll_vote_rejected",
+		"poll_id": pollID,
+		"message": err.Error(),
+	})
+	select {
+	case c.Send <- data:
+	default:
+		dropFrame(data)
+	}
+}
+
+// PublishPollClosed bắn kết quả cuối cùng và tin nhắn hệ thống tổng kết
+func (h *Hub) PublishPollClosed(ev biz.PollClosedEvent) {
+	h.PublishPoll("poll_closed", ev.Poll)
+
+	if ev.Summary == nil {
+		return
+	}
+	msg := ev.Summary
+	h.Broadcast <- HubEvent{
+		Type: "chat",
+		Payload: &models.MessageResponse{
+			ID:           msg.ID,
+			SenderID:     msg.SenderID,
+			ReceiverID:   msg.ReceiverID,
+			GroupID:      msg.GroupID,
+			Content:      msg.Content,
+			Type:         msg.Type,
+			CreatedAt:    msg.CreatedAt,
+			Status:       msg.Status,
+			SystemAction: msg.SystemAction,
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	ModelsUser "my-app/modules/user/models"
+	StorageUser "my-app/modules/user/storage"
+	"time"
+)
+
+// loadPresence nạp thiết lập presence của user từ DB vào cache của hub
+func (h *Hub) loadPresence(userID string) {
+	if h.DB == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	presence, err := StorageUser.NewMongoStore(h.DB).GetPresence(ctx, userID)
+	if err != nil {
+		log.Printf("Lỗi GetPresence %s: %v", userID, err)
+		return
+	}
+	h.presence.Store(userID, presence)
+}
+
+func (h *Hub) getPresence(userID string) *ModelsUser.UserStatus {
+	if val, ok := h.presence.Load(userID); ok {
+		return val.(*ModelsUser.UserStatus)
+	}
+	return &ModelsUser.UserStatus{}
+}
+
+// effectiveStatus: trạng thái thủ công được ưu tiên, sau đó tới away nếu mọi session đều idle
+func (h *Hub) effectiveStatus(userID string) string {
+	if manual := h.getPresence(userID).ManualStatus; manual != "" {
+		return manual
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sessions, ok := h.Clients[userID]
+	if !ok || len(sessions) == 0 {
+		return ModelsUser.StatusOffline
+	}
+	for _, c := range sessions {
+		if !c.Idle {
+			return ModelsUser.StatusOnline
+		}
+	}
+	return ModelsUser.StatusAway
+}
+
+// refreshStatus broadcast lại khi trạng thái hiệu lực thay đổi
+func (h *Hub) refreshStatus(userID string) {
+	if !h.IsUserOnline(userID) {
+		return
+	}
+
+	status := h.effectiveStatus(userID)
+	if last, ok := h.lastStatus.Load(userID); ok && last.(string) == status {
+		return
+	}
+	h.BroadcastUserStatus(userID, status)
+}
+
+// SetPresence cập nhật thiết lập presence (gọi từ API) và thông báo cho mọi người
+func (h *Hub) SetPresence(userID string, presence *ModelsUser.UserStatus) {
+	h.presence.Store(userID, presence)
+	if h.IsUserOnline(userID) {
+		h.BroadcastUserStatus(userID, h.effectiveStatus(userID))
+	}
+}
+
+// SetIdle được client báo khi user không thao tác trong 1 khoảng thời gian
+func (h *Hub) SetIdle(c *Client, idle bool) {
+	h.mu.Lock()
+	c.Idle = idle
+	h.mu.Unlock()
+
+	h.refreshStatus(c.UserID)
+}
+
+// IsDND kiểm tra user có đang bật chế độ không làm phiền
+func (h *Hub) IsDND(userID string) bool {
+	return h.getPresence(userID).ManualStatus == ModelsUser.StatusDND
+}
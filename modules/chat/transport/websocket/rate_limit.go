@@ -0,0 +1,50 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"my-app/common/ratelimit"
+	"my-app/common/telemetry"
+)
+
+// SetFrameLimits đặt giới hạn theo loại frame (WS_RATE_LIMITS), gọi trước khi nhận kết nối
+func (h *Hub) SetFrameLimits(limiter ratelimit.Limiter, limits map[string]ratelimit.Limit) {
+	h.frameLimiter = limiter
+	h.frameLimits = limits
+}
+
+// allowFrame trừ token của user cho loại frame; vượt giới hạn thì bỏ frame và gửi rate_limited để client
+// biết khi nào gửi lại (kèm client_message_id nếu là tin nhắn chat)
+func (c *Client) allowFrame(ctx context.Context, incoming *WSMessage) bool {
+	limit, ok := c.Hub.frameLimits[incoming.Type]
+	if !ok || c.IsStressUser || c.Hub.frameLimiter == nil {
+		return true
+	}
+
+	// Không để backend chậm (mongo) chặn ReadPump: quá ratelimit.Timeout thì cho frame đi qua
+	limitCtx, cancel := context.WithTimeout(ctx, ratelimit.Timeout)
+	res, err := c.Hub.frameLimiter.Allow(limitCtx, c.UserID+":"+incoming.Type, limit)
+	cancel()
+	if err != nil || res.Allowed {
+		return true
+	}
+
+	telemetry.RateLimited.WithLabelValues("ws:" + incoming.Type).Inc()
+	frame := map[string]interface{}{
+		"type":           "rate_limited",
+		"event":          incoming.Type,
+		"retry_after_ms": max(1, res.RetryAfter.Milliseconds()),
+	}
+	if incoming.Message != nil && incoming.Message.ClientMessageID != "" {
+		frame["client_message_id"] = incoming.Message.ClientMessageID
+	}
+
+	data, _ := json.Marshal(frame)
+	select {
+	case c.Send <- data:
+	default:
+		dropFrame(data)
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"my-app/common/telemetry"
+)
+
+// Draining cho biết server đang dừng, handler từ chối nâng cấp kết nối WebSocket mới
+func (h *Hub) Draining() bool {
+	return h.draining.Load()
+}
+
+// Shutdown gửi server_going_away kèm gợi ý thời điểm kết nối lại cho mọi session, đợi các frame còn trong
+// hàng đợi được ghi xong rồi đóng kết nối, sau đó dừng Hub.Run. Hết ctx thì đóng cưỡng bức các kết nối còn lại.
+func (h *Hub) Shutdown(ctx context.Context, reconnectHint time.Duration) error {
+	if h.draining.Swap(true) {
+		return nil
+	}
+	defer close(h.stop)
+
+	// Tách toàn bộ session khỏi Hub trước khi đóng Send, để không goroutine broadcast nào còn gửi vào kênh đã đóng
+	h.mu.Lock()
+	var clients []*Client
+	for _, sessions := range h.Clients {
+		for _, c := range sessions {
+			clients = append(clients, c)
+		}
+	}
+	h.Clients = make(map[string]map[string]*Client)
+	h.mu.Unlock()
+	telemetry.WSActiveSessions.Sub(float64(len(clients)))
+
+	log.Printf("🔌 [Hub] Draining %d WebSocket sessions", len(clients))
+	for _, c := range clients {
+		// Rải thời điểm kết nối lại trong [hint, 2*hint) để các instance còn lại không bị dồn cùng lúc
+		reconnectAfter := reconnectHint
+		if reconnectHint > 0 {
+			reconnectAfter += rand.N(reconnectHint)
+		}
+		data, _ := json.Marshal(map[string]interface{}{
+			"type":               "server_going_away",
+			"reconnect_after_ms": reconnectAfter.Milliseconds(),
+		})
+		select {
+		case c.Send <- data:
+		default:
+			// Buffer đầy: client vẫn nhận close frame 1012 (service restart) và tự kết nối lại
+			dropFrame(data)
+		}
+		c.SafeClose()
+	}
+
+	for _, c := range clients {
+		if c.writeDone == nil {
+			continue
+		}
+		select {
+		case <-c.writeDone:
+		case <-ctx.Done():
+			remaining := 0
+			for _, c := range clients {
+				if c.writeDone != nil {
+					select {
+					case <-c.writeDone:
+						continue
+					default:
+					}
+				}
+				remaining++
+				c.Conn.Close()
+			}
+			log.Printf("⚠️ [Hub] Shutdown deadline reached, force-closed %d WebSocket sessions", remaining)
+			return ctx.Err()
+		}
+	}
+
+	log.Println("✅ [Hub] All WebSocket sessions closed")
+	return nil
+}
@@ -0,0 +1,68 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/friend/models"
+	"my-app/modules/friend/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BlockFriendStorage interface {
+	CheckUserExists(ctx context.Context, userID string) (bool, error)
+	CheckFriend(ctx context.Context, userID, friendID string) (models.FriendShip, error)
+	Block(ctx context.Context, blockerID, targetID string) error
+	UpdateStatusWithAction(ctx context.Context, id primitive.ObjectID, newStatus storage.FriendStatus, lastActionBy string) error
+}
+
+type BlockFriendBiz struct {
+	store BlockFriendStorage
+}
+
+func NewBlockFriendBiz(store BlockFriendStorage) *BlockFriendBiz {
+	return &BlockFriendBiz{store: store}
+}
+
+func (biz *BlockFriendBiz) Block(ctx context.Context, userID, targetID string) error {
+	if userID == targetID {
+		return errors.New("Không thể tự chặn chính mình")
+	}
+
+	exist, err := biz.store.CheckUserExists(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return errors.New("Người dùng không tồn tại")
+	}
+
+	// Chưa có quan hệ nào thì vẫn chặn được, lỗi khác phải trả về
+	friend, err := biz.store.CheckFriend(ctx, userID, targetID)
+	if err != nil && !errors.Is(err, storage.ErrFriendShipNotFound) {
+		return err
+	}
+	// Đối phương đã chặn trước thì giữ nguyên để họ vẫn là người quyết định bỏ chặn
+	if friend.Status == string(storage.StatusBlock) {
+		if friend.LastActionBy == userID {
+			return nil
+		}
+		return errors.New("Bạn không thể chặn người dùng này")
+	}
+
+	return biz.store.Block(ctx, userID, targetID)
+}
+
+// Unblock chỉ người đã chặn mới được bỏ chặn, quan hệ trở về not_friend
+func (biz *BlockFriendBiz) Unblock(ctx context.Context, userID, targetID string) error {
+	friend, err := biz.store.CheckFriend(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+
+	if friend.Status != string(storage.StatusBlock) || friend.LastActionBy != userID {
+		return errors.New("Bạn chưa chặn người dùng này")
+	}
+
+	return biz.store.UpdateStatusWithAction(ctx, friend.ID, storage.StatusNotFriend, userID)
+}
@@ -0,0 +1,37 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/friend/models"
+	"my-app/modules/friend/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CancelRequestStorage interface {
+	CheckFriend(ctx context.Context, userID, friendID string) (models.FriendShip, error)
+	UpdateStatusWithAction(ctx context.Context, id primitive.ObjectID, newStatus storage.FriendStatus, lastActionBy string) error
+}
+
+type CancelRequestBiz struct {
+	store CancelRequestStorage
+}
+
+func NewCancelRequestBiz(store CancelRequestStorage) *CancelRequestBiz {
+	return &CancelRequestBiz{store: store}
+}
+
+// Cancel thu hồi lời mời kết bạn mà chính mình đã gửi
+func (biz *CancelRequestBiz) Cancel(ctx context.Context, userID, friendID string) error {
+	friend, err := biz.store.CheckFriend(ctx, userID, friendID)
+	if err != nil {
+		return err
+	}
+
+	if friend.Status != string(storage.StatusPending) || friend.LastActionBy != userID {
+		return errors.New("Không có lời mời kết bạn nào để hủy")
+	}
+
+	return biz.store.UpdateStatusWithAction(ctx, friend.ID, storage.StatusNotFriend, userID)
+}
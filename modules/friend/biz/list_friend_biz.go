@@ -0,0 +1,22 @@
+package biz
+
+import (
+	"context"
+	"my-app/modules/friend/models"
+)
+
+type ListFriendStorage interface {
+	ListFriends(ctx context.Context, userID, listType string, page, limit int) ([]models.FriendItem, int64, error)
+}
+
+type ListFriendBiz struct {
+	store ListFriendStorage
+}
+
+func NewListFriendBiz(store ListFriendStorage) *ListFriendBiz {
+	return &ListFriendBiz{store: store}
+}
+
+func (biz *ListFriendBiz) List(ctx context.Context, userID string, query *models.FriendListQuery) ([]models.FriendItem, int64, error) {
+	return biz.store.ListFriends(ctx, userID, query.Type, query.Page, query.Limit)
+}
@@ -0,0 +1,36 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/friend/models"
+	"my-app/modules/friend/storage"
+	"strings"
+)
+
+type NicknameStorage interface {
+	CheckFriend(ctx context.Context, userID, friendID string) (models.FriendShip, error)
+	SetNickname(ctx context.Context, friendShip models.FriendShip, setterID, nickname string) error
+}
+
+type NicknameBiz struct {
+	store NicknameStorage
+}
+
+func NewNicknameBiz(store NicknameStorage) *NicknameBiz {
+	return &NicknameBiz{store: store}
+}
+
+// SetNickname đặt (hoặc xóa nếu rỗng) biệt danh cho một người bạn
+func (biz *NicknameBiz) SetNickname(ctx context.Context, userID string, req *models.NicknameRequest) error {
+	friend, err := biz.store.CheckFriend(ctx, userID, req.FriendID)
+	if err != nil {
+		return err
+	}
+
+	if friend.Status != string(storage.StatusAccepted) {
+		return errors.New("Chỉ có thể đặt biệt danh cho bạn bè")
+	}
+
+	return biz.store.SetNickname(ctx, friend, userID, strings.TrimSpace(req.NickName))
+}
@@ -2,11 +2,31 @@ package biz
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	friendModels "my-app/modules/friend/models"
 	"my-app/modules/user/models"
+	"sort"
+	"time"
+)
+
+// Trọng số chấm điểm gợi ý
+const (
+	mutualFriendWeight = 3.0
+	sharedGroupWeight  = 2.0
+	recentChatWeight   = 0.25
+	maxRecentChats     = 20
+	recentChatWindow   = 30 * 24 * time.Hour
 )
 
 type SuggestFriendStorage interface {
-	GetFriendSuggestions(ctx context.Context, userID string, keyword string, page, limit int) ([]models.User, int64, error)
+	GetRelatedUserIDs(ctx context.Context, userID string) (excluded []string, friends []string, err error)
+	CountMutualFriends(ctx context.Context, userID string, friendIDs []string) (map[string]int, error)
+	CountSharedGroups(ctx context.Context, userID string) (map[string]int, error)
+	CountRecentChats(ctx context.Context, userID string, since time.Time) (map[string]int, error)
+	FindActiveUsers(ctx context.Context, ids []string, keyword string) ([]models.User, error)
+	FindOtherUsers(ctx context.Context, excludeIDs []string, keyword string, skip, limit int) ([]models.User, int64, error)
+	DismissSuggestion(ctx context.Context, data *friendModels.DismissedSuggestion) error
 }
 
 type SuggestFriendBiz struct {
@@ -17,6 +37,162 @@ func NewSuggestFriendBiz(store SuggestFriendStorage) *SuggestFriendBiz {
 	return &SuggestFriendBiz{store: store}
 }
 
-func (biz *SuggestFriendBiz) Suggest(ctx context.Context, userID, keyword string, page, limit int) ([]models.User, int64, error) {
-	return biz.store.GetFriendSuggestions(ctx, userID, keyword, page, limit)
+// Suggest trả về danh sách gợi ý đã sắp xếp theo điểm (bạn chung, nhóm chung, trò chuyện gần đây).
+// Khi hết ứng viên có điểm sẽ lấp bằng những người dùng còn lại với score = 0.
+func (biz *SuggestFriendBiz) Suggest(ctx context.Context, userID, keyword string, page, limit int) ([]friendModels.SuggestionItem, int64, error) {
+	excluded, friends, err := biz.store.GetRelatedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	mutual, err := biz.store.CountMutualFriends(ctx, userID, friends)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups, err := biz.store.CountSharedGroups(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	chats, err := biz.store.CountRecentChats(ctx, userID, time.Now().Add(-recentChatWindow))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := make(map[string]bool, len(excluded)+1)
+	skip[userID] = true
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	candidates := make(map[string]*friendModels.SuggestionItem)
+	get := func(id string) *friendModels.SuggestionItem {
+		item, ok := candidates[id]
+		if !ok {
+			item = &friendModels.SuggestionItem{UserID: id}
+			candidates[id] = item
+		}
+		return item
+	}
+	for id, n := range mutual {
+		if !skip[id] {
+			get(id).MutualFriends = n
+		}
+	}
+	for id, n := range groups {
+		if !skip[id] {
+			get(id).SharedGroups = n
+		}
+	}
+	for id, n := range chats {
+		if !skip[id] {
+			get(id).RecentChats = n
+		}
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+
+	users, err := biz.store.FindActiveUsers(ctx, ids, keyword)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ranked := make([]friendModels.SuggestionItem, 0, len(users))
+	for _, u := range users {
+		item := candidates[u.ID.Hex()]
+		item.Username = u.Username
+		item.DisplayName = u.DisplayName
+		item.Avatar = u.Avatar
+		item.Score = scoreSuggestion(item)
+		item.Reasons = explainSuggestion(item)
+		ranked = append(ranked, *item)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].UserID < ranked[j].UserID
+	})
+
+	offset := (page - 1) * limit
+	result := []friendModels.SuggestionItem{}
+	if offset < len(ranked) {
+		end := offset + limit
+		if end > len(ranked) {
+			end = len(ranked)
+		}
+		result = append(result, ranked[offset:end]...)
+	}
+
+	// Lấp đầy bằng người dùng khác (không có điểm)
+	rankedIDs := make([]string, 0, len(skip)+len(ranked))
+	for id := range skip {
+		rankedIDs = append(rankedIDs, id)
+	}
+	for _, item := range ranked {
+		rankedIDs = append(rankedIDs, item.UserID)
+	}
+
+	fillSkip := offset - len(ranked)
+	if fillSkip < 0 {
+		fillSkip = 0
+	}
+	others, otherTotal, err := biz.store.FindOtherUsers(ctx, rankedIDs, keyword, fillSkip, limit-len(result))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, u := range others {
+		result = append(result, friendModels.SuggestionItem{
+			UserID:      u.ID.Hex(),
+			Username:    u.Username,
+			DisplayName: u.DisplayName,
+			Avatar:      u.Avatar,
+			Reasons:     []string{},
+		})
+	}
+
+	return result, int64(len(ranked)) + otherTotal, nil
+}
+
+// Dismiss ẩn vĩnh viễn 1 người khỏi danh sách gợi ý
+func (biz *SuggestFriendBiz) Dismiss(ctx context.Context, userID, targetID string) error {
+	if userID == targetID {
+		return errors.New("Không thể bỏ qua chính mình")
+	}
+
+	return biz.store.DismissSuggestion(ctx, &friendModels.DismissedSuggestion{
+		UserID:          userID,
+		DismissedUserID: targetID,
+		CreatedAt:       time.Now(),
+	})
+}
+
+func scoreSuggestion(item *friendModels.SuggestionItem) float64 {
+	chats := item.RecentChats
+	if chats > maxRecentChats {
+		chats = maxRecentChats
+	}
+	return float64(item.MutualFriends)*mutualFriendWeight +
+		float64(item.SharedGroups)*sharedGroupWeight +
+		float64(chats)*recentChatWeight
+}
+
+func explainSuggestion(item *friendModels.SuggestionItem) []string {
+	reasons := []string{}
+	if item.MutualFriends > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d bạn chung", item.MutualFriends))
+	}
+	if item.SharedGroups > 0 {
+		reasons = append(reasons, fmt.Sprintf("Cùng %d nhóm với bạn", item.SharedGroups))
+	}
+	if item.RecentChats > 0 {
+		reasons = append(reasons, "Đã trò chuyện gần đây")
+	}
+	return reasons
 }
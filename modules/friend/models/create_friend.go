@@ -1,17 +1,42 @@
 package models
 
-import "my-app/common"
+import (
+	"my-app/common"
+	"time"
+)
 
 type FriendShip struct {
 	common.MongoModel `bson:",inline"`
 	UserID            string `bson:"user_id" json:"user_id"`
 	FriendID          string `bson:"friend_id" json:"friend_id"`
 	LastActionBy      string `bson:"last_action_by"`
-	Status            string `bson:"status" json:"status"` // pending, accepted, rejected, blocked
-	NickName          string `bson:"nickname,omitempty" json:"nickname,omitempty"`
+	Status            string `bson:"status" json:"status"`                                       // pending, accepted, rejected, blocked
+	NickName          string `bson:"nickname,omitempty" json:"nickname,omitempty"`               // biệt danh UserID đặt cho FriendID
+	FriendNickName    string `bson:"friend_nickname,omitempty" json:"friend_nickname,omitempty"` // biệt danh FriendID đặt cho UserID
 }
 
 type UserFriend struct {
 	UserID   string `json:"user_id" binding:"required"`
 	FriendID string `json:"friend_id" binding:"required"`
 }
+
+type NicknameRequest struct {
+	FriendID string `json:"friend_id" binding:"required"`
+	NickName string `json:"nickname" binding:"max=50"`
+}
+
+type FriendListQuery struct {
+	Type  string `form:"type,default=friends" binding:"omitempty,oneof=friends pending sent blocked"`
+	Page  int    `form:"page,default=1" binding:"min=1"`
+	Limit int    `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+type FriendItem struct {
+	UserID      string    `json:"user_id" bson:"user_id"`
+	Username    string    `json:"username" bson:"username"`
+	DisplayName string    `json:"display_name" bson:"display_name"`
+	Avatar      string    `json:"avatar" bson:"avatar"`
+	NickName    string    `json:"nickname,omitempty" bson:"nickname,omitempty"`
+	Status      string    `json:"status" bson:"status"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
@@ -1,8 +1,34 @@
 package models
 
+import "time"
+
+// SuggestFriendQuery: gợi ý luôn tính cho user trong token, không nhận user_id từ query
 type SuggestFriendQuery struct {
-	UserID  string `form:"user_id" binding:"required"`
 	Keyword string `form:"keyword"`
 	Page    int    `form:"page,default=1" binding:"min=1"`
 	Limit   int    `form:"limit,default=10" binding:"min=1,max=100"`
 }
+
+// SuggestionItem là 1 gợi ý kết bạn đã được chấm điểm
+type SuggestionItem struct {
+	UserID        string   `json:"user_id" bson:"user_id"`
+	Username      string   `json:"username" bson:"username"`
+	DisplayName   string   `json:"display_name" bson:"display_name"`
+	Avatar        string   `json:"avatar" bson:"avatar"`
+	Score         float64  `json:"score" bson:"score"`
+	MutualFriends int      `json:"mutual_friends" bson:"mutual_friends"`
+	SharedGroups  int      `json:"shared_groups" bson:"shared_groups"`
+	RecentChats   int      `json:"recent_chats" bson:"recent_chats"`
+	Reasons       []string `json:"reasons" bson:"reasons"` // VD: "5 bạn chung", "Cùng 2 nhóm với bạn"
+}
+
+// DismissedSuggestion lưu những người user không muốn được gợi ý nữa
+type DismissedSuggestion struct {
+	UserID          string    `json:"user_id" bson:"user_id"`
+	DismissedUserID string    `json:"dismissed_user_id" bson:"dismissed_user_id"`
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
+}
+
+type DismissSuggestionRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
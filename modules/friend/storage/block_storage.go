@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"my-app/modules/friend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Block tạo mới hoặc chuyển quan hệ sang StatusBlock, last_action_by là người chặn
+func (s *mongoStoreFriend) Block(ctx context.Context, blockerID, targetID string) error {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"user_id": blockerID, "friend_id": targetID},
+			{"user_id": targetID, "friend_id": blockerID},
+		},
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":         StatusBlock,
+			"last_action_by": blockerID,
+			"updated_at":     now,
+		},
+	}
+
+	result, err := s.db.Collection("friend_ship").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to block user: %v", err)
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// Chưa từng có quan hệ -> tạo mới bản ghi chặn
+	fri := &models.FriendShip{
+		UserID:       blockerID,
+		FriendID:     targetID,
+		LastActionBy: blockerID,
+		Status:       string(StatusBlock),
+	}
+	fri.ID = primitive.NewObjectID()
+	fri.CreatedAt = now
+	fri.UpdatedAt = now
+
+	_, err = s.db.Collection("friend_ship").InsertOne(ctx, fri)
+	return err
+}
+
+// IsBlocked kiểm tra 2 user có chặn nhau không (theo cả 2 chiều)
+func (s *mongoStoreFriend) IsBlocked(ctx context.Context, userA, userB string) (bool, error) {
+	filter := bson.M{
+		"status": StatusBlock,
+		"$or": []bson.M{
+			{"user_id": userA, "friend_id": userB},
+			{"user_id": userB, "friend_id": userA},
+		},
+	}
+
+	err := s.db.Collection("friend_ship").FindOne(ctx, filter).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetBlockedUserIDs trả về danh sách user đang chặn hoặc bị chặn bởi userID
+func (s *mongoStoreFriend) GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	return s.relatedUserIDs(ctx, userID, StatusBlock)
+}
+
+// GetFriendIDs lấy danh sách bạn bè (đã chấp nhận) của user
+func (s *mongoStoreFriend) GetFriendIDs(ctx context.Context, userID string) ([]string, error) {
+	return s.relatedUserIDs(ctx, userID, StatusAccepted)
+}
+
+// relatedUserIDs lấy phía còn lại của các quan hệ có trạng thái status mà userID tham gia
+func (s *mongoStoreFriend) relatedUserIDs(ctx context.Context, userID string, status FriendStatus) ([]string, error) {
+	filter := bson.M{
+		"status": status,
+		"$or": []bson.M{
+			{"user_id": userID},
+			{"friend_id": userID},
+		},
+	}
+
+	cursor, err := s.db.Collection("friend_ship").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []string
+	for cursor.Next(ctx) {
+		var f models.FriendShip
+		if err := cursor.Decode(&f); err != nil {
+			continue
+		}
+		if f.UserID == userID {
+			ids = append(ids, f.FriendID)
+		} else {
+			ids = append(ids, f.UserID)
+		}
+	}
+
+	return ids, cursor.Err()
+}
@@ -10,6 +10,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrFriendShipNotFound trả về khi 2 user chưa có bản ghi quan hệ nào (mongo.ErrNoDocuments)
+var ErrFriendShipNotFound = errors.New("Friendship not found")
+
 type mongoStoreFriend struct {
 	db *mongo.Database
 }
@@ -51,7 +54,7 @@ func (s *mongoStoreFriend) CheckFriend(ctx context.Context, userID, friendID str
 	err := s.db.Collection("friend_ship").FindOne(ctx, filter).Decode(&friendShip)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return models.FriendShip{}, errors.New("Friendship not found")
+			return models.FriendShip{}, ErrFriendShipNotFound
 		}
 		return models.FriendShip{}, err
 	}
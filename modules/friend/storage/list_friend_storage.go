@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/friend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ListFriends lấy danh sách theo loại:
+// friends: đã kết bạn, pending: lời mời nhận được, sent: lời mời đã gửi, blocked: người mình đã chặn
+func (s *mongoStoreFriend) ListFriends(ctx context.Context, userID, listType string, page, limit int) ([]models.FriendItem, int64, error) {
+	me := bson.M{"$or": []bson.M{{"user_id": userID}, {"friend_id": userID}}}
+
+	var match bson.M
+	switch listType {
+	case "pending":
+		match = bson.M{"$and": []bson.M{me, {"status": StatusPending, "last_action_by": bson.M{"$ne": userID}}}}
+	case "sent":
+		match = bson.M{"$and": []bson.M{me, {"status": StatusPending, "last_action_by": userID}}}
+	case "blocked":
+		match = bson.M{"$and": []bson.M{me, {"status": StatusBlock, "last_action_by": userID}}}
+	default:
+		match = bson.M{"$and": []bson.M{me, {"status": StatusAccepted}}}
+	}
+
+	col := s.db.Collection("friend_ship")
+
+	total, err := col.CountDocuments(ctx, match)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.M{"updated_at": -1}}},
+		{{Key: "$skip", Value: int64((page - 1) * limit)}},
+		{{Key: "$limit", Value: int64(limit)}},
+		// Xác định "người còn lại" và biệt danh mà mình đã đặt cho họ
+		{{Key: "$addFields", Value: bson.M{
+			"other_id": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$user_id", userID}}, "$friend_id", "$user_id",
+			}},
+			"my_nickname": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$user_id", userID}}, "$nickname", "$friend_nickname",
+			}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"other_oid": bson.M{"$toObjectId": "$other_id"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "other_oid",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$match", Value: bson.M{"user.is_deleted": bson.M{"$ne": true}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":          0,
+			"user_id":      "$other_id",
+			"username":     "$user.username",
+			"display_name": "$user.display_name",
+			"avatar":       "$user.avatar",
+			"nickname":     "$my_nickname",
+			"status":       "$status",
+			"updated_at":   "$updated_at",
+		}}},
+	}
+
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.FriendItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"my-app/modules/friend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetNickname lưu biệt danh vào đúng phía của người đặt:
+// user_id đặt -> nickname, friend_id đặt -> friend_nickname
+func (s *mongoStoreFriend) SetNickname(ctx context.Context, friendShip models.FriendShip, setterID, nickname string) error {
+	field := "nickname"
+	if friendShip.FriendID == setterID {
+		field = "friend_nickname"
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			field:        nickname,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := s.db.Collection("friend_ship").UpdateOne(ctx, bson.M{"_id": friendShip.ID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update nickname: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no friend record found with id %v", friendShip.ID.Hex())
+	}
+
+	return nil
+}
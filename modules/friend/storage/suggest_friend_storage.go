@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"my-app/modules/friend/models"
 	modelUser "my-app/modules/user/models"
+	"regexp"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,89 +22,288 @@ func NewMongoStore(db *mongo.Database) *mongoStore {
 	return &mongoStore{db: db}
 }
 
-func (s *mongoStore) GetFriendSuggestions(ctx context.Context, userID string, keyword string, page, limit int) ([]modelUser.User, int64, error) {
-	friendCollection := s.db.Collection("friends")
-	userCollection := s.db.Collection("users")
+// GetRelatedUserIDs trả về những người đã có quan hệ với user (bạn bè, đang chờ, chặn...)
+// cùng những người user đã bỏ qua gợi ý, và riêng danh sách bạn bè đã chấp nhận
+func (s *mongoStore) GetRelatedUserIDs(ctx context.Context, userID string) (excluded []string, friends []string, err error) {
+	cursor, err := s.db.Collection("friend_ship").Find(ctx, bson.M{
+		"$or": []bson.M{
+			{"user_id": userID},
+			{"friend_id": userID},
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var f models.FriendShip
+		if err := cursor.Decode(&f); err != nil {
+			continue
+		}
+
+		other := f.UserID
+		if other == userID {
+			other = f.FriendID
+		}
+
+		// Đã hủy / từ chối thì vẫn được gợi ý lại
+		if f.Status == string(StatusNotFriend) {
+			continue
+		}
 
-	userObjID, err := primitive.ObjectIDFromHex(userID)
+		excluded = append(excluded, other)
+		if f.Status == string(StatusAccepted) {
+			friends = append(friends, other)
+		}
+	}
+
+	dismissCursor, err := s.db.Collection("friend_suggestion_dismissed").Find(ctx, bson.M{"user_id": userID})
 	if err != nil {
-		return nil, 0, fmt.Errorf("userID không hợp lệ: %v", err)
+		return nil, nil, err
 	}
+	defer dismissCursor.Close(ctx)
 
-	// Lấy hết các friend mà user đã kết bạn
+	for dismissCursor.Next(ctx) {
+		var d models.DismissedSuggestion
+		if err := dismissCursor.Decode(&d); err == nil {
+			excluded = append(excluded, d.DismissedUserID)
+		}
+	}
 
-	var friendIDs []primitive.ObjectID
-	cursor, err := friendCollection.Find(
-		ctx, bson.M{
-			"$or": []bson.M{
-				{"user_id": userID},
-				{"friend_id": userID},
-			},
+	return excluded, friends, nil
+}
+
+// CountMutualFriends đếm số bạn chung: bạn của bạn bè mình
+func (s *mongoStore) CountMutualFriends(ctx context.Context, userID string, friendIDs []string) (map[string]int, error) {
+	result := make(map[string]int)
+	if len(friendIDs) == 0 {
+		return result, nil
+	}
+
+	cursor, err := s.db.Collection("friend_ship").Find(ctx, bson.M{
+		"status": StatusAccepted,
+		"$or": []bson.M{
+			{"user_id": bson.M{"$in": friendIDs}},
+			{"friend_id": bson.M{"$in": friendIDs}},
 		},
-	)
+	})
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	isFriend := make(map[string]bool, len(friendIDs))
+	for _, id := range friendIDs {
+		isFriend[id] = true
 	}
 
-	// nếu ta chưa có bạn bè gì hết thì bỏ qua khúc này
-	// nếu ta có bạn bè gì thì add vào 1 danh sách
 	for cursor.Next(ctx) {
 		var f models.FriendShip
-		if err := cursor.Decode(&f); err == nil {
-			// các trường FriendID/UserID trong FriendShip là string
-			if f.UserID == userID {
-				if objID, err := primitive.ObjectIDFromHex(f.FriendID); err == nil {
-					friendIDs = append(friendIDs, objID)
-				}
-			} else {
-				if objID, err := primitive.ObjectIDFromHex(f.UserID); err == nil {
-					friendIDs = append(friendIDs, objID)
-				}
-			}
+		if err := cursor.Decode(&f); err != nil {
+			continue
+		}
+
+		// Mỗi cạnh chỉ tính cho phía không phải bạn của mình
+		if isFriend[f.UserID] && f.FriendID != userID {
+			result[f.FriendID]++
+		}
+		if isFriend[f.FriendID] && f.UserID != userID {
+			result[f.UserID]++
 		}
 	}
 
-	filter := bson.M{
-		"_id":        bson.M{"$ne": userObjID}, // loại bỏ chính mình
+	return result, nil
+}
+
+// CountSharedGroups đếm số nhóm chung (thành viên nhóm nằm ở group_user_roles)
+func (s *mongoStore) CountSharedGroups(ctx context.Context, userID string) (map[string]int, error) {
+	result := make(map[string]int)
+	col := s.db.Collection("group_user_roles")
+
+	groupIDs, err := col.Distinct(ctx, "group_id", bson.M{
+		"user_id":    userID,
 		"is_deleted": bson.M{"$ne": true},
+	})
+	if err != nil {
+		return nil, err
 	}
+	if len(groupIDs) == 0 {
+		return result, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"group_id":   bson.M{"$in": groupIDs},
+			"user_id":    bson.M{"$ne": userID},
+			"is_deleted": bson.M{"$ne": true},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$user_id",
+			"groups": bson.M{"$addToSet": "$group_id"},
+		}}},
+		{{Key: "$project", Value: bson.M{"count": bson.M{"$size": "$groups"}}}},
+	}
+
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
-	if len(friendIDs) > 0 {
-		filter["_id"] = bson.M{
-			"$nin": append(friendIDs, userObjID), // loại bạn bè + bản thân
+	for cursor.Next(ctx) {
+		var row struct {
+			ID    string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err == nil {
+			result[row.ID] = row.Count
 		}
 	}
 
-	if keyword != "" {
-		filter["$or"] = []bson.M{
-			{"username": bson.M{"$regex": keyword, "$options": "i"}},
-			{"email": bson.M{"$regex": keyword, "$options": "i"}},
-			{"full_name": bson.M{"$regex": keyword, "$options": "i"}},
+	return result, nil
+}
+
+// CountRecentChats đếm số tin nhắn 1-1 qua lại với từng người kể từ `since`
+func (s *mongoStore) CountRecentChats(ctx context.Context, userID string, since time.Time) (map[string]int, error) {
+	result := make(map[string]int)
+
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("userID không hợp lệ: %v", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"created_at":  bson.M{"$gte": since},
+			"receiver_id": bson.M{"$exists": true},
+			"$or": []bson.M{
+				{"sender_id": oid},
+				{"receiver_id": oid},
+			},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"other": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$sender_id", oid}}, "$receiver_id", "$sender_id",
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$other",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := s.db.Collection("messages").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Count int                `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err == nil && !row.ID.IsZero() {
+			result[row.ID.Hex()] = row.Count
 		}
 	}
 
-	// tính tổng số
+	return result, nil
+}
+
+func keywordFilter(filter bson.M, keyword string) {
+	if keyword == "" {
+		return
+	}
+	pattern := regexp.QuoteMeta(keyword)
+	filter["$or"] = []bson.M{
+		{"username": bson.M{"$regex": pattern, "$options": "i"}},
+		{"email": bson.M{"$regex": pattern, "$options": "i"}},
+		{"display_name": bson.M{"$regex": pattern, "$options": "i"}},
+	}
+}
+
+func toObjectIDs(ids []string) []primitive.ObjectID {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	return oids
+}
+
+// FindActiveUsers lấy thông tin các ứng viên còn hoạt động và khớp từ khóa
+func (s *mongoStore) FindActiveUsers(ctx context.Context, ids []string, keyword string) ([]modelUser.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"_id":        bson.M{"$in": toObjectIDs(ids)},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	keywordFilter(filter, keyword)
+
+	cursor, err := s.db.Collection("users").Find(ctx, filter, options.Find().SetProjection(bson.M{"password": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []modelUser.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindOtherUsers lấy những người không có điểm gợi ý, dùng để lấp đầy khi ứng viên ít
+func (s *mongoStore) FindOtherUsers(ctx context.Context, excludeIDs []string, keyword string, skip, limit int) ([]modelUser.User, int64, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$nin": toObjectIDs(excludeIDs)},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	keywordFilter(filter, keyword)
+
+	userCollection := s.db.Collection("users")
+
 	total, err := userCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
+	if limit <= 0 {
+		return nil, total, nil
+	}
 
 	opts := options.Find().
-		SetSkip(int64((page - 1) * limit)).
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64(skip)).
 		SetLimit(int64(limit)).
 		SetProjection(bson.M{"password": 0}) // ẩn mật khẩu
 
-	cursorUsers, err := userCollection.Find(ctx, filter, opts)
+	cursor, err := userCollection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer cursorUsers.Close(ctx)
-	fmt.Println("cursorUsers: ", cursorUsers)
+	defer cursor.Close(ctx)
+
 	var users []modelUser.User
-	if err := cursorUsers.All(ctx, &users); err != nil {
+	if err := cursor.All(ctx, &users); err != nil {
 		return nil, 0, err
 	}
 
 	return users, total, nil
+}
 
+// DismissSuggestion ẩn vĩnh viễn 1 người khỏi danh sách gợi ý
+func (s *mongoStore) DismissSuggestion(ctx context.Context, data *models.DismissedSuggestion) error {
+	_, err := s.db.Collection("friend_suggestion_dismissed").UpdateOne(
+		ctx,
+		bson.M{"user_id": data.UserID, "dismissed_user_id": data.DismissedUserID},
+		bson.M{"$setOnInsert": data},
+		options.Update().SetUpsert(true),
+	)
+	return err
 }
@@ -0,0 +1,74 @@
+package ginFriend
+
+import (
+	"context"
+	"my-app/common"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/modules/friend/biz"
+	"my-app/modules/friend/storage"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func BlockFriendHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		friendID := c.Query("friend_id")
+		if friendID == "" {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "Thiếu friend_id", nil))
+			return
+		}
+
+		store := storage.NewMongoStoreFriend(db)
+		business := biz.NewBlockFriendBiz(store)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := business.Block(ctx, userID.(string), friendID); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		hub.InvalidateBlocks(userID.(string), friendID)
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã chặn người dùng", nil))
+	}
+}
+
+func UnblockFriendHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		friendID := c.Query("friend_id")
+		if friendID == "" {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "Thiếu friend_id", nil))
+			return
+		}
+
+		store := storage.NewMongoStoreFriend(db)
+		business := biz.NewBlockFriendBiz(store)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := business.Unblock(ctx, userID.(string), friendID); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		hub.InvalidateBlocks(userID.(string), friendID)
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã bỏ chặn người dùng", nil))
+	}
+}
@@ -2,7 +2,6 @@ package ginFriend
 
 import (
 	"context"
-	"fmt"
 	"my-app/common"
 	"my-app/modules/friend/biz"
 	"my-app/modules/friend/models"
@@ -30,9 +29,6 @@ func CreateFriendHandler(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
-		fmt.Println("user_Id_local: ", userID)
-		fmt.Println("user_Id_local: ", data.UserID)
-
 		if userID != data.UserID {
 			ctx.JSON(http.StatusUnauthorized, common.NewResponse(http.StatusBadRequest, "Bạn không phải là người gửi", nil))
 			return
@@ -0,0 +1,46 @@
+package ginFriend
+
+import (
+	"my-app/common"
+	"my-app/modules/friend/biz"
+	"my-app/modules/friend/models"
+	"my-app/modules/friend/storage"
+	"my-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func ListFriendHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var query models.FriendListQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreFriend(db)
+		business := biz.NewListFriendBiz(store)
+
+		items, total, err := business.List(c.Request.Context(), userID.(string), &query)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, common.NewResponse(http.StatusInternalServerError, err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách bạn bè thành công", gin.H{
+			"total": total,
+			"page":  query.Page,
+			"limit": query.Limit,
+			"type":  query.Type,
+			"data":  items,
+		}))
+	}
+}
@@ -0,0 +1,44 @@
+package ginFriend
+
+import (
+	"context"
+	"my-app/common"
+	"my-app/modules/friend/biz"
+	"my-app/modules/friend/models"
+	"my-app/modules/friend/storage"
+	"my-app/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func SetNicknameHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.NicknameRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreFriend(db)
+		business := biz.NewNicknameBiz(store)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := business.SetNickname(ctx, userID.(string), &req); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Cập nhật biệt danh thành công", req))
+	}
+}
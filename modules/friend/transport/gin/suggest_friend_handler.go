@@ -14,6 +14,12 @@ import (
 
 func SuggestFriendHandler(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
 		var query models.SuggestFriendQuery
 
 		// Validate cơ bản
@@ -27,7 +33,7 @@ func SuggestFriendHandler(db *mongo.Database) gin.HandlerFunc {
 		business := biz.NewSuggestFriendBiz(store)
 
 		users, total, err := business.Suggest(c.Request.Context(),
-			query.UserID, query.Keyword, query.Page, query.Limit)
+			userID.(string), query.Keyword, query.Page, query.Limit)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
@@ -42,3 +48,29 @@ func SuggestFriendHandler(db *mongo.Database) gin.HandlerFunc {
 		}))
 	}
 }
+
+func DismissSuggestionHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.DismissSuggestionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStore(db)
+		business := biz.NewSuggestFriendBiz(store)
+
+		if err := business.Dismiss(c.Request.Context(), userID.(string), req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã ẩn gợi ý", nil))
+	}
+}
@@ -0,0 +1,255 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/group/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrNotCommunity          = errors.New("nhóm không phải cộng đồng")
+	ErrChannelNotFound       = errors.New("kênh không tồn tại")
+	ErrChannelNotRestricted  = errors.New("kênh mở cho toàn bộ thành viên cộng đồng, không cần thêm/xóa thành viên")
+	ErrNotCommunityMember    = errors.New("người dùng không phải thành viên của cộng đồng")
+	ErrCannotRemoveCommunity = errors.New("không thể xóa trưởng nhóm hoặc quản trị viên cộng đồng khỏi kênh")
+)
+
+type CommunityStorage interface {
+	Create(ctx context.Context, data *models.Group) error
+	GetGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error)
+	FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error)
+	UpdateMemberRole(ctx context.Context, groupID, userID primitive.ObjectID, role string) error
+
+	SeedChannelMembers(ctx context.Context, communityID primitive.ObjectID, channel *models.Group, explicitMembers []primitive.ObjectID) error
+	AddChannelMember(ctx context.Context, communityID, channelID, userID primitive.ObjectID) error
+	RemoveChannelMember(ctx context.Context, channelID, userID primitive.ObjectID) error
+	ListUserChannels(ctx context.Context, communityID, userID primitive.ObjectID) ([]models.ChannelPreview, error)
+}
+
+type CommunityBiz struct {
+	store CommunityStorage
+}
+
+func NewCommunityBiz(store CommunityStorage) *CommunityBiz {
+	return &CommunityBiz{store: store}
+}
+
+func (biz *CommunityBiz) memberRole(ctx context.Context, groupID, userID primitive.ObjectID) string {
+	member, err := biz.store.FindMember(ctx, groupID, userID)
+	if err != nil || member == nil {
+		return ""
+	}
+	return member.Role
+}
+
+func (biz *CommunityBiz) getCommunity(ctx context.Context, communityID primitive.ObjectID) (*models.Group, error) {
+	community, err := biz.store.GetGroup(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if community.Kind != models.GroupKindCommunity {
+		return nil, ErrNotCommunity
+	}
+	return community, nil
+}
+
+// getChannel trả về kênh và cộng đồng chứa nó
+func (biz *CommunityBiz) getChannel(ctx context.Context, channelID primitive.ObjectID) (*models.Group, *models.Group, error) {
+	channel, err := biz.store.GetGroup(ctx, channelID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrChannelNotFound
+		}
+		return nil, nil, err
+	}
+	if channel.Kind != models.GroupKindChannel || channel.ParentID == nil {
+		return nil, nil, ErrChannelNotFound
+	}
+
+	community, err := biz.getCommunity(ctx, *channel.ParentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return channel, community, nil
+}
+
+// CreateCommunity tạo cộng đồng, người tạo là owner, kèm một kênh "Chung" cho mọi thành viên
+func (biz *CommunityBiz) CreateCommunity(ctx context.Context, requesterID string, req *models.CreateCommunityRequest) (*models.Group, *models.Group, error) {
+	creator, err := primitive.ObjectIDFromHex(requesterID)
+	if err != nil {
+		return nil, nil, ErrInvalidGroupOrUserID
+	}
+
+	now := time.Now()
+	community := &models.Group{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Image:       "null",
+		CreatorID:   creator,
+		Kind:        models.GroupKindCommunity,
+	}
+	community.ID = primitive.NewObjectID()
+	community.CreatedAt = now
+	community.UpdatedAt = now
+
+	if err := biz.store.Create(ctx, community); err != nil {
+		return nil, nil, err
+	}
+	if err := biz.store.UpdateMemberRole(ctx, community.ID, creator, "owner"); err != nil {
+		return nil, nil, err
+	}
+
+	channel, err := biz.createChannel(ctx, community, creator, models.DefaultChannelName, "", models.ChannelAccessInherited, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return community, channel, nil
+}
+
+// CreateChannel - owner/admin cộng đồng tạo kênh mới
+func (biz *CommunityBiz) CreateChannel(ctx context.Context, requesterID string, req *models.CreateChannelRequest) (*models.Group, error) {
+	communityID, err1 := primitive.ObjectIDFromHex(req.CommunityID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	community, err := biz.getCommunity(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if role := biz.memberRole(ctx, communityID, requester); role != "owner" && role != "admin" {
+		return nil, ErrNotGroupAdmin
+	}
+
+	access := req.Access
+	if access == "" {
+		access = models.ChannelAccessInherited
+	}
+
+	var members []primitive.ObjectID
+	for _, id := range req.MemberIDs {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			members = append(members, oid)
+		}
+	}
+
+	return biz.createChannel(ctx, community, requester, strings.TrimSpace(req.Name), strings.TrimSpace(req.Description), access, members)
+}
+
+func (biz *CommunityBiz) createChannel(ctx context.Context, community *models.Group, creator primitive.ObjectID, name, description, access string, members []primitive.ObjectID) (*models.Group, error) {
+	now := time.Now()
+	channel := &models.Group{
+		Name:          name,
+		Description:   description,
+		Image:         community.Image,
+		CreatorID:     creator,
+		Kind:          models.GroupKindChannel,
+		ParentID:      &community.ID,
+		ChannelAccess: access,
+		// Quyền trong kênh mặc định theo cài đặt của cộng đồng
+		Settings: community.Settings,
+	}
+	channel.ID = primitive.NewObjectID()
+	channel.CreatedAt = now
+	channel.UpdatedAt = now
+
+	if err := biz.store.Create(ctx, channel); err != nil {
+		return nil, err
+	}
+	if err := biz.store.SeedChannelMembers(ctx, community.ID, channel, members); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// ListChannels - các kênh user truy cập được trong cộng đồng, kèm số tin chưa đọc
+func (biz *CommunityBiz) ListChannels(ctx context.Context, requesterID, communityID string) ([]models.ChannelPreview, error) {
+	cid, err1 := primitive.ObjectIDFromHex(communityID)
+	uid, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	if _, err := biz.getCommunity(ctx, cid); err != nil {
+		return nil, err
+	}
+	if biz.memberRole(ctx, cid, uid) == "" {
+		return nil, ErrNotGroupMember
+	}
+	return biz.store.ListUserChannels(ctx, cid, uid)
+}
+
+// AddChannelMembers - owner/admin cộng đồng thêm thành viên cộng đồng vào kênh restricted.
+// Trả về danh sách user được thêm.
+func (biz *CommunityBiz) AddChannelMembers(ctx context.Context, requesterID string, req *models.ChannelMembersRequest) (*models.Group, []primitive.ObjectID, error) {
+	channel, community, err := biz.requireChannelAdmin(ctx, requesterID, req.ChannelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added := make([]primitive.ObjectID, 0, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		uid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, nil, ErrInvalidGroupOrUserID
+		}
+		if biz.memberRole(ctx, community.ID, uid) == "" {
+			return nil, nil, ErrNotCommunityMember
+		}
+		if err := biz.store.AddChannelMember(ctx, community.ID, channel.ID, uid); err != nil {
+			return nil, nil, err
+		}
+		added = append(added, uid)
+	}
+	return channel, added, nil
+}
+
+// RemoveChannelMembers - gỡ thành viên khỏi kênh restricted, owner/admin cộng đồng luôn có mặt
+func (biz *CommunityBiz) RemoveChannelMembers(ctx context.Context, requesterID string, req *models.ChannelMembersRequest) (*models.Group, []primitive.ObjectID, error) {
+	channel, community, err := biz.requireChannelAdmin(ctx, requesterID, req.ChannelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	removed := make([]primitive.ObjectID, 0, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		uid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, nil, ErrInvalidGroupOrUserID
+		}
+		if role := biz.memberRole(ctx, community.ID, uid); role == "owner" || role == "admin" {
+			return nil, nil, ErrCannotRemoveCommunity
+		}
+		if err := biz.store.RemoveChannelMember(ctx, channel.ID, uid); err != nil {
+			return nil, nil, err
+		}
+		removed = append(removed, uid)
+	}
+	return channel, removed, nil
+}
+
+func (biz *CommunityBiz) requireChannelAdmin(ctx context.Context, requesterID, channelID string) (*models.Group, *models.Group, error) {
+	chID, err1 := primitive.ObjectIDFromHex(channelID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, nil, ErrInvalidGroupOrUserID
+	}
+
+	channel, community, err := biz.getChannel(ctx, chID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role := biz.memberRole(ctx, community.ID, requester); role != "owner" && role != "admin" {
+		return nil, nil, ErrNotGroupAdmin
+	}
+	if channel.ChannelAccess != models.ChannelAccessRestricted {
+		return nil, nil, ErrChannelNotRestricted
+	}
+	return channel, community, nil
+}
@@ -10,7 +10,7 @@ import (
 
 type DissolveGroupStorage interface {
 	GetGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error)
-	DissolveGroup(ctx context.Context, groupID primitive.ObjectID) error
+	DissolveGroup(ctx context.Context, groupID, requesterID primitive.ObjectID) error
 }
 
 type dissolveGroupBiz struct {
@@ -43,8 +43,8 @@ func (biz *dissolveGroupBiz) DissolveGroup(ctx context.Context, requesterIDStr s
 		return "", errors.New("chỉ có nhóm trưởng mới có quyền giải tán nhóm")
 	}
 
-	// 3. Dissolve group
-	if err := biz.store.DissolveGroup(ctx, groupID); err != nil {
+	// 3. Dissolve group (soft, admin hệ thống có thể khôi phục trong thời gian lưu giữ)
+	if err := biz.store.DissolveGroup(ctx, groupID, requesterID); err != nil {
 		return "", err
 	}
 
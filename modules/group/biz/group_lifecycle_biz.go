@@ -0,0 +1,198 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"my-app/modules/group/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrGroupAlreadyArchived = errors.New("nhóm đã được lưu trữ")
+	ErrGroupNotArchived     = errors.New("nhóm chưa được lưu trữ")
+	ErrGroupNotDissolved    = errors.New("nhóm không tồn tại hoặc chưa bị giải tán")
+	ErrRestoreWindowExpired = errors.New("đã quá thời hạn khôi phục nhóm")
+)
+
+type GroupLifecycleStorage interface {
+	GetGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error)
+	FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error)
+	UpdateGroupProfile(ctx context.Context, groupID primitive.ObjectID, changes *models.GroupProfileChanges) error
+	SetGroupArchived(ctx context.Context, groupID, actorID primitive.ObjectID, archived bool) error
+	ListArchivedGroupsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Group, error)
+
+	FindDissolvedGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error)
+	ListDissolvedGroups(ctx context.Context, since time.Time) ([]models.DissolvedGroup, error)
+	RestoreGroup(ctx context.Context, groupID primitive.ObjectID) error
+	ListDissolvedGroupIDsBefore(ctx context.Context, before time.Time) ([]primitive.ObjectID, error)
+	PurgeGroup(ctx context.Context, groupID primitive.ObjectID) error
+}
+
+type GroupLifecycleBiz struct {
+	store     GroupLifecycleStorage
+	retention time.Duration
+}
+
+func NewGroupLifecycleBiz(store GroupLifecycleStorage, retention time.Duration) *GroupLifecycleBiz {
+	return &GroupLifecycleBiz{store: store, retention: retention}
+}
+
+func (biz *GroupLifecycleBiz) requireAdmin(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	member, err := biz.store.FindMember(ctx, groupID, userID)
+	if err != nil || member == nil || (member.Role != "owner" && member.Role != "admin") {
+		return ErrNotGroupAdmin
+	}
+	return nil
+}
+
+// RequireProfileEditor kiểm tra quyền sửa thông tin nhóm, gọi trước khi upload ảnh để không để lại file rác
+func (biz *GroupLifecycleBiz) RequireProfileEditor(ctx context.Context, requesterID, groupID string) error {
+	gid, err1 := primitive.ObjectIDFromHex(groupID)
+	uid, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return ErrInvalidGroupOrUserID
+	}
+	return biz.requireAdmin(ctx, gid, uid)
+}
+
+// UpdateProfile đổi tên/ảnh/mô tả nhóm, chỉ trả về những field thực sự thay đổi
+func (biz *GroupLifecycleBiz) UpdateProfile(ctx context.Context, requesterID string, req *models.UpdateGroupProfileRequest, imageURL *string) (*models.Group, *models.GroupProfileChanges, error) {
+	gid, err1 := primitive.ObjectIDFromHex(req.GroupID)
+	uid, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, nil, ErrInvalidGroupOrUserID
+	}
+
+	group, err := biz.store.GetGroup(ctx, gid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := biz.requireAdmin(ctx, gid, uid); err != nil {
+		return nil, nil, err
+	}
+
+	changes := &models.GroupProfileChanges{}
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name != "" && name != group.Name {
+			changes.Name = &name
+			group.Name = name
+		}
+	}
+	if req.Description != nil {
+		if desc := strings.TrimSpace(*req.Description); desc != group.Description {
+			changes.Description = &desc
+			group.Description = desc
+		}
+	}
+	if imageURL != nil && *imageURL != group.Image {
+		changes.Image = imageURL
+		group.Image = *imageURL
+	}
+
+	if changes.IsEmpty() {
+		return group, changes, nil
+	}
+	if err := biz.store.UpdateGroupProfile(ctx, gid, changes); err != nil {
+		return nil, nil, err
+	}
+	return group, changes, nil
+}
+
+// SetArchived lưu trữ/bỏ lưu trữ nhóm, nhóm lưu trữ vẫn giữ thành viên và lịch sử tin nhắn
+func (biz *GroupLifecycleBiz) SetArchived(ctx context.Context, requesterID, groupID string, archived bool) (*models.Group, error) {
+	gid, err1 := primitive.ObjectIDFromHex(groupID)
+	uid, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	group, err := biz.store.GetGroup(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+	if err := biz.requireAdmin(ctx, gid, uid); err != nil {
+		return nil, err
+	}
+
+	if archived && group.Archived {
+		return nil, ErrGroupAlreadyArchived
+	}
+	if !archived && !group.Archived {
+		return nil, ErrGroupNotArchived
+	}
+
+	if err := biz.store.SetGroupArchived(ctx, gid, uid, archived); err != nil {
+		return nil, err
+	}
+	group.Archived = archived
+	return group, nil
+}
+
+func (biz *GroupLifecycleBiz) ListArchived(ctx context.Context, userID string) ([]models.Group, error) {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+	return biz.store.ListArchivedGroupsByUser(ctx, uid)
+}
+
+// ListDissolved - nhóm đã giải tán còn có thể khôi phục
+func (biz *GroupLifecycleBiz) ListDissolved(ctx context.Context) ([]models.DissolvedGroup, error) {
+	groups, err := biz.store.ListDissolvedGroups(ctx, time.Now().Add(-biz.retention))
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].DissolvedAt != nil {
+			groups[i].RestoreUntil = groups[i].DissolvedAt.Add(biz.retention)
+		}
+	}
+	return groups, nil
+}
+
+// Restore khôi phục nhóm đã giải tán nếu còn trong thời gian lưu giữ
+func (biz *GroupLifecycleBiz) Restore(ctx context.Context, groupID string) (*models.Group, error) {
+	gid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	group, err := biz.store.FindDissolvedGroup(ctx, gid)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrGroupNotDissolved
+		}
+		return nil, err
+	}
+	if group.DissolvedAt == nil || time.Since(*group.DissolvedAt) > biz.retention {
+		return nil, ErrRestoreWindowExpired
+	}
+
+	if err := biz.store.RestoreGroup(ctx, gid); err != nil {
+		return nil, err
+	}
+	group.DissolvedAt = nil
+	group.DissolvedBy = nil
+	return group, nil
+}
+
+// PurgeExpired xóa hẳn các nhóm đã quá thời gian lưu giữ
+func (biz *GroupLifecycleBiz) PurgeExpired(ctx context.Context) (int, error) {
+	ids, err := biz.store.ListDissolvedGroupIDsBefore(ctx, time.Now().Add(-biz.retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := biz.store.PurgeGroup(ctx, id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
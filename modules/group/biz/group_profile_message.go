@@ -0,0 +1,63 @@
+package biz
+
+import (
+	"fmt"
+	"my-app/modules/group/models"
+)
+
+const (
+	GroupActionRenamed            = "group_renamed"
+	GroupActionImageChanged       = "group_image_changed"
+	GroupActionDescriptionChanged = "group_description_changed"
+	GroupActionArchived           = "group_archived"
+	GroupActionUnarchived         = "group_unarchived"
+	GroupActionRestored           = "group_restored"
+)
+
+type GroupSystemMessage struct {
+	Action  string
+	Content string
+}
+
+// BuildProfileChangeMessages tạo một tin nhắn hệ thống cho mỗi thay đổi thông tin nhóm
+func BuildProfileChangeMessages(changes *models.GroupProfileChanges, actorName string) []GroupSystemMessage {
+	var msgs []GroupSystemMessage
+
+	if changes.Name != nil {
+		msgs = append(msgs, GroupSystemMessage{
+			Action:  GroupActionRenamed,
+			Content: fmt.Sprintf("%s đã đổi tên nhóm thành \"%s\"", actorName, *changes.Name),
+		})
+	}
+	if changes.Image != nil {
+		msgs = append(msgs, GroupSystemMessage{
+			Action:  GroupActionImageChanged,
+			Content: fmt.Sprintf("%s đã đổi ảnh đại diện nhóm", actorName),
+		})
+	}
+	if changes.Description != nil {
+		content := fmt.Sprintf("%s đã cập nhật mô tả nhóm", actorName)
+		if *changes.Description == "" {
+			content = fmt.Sprintf("%s đã xóa mô tả nhóm", actorName)
+		}
+		msgs = append(msgs, GroupSystemMessage{Action: GroupActionDescriptionChanged, Content: content})
+	}
+
+	return msgs
+}
+
+// BuildLifecycleMessage tạo tin nhắn hệ thống khi nhóm được lưu trữ/bỏ lưu trữ/khôi phục
+func BuildLifecycleMessage(action, actorName string) string {
+	switch action {
+	case GroupActionArchived:
+		return fmt.Sprintf("%s đã lưu trữ nhóm", actorName)
+
+	case GroupActionUnarchived:
+		return fmt.Sprintf("%s đã bỏ lưu trữ nhóm", actorName)
+
+	case GroupActionRestored:
+		return "Nhóm đã được quản trị viên hệ thống khôi phục"
+	}
+
+	return ""
+}
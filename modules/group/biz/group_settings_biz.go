@@ -0,0 +1,123 @@
+package biz
+
+import (
+	"context"
+	chatBiz "my-app/modules/chat/biz"
+	chatModels "my-app/modules/chat/models"
+	"my-app/modules/group/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GroupSettingsStorage interface {
+	GetGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error)
+	FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error)
+	UpdateGroupSettings(ctx context.Context, groupID primitive.ObjectID, settings *chatModels.GroupSettings) error
+}
+
+type GroupSettingsBiz struct {
+	store GroupSettingsStorage
+}
+
+func NewGroupSettingsBiz(store GroupSettingsStorage) *GroupSettingsBiz {
+	return &GroupSettingsBiz{store: store}
+}
+
+func (biz *GroupSettingsBiz) parseIDs(groupID, userID string) (primitive.ObjectID, primitive.ObjectID, error) {
+	gid, err1 := primitive.ObjectIDFromHex(groupID)
+	uid, err2 := primitive.ObjectIDFromHex(userID)
+	if err1 != nil || err2 != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrInvalidGroupOrUserID
+	}
+	return gid, uid, nil
+}
+
+func (biz *GroupSettingsBiz) role(ctx context.Context, groupID, userID primitive.ObjectID) string {
+	member, err := biz.store.FindMember(ctx, groupID, userID)
+	if err != nil || member == nil {
+		return ""
+	}
+	return member.Role
+}
+
+// GetSettings - thành viên nào cũng xem được cài đặt nhóm
+func (biz *GroupSettingsBiz) GetSettings(ctx context.Context, requesterID, groupID string) (*chatModels.GroupSettings, error) {
+	gid, uid, err := biz.parseIDs(groupID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := biz.store.GetGroup(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+	if biz.role(ctx, gid, uid) == "" {
+		return nil, ErrNotGroupMember
+	}
+
+	settings := group.Settings
+	if settings.MemberAddPermission == "" {
+		settings.MemberAddPermission = chatModels.MemberAddAll
+	}
+	return &settings, nil
+}
+
+// UpdateSettings - chỉ owner/admin được đổi cài đặt
+func (biz *GroupSettingsBiz) UpdateSettings(ctx context.Context, requesterID string, req *models.UpdateGroupSettingsRequest) (*chatModels.GroupSettings, error) {
+	gid, uid, err := biz.parseIDs(req.GroupID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := biz.store.GetGroup(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+	if !chatModels.IsGroupAdminRole(biz.role(ctx, gid, uid)) {
+		return nil, ErrNotGroupAdmin
+	}
+
+	settings := group.Settings
+	if req.OnlyAdminsCanPost != nil {
+		settings.OnlyAdminsCanPost = *req.OnlyAdminsCanPost
+	}
+	if req.OnlyAdminsCanPin != nil {
+		settings.OnlyAdminsCanPin = *req.OnlyAdminsCanPin
+	}
+	if req.MemberAddPermission != nil {
+		settings.MemberAddPermission = *req.MemberAddPermission
+	}
+	if req.SlowModeSeconds != nil {
+		settings.SlowModeSeconds = *req.SlowModeSeconds
+	}
+	if req.AnnouncementMode != nil {
+		settings.AnnouncementMode = *req.AnnouncementMode
+	}
+	if settings.MemberAddPermission == "" {
+		settings.MemberAddPermission = chatModels.MemberAddAll
+	}
+
+	if err := biz.store.UpdateGroupSettings(ctx, gid, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// CanAddMember kiểm tra requester có được thêm thành viên theo cài đặt nhóm không
+func (biz *GroupSettingsBiz) CanAddMember(ctx context.Context, requesterID string, groupID primitive.ObjectID) error {
+	uid, err := primitive.ObjectIDFromHex(requesterID)
+	if err != nil {
+		return ErrInvalidGroupOrUserID
+	}
+
+	group, err := biz.store.GetGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	role := biz.role(ctx, groupID, uid)
+	if role == "" {
+		return ErrNotGroupMember
+	}
+	return chatBiz.CheckGroupMemberAdd(&group.Settings, role)
+}
@@ -0,0 +1,285 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"my-app/modules/group/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInviteNotFound       = errors.New("link mời không tồn tại")
+	ErrInviteUnusable       = errors.New("link mời đã hết hạn, hết lượt hoặc đã bị thu hồi")
+	ErrAlreadyMember        = errors.New("bạn đã là thành viên của nhóm")
+	ErrJoinRequestExists    = errors.New("bạn đã gửi yêu cầu tham gia, vui lòng chờ quản trị viên duyệt")
+	ErrJoinRequestNotFound  = errors.New("yêu cầu tham gia không tồn tại")
+	ErrJoinRequestReviewed  = errors.New("yêu cầu tham gia đã được xử lý")
+	ErrNotGroupAdmin        = errors.New("chỉ trưởng nhóm hoặc quản trị viên mới có quyền thực hiện")
+	ErrNotGroupMember       = errors.New("bạn không phải thành viên của nhóm")
+	ErrInvalidGroupOrUserID = errors.New("group_id hoặc user_id không hợp lệ")
+)
+
+type GroupInviteStorage interface {
+	GetGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error)
+	FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error)
+	UpdateMemberRole(ctx context.Context, groupID, userID primitive.ObjectID, role string) error
+
+	CreateInvite(ctx context.Context, data *models.GroupInvite) error
+	FindInviteByCode(ctx context.Context, code string) (*models.GroupInvite, error)
+	FindInviteByID(ctx context.Context, id primitive.ObjectID) (*models.GroupInvite, error)
+	ListInvites(ctx context.Context, groupID primitive.ObjectID) ([]models.GroupInvite, error)
+	RevokeInvite(ctx context.Context, id primitive.ObjectID) error
+	ConsumeInvite(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error)
+
+	CreateJoinRequest(ctx context.Context, data *models.GroupJoinRequest) error
+	FindPendingJoinRequest(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupJoinRequest, error)
+	FindJoinRequest(ctx context.Context, id primitive.ObjectID) (*models.GroupJoinRequest, error)
+	ReviewJoinRequest(ctx context.Context, id, reviewerID primitive.ObjectID, status string) (bool, error)
+	ListJoinRequests(ctx context.Context, groupID primitive.ObjectID, status string) ([]models.GroupJoinRequest, error)
+}
+
+type GroupInviteBiz struct {
+	store GroupInviteStorage
+}
+
+func NewGroupInviteBiz(store GroupInviteStorage) *GroupInviteBiz {
+	return &GroupInviteBiz{store: store}
+}
+
+// requireAdmin kiểm tra requester là owner/admin của nhóm
+func (biz *GroupInviteBiz) requireAdmin(ctx context.Context, groupID, requesterID primitive.ObjectID) error {
+	member, err := biz.store.FindMember(ctx, groupID, requesterID)
+	if err != nil || member == nil {
+		return ErrNotGroupAdmin
+	}
+	if member.Role != "owner" && member.Role != "admin" {
+		return ErrNotGroupAdmin
+	}
+	return nil
+}
+
+func (biz *GroupInviteBiz) isMember(ctx context.Context, groupID, userID primitive.ObjectID) bool {
+	member, err := biz.store.FindMember(ctx, groupID, userID)
+	return err == nil && member != nil
+}
+
+func (biz *GroupInviteBiz) CreateInvite(ctx context.Context, requesterID string, req *models.CreateInviteRequest) (*models.GroupInvite, error) {
+	groupID, err1 := primitive.ObjectIDFromHex(req.GroupID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	if _, err := biz.store.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if err := biz.requireAdmin(ctx, groupID, requester); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invite := &models.GroupInvite{
+		ID:              primitive.NewObjectID(),
+		GroupID:         groupID,
+		Code:            generateInviteCode(),
+		CreatedBy:       requester,
+		MaxUses:         req.MaxUses,
+		RequireApproval: req.RequireApproval,
+		CreatedAt:       now,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := biz.store.CreateInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (biz *GroupInviteBiz) ListInvites(ctx context.Context, requesterID, groupID string) ([]models.GroupInvite, error) {
+	gid, err1 := primitive.ObjectIDFromHex(groupID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	if err := biz.requireAdmin(ctx, gid, requester); err != nil {
+		return nil, err
+	}
+	return biz.store.ListInvites(ctx, gid)
+}
+
+func (biz *GroupInviteBiz) RevokeInvite(ctx context.Context, requesterID, inviteID string) error {
+	id, err1 := primitive.ObjectIDFromHex(inviteID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return ErrInviteNotFound
+	}
+
+	invite, err := biz.store.FindInviteByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrInviteNotFound
+		}
+		return err
+	}
+	if err := biz.requireAdmin(ctx, invite.GroupID, requester); err != nil {
+		return err
+	}
+	return biz.store.RevokeInvite(ctx, id)
+}
+
+// PreviewInvite trả về thông tin nhóm cho màn hình "tham gia nhóm"
+func (biz *GroupInviteBiz) PreviewInvite(ctx context.Context, code string) (*models.GroupInvite, *models.Group, error) {
+	invite, err := biz.store.FindInviteByCode(ctx, code)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrInviteNotFound
+		}
+		return nil, nil, err
+	}
+	if !invite.IsUsable(time.Now()) {
+		return nil, nil, ErrInviteUnusable
+	}
+
+	group, err := biz.store.GetGroup(ctx, invite.GroupID)
+	if err != nil {
+		return nil, nil, ErrInviteNotFound
+	}
+	return invite, group, nil
+}
+
+// JoinByInvite vào nhóm ngay, hoặc tạo yêu cầu chờ duyệt nếu link bật RequireApproval
+func (biz *GroupInviteBiz) JoinByInvite(ctx context.Context, userID, code, message string) (*models.JoinResult, error) {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	invite, _, err := biz.PreviewInvite(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if biz.isMember(ctx, invite.GroupID, uid) {
+		return nil, ErrAlreadyMember
+	}
+
+	result := &models.JoinResult{GroupID: invite.GroupID}
+
+	if invite.RequireApproval {
+		pending, err := biz.store.FindPendingJoinRequest(ctx, invite.GroupID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if pending != nil {
+			return nil, ErrJoinRequestExists
+		}
+
+		req := &models.GroupJoinRequest{
+			ID:        primitive.NewObjectID(),
+			GroupID:   invite.GroupID,
+			UserID:    uid,
+			InviteID:  invite.ID,
+			Message:   message,
+			Status:    models.JoinRequestPending,
+			CreatedAt: time.Now(),
+		}
+		if err := biz.store.CreateJoinRequest(ctx, req); err != nil {
+			return nil, err
+		}
+		result.Request = req
+		return result, nil
+	}
+
+	ok, err := biz.store.ConsumeInvite(ctx, invite.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInviteUnusable
+	}
+
+	if err := biz.store.UpdateMemberRole(ctx, invite.GroupID, uid, "member"); err != nil {
+		return nil, err
+	}
+	result.Joined = true
+	return result, nil
+}
+
+func (biz *GroupInviteBiz) ListJoinRequests(ctx context.Context, requesterID, groupID, status string) ([]models.GroupJoinRequest, error) {
+	gid, err1 := primitive.ObjectIDFromHex(groupID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidGroupOrUserID
+	}
+
+	if err := biz.requireAdmin(ctx, gid, requester); err != nil {
+		return nil, err
+	}
+	if status == "" {
+		status = models.JoinRequestPending
+	}
+	return biz.store.ListJoinRequests(ctx, gid, status)
+}
+
+// ReviewJoinRequest duyệt/từ chối yêu cầu. Duyệt thì thêm vào nhóm với vai trò member.
+// Admin duyệt tay nên vẫn thêm vào nhóm kể cả khi link đã hết lượt/bị thu hồi.
+func (biz *GroupInviteBiz) ReviewJoinRequest(ctx context.Context, requesterID, requestID string, approve bool) (*models.GroupJoinRequest, error) {
+	id, err1 := primitive.ObjectIDFromHex(requestID)
+	requester, err2 := primitive.ObjectIDFromHex(requesterID)
+	if err1 != nil || err2 != nil {
+		return nil, ErrJoinRequestNotFound
+	}
+
+	req, err := biz.store.FindJoinRequest(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, err
+	}
+	if err := biz.requireAdmin(ctx, req.GroupID, requester); err != nil {
+		return nil, err
+	}
+
+	status := models.JoinRequestRejected
+	if approve {
+		status = models.JoinRequestApproved
+	}
+
+	ok, err := biz.store.ReviewJoinRequest(ctx, id, requester, status)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJoinRequestReviewed
+	}
+
+	// Người dùng có thể đã được thêm vào nhóm bằng cách khác, không ghi đè vai trò hiện tại
+	if approve && !biz.isMember(ctx, req.GroupID, req.UserID) {
+		if err := biz.store.UpdateMemberRole(ctx, req.GroupID, req.UserID, "member"); err != nil {
+			return nil, err
+		}
+		_, _ = biz.store.ConsumeInvite(ctx, req.InviteID, time.Now())
+	}
+
+	now := time.Now()
+	req.Status = status
+	req.ReviewedBy = &requester
+	req.ReviewedAt = &now
+	return req, nil
+}
+
+func generateInviteCode() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
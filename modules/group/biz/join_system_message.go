@@ -0,0 +1,21 @@
+package biz
+
+import "fmt"
+
+const (
+	JoinActionInvite   = "join_via_invite"
+	JoinActionApproved = "join_request_approved"
+)
+
+// BuildJoinSystemMessage tạo nội dung tin nhắn hệ thống khi có thành viên mới vào nhóm
+func BuildJoinSystemMessage(action, memberName, reviewerName string) string {
+	switch action {
+	case JoinActionInvite:
+		return fmt.Sprintf("Người dùng %s đã tham gia nhóm bằng link mời", memberName)
+
+	case JoinActionApproved:
+		return fmt.Sprintf("Người dùng %s đã duyệt %s vào nhóm", reviewerName, memberName)
+	}
+
+	return ""
+}
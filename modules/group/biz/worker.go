@@ -0,0 +1,31 @@
+package biz
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunPurgeWorker định kỳ xóa hẳn các nhóm giải tán quá thời gian lưu giữ, dừng khi ctx bị hủy
+func RunPurgeWorker(ctx context.Context, interval time.Duration, lifecycle *GroupLifecycleBiz) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := lifecycle.PurgeExpired(ctx); err != nil {
+			log.Printf("⚠️ Group worker: xóa nhóm đã giải tán lỗi: %v", err)
+		} else if n > 0 {
+			log.Printf("🧹 Group worker: đã xóa vĩnh viễn %d nhóm", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
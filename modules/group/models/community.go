@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	GroupKindCommunity = "community"
+	GroupKindChannel   = "channel"
+
+	// ChannelAccessInherited - mọi thành viên cộng đồng đều ở trong kênh
+	ChannelAccessInherited = "inherited"
+	// ChannelAccessRestricted - chỉ thành viên được thêm (và owner/admin cộng đồng)
+	ChannelAccessRestricted = "restricted"
+
+	DefaultChannelName = "Chung"
+)
+
+type CreateCommunityRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+type CreateChannelRequest struct {
+	CommunityID string   `json:"community_id" binding:"required"`
+	Name        string   `json:"name" binding:"required,min=1,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	Access      string   `json:"access" binding:"omitempty,oneof=inherited restricted"`
+	MemberIDs   []string `json:"member_ids"`
+}
+
+type ChannelMembersRequest struct {
+	ChannelID string   `json:"channel_id" binding:"required"`
+	UserIDs   []string `json:"user_ids" binding:"required,min=1"`
+}
+
+// ChannelPreview - kênh trong cộng đồng kèm số tin chưa đọc của user
+type ChannelPreview struct {
+	ID            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name"`
+	Description   string             `json:"description"`
+	Image         string             `json:"image"`
+	Access        string             `json:"access"`
+	UnreadCount   int64              `json:"unread_count"`
+	LastMessage   string             `json:"last_message,omitempty"`
+	LastMessageAt *time.Time         `json:"last_message_at,omitempty"`
+}
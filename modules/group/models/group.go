@@ -10,10 +10,26 @@ import (
 
 type Group struct {
 	common.MongoModel `bson:",inline"`
-	Name              string             `json:"name" bson:"name"`
-	Image             string             `json:"image" bson:"image"`
-	CreatorID         primitive.ObjectID `json:"creator_id" bson:"creator_id"`
-	Status            string             `json:"status" bson:"status"`
+	Name              string               `json:"name" bson:"name"`
+	Image             string               `json:"image" bson:"image"`
+	CreatorID         primitive.ObjectID   `json:"creator_id" bson:"creator_id"`
+	Status            string               `json:"status" bson:"status"`
+	Settings          models.GroupSettings `json:"settings" bson:"settings"`
+	Description       string               `json:"description" bson:"description"`
+
+	// Cộng đồng & kênh: kênh là một Group có ParentID trỏ tới cộng đồng
+	Kind          string              `json:"kind,omitempty" bson:"kind,omitempty"`
+	ParentID      *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	ChannelAccess string              `json:"channel_access,omitempty" bson:"channel_access,omitempty"`
+
+	// Lưu trữ: ẩn khỏi danh sách nhóm nhưng vẫn giữ lịch sử, có thể bỏ lưu trữ
+	Archived   bool                `json:"archived" bson:"archived"`
+	ArchivedAt *time.Time          `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+	ArchivedBy *primitive.ObjectID `json:"archived_by,omitempty" bson:"archived_by,omitempty"`
+
+	// Giải tán: giữ lại trong thời gian lưu giữ để admin hệ thống có thể khôi phục
+	DissolvedAt *time.Time          `json:"dissolved_at,omitempty" bson:"dissolved_at,omitempty"`
+	DissolvedBy *primitive.ObjectID `json:"dissolved_by,omitempty" bson:"dissolved_by,omitempty"`
 }
 
 type RoleInfo struct {
@@ -79,3 +95,46 @@ type KafkaGroupMemberEvent struct {
 	Inviter string             `json:"inviter_id"` // Group creator / inviter
 	Created time.Time          `json:"created_at"`
 }
+
+// UpdateGroupSettingsRequest - chỉ cập nhật các field được gửi lên
+type UpdateGroupSettingsRequest struct {
+	GroupID             string  `json:"group_id" binding:"required"`
+	OnlyAdminsCanPost   *bool   `json:"only_admins_can_post"`
+	OnlyAdminsCanPin    *bool   `json:"only_admins_can_pin"`
+	MemberAddPermission *string `json:"member_add_permission" binding:"omitempty,oneof=all admins"`
+	SlowModeSeconds     *int    `json:"slow_mode_seconds" binding:"omitempty,min=0,max=3600"`
+	AnnouncementMode    *bool   `json:"announcement_mode"`
+}
+
+// UpdateGroupProfileRequest - form multipart, ảnh nhóm gửi kèm field "image"
+type UpdateGroupProfileRequest struct {
+	GroupID     string  `form:"group_id" binding:"required"`
+	Name        *string `form:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `form:"description" binding:"omitempty,max=500"`
+}
+
+// GroupProfileChanges - các thay đổi thực sự được áp dụng, dùng để tạo tin nhắn hệ thống
+type GroupProfileChanges struct {
+	Name        *string `json:"name,omitempty"`
+	Image       *string `json:"image,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+func (c *GroupProfileChanges) IsEmpty() bool {
+	return c.Name == nil && c.Image == nil && c.Description == nil
+}
+
+type ArchiveGroupRequest struct {
+	GroupID string `json:"group_id" binding:"required"`
+}
+
+// DissolvedGroup - nhóm đã giải tán còn trong thời gian khôi phục
+type DissolvedGroup struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id"`
+	Name         string              `json:"name" bson:"name"`
+	Image        string              `json:"image" bson:"image"`
+	CreatorID    primitive.ObjectID  `json:"creator_id" bson:"creator_id"`
+	DissolvedAt  *time.Time          `json:"dissolved_at" bson:"dissolved_at"`
+	DissolvedBy  *primitive.ObjectID `json:"dissolved_by" bson:"dissolved_by"`
+	RestoreUntil time.Time           `json:"restore_until" bson:"-"`
+}
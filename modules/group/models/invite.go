@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestRejected = "rejected"
+)
+
+// GroupInvite - link mời vào nhóm, có thể giới hạn thời gian và số lượt dùng
+type GroupInvite struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id"`
+	GroupID         primitive.ObjectID `json:"group_id" bson:"group_id"`
+	Code            string             `json:"code" bson:"code"`
+	CreatedBy       primitive.ObjectID `json:"created_by" bson:"created_by"`
+	ExpiresAt       *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	MaxUses         int                `json:"max_uses" bson:"max_uses"` // 0 = không giới hạn
+	UseCount        int                `json:"use_count" bson:"use_count"`
+	RequireApproval bool               `json:"require_approval" bson:"require_approval"` // true = vào nhóm phải được admin duyệt
+	Revoked         bool               `json:"revoked" bson:"revoked"`
+	RevokedAt       *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// IsUsable kiểm tra link còn dùng được không
+func (i *GroupInvite) IsUsable(now time.Time) bool {
+	if i.Revoked {
+		return false
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(now) {
+		return false
+	}
+	return i.MaxUses == 0 || i.UseCount < i.MaxUses
+}
+
+type GroupJoinRequest struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id"`
+	GroupID    primitive.ObjectID  `json:"group_id" bson:"group_id"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	InviteID   primitive.ObjectID  `json:"invite_id" bson:"invite_id"`
+	Message    string              `json:"message,omitempty" bson:"message,omitempty"`
+	Status     string              `json:"status" bson:"status"`
+	ReviewedBy *primitive.ObjectID `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time          `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+
+	// Thông tin người xin vào nhóm (chỉ dùng khi trả về)
+	DisplayName string `json:"display_name,omitempty" bson:"display_name,omitempty"`
+	Avatar      string `json:"avatar,omitempty" bson:"avatar,omitempty"`
+}
+
+type CreateInviteRequest struct {
+	GroupID         string `json:"group_id" binding:"required"`
+	ExpiresInHours  int    `json:"expires_in_hours" binding:"min=0,max=8760"` // 0 = không hết hạn
+	MaxUses         int    `json:"max_uses" binding:"min=0,max=10000"`
+	RequireApproval bool   `json:"require_approval"`
+}
+
+type JoinByInviteRequest struct {
+	Message string `json:"message" binding:"max=300"`
+}
+
+type ReviewJoinRequest struct {
+	Action string `json:"action" binding:"required,oneof=approve reject"`
+}
+
+// JoinResult - kết quả khi dùng link mời
+type JoinResult struct {
+	GroupID primitive.ObjectID `json:"group_id"`
+	Joined  bool               `json:"joined"`            // true = đã vào nhóm
+	Request *GroupJoinRequest  `json:"request,omitempty"` // != nil khi cần admin duyệt
+}
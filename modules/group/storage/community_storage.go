@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/group/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListChannels lấy các kênh (chưa giải tán) của cộng đồng
+func (s *mongoStoreGroup) ListChannels(ctx context.Context, communityID primitive.ObjectID) ([]models.Group, error) {
+	cursor, err := s.db.Collection("group").Find(ctx, bson.M{
+		"parent_id":    communityID,
+		"dissolved_at": bson.M{"$exists": false},
+	}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	channels := []models.Group{}
+	if err := cursor.All(ctx, &channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (s *mongoStoreGroup) isPrivilegedRole(ctx context.Context, roleID string) bool {
+	oid, err := primitive.ObjectIDFromHex(roleID)
+	if err != nil {
+		return false
+	}
+
+	var role struct {
+		Code string `bson:"code"`
+	}
+	if err := s.db.Collection("roles").FindOne(ctx, bson.M{"_id": oid}).Decode(&role); err != nil {
+		return false
+	}
+	return role.Code == "owner" || role.Code == "admin"
+}
+
+// upsertChannelMember thêm/cập nhật thành viên kênh với role lấy từ cộng đồng.
+// inherited=true đánh dấu dòng do đồng bộ tạo ra, để có thể gỡ khi user mất quyền ở cộng đồng.
+func (s *mongoStoreGroup) upsertChannelMember(ctx context.Context, channelID, userID primitive.ObjectID, roleID string, inherited bool) error {
+	_, err := s.db.Collection("group_user_roles").UpdateOne(ctx, bson.M{
+		"group_id": channelID.Hex(),
+		"user_id":  userID.Hex(),
+	}, bson.M{
+		"$set": bson.M{
+			"role_id":    roleID,
+			"is_deleted": false,
+			"inherited":  inherited,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": time.Now(),
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (s *mongoStoreGroup) removeChannelMember(ctx context.Context, channelID, userID primitive.ObjectID) error {
+	_, err := s.db.Collection("group_user_roles").UpdateOne(ctx, bson.M{
+		"group_id":   channelID.Hex(),
+		"user_id":    userID.Hex(),
+		"is_deleted": bson.M{"$ne": true},
+	}, bson.M{
+		"$set": bson.M{
+			"is_deleted": true,
+			"role_id":    "",
+			"updated_at": time.Now(),
+		},
+	})
+	return err
+}
+
+// SyncCommunityMember đồng bộ tư cách thành viên của user từ cộng đồng xuống các kênh:
+//   - rời cộng đồng: rời mọi kênh
+//   - kênh inherited: luôn có mặt, role theo cộng đồng
+//   - kênh restricted: owner/admin cộng đồng luôn có mặt, member chỉ giữ nếu được thêm trực tiếp
+//
+// Không làm gì nếu groupID không phải cộng đồng.
+func (s *mongoStoreGroup) SyncCommunityMember(ctx context.Context, communityID, userID primitive.ObjectID) error {
+	var community struct {
+		Kind string `bson:"kind"`
+	}
+	err := s.db.Collection("group").FindOne(ctx, bson.M{"_id": communityID}).Decode(&community)
+	if err != nil || community.Kind != models.GroupKindCommunity {
+		return nil
+	}
+
+	channels, err := s.ListChannels(ctx, communityID)
+	if err != nil || len(channels) == 0 {
+		return err
+	}
+
+	var row struct {
+		RoleID    string `bson:"role_id"`
+		IsDeleted bool   `bson:"is_deleted"`
+	}
+	err = s.db.Collection("group_user_roles").FindOne(ctx, bson.M{
+		"group_id": communityID.Hex(),
+		"user_id":  userID.Hex(),
+	}).Decode(&row)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	active := err == nil && !row.IsDeleted && row.RoleID != ""
+	privileged := active && s.isPrivilegedRole(ctx, row.RoleID)
+
+	for _, ch := range channels {
+		if !active {
+			if err := s.removeChannelMember(ctx, ch.ID, userID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if ch.ChannelAccess != models.ChannelAccessRestricted || privileged {
+			if err := s.upsertChannelMember(ctx, ch.ID, userID, row.RoleID, true); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Kênh restricted & user chỉ là member: giữ nếu được thêm trực tiếp (cập nhật role), gỡ nếu chỉ có do kế thừa
+		var chRow struct {
+			IsDeleted bool `bson:"is_deleted"`
+			Inherited bool `bson:"inherited"`
+		}
+		err := s.db.Collection("group_user_roles").FindOne(ctx, bson.M{
+			"group_id": ch.ID.Hex(),
+			"user_id":  userID.Hex(),
+		}).Decode(&chRow)
+		if err == mongo.ErrNoDocuments || chRow.IsDeleted {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if chRow.Inherited {
+			err = s.removeChannelMember(ctx, ch.ID, userID)
+		} else {
+			err = s.upsertChannelMember(ctx, ch.ID, userID, row.RoleID, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SeedChannelMembers thêm thành viên ban đầu cho kênh mới tạo
+func (s *mongoStoreGroup) SeedChannelMembers(ctx context.Context, communityID primitive.ObjectID, channel *models.Group, explicitMembers []primitive.ObjectID) error {
+	cursor, err := s.db.Collection("group_user_roles").Find(ctx, bson.M{
+		"group_id":   communityID.Hex(),
+		"is_deleted": bson.M{"$ne": true},
+		"role_id":    bson.M{"$ne": ""},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID string `bson:"user_id"`
+		RoleID string `bson:"role_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool, len(explicitMembers))
+	for _, id := range explicitMembers {
+		explicit[id.Hex()] = true
+	}
+
+	for _, r := range rows {
+		uid, err := primitive.ObjectIDFromHex(r.UserID)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case channel.ChannelAccess != models.ChannelAccessRestricted, s.isPrivilegedRole(ctx, r.RoleID):
+			err = s.upsertChannelMember(ctx, channel.ID, uid, r.RoleID, !explicit[r.UserID])
+		case explicit[r.UserID]:
+			err = s.upsertChannelMember(ctx, channel.ID, uid, r.RoleID, false)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddChannelMember thêm trực tiếp thành viên cộng đồng vào kênh restricted, role kế thừa từ cộng đồng
+func (s *mongoStoreGroup) AddChannelMember(ctx context.Context, communityID, channelID, userID primitive.ObjectID) error {
+	var row struct {
+		RoleID string `bson:"role_id"`
+	}
+	err := s.db.Collection("group_user_roles").FindOne(ctx, bson.M{
+		"group_id":   communityID.Hex(),
+		"user_id":    userID.Hex(),
+		"is_deleted": bson.M{"$ne": true},
+		"role_id":    bson.M{"$ne": ""},
+	}).Decode(&row)
+	if err != nil {
+		return err
+	}
+	return s.upsertChannelMember(ctx, channelID, userID, row.RoleID, false)
+}
+
+func (s *mongoStoreGroup) RemoveChannelMember(ctx context.Context, channelID, userID primitive.ObjectID) error {
+	return s.removeChannelMember(ctx, channelID, userID)
+}
+
+// ListUserChannels lấy các kênh user truy cập được kèm số tin chưa đọc và tin nhắn cuối
+func (s *mongoStoreGroup) ListUserChannels(ctx context.Context, communityID, userID primitive.ObjectID) ([]models.ChannelPreview, error) {
+	channels, err := s.ListChannels(ctx, communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ChannelPreview, 0, len(channels))
+	for _, ch := range channels {
+		var row struct {
+			CreatedAt time.Time `bson:"created_at"`
+		}
+		err := s.db.Collection("group_user_roles").FindOne(ctx, bson.M{
+			"group_id":   ch.ID.Hex(),
+			"user_id":    userID.Hex(),
+			"is_deleted": bson.M{"$ne": true},
+			"role_id":    bson.M{"$ne": ""},
+		}).Decode(&row)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		preview := models.ChannelPreview{
+			ID:          ch.ID,
+			Name:        ch.Name,
+			Description: ch.Description,
+			Image:       ch.Image,
+			Access:      ch.ChannelAccess,
+		}
+
+		var seen struct {
+			LastSeenMessageID primitive.ObjectID `bson:"last_seen_message_id"`
+		}
+		_ = s.db.Collection("chat_seen_status").FindOne(ctx, bson.M{
+			"conversation_id": ch.ID,
+			"user_id":         userID,
+		}).Decode(&seen)
+
+		visible := bson.M{
+			"group_id":          ch.ID,
+			"created_at":        bson.M{"$gte": row.CreatedAt},
+			"deleted_for":       bson.M{"$ne": userID},
+			"parent_message_id": bson.M{"$exists": false},
+		}
+
+		unread := bson.M{
+			"_id":       bson.M{"$gt": seen.LastSeenMessageID},
+			"sender_id": bson.M{"$ne": userID},
+			"type":      bson.M{"$ne": "system"},
+		}
+		for k, v := range visible {
+			unread[k] = v
+		}
+		if preview.UnreadCount, err = s.db.Collection("messages").CountDocuments(ctx, unread); err != nil {
+			return nil, err
+		}
+
+		var last struct {
+			Content   string    `bson:"content"`
+			CreatedAt time.Time `bson:"created_at"`
+		}
+		err = s.db.Collection("messages").FindOne(ctx, visible,
+			options.FindOne().SetSort(bson.M{"created_at": -1}),
+		).Decode(&last)
+		if err == nil {
+			preview.LastMessage = last.Content
+			preview.LastMessageAt = &last.CreatedAt
+		}
+
+		result = append(result, preview)
+	}
+	return result, nil
+}
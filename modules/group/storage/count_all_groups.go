@@ -7,7 +7,7 @@ import (
 )
 
 func (s *mongoStoreGroup) CountAllGroups(ctx context.Context) (int64, error) {
-	count, err := s.db.Collection("group").CountDocuments(ctx, bson.M{})
+	count, err := s.db.Collection("group").CountDocuments(ctx, bson.M{"dissolved_at": bson.M{"$exists": false}})
 	if err != nil {
 		return 0, err
 	}
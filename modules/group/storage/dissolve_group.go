@@ -3,33 +3,73 @@ package storage
 import (
 	"context"
 	"my-app/modules/group/models"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func (s *mongoStoreGroup) DissolveGroup(ctx context.Context, groupID primitive.ObjectID) error {
-	// 1. Delete the group from "group" collection
-	_, err := s.db.Collection("group").DeleteOne(ctx, bson.M{"_id": groupID})
+// DissolveGroup giải tán mềm: đánh dấu nhóm và vô hiệu hóa thành viên để có thể khôi phục
+// trong thời gian lưu giữ. PurgeGroup mới xóa hẳn dữ liệu.
+func (s *mongoStoreGroup) DissolveGroup(ctx context.Context, groupID, requesterID primitive.ObjectID) error {
+	now := time.Now()
+
+	// 1. Mark the group as dissolved
+	_, err := s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": groupID}, bson.M{
+		"$set": bson.M{
+			"dissolved_at": now,
+			"dissolved_by": requesterID,
+			"updated_at":   now,
+		},
+	})
 	if err != nil {
 		return err
 	}
 
-	// 2. Delete all members from "group_user_roles" collection
-	_, err = s.db.Collection("group_user_roles").DeleteMany(ctx, bson.M{"group_id": groupID.Hex()})
+	// 2. Deactivate members in "group_user_roles", keep role_id/updated_at so history is intact after restore
+	_, err = s.db.Collection("group_user_roles").UpdateMany(ctx, bson.M{
+		"group_id":   groupID.Hex(),
+		"is_deleted": bson.M{"$ne": true},
+	}, bson.M{
+		"$set": bson.M{
+			"is_deleted":           true,
+			"dissolved_with_group": true,
+		},
+	})
 	if err != nil {
 		return err
 	}
 
-	// 3. Delete from "group_members" collection (legacy)
-	_, _ = s.db.Collection("group_members").DeleteMany(ctx, bson.M{"group_id": groupID})
+	// 3. Mark "group_members" collection (legacy)
+	_, _ = s.db.Collection("group_members").UpdateMany(ctx,
+		bson.M{"group_id": groupID, "status": "active"},
+		bson.M{"$set": bson.M{"status": "dissolved"}},
+	)
+
+	// 4. Dissolving a community dissolves its channels, they are restored/purged together with it
+	channels, err := s.ListChannels(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		if err := s.DissolveGroup(ctx, ch.ID, requesterID); err != nil {
+			return err
+		}
+		_, _ = s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": ch.ID}, bson.M{
+			"$set": bson.M{"dissolved_with_parent": true},
+		})
+	}
 
 	return nil
 }
 
+// GetGroup không trả về nhóm đã giải tán
 func (s *mongoStoreGroup) GetGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error) {
 	var group models.Group
-	err := s.db.Collection("group").FindOne(ctx, bson.M{"_id": groupID}).Decode(&group)
+	err := s.db.Collection("group").FindOne(ctx, bson.M{
+		"_id":          groupID,
+		"dissolved_at": bson.M{"$exists": false},
+	}).Decode(&group)
 	if err != nil {
 		return nil, err
 	}
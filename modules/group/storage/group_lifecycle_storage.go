@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/group/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *mongoStoreGroup) UpdateGroupProfile(ctx context.Context, groupID primitive.ObjectID, changes *models.GroupProfileChanges) error {
+	set := bson.M{"updated_at": time.Now()}
+	if changes.Name != nil {
+		set["name"] = *changes.Name
+	}
+	if changes.Image != nil {
+		set["image"] = *changes.Image
+	}
+	if changes.Description != nil {
+		set["description"] = *changes.Description
+	}
+
+	_, err := s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": groupID}, bson.M{"$set": set})
+	return err
+}
+
+func (s *mongoStoreGroup) SetGroupArchived(ctx context.Context, groupID, actorID primitive.ObjectID, archived bool) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"archived":    true,
+			"archived_at": now,
+			"archived_by": actorID,
+			"updated_at":  now,
+		},
+	}
+	if !archived {
+		update = bson.M{
+			"$set":   bson.M{"archived": false, "updated_at": now},
+			"$unset": bson.M{"archived_at": "", "archived_by": ""},
+		}
+	}
+
+	_, err := s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": groupID}, update)
+	return err
+}
+
+// ListArchivedGroupsByUser lấy các nhóm đã lưu trữ mà user vẫn là thành viên
+func (s *mongoStoreGroup) ListArchivedGroupsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Group, error) {
+	groupIDs, err := s.activeGroupIDsOfUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupIDs) == 0 {
+		return []models.Group{}, nil
+	}
+
+	cursor, err := s.db.Collection("group").Find(ctx, bson.M{
+		"_id":          bson.M{"$in": groupIDs},
+		"archived":     true,
+		"dissolved_at": bson.M{"$exists": false},
+	}, options.Find().SetSort(bson.M{"archived_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []models.Group{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (s *mongoStoreGroup) activeGroupIDsOfUser(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := s.db.Collection("group_user_roles").Find(ctx, bson.M{
+		"user_id":    userID.Hex(),
+		"is_deleted": bson.M{"$ne": true},
+		"role_id":    bson.M{"$ne": ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		GroupID string `bson:"group_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		if oid, err := primitive.ObjectIDFromHex(r.GroupID); err == nil {
+			ids = append(ids, oid)
+		}
+	}
+	return ids, nil
+}
+
+func (s *mongoStoreGroup) FindDissolvedGroup(ctx context.Context, groupID primitive.ObjectID) (*models.Group, error) {
+	var group models.Group
+	err := s.db.Collection("group").FindOne(ctx, bson.M{
+		"_id":          groupID,
+		"dissolved_at": bson.M{"$exists": true},
+	}).Decode(&group)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListDissolvedGroups lấy các nhóm giải tán sau mốc since (còn trong thời gian khôi phục)
+func (s *mongoStoreGroup) ListDissolvedGroups(ctx context.Context, since time.Time) ([]models.DissolvedGroup, error) {
+	cursor, err := s.db.Collection("group").Find(ctx,
+		bson.M{"dissolved_at": bson.M{"$gt": since}, "dissolved_with_parent": bson.M{"$ne": true}},
+		options.Find().SetSort(bson.M{"dissolved_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []models.DissolvedGroup{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// RestoreGroup bỏ đánh dấu giải tán và kích hoạt lại đúng những thành viên bị vô hiệu hóa khi giải tán
+func (s *mongoStoreGroup) RestoreGroup(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": groupID}, bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"dissolved_at": "", "dissolved_by": ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Collection("group_user_roles").UpdateMany(ctx, bson.M{
+		"group_id":             groupID.Hex(),
+		"dissolved_with_group": true,
+	}, bson.M{
+		"$set":   bson.M{"is_deleted": false},
+		"$unset": bson.M{"dissolved_with_group": ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = s.db.Collection("group_members").UpdateMany(ctx,
+		bson.M{"group_id": groupID, "status": "dissolved"},
+		bson.M{"$set": bson.M{"status": "active"}},
+	)
+
+	// Kênh bị giải tán cùng cộng đồng được khôi phục theo
+	channelIDs, err := s.channelIDsDissolvedWithParent(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, id := range channelIDs {
+		if err := s.RestoreGroup(ctx, id); err != nil {
+			return err
+		}
+		_, _ = s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$unset": bson.M{"dissolved_with_parent": ""},
+		})
+	}
+	return nil
+}
+
+func (s *mongoStoreGroup) channelIDsDissolvedWithParent(ctx context.Context, parentID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := s.db.Collection("group").Find(ctx,
+		bson.M{"parent_id": parentID, "dissolved_with_parent": true},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// ListDissolvedGroupIDsBefore lấy các nhóm đã hết thời gian khôi phục
+func (s *mongoStoreGroup) ListDissolvedGroupIDsBefore(ctx context.Context, before time.Time) ([]primitive.ObjectID, error) {
+	cursor, err := s.db.Collection("group").Find(ctx,
+		bson.M{"dissolved_at": bson.M{"$lte": before}, "dissolved_with_parent": bson.M{"$ne": true}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// PurgeGroup xóa hẳn nhóm đã giải tán quá thời gian lưu giữ. Document nhóm bị xóa cuối cùng: lỗi giữa chừng
+// thì nhóm vẫn còn trong ListDissolvedGroupIDsBefore và lần chạy sau xóa tiếp phần còn lại
+func (s *mongoStoreGroup) PurgeGroup(ctx context.Context, groupID primitive.ObjectID) error {
+	n, err := s.db.Collection("group").CountDocuments(ctx, bson.M{
+		"_id":          groupID,
+		"dissolved_at": bson.M{"$exists": true},
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	// Kênh con giải tán cùng nhóm chỉ tìm được qua parent_id, phải xóa trước nhóm cha
+	channelIDs, err := s.channelIDsDissolvedWithParent(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, id := range channelIDs {
+		if err := s.PurgeGroup(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.Collection("group_user_roles").DeleteMany(ctx, bson.M{"group_id": groupID.Hex()})
+	if err != nil {
+		return err
+	}
+
+	_, _ = s.db.Collection("group_members").DeleteMany(ctx, bson.M{"group_id": groupID})
+	_, _ = s.db.Collection("group_invites").DeleteMany(ctx, bson.M{"group_id": groupID})
+	_, _ = s.db.Collection("group_join_requests").DeleteMany(ctx, bson.M{"group_id": groupID})
+
+	_, err = s.db.Collection("group").DeleteOne(ctx, bson.M{
+		"_id":          groupID,
+		"dissolved_at": bson.M{"$exists": true},
+	})
+	return err
+}
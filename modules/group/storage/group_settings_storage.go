@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	chatModels "my-app/modules/chat/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *mongoStoreGroup) UpdateGroupSettings(ctx context.Context, groupID primitive.ObjectID, settings *chatModels.GroupSettings) error {
+	_, err := s.db.Collection("group").UpdateOne(ctx, bson.M{"_id": groupID}, bson.M{
+		"$set": bson.M{
+			"settings":   settings,
+			"updated_at": time.Now(),
+		},
+	})
+	return err
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/group/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *mongoStoreGroup) CreateInvite(ctx context.Context, data *models.GroupInvite) error {
+	_, err := s.db.Collection("group_invites").InsertOne(ctx, data)
+	return err
+}
+
+func (s *mongoStoreGroup) FindInviteByCode(ctx context.Context, code string) (*models.GroupInvite, error) {
+	var invite models.GroupInvite
+	if err := s.db.Collection("group_invites").FindOne(ctx, bson.M{"code": code}).Decode(&invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (s *mongoStoreGroup) FindInviteByID(ctx context.Context, id primitive.ObjectID) (*models.GroupInvite, error) {
+	var invite models.GroupInvite
+	if err := s.db.Collection("group_invites").FindOne(ctx, bson.M{"_id": id}).Decode(&invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (s *mongoStoreGroup) ListInvites(ctx context.Context, groupID primitive.ObjectID) ([]models.GroupInvite, error) {
+	cursor, err := s.db.Collection("group_invites").Find(ctx,
+		bson.M{"group_id": groupID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invites := []models.GroupInvite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (s *mongoStoreGroup) RevokeInvite(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	_, err := s.db.Collection("group_invites").UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"revoked": true, "revoked_at": now},
+	})
+	return err
+}
+
+// ConsumeInvite tăng số lượt dùng nếu link còn hiệu lực, trả về false nếu hết lượt/hết hạn/đã thu hồi.
+// Điều kiện nằm trong filter nên an toàn khi nhiều người dùng link cùng lúc.
+func (s *mongoStoreGroup) ConsumeInvite(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error) {
+	res, err := s.db.Collection("group_invites").UpdateOne(ctx, bson.M{
+		"_id":     id,
+		"revoked": false,
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"expires_at": bson.M{"$exists": false}},
+				{"expires_at": bson.M{"$gt": now}},
+			}},
+			{"$or": []bson.M{
+				{"max_uses": 0},
+				{"$expr": bson.M{"$lt": bson.A{"$use_count", "$max_uses"}}},
+			}},
+		},
+	}, bson.M{"$inc": bson.M{"use_count": 1}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (s *mongoStoreGroup) CreateJoinRequest(ctx context.Context, data *models.GroupJoinRequest) error {
+	_, err := s.db.Collection("group_join_requests").InsertOne(ctx, data)
+	return err
+}
+
+func (s *mongoStoreGroup) FindPendingJoinRequest(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupJoinRequest, error) {
+	var req models.GroupJoinRequest
+	err := s.db.Collection("group_join_requests").FindOne(ctx, bson.M{
+		"group_id": groupID,
+		"user_id":  userID,
+		"status":   models.JoinRequestPending,
+	}).Decode(&req)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (s *mongoStoreGroup) FindJoinRequest(ctx context.Context, id primitive.ObjectID) (*models.GroupJoinRequest, error) {
+	var req models.GroupJoinRequest
+	if err := s.db.Collection("group_join_requests").FindOne(ctx, bson.M{"_id": id}).Decode(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ReviewJoinRequest chỉ cập nhật yêu cầu còn pending, tránh 2 admin duyệt trùng
+func (s *mongoStoreGroup) ReviewJoinRequest(ctx context.Context, id, reviewerID primitive.ObjectID, status string) (bool, error) {
+	now := time.Now()
+	res, err := s.db.Collection("group_join_requests").UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": models.JoinRequestPending,
+	}, bson.M{"$set": bson.M{
+		"status":      status,
+		"reviewed_by": reviewerID,
+		"reviewed_at": now,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (s *mongoStoreGroup) ListJoinRequests(ctx context.Context, groupID primitive.ObjectID, status string) ([]models.GroupJoinRequest, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"group_id": groupID, "status": status}}},
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$addFields", Value: bson.M{
+			"display_name": "$user.display_name",
+			"avatar":       "$user.avatar",
+		}}},
+		{{Key: "$project", Value: bson.M{"user": 0}}},
+	}
+
+	cursor, err := s.db.Collection("group_join_requests").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	requests := []models.GroupJoinRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ListGroupAdminIDs lấy owner + admin của nhóm để gửi thông báo duyệt
+func (s *mongoStoreGroup) ListGroupAdminIDs(ctx context.Context, groupID primitive.ObjectID) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"group_id":   groupID.Hex(),
+			"is_deleted": bson.M{"$ne": true},
+			"role_id":    bson.M{"$ne": ""},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "roles",
+			"let":  bson.M{"rid": "$role_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{
+					"$eq": bson.A{"$_id", bson.M{"$toObjectId": "$$rid"}},
+				}}}},
+			},
+			"as": "role",
+		}}},
+		{{Key: "$unwind", Value: "$role"}},
+		{{Key: "$match", Value: bson.M{"role.code": bson.M{"$in": bson.A{"owner", "admin"}}}}},
+		{{Key: "$project", Value: bson.M{"user_id": 1}}},
+	}
+
+	cursor, err := s.db.Collection("group_user_roles").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID string `bson:"user_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.UserID)
+	}
+	return ids, nil
+}
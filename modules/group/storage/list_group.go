@@ -58,8 +58,12 @@ func (s *mongoStoreGroup) ListGroupsByUser(
 		SetSkip(skip).
 		SetSort(bson.M{"created_at": -1})
 
+	// archived groups are listed separately, dissolved groups are hidden until restored
 	groupCursor, err := groupColl.Find(ctx, bson.M{
-		"_id": bson.M{"$in": groupIDs},
+		"_id":          bson.M{"$in": groupIDs},
+		"archived":     bson.M{"$ne": true},
+		"dissolved_at": bson.M{"$exists": false},
+		"parent_id":    bson.M{"$exists": false}, // channels are listed inside their community
 	}, findOptions)
 	if err != nil {
 		return nil, err
@@ -118,7 +122,7 @@ func (s *mongoStoreGroup) ListAllGroupsWithStats(
 	}
 
 	// 3. Match conditions
-	match := bson.M{}
+	match := bson.M{"dissolved_at": bson.M{"$exists": false}}
 	if search != "" {
 		match["name"] = bson.M{"$regex": search, "$options": "i"}
 	}
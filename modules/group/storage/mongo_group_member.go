@@ -38,8 +38,12 @@ func (s *mongoStoreGroup) RemoveMember(ctx context.Context, groupID, userID prim
 		"group_id": groupID,
 		"user_id":  userID,
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Leaving a community also removes the user from its channels
+	return s.SyncCommunityMember(ctx, groupID, userID)
 }
 
 func (s *mongoStoreGroup) UpdateGroupCreator(ctx context.Context, groupID, newCreatorID primitive.ObjectID) error {
@@ -108,7 +112,8 @@ func (s *mongoStoreGroup) UpdateMemberRole(ctx context.Context, groupID, userID
 		"user_id":  userID,
 	}, bson.M{"$set": bson.M{"role": roleCode}})
 
-	return nil
+	// 4. Cascade membership & role to community channels
+	return s.SyncCommunityMember(ctx, groupID, userID)
 }
 
 func (s *mongoStoreGroup) FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error) {
@@ -0,0 +1,174 @@
+package ginGroup
+
+import (
+	"errors"
+	"my-app/common"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/modules/group/biz"
+	"my-app/modules/group/models"
+	"my-app/modules/group/storage"
+	"my-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateCommunityHandler - tạo cộng đồng kèm kênh "Chung"
+func CreateCommunityHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateCommunityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		requesterID := c.GetString("userID")
+		if requesterID == "" {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewCommunityBiz(store)
+
+		community, channel, err := business.CreateCommunity(c.Request.Context(), requesterID, &req)
+		if err != nil {
+			writeCommunityError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Tạo cộng đồng thành công", gin.H{
+			"community": community,
+			"channels":  []*models.Group{channel},
+		}))
+	}
+}
+
+// CreateChannelHandler - owner/admin cộng đồng tạo kênh
+func CreateChannelHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateChannelRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewCommunityBiz(store)
+
+		channel, err := business.CreateChannel(c.Request.Context(), c.GetString("userID"), &req)
+		if err != nil {
+			writeCommunityError(c, err)
+			return
+		}
+
+		// Chỉ thành viên của kênh nhận được sự kiện
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "community_channel_created",
+			Payload: map[string]interface{}{
+				"group_id":     channel.ID.Hex(),
+				"community_id": req.CommunityID,
+				"name":         channel.Name,
+				"description":  channel.Description,
+				"access":       channel.ChannelAccess,
+			},
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Tạo kênh thành công", channel))
+	}
+}
+
+// ListChannelsHandler - kênh user truy cập được trong cộng đồng
+func ListChannelsHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewCommunityBiz(store)
+
+		channels, err := business.ListChannels(c.Request.Context(), c.GetString("userID"), c.Query("community_id"))
+		if err != nil {
+			writeCommunityError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách kênh thành công", channels))
+	}
+}
+
+// AddChannelMembersHandler - thêm thành viên vào kênh restricted
+func AddChannelMembersHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ChannelMembersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewCommunityBiz(store)
+
+		channel, added, err := business.AddChannelMembers(c.Request.Context(), c.GetString("userID"), &req)
+		if err != nil {
+			writeCommunityError(c, err)
+			return
+		}
+
+		broadcastChannelMembers(hub, channel, "added", added)
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Thêm thành viên vào kênh thành công", added))
+	}
+}
+
+// RemoveChannelMembersHandler - gỡ thành viên khỏi kênh restricted
+func RemoveChannelMembersHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ChannelMembersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewCommunityBiz(store)
+
+		channel, removed, err := business.RemoveChannelMembers(c.Request.Context(), c.GetString("userID"), &req)
+		if err != nil {
+			writeCommunityError(c, err)
+			return
+		}
+
+		broadcastChannelMembers(hub, channel, "removed", removed)
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã gỡ thành viên khỏi kênh", removed))
+	}
+}
+
+func broadcastChannelMembers(hub *websocket.Hub, channel *models.Group, action string, userIDs []primitive.ObjectID) {
+	ids := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		ids = append(ids, id.Hex())
+	}
+
+	hub.Broadcast <- websocket.HubEvent{
+		Type: "channel_members_changed",
+		Payload: map[string]interface{}{
+			"group_id":     channel.ID.Hex(),
+			"community_id": channel.ParentID.Hex(),
+			"action":       action,
+			"user_ids":     ids,
+			"recipients":   ids,
+		},
+	}
+}
+
+func writeCommunityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, biz.ErrNotGroupAdmin), errors.Is(err, biz.ErrNotGroupMember), errors.Is(err, biz.ErrCannotRemoveCommunity):
+		c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, biz.ErrChannelNotFound), errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, biz.ErrChannelNotRestricted):
+		c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
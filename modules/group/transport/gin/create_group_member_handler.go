@@ -26,6 +26,15 @@ func CreateGroupMemberHandler(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
+		// Nhóm chỉ cho admin thêm thành viên thì chặn ở đây
+		if requesterID := c.GetString("userID"); requesterID != "" {
+			settingsBiz := biz.NewGroupSettingsBiz(storage.NewMongoStoreGroup(db))
+			if err := settingsBiz.CanAddMember(c.Request.Context(), requesterID, body.GroupID); err != nil {
+				c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+				return
+			}
+		}
+
 		body.Role = "member" // Normalize to 'number' for new members
 
 		body.Status = "active"
@@ -0,0 +1,255 @@
+package ginGroup
+
+import (
+	"context"
+	"errors"
+	"my-app/common"
+	modelsChat "my-app/modules/chat/models"
+	storageChat "my-app/modules/chat/storage"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/modules/group/biz"
+	"my-app/modules/group/models"
+	"my-app/modules/group/storage"
+	"my-app/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UpdateGroupProfileHandler - owner/admin đổi tên, ảnh, mô tả nhóm (multipart form)
+func UpdateGroupProfileHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateGroupProfileRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		requesterID := c.GetString("userID")
+		if requesterID == "" {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupLifecycleBiz(store, 0)
+
+		if err := business.RequireProfileEditor(c.Request.Context(), requesterID, req.GroupID); err != nil {
+			writeGroupLifecycleError(c, err)
+			return
+		}
+
+		var imageURL *string
+		if image, _ := c.FormFile("image"); image != nil {
+			if err := utils.ValidateAndSaveFile(image); err != nil {
+				c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+				return
+			}
+
+			file, err := image.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+				return
+			}
+			defer file.Close()
+
+			url, err := utils.UploadFileToMinio(file, image)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, common.ErrDB(err))
+				return
+			}
+			imageURL = &url
+		}
+
+		group, changes, err := business.UpdateProfile(c.Request.Context(), requesterID, &req, imageURL)
+		if err != nil {
+			writeGroupLifecycleError(c, err)
+			return
+		}
+
+		if !changes.IsEmpty() {
+			ctx := c.Request.Context()
+			actorID, _ := primitive.ObjectIDFromHex(requesterID)
+			actorName := userDisplayName(ctx, db, actorID)
+
+			for _, m := range biz.BuildProfileChangeMessages(changes, actorName) {
+				saveGroupSystemMessage(ctx, db, hub, group.ID, actorID, actorName, m.Content, m.Action)
+			}
+
+			hub.Broadcast <- websocket.HubEvent{
+				Type: "group_updated",
+				Payload: map[string]interface{}{
+					"group_id":   group.ID.Hex(),
+					"changes":    changes,
+					"updated_by": requesterID,
+				},
+			}
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Cập nhật thông tin nhóm thành công", group))
+	}
+}
+
+// SetGroupArchivedHandler - owner/admin lưu trữ (archived=true) hoặc bỏ lưu trữ nhóm
+func SetGroupArchivedHandler(db *mongo.Database, hub *websocket.Hub, archived bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ArchiveGroupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		requesterID := c.GetString("userID")
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupLifecycleBiz(store, 0)
+
+		group, err := business.SetArchived(c.Request.Context(), requesterID, req.GroupID, archived)
+		if err != nil {
+			writeGroupLifecycleError(c, err)
+			return
+		}
+
+		action, eventType, message := biz.GroupActionArchived, "group_archived", "Đã lưu trữ nhóm"
+		if !archived {
+			action, eventType, message = biz.GroupActionUnarchived, "group_unarchived", "Đã bỏ lưu trữ nhóm"
+		}
+
+		ctx := c.Request.Context()
+		actorID, _ := primitive.ObjectIDFromHex(requesterID)
+		actorName := userDisplayName(ctx, db, actorID)
+		saveGroupSystemMessage(ctx, db, hub, group.ID, actorID, actorName, biz.BuildLifecycleMessage(action, actorName), action)
+
+		hub.Broadcast <- websocket.HubEvent{
+			Type: eventType,
+			Payload: map[string]interface{}{
+				"group_id":   group.ID.Hex(),
+				"updated_by": requesterID,
+			},
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, message, group))
+	}
+}
+
+// ListArchivedGroupsHandler - danh sách nhóm đã lưu trữ của user
+func ListArchivedGroupsHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupLifecycleBiz(store, 0)
+
+		groups, err := business.ListArchived(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			writeGroupLifecycleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách nhóm đã lưu trữ thành công", groups))
+	}
+}
+
+// ListDissolvedGroupsHandler - admin hệ thống xem các nhóm đã giải tán còn khôi phục được
+func ListDissolvedGroupsHandler(db *mongo.Database, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupLifecycleBiz(store, retention)
+
+		groups, err := business.ListDissolved(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, common.ErrDB(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách nhóm đã giải tán thành công", groups))
+	}
+}
+
+// RestoreGroupHandler - admin hệ thống khôi phục nhóm đã giải tán trong thời gian lưu giữ
+func RestoreGroupHandler(db *mongo.Database, hub *websocket.Hub, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupLifecycleBiz(store, retention)
+
+		group, err := business.Restore(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			writeGroupLifecycleError(c, err)
+			return
+		}
+
+		// Admin hệ thống không phải thành viên nhóm nên tin nhắn do hệ thống gửi
+		saveGroupSystemMessage(c.Request.Context(), db, hub, group.ID, modelsChat.SystemSenderID, modelsChat.SystemSenderName,
+			biz.BuildLifecycleMessage(biz.GroupActionRestored, ""), biz.GroupActionRestored)
+
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "group_restored",
+			Payload: map[string]interface{}{
+				"group_id":     group.ID.Hex(),
+				"display_name": group.Name,
+				"avatar":       group.Image,
+				"restored_by":  c.GetString("userID"),
+			},
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Khôi phục nhóm thành công", group))
+	}
+}
+
+func userDisplayName(ctx context.Context, db *mongo.Database, userID primitive.ObjectID) string {
+	if user, err := storageChat.NewMongoChatStore(db).GetUserById(ctx, userID); err == nil && user != nil {
+		return user.DisplayName
+	}
+	return userID.Hex()
+}
+
+// saveGroupSystemMessage lưu tin nhắn hệ thống vào nhóm và phát tới các thành viên
+func saveGroupSystemMessage(ctx context.Context, db *mongo.Database, hub *websocket.Hub, groupID, actorID primitive.ObjectID, actorName, content, action string) {
+	msg := &modelsChat.Message{
+		ID:           primitive.NewObjectID(),
+		SenderID:     actorID,
+		GroupID:      groupID,
+		Content:      content,
+		Type:         "system",
+		CreatedAt:    time.Now(),
+		Status:       modelsChat.StatusSent,
+		SystemAction: action,
+	}
+
+	if err := storageChat.NewMongoChatStore(db).SaveMessage(ctx, msg); err != nil {
+		return
+	}
+
+	hub.Broadcast <- websocket.HubEvent{
+		Type: "chat",
+		Payload: &modelsChat.MessageResponse{
+			ID:           msg.ID,
+			SenderID:     msg.SenderID,
+			GroupID:      msg.GroupID,
+			Content:      msg.Content,
+			Type:         msg.Type,
+			CreatedAt:    msg.CreatedAt,
+			Status:       msg.Status,
+			SenderName:   actorName,
+			SystemAction: msg.SystemAction,
+		},
+	}
+}
+
+func writeGroupLifecycleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, biz.ErrNotGroupAdmin):
+		c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, biz.ErrGroupNotDissolved):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, "Nhóm không tồn tại", nil))
+	case errors.Is(err, biz.ErrRestoreWindowExpired):
+		c.JSON(http.StatusGone, common.NewResponse(http.StatusGone, err.Error(), nil))
+	case errors.Is(err, biz.ErrGroupAlreadyArchived), errors.Is(err, biz.ErrGroupNotArchived):
+		c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
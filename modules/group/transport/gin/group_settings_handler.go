@@ -0,0 +1,75 @@
+package ginGroup
+
+import (
+	"errors"
+	"my-app/common"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/modules/group/biz"
+	"my-app/modules/group/models"
+	"my-app/modules/group/storage"
+	"my-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetGroupSettingsHandler - xem cài đặt nhóm
+func GetGroupSettingsHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupSettingsBiz(store)
+
+		settings, err := business.GetSettings(c.Request.Context(), c.GetString("userID"), c.Query("group_id"))
+		if err != nil {
+			writeGroupSettingsError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy cài đặt nhóm thành công", settings))
+	}
+}
+
+// UpdateGroupSettingsHandler - owner/admin đổi cài đặt, báo realtime cho thành viên
+func UpdateGroupSettingsHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateGroupSettingsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		requesterID := c.GetString("userID")
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupSettingsBiz(store)
+
+		settings, err := business.UpdateSettings(c.Request.Context(), requesterID, &req)
+		if err != nil {
+			writeGroupSettingsError(c, err)
+			return
+		}
+
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "group_settings_updated",
+			Payload: map[string]interface{}{
+				"group_id":   req.GroupID,
+				"settings":   settings,
+				"updated_by": requesterID,
+			},
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Cập nhật cài đặt nhóm thành công", settings))
+	}
+}
+
+func writeGroupSettingsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, biz.ErrNotGroupAdmin), errors.Is(err, biz.ErrNotGroupMember):
+		c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, "Nhóm không tồn tại", nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
@@ -0,0 +1,318 @@
+package ginGroup
+
+import (
+	"context"
+	"errors"
+	"my-app/common"
+	modelsChat "my-app/modules/chat/models"
+	storageChat "my-app/modules/chat/storage"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/modules/group/biz"
+	"my-app/modules/group/models"
+	"my-app/modules/group/storage"
+	bizUser "my-app/modules/user/biz"
+	modelsUser "my-app/modules/user/models"
+	storageUser "my-app/modules/user/storage"
+	"my-app/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateInviteHandler - owner/admin tạo link mời
+func CreateInviteHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateInviteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		invite, err := business.CreateInvite(c.Request.Context(), c.GetString("userID"), &req)
+		if err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Tạo link mời thành công", invite))
+	}
+}
+
+func ListInvitesHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		invites, err := business.ListInvites(c.Request.Context(), c.GetString("userID"), c.Query("group_id"))
+		if err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách link mời thành công", invites))
+	}
+}
+
+func RevokeInviteHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		if err := business.RevokeInvite(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã thu hồi link mời", true))
+	}
+}
+
+// PreviewInviteHandler - xem thông tin nhóm trước khi tham gia
+func PreviewInviteHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		invite, group, err := business.PreviewInvite(c.Request.Context(), c.Param("code"))
+		if err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy thông tin link mời thành công", gin.H{
+			"group_id":         group.ID,
+			"name":             group.Name,
+			"image":            group.Image,
+			"require_approval": invite.RequireApproval,
+			"expires_at":       invite.ExpiresAt,
+		}))
+	}
+}
+
+// JoinByInviteHandler - tham gia nhóm bằng link mời
+func JoinByInviteHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.JoinByInviteRequest
+		if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		result, err := business.JoinByInvite(c.Request.Context(), userID, c.Param("code"), req.Message)
+		if err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		userOID, _ := primitive.ObjectIDFromHex(userID)
+
+		if result.Request != nil {
+			notifyJoinRequest(c.Request.Context(), db, hub, result.Request)
+			c.JSON(http.StatusAccepted, common.NewResponse(http.StatusAccepted, "Đã gửi yêu cầu tham gia, vui lòng chờ quản trị viên duyệt", result))
+			return
+		}
+
+		announceMemberJoined(c.Request.Context(), db, hub, result.GroupID, userOID, userOID, biz.JoinActionInvite)
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Tham gia nhóm thành công", result))
+	}
+}
+
+func ListJoinRequestsHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		requests, err := business.ListJoinRequests(c.Request.Context(), c.GetString("userID"), c.Query("group_id"), c.Query("status"))
+		if err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy danh sách yêu cầu tham gia thành công", requests))
+	}
+}
+
+// ReviewJoinRequestHandler - admin duyệt hoặc từ chối yêu cầu tham gia
+func ReviewJoinRequestHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body models.ReviewJoinRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		reviewerID := c.GetString("userID")
+
+		store := storage.NewMongoStoreGroup(db)
+		business := biz.NewGroupInviteBiz(store)
+
+		req, err := business.ReviewJoinRequest(c.Request.Context(), reviewerID, c.Param("id"), body.Action == "approve")
+		if err != nil {
+			writeInviteError(c, err)
+			return
+		}
+
+		if req.Status == models.JoinRequestApproved {
+			reviewerOID, _ := primitive.ObjectIDFromHex(reviewerID)
+			announceMemberJoined(c.Request.Context(), db, hub, req.GroupID, req.UserID, reviewerOID, biz.JoinActionApproved)
+		}
+
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "group_join_request_reviewed",
+			Payload: map[string]interface{}{
+				"recipients": []string{req.UserID.Hex()},
+				"request_id": req.ID.Hex(),
+				"group_id":   req.GroupID.Hex(),
+				"status":     req.Status,
+			},
+		}
+
+		message := "Đã từ chối yêu cầu tham gia"
+		if req.Status == models.JoinRequestApproved {
+			message = "Đã duyệt yêu cầu tham gia"
+		}
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, message, req))
+	}
+}
+
+// notifyJoinRequest gửi thông báo realtime cho owner/admin của nhóm
+func notifyJoinRequest(ctx context.Context, db *mongo.Database, hub *websocket.Hub, req *models.GroupJoinRequest) {
+	adminIDs, err := storage.NewMongoStoreGroup(db).ListGroupAdminIDs(ctx, req.GroupID)
+	if err != nil || len(adminIDs) == 0 {
+		return
+	}
+
+	displayName := req.UserID.Hex()
+	avatar := ""
+	if user, err := storageChat.NewMongoChatStore(db).GetUserById(ctx, req.UserID); err == nil && user != nil {
+		displayName = user.DisplayName
+		avatar = user.Avatar
+	}
+
+	hub.Broadcast <- websocket.HubEvent{
+		Type: "group_join_request",
+		Payload: map[string]interface{}{
+			"recipients":   adminIDs,
+			"request_id":   req.ID.Hex(),
+			"group_id":     req.GroupID.Hex(),
+			"user_id":      req.UserID.Hex(),
+			"display_name": displayName,
+			"avatar":       avatar,
+			"message":      req.Message,
+			"created_at":   req.CreatedAt,
+		},
+	}
+}
+
+// announceMemberJoined tạo cài đặt chat, tin nhắn hệ thống và báo cho client khi có thành viên mới
+func announceMemberJoined(ctx context.Context, db *mongo.Database, hub *websocket.Hub, groupID, userID, actorID primitive.ObjectID, action string) {
+	storeUser := storageUser.NewMongoStore(db)
+	_ = bizUser.NewUpdateSettingBiz(storeUser).UpsertSetting(ctx, &modelsUser.UserChatSettingRequest{
+		UserID:   userID,
+		TargetID: groupID,
+		IsGroup:  true,
+		IsMuted:  false,
+	})
+
+	chatStore := storageChat.NewMongoChatStore(db)
+	nameOf := func(id primitive.ObjectID) string {
+		if user, err := chatStore.GetUserById(ctx, id); err == nil && user != nil {
+			return user.DisplayName
+		}
+		return id.Hex()
+	}
+
+	memberName := nameOf(userID)
+	actorName := memberName
+	if actorID != userID {
+		actorName = nameOf(actorID)
+	}
+
+	now := time.Now()
+	msg := &modelsChat.Message{
+		ID:           primitive.NewObjectID(),
+		SenderID:     actorID,
+		GroupID:      groupID,
+		Content:      biz.BuildJoinSystemMessage(action, memberName, actorName),
+		Type:         "system",
+		CreatedAt:    now,
+		Status:       modelsChat.StatusSent,
+		SystemAction: action,
+	}
+
+	if err := chatStore.SaveMessage(ctx, msg); err == nil {
+		hub.Broadcast <- websocket.HubEvent{
+			Type: "chat",
+			Payload: &modelsChat.MessageResponse{
+				ID:           msg.ID,
+				SenderID:     msg.SenderID,
+				GroupID:      msg.GroupID,
+				Content:      msg.Content,
+				Type:         msg.Type,
+				CreatedAt:    msg.CreatedAt,
+				Status:       msg.Status,
+				SenderName:   actorName,
+				SystemAction: msg.SystemAction,
+			},
+		}
+	}
+
+	groupName, groupImage := "", ""
+	if group, err := storage.NewMongoStoreGroup(db).GetGroup(ctx, groupID); err == nil {
+		groupName, groupImage = group.Name, group.Image
+	}
+
+	// Thêm nhóm vào danh sách hội thoại của thành viên mới
+	hub.Broadcast <- websocket.HubEvent{
+		Type: "group_member_added",
+		Payload: map[string]interface{}{
+			"group_id":          groupID.Hex(),
+			"display_name":      groupName,
+			"avatar":            groupImage,
+			"sender_id":         actorID.Hex(),
+			"last_message_id":   msg.ID.Hex(),
+			"last_message":      msg.Content,
+			"last_message_type": msg.Type,
+			"last_date":         msg.CreatedAt,
+			"status":            msg.Status,
+			"updated_at":        now,
+			"members": []modelsChat.Member{{
+				UserID:      userID,
+				UserName:    memberName,
+				DisplayName: memberName,
+				Role:        "member",
+			}},
+		},
+	}
+}
+
+func writeInviteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, biz.ErrNotGroupAdmin):
+		c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, biz.ErrInviteNotFound), errors.Is(err, biz.ErrJoinRequestNotFound), errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, biz.ErrInviteUnusable):
+		c.JSON(http.StatusGone, common.NewResponse(http.StatusGone, err.Error(), nil))
+	case errors.Is(err, biz.ErrAlreadyMember), errors.Is(err, biz.ErrJoinRequestExists), errors.Is(err, biz.ErrJoinRequestReviewed):
+		c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
@@ -1,8 +1,10 @@
 package ginGroup
 
 import (
+	"context"
 	"fmt"
 	"my-app/common"
+	"my-app/database"
 	modelsChat "my-app/modules/chat/models"
 	storageChat "my-app/modules/chat/storage"
 	"my-app/modules/chat/transport/websocket"
@@ -40,16 +42,10 @@ func RemoveGroupMemberHandler(db *mongo.Database, hub *websocket.Hub) gin.Handle
 
 		store := storage.NewMongoStoreGroup(db)
 		business := biz.NewRemoveGroupMemberBiz(store)
-
-		successorID, err := business.RemoveMember(c.Request.Context(), requesterIDStr, groupID, userID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
+		chatStore := storageChat.NewMongoChatStore(db)
 
 		// 1. Name of the person performing the removal (group owner) or self
 		requesterOID, _ := primitive.ObjectIDFromHex(requesterIDStr)
-		chatStore := storageChat.NewMongoChatStore(db)
 		requesterUser, err := chatStore.GetUserById(c.Request.Context(), requesterOID)
 		ownerName := requesterIDStr // fallback
 		if err == nil && requesterUser != nil {
@@ -64,63 +60,77 @@ func RemoveGroupMemberHandler(db *mongo.Database, hub *websocket.Hub) gin.Handle
 			targetUserName = targetUser.DisplayName
 		}
 
-		// 3. Handle leave group & succession message
-		content := fmt.Sprintf("%s đã Xóa %s rời khỏi nhóm", ownerName, targetUserName)
-		if requesterIDStr == userID {
-			content = fmt.Sprintf("%s đã rời khỏi nhóm", targetUserName)
-		}
+		groupOID, _ := primitive.ObjectIDFromHex(groupID)
 
-		// If there is a successor
-		var newOwnerIDStr string
-		if successorID != nil {
-			newOwnerIDStr = successorID.Hex()
-			successorUser, _ := chatStore.GetUserById(c.Request.Context(), *successorID)
-			successorDisplayName := newOwnerIDStr
-			if successorUser != nil {
-				successorDisplayName = successorUser.DisplayName
+		// Xóa thành viên, chuyển quyền trưởng nhóm và tin nhắn hệ thống cùng một transaction:
+		// không còn trường hợp đã xóa thành viên mà mất tin nhắn thông báo (hoặc ngược lại)
+		var msg *modelsChat.Message
+		err = database.WithTransaction(c.Request.Context(), db, func(ctx context.Context) error {
+			successorID, err := business.RemoveMember(ctx, requesterIDStr, groupID, userID)
+			if err != nil {
+				return err
 			}
-			content += fmt.Sprintf(". %s đã trở thành Trưởng nhóm mới", successorDisplayName)
-		}
 
-		groupOID, _ := primitive.ObjectIDFromHex(groupID)
-
-		msg := &modelsChat.Message{
-			ID:           primitive.NewObjectID(),
-			SenderID:     requesterOID,
-			GroupID:      groupOID,
-			Content:      content,
-			Type:         "system",
-			CreatedAt:    time.Now(),
-			Status:       modelsChat.StatusSent,
-			SystemAction: "leave",
-			NewOwnerID:   newOwnerIDStr,
-			OldOwnerID:   userID,
-		}
+			// 3. Handle leave group & succession message
+			content := fmt.Sprintf("%s đã Xóa %s rời khỏi nhóm", ownerName, targetUserName)
+			if requesterIDStr == userID {
+				content = fmt.Sprintf("%s đã rời khỏi nhóm", targetUserName)
+			}
 
-		if newOwnerIDStr != "" {
-			msg.SystemAction = "leave_with_succession"
-		}
+			// If there is a successor
+			var newOwnerIDStr string
+			if successorID != nil {
+				newOwnerIDStr = successorID.Hex()
+				successorUser, _ := chatStore.GetUserById(ctx, *successorID)
+				successorDisplayName := newOwnerIDStr
+				if successorUser != nil {
+					successorDisplayName = successorUser.DisplayName
+				}
+				content += fmt.Sprintf(". %s đã trở thành Trưởng nhóm mới", successorDisplayName)
+			}
 
-		if err := chatStore.SaveMessage(c.Request.Context(), msg); err == nil {
-			// Broadcast realtime
-			msgResponse := &modelsChat.MessageResponse{
-				ID:           msg.ID,
-				SenderID:     msg.SenderID,
-				GroupID:      msg.GroupID,
-				Content:      msg.Content,
-				Type:         msg.Type,
-				CreatedAt:    msg.CreatedAt,
-				Status:       msg.Status,
-				SenderName:   ownerName,
-				SystemAction: msg.SystemAction,
-				NewOwnerID:   msg.NewOwnerID,
-				OldOwnerID:   msg.OldOwnerID,
+			msg = &modelsChat.Message{
+				ID:           primitive.NewObjectID(),
+				SenderID:     requesterOID,
+				GroupID:      groupOID,
+				Content:      content,
+				Type:         "system",
+				CreatedAt:    time.Now(),
+				Status:       modelsChat.StatusSent,
+				SystemAction: "leave",
+				NewOwnerID:   newOwnerIDStr,
+				OldOwnerID:   userID,
 			}
 
-			hub.Broadcast <- websocket.HubEvent{
-				Type:    "chat",
-				Payload: msgResponse,
+			if newOwnerIDStr != "" {
+				msg.SystemAction = "leave_with_succession"
 			}
+
+			return chatStore.SaveMessage(ctx, msg)
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Broadcast realtime sau khi transaction đã commit
+		msgResponse := &modelsChat.MessageResponse{
+			ID:           msg.ID,
+			SenderID:     msg.SenderID,
+			GroupID:      msg.GroupID,
+			Content:      msg.Content,
+			Type:         msg.Type,
+			CreatedAt:    msg.CreatedAt,
+			Status:       msg.Status,
+			SenderName:   ownerName,
+			SystemAction: msg.SystemAction,
+			NewOwnerID:   msg.NewOwnerID,
+			OldOwnerID:   msg.OldOwnerID,
+		}
+
+		hub.Broadcast <- websocket.HubEvent{
+			Type:    "chat",
+			Payload: msgResponse,
 		}
 
 		hub.Broadcast <- websocket.HubEvent{
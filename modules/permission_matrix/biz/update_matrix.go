@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"my-app/common"
+	"my-app/common/logger"
 	permissionModels "my-app/modules/permission/models"
 	matrixModels "my-app/modules/permission_matrix/models"
 	roleModels "my-app/modules/role/models"
@@ -152,8 +153,8 @@ func (biz *UpdateMatrixBiz) logAudit(
 	}
 
 	// TODO: Save to audit_logs collection
-	// For now, just log to console (có thể mở rộng sau)
-	fmt.Printf("[AUDIT] %+v\n", auditLog)
+	// For now, just log (có thể mở rộng sau)
+	logger.Ctx(ctx, "permission").Info().Interface("audit", auditLog).Msg("role permissions updated")
 }
 
 // difference - Tìm phần tử có trong a nhưng không có trong b
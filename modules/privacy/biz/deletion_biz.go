@@ -0,0 +1,215 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"my-app/modules/privacy/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrDeletionExists   = errors.New("tài khoản đã có lịch xóa")
+	ErrDeletionNotFound = errors.New("không có yêu cầu xóa tài khoản nào đang chờ")
+	ErrReauthRequired   = errors.New("vui lòng xác thực lại (nhập mật khẩu hoặc đăng nhập lại) để xóa tài khoản")
+)
+
+// Tài khoản không có mật khẩu (OAuth) phải đăng nhập trong khoảng này mới được tự xóa
+const reauthWindow = 10 * time.Minute
+
+type DeletionStorage interface {
+	GetProfile(ctx context.Context, userID primitive.ObjectID) (bson.M, error)
+	CreateDeletion(ctx context.Context, data *models.AccountDeletion) error
+	FindScheduledDeletion(ctx context.Context, userID primitive.ObjectID) (*models.AccountDeletion, error)
+	UpdateDeletion(ctx context.Context, id primitive.ObjectID, set bson.M) error
+	ListDueDeletions(ctx context.Context, now time.Time) ([]models.AccountDeletion, error)
+
+	GetPasswordHash(ctx context.Context, userID primitive.ObjectID) (string, error)
+	AnonymizeMessages(ctx context.Context, userID, tombstoneID primitive.ObjectID) error
+	DeleteSearchDocuments(ctx context.Context, userID primitive.ObjectID) error
+	DeleteSentMedia(ctx context.Context, userID primitive.ObjectID) ([]string, error)
+	DeleteRelations(ctx context.Context, userID primitive.ObjectID) error
+	AnonymizeTaskComments(ctx context.Context, userID primitive.ObjectID) error
+	DeleteUser(ctx context.Context, userID primitive.ObjectID) error
+
+	RemoveObject(ctx context.Context, objectName string) error
+	RemovePrefix(ctx context.Context, prefix string) error
+}
+
+type DeletionBiz struct {
+	store DeletionStorage
+	grace time.Duration
+	// OnDeleted được gọi sau khi xóa xong 1 tài khoản (VD: ngắt kết nối WS)
+	OnDeleted func(userID string)
+}
+
+func NewDeletionBiz(store DeletionStorage, grace time.Duration) *DeletionBiz {
+	return &DeletionBiz{store: store, grace: grace}
+}
+
+// Reauthenticate xác nhận lại danh tính trước khi user tự xóa tài khoản:
+// kiểm tra mật khẩu, hoặc với tài khoản OAuth thì token phải vừa được cấp (authTime).
+func (biz *DeletionBiz) Reauthenticate(ctx context.Context, userID, password string, authTime time.Time) error {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("userID không hợp lệ")
+	}
+
+	hash, err := biz.store.GetPasswordHash(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if hash == "" {
+		if authTime.IsZero() || time.Since(authTime) > reauthWindow {
+			return ErrReauthRequired
+		}
+		return nil
+	}
+
+	if password == "" || bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+// Schedule đặt lịch xóa vĩnh viễn sau thời gian chờ, trong thời gian này user có thể hủy
+func (biz *DeletionBiz) Schedule(ctx context.Context, userID, requestedBy, reason string) (*models.AccountDeletion, error) {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("userID không hợp lệ")
+	}
+	rid, err := primitive.ObjectIDFromHex(requestedBy)
+	if err != nil {
+		return nil, fmt.Errorf("requestedBy không hợp lệ")
+	}
+
+	if _, err := biz.store.GetProfile(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	existing, err := biz.store.FindScheduledDeletion(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, ErrDeletionExists
+	}
+
+	now := time.Now()
+	data := &models.AccountDeletion{
+		ID:          primitive.NewObjectID(),
+		UserID:      uid,
+		RequestedBy: rid,
+		Reason:      reason,
+		Status:      models.DeletionScheduled,
+		ScheduledAt: now.Add(biz.grace),
+		TombstoneID: primitive.NewObjectID(),
+		CreatedAt:   now,
+	}
+	if err := biz.store.CreateDeletion(ctx, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (biz *DeletionBiz) Get(ctx context.Context, userID string) (*models.AccountDeletion, error) {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("userID không hợp lệ")
+	}
+
+	data, err := biz.store.FindScheduledDeletion(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrDeletionNotFound
+	}
+	return data, nil
+}
+
+func (biz *DeletionBiz) Cancel(ctx context.Context, userID string) error {
+	data, err := biz.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return biz.store.UpdateDeletion(ctx, data.ID, bson.M{"status": models.DeletionCancelled})
+}
+
+// ProcessDue xóa các tài khoản đã hết thời gian chờ
+func (biz *DeletionBiz) ProcessDue(ctx context.Context) error {
+	items, err := biz.store.ListDueDeletions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		// Lịch xóa tạo trước khi có tombstone_id: tạo và lưu lại trước khi xóa để lần chạy lại dùng cùng ID
+		if item.TombstoneID.IsZero() {
+			item.TombstoneID = primitive.NewObjectID()
+			if err := biz.store.UpdateDeletion(ctx, item.ID, bson.M{"tombstone_id": item.TombstoneID}); err != nil {
+				log.Printf("❌ Không lưu được tombstone cho %s: %v", item.UserID.Hex(), err)
+				continue
+			}
+		}
+
+		if err := biz.purge(ctx, item.UserID, item.TombstoneID); err != nil {
+			// Giữ trạng thái scheduled để lần chạy sau thử lại
+			log.Printf("❌ Xóa tài khoản %s thất bại: %v", item.UserID.Hex(), err)
+			_ = biz.store.UpdateDeletion(ctx, item.ID, bson.M{"error": err.Error()})
+			continue
+		}
+
+		now := time.Now()
+		_ = biz.store.UpdateDeletion(ctx, item.ID, bson.M{
+			"status":       models.DeletionCompleted,
+			"completed_at": now,
+			"error":        "",
+		})
+		log.Printf("🗑️ Đã xóa vĩnh viễn tài khoản %s", item.UserID.Hex())
+
+		if biz.OnDeleted != nil {
+			biz.OnDeleted(item.UserID.Hex())
+		}
+	}
+	return nil
+}
+
+// purge chạy lại được nhiều lần: mỗi bước đều bỏ qua dữ liệu đã xử lý
+func (biz *DeletionBiz) purge(ctx context.Context, userID, tombstoneID primitive.ObjectID) error {
+	objects, err := biz.store.DeleteSentMedia(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("media: %w", err)
+	}
+	for _, name := range objects {
+		if err := biz.store.RemoveObject(ctx, name); err != nil {
+			log.Printf("⚠️ Không xóa được file %s: %v", name, err)
+		}
+	}
+
+	if err := biz.store.DeleteSearchDocuments(ctx, userID); err != nil {
+		return fmt.Errorf("elasticsearch: %w", err)
+	}
+	if err := biz.store.AnonymizeMessages(ctx, userID, tombstoneID); err != nil {
+		return fmt.Errorf("messages: %w", err)
+	}
+	if err := biz.store.AnonymizeTaskComments(ctx, userID); err != nil {
+		return fmt.Errorf("task_comments: %w", err)
+	}
+	if err := biz.store.RemovePrefix(ctx, fmt.Sprintf("exports/%s/", userID.Hex())); err != nil {
+		log.Printf("⚠️ Không xóa được file export của %s: %v", userID.Hex(), err)
+	}
+	if err := biz.store.DeleteRelations(ctx, userID); err != nil {
+		return fmt.Errorf("relations: %w", err)
+	}
+	if err := biz.store.DeleteUser(ctx, userID); err != nil {
+		return fmt.Errorf("user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package biz
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	chatModels "my-app/modules/chat/models"
+	"my-app/modules/privacy/models"
+	"os"
+	"path"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	exportTTL     = 7 * 24 * time.Hour // file export được giữ 7 ngày
+	exportTimeout = 30 * time.Minute
+)
+
+var (
+	ErrExportRunning  = errors.New("yêu cầu xuất dữ liệu trước đó đang được xử lý")
+	ErrExportNotReady = errors.New("file dữ liệu chưa sẵn sàng")
+	ErrExportExpired  = errors.New("file dữ liệu đã hết hạn, vui lòng tạo yêu cầu mới")
+	ErrForbidden      = errors.New("bạn không có quyền truy cập dữ liệu này")
+)
+
+type ExportStorage interface {
+	CreateExportJob(ctx context.Context, job *models.DataExportJob) error
+	UpdateExportJob(ctx context.Context, id primitive.ObjectID, set bson.M) error
+	FindExportJob(ctx context.Context, id primitive.ObjectID) (*models.DataExportJob, error)
+	FindRunningExportJob(ctx context.Context, userID primitive.ObjectID, startedAfter time.Time) (*models.DataExportJob, error)
+	FailStaleExportJobs(ctx context.Context, startedBefore time.Time) (int64, error)
+	ListExpiredExportJobs(ctx context.Context, now time.Time) ([]models.DataExportJob, error)
+
+	GetProfile(ctx context.Context, userID primitive.ObjectID) (bson.M, error)
+	EachSentMessage(ctx context.Context, userID primitive.ObjectID, fn func(doc bson.M) error) error
+	EachTask(ctx context.Context, userID primitive.ObjectID, fn func(doc bson.M) error) error
+	EachTaskComment(ctx context.Context, userID primitive.ObjectID, fn func(doc bson.M) error) error
+	ListSentMedia(ctx context.Context, userID primitive.ObjectID) ([]chatModels.Media, error)
+
+	OpenObject(ctx context.Context, objectName string) (io.ReadCloser, error)
+	PutObject(ctx context.Context, objectName string, r io.Reader, size int64, contentType string) error
+	RemoveObject(ctx context.Context, objectName string) error
+}
+
+type ExportBiz struct {
+	store ExportStorage
+}
+
+func NewExportBiz(store ExportStorage) *ExportBiz {
+	return &ExportBiz{store: store}
+}
+
+// RequestExport tạo job và build file ZIP ở background
+func (biz *ExportBiz) RequestExport(ctx context.Context, userID, requestedBy string) (*models.DataExportJob, error) {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("userID không hợp lệ")
+	}
+	rid, err := primitive.ObjectIDFromHex(requestedBy)
+	if err != nil {
+		return nil, fmt.Errorf("requestedBy không hợp lệ")
+	}
+
+	if _, err := biz.store.GetProfile(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	// Job quá exportTimeout mà chưa xong là job mồ côi (process restart), không chặn yêu cầu mới
+	running, err := biz.store.FindRunningExportJob(ctx, uid, time.Now().Add(-exportTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if running != nil {
+		return running, ErrExportRunning
+	}
+
+	job := &models.DataExportJob{
+		ID:          primitive.NewObjectID(),
+		UserID:      uid,
+		RequestedBy: rid,
+		Status:      models.JobPending,
+		CreatedAt:   time.Now(),
+	}
+	if err := biz.store.CreateExportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	go biz.run(*job)
+
+	return job, nil
+}
+
+func (biz *ExportBiz) run(job models.DataExportJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	_ = biz.store.UpdateExportJob(ctx, job.ID, bson.M{"status": models.JobProcessing})
+
+	objectName, size, err := biz.build(ctx, job)
+	if err != nil {
+		log.Printf("❌ Export dữ liệu user %s thất bại: %v", job.UserID.Hex(), err)
+		_ = biz.store.UpdateExportJob(ctx, job.ID, bson.M{
+			"status": models.JobFailed,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	_ = biz.store.UpdateExportJob(ctx, job.ID, bson.M{
+		"status":       models.JobCompleted,
+		"object_name":  objectName,
+		"size":         size,
+		"completed_at": now,
+		"expires_at":   now.Add(exportTTL),
+	})
+}
+
+// build ghi file ZIP ra file tạm rồi upload lên MinIO
+func (biz *ExportBiz) build(ctx context.Context, job models.DataExportJob) (string, int64, error) {
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+
+	profile, err := biz.store.GetProfile(ctx, job.UserID)
+	if err != nil {
+		return "", 0, fmt.Errorf("profile: %w", err)
+	}
+	if err := writeJSON(zw, "profile.json", profile); err != nil {
+		return "", 0, err
+	}
+
+	if err := writeJSONArray(zw, "messages.json", func(fn func(doc bson.M) error) error {
+		return biz.store.EachSentMessage(ctx, job.UserID, fn)
+	}); err != nil {
+		return "", 0, fmt.Errorf("messages: %w", err)
+	}
+
+	if err := writeJSONArray(zw, "tasks.json", func(fn func(doc bson.M) error) error {
+		return biz.store.EachTask(ctx, job.UserID, fn)
+	}); err != nil {
+		return "", 0, fmt.Errorf("tasks: %w", err)
+	}
+
+	if err := writeJSONArray(zw, "task_comments.json", func(fn func(doc bson.M) error) error {
+		return biz.store.EachTaskComment(ctx, job.UserID, fn)
+	}); err != nil {
+		return "", 0, fmt.Errorf("task_comments: %w", err)
+	}
+
+	medias, err := biz.store.ListSentMedia(ctx, job.UserID)
+	if err != nil {
+		return "", 0, fmt.Errorf("media: %w", err)
+	}
+	if medias == nil {
+		medias = []chatModels.Media{}
+	}
+	if err := writeJSON(zw, "media.json", medias); err != nil {
+		return "", 0, err
+	}
+	for _, m := range medias {
+		// File lỗi/không còn trên MinIO thì bỏ qua, metadata vẫn có trong media.json
+		if err := biz.copyMedia(ctx, zw, m); err != nil {
+			log.Printf("⚠️ Export: bỏ qua media %s: %v", m.ID.Hex(), err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
+	objectName := fmt.Sprintf("exports/%s/%s.zip", job.UserID.Hex(), job.ID.Hex())
+	if err := biz.store.PutObject(ctx, objectName, tmp, info.Size(), "application/zip"); err != nil {
+		return "", 0, fmt.Errorf("upload: %w", err)
+	}
+
+	return objectName, info.Size(), nil
+}
+
+func (biz *ExportBiz) copyMedia(ctx context.Context, zw *zip.Writer, m chatModels.Media) error {
+	if m.URL == "" {
+		return nil
+	}
+
+	src, err := biz.store.OpenObject(ctx, m.URL)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	name := m.Filename
+	if name == "" {
+		name = path.Base(m.URL)
+	}
+
+	dst, err := zw.Create(fmt.Sprintf("media/%s_%s", m.ID.Hex(), path.Base(name)))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// GetJob chỉ cho phép chủ dữ liệu hoặc admin (isAdmin) xem job
+func (biz *ExportBiz) GetJob(ctx context.Context, jobID, requesterID string, isAdmin bool) (*models.DataExportJob, error) {
+	oid, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("ID không hợp lệ")
+	}
+
+	job, err := biz.store.FindExportJob(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && job.UserID.Hex() != requesterID {
+		return nil, ErrForbidden
+	}
+	return job, nil
+}
+
+// OpenDownload trả về nội dung file ZIP của job đã hoàn thành
+func (biz *ExportBiz) OpenDownload(ctx context.Context, jobID, requesterID string, isAdmin bool) (*models.DataExportJob, io.ReadCloser, error) {
+	job, err := biz.GetJob(ctx, jobID, requesterID, isAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.JobCompleted {
+		return nil, nil, ErrExportNotReady
+	}
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now()) {
+		return nil, nil, ErrExportExpired
+	}
+
+	r, err := biz.store.OpenObject(ctx, job.ObjectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, r, nil
+}
+
+// CleanupExpired xóa file export đã hết hạn trên MinIO và đóng các job bị bỏ dở do process restart
+func (biz *ExportBiz) CleanupExpired(ctx context.Context) error {
+	if n, err := biz.store.FailStaleExportJobs(ctx, time.Now().Add(-exportTimeout)); err != nil {
+		log.Printf("⚠️ Không đóng được job export bị gián đoạn: %v", err)
+	} else if n > 0 {
+		log.Printf("🧹 Đã đánh dấu failed %d job export bị gián đoạn", n)
+	}
+
+	jobs, err := biz.store.ListExpiredExportJobs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.ObjectName != "" {
+			if err := biz.store.RemoveObject(ctx, job.ObjectName); err != nil {
+				log.Printf("⚠️ Không xóa được file export %s: %v", job.ObjectName, err)
+				continue
+			}
+		}
+		_ = biz.store.UpdateExportJob(ctx, job.ID, bson.M{"status": models.JobExpired, "object_name": ""})
+	}
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeJSONArray ghi từng document ra mảng JSON, không giữ toàn bộ dữ liệu trong RAM
+func writeJSONArray(zw *zip.Writer, name string, each func(fn func(doc bson.M) error) error) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err = each(func(doc bson.M) error {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
@@ -0,0 +1,32 @@
+package biz
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunWorker định kỳ xóa các tài khoản đến hạn và dọn file export hết hạn, dừng khi ctx bị hủy
+func RunWorker(ctx context.Context, interval time.Duration, deletion *DeletionBiz, export *ExportBiz) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := deletion.ProcessDue(ctx); err != nil {
+			log.Printf("⚠️ Privacy worker: xử lý xóa tài khoản lỗi: %v", err)
+		}
+		if err := export.CleanupExpired(ctx); err != nil {
+			log.Printf("⚠️ Privacy worker: dọn file export lỗi: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
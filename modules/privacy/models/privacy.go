@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobStatus string
+
+const (
+	JobPending    JobStatus = "pending"
+	JobProcessing JobStatus = "processing"
+	JobCompleted  JobStatus = "completed"
+	JobFailed     JobStatus = "failed"
+	JobExpired    JobStatus = "expired" // file ZIP đã bị dọn khỏi MinIO
+)
+
+type DeletionStatus string
+
+const (
+	DeletionScheduled DeletionStatus = "scheduled"
+	DeletionCancelled DeletionStatus = "cancelled"
+	DeletionCompleted DeletionStatus = "completed"
+)
+
+// DataExportJob - yêu cầu "tải dữ liệu của tôi", file ZIP được lưu trên MinIO
+type DataExportJob struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RequestedBy primitive.ObjectID `bson:"requested_by" json:"requested_by"` // user hoặc admin
+	Status      JobStatus          `bson:"status" json:"status"`
+	ObjectName  string             `bson:"object_name,omitempty" json:"-"`
+	Size        int64              `bson:"size,omitempty" json:"size,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// AccountDeletion - yêu cầu xóa vĩnh viễn tài khoản, chỉ thực hiện sau thời gian chờ
+type AccountDeletion struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RequestedBy primitive.ObjectID `bson:"requested_by" json:"requested_by"`
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status      DeletionStatus     `bson:"status" json:"status"`
+	ScheduledAt time.Time          `bson:"scheduled_at" json:"scheduled_at"` // thời điểm sẽ xóa
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	// ID thay thế user trong tin nhắn sau khi xóa, cố định qua các lần chạy lại để hội thoại của người khác không bị tách
+	TombstoneID primitive.ObjectID `bson:"tombstone_id,omitempty" json:"-"`
+}
+
+type DeleteAccountRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+	// User tự xóa phải nhập lại mật khẩu (tài khoản đăng nhập qua OAuth thì cần vừa đăng nhập lại)
+	Password string `json:"password"`
+}
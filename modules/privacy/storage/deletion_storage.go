@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	chatModels "my-app/modules/chat/models"
+	"my-app/modules/privacy/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (s *mongoStore) CreateDeletion(ctx context.Context, data *models.AccountDeletion) error {
+	_, err := s.db.Collection("account_deletions").InsertOne(ctx, data)
+	return err
+}
+
+// FindScheduledDeletion trả về nil nếu user không có yêu cầu xóa đang chờ
+func (s *mongoStore) FindScheduledDeletion(ctx context.Context, userID primitive.ObjectID) (*models.AccountDeletion, error) {
+	var data models.AccountDeletion
+	err := s.db.Collection("account_deletions").FindOne(ctx, bson.M{
+		"user_id": userID,
+		"status":  models.DeletionScheduled,
+	}).Decode(&data)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *mongoStore) UpdateDeletion(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+	_, err := s.db.Collection("account_deletions").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+func (s *mongoStore) ListDueDeletions(ctx context.Context, now time.Time) ([]models.AccountDeletion, error) {
+	cursor, err := s.db.Collection("account_deletions").Find(ctx, bson.M{
+		"status":       models.DeletionScheduled,
+		"scheduled_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.AccountDeletion
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AnonymizeMessages xóa nội dung tin nhắn user đã gửi và thay user bằng tombstoneID ở cả 2 chiều,
+// giữ lại bản ghi để lịch sử hội thoại của người khác không bị lệch. Phiếu bình chọn cũng chuyển sang
+// tombstoneID để kết quả bình chọn không đổi.
+func (s *mongoStore) AnonymizeMessages(ctx context.Context, userID, tombstoneID primitive.ObjectID) error {
+	col := s.db.Collection("messages")
+
+	_, err := col.UpdateMany(ctx, bson.M{"sender_id": userID}, bson.M{
+		"$set": bson.M{
+			"sender_id":      tombstoneID,
+			"content":        "",
+			"sender_deleted": true,
+		},
+		"$unset": bson.M{"media_ids": "", "reply": "", "task": ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Chỉ đổi ID người nhận, nội dung là của người gửi nên giữ nguyên
+	if _, err := col.UpdateMany(ctx, bson.M{"receiver_id": userID}, bson.M{
+		"$set": bson.M{"receiver_id": tombstoneID},
+	}); err != nil {
+		return err
+	}
+
+	_, err = col.UpdateMany(ctx, bson.M{"$or": []bson.M{
+		{"reactions.user_id": userID},
+		{"deleted_for": userID},
+	}}, bson.M{"$pull": bson.M{
+		"reactions":   bson.M{"user_id": userID},
+		"deleted_for": userID,
+	}})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Collection("poll_votes").UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{"user_id": tombstoneID},
+	})
+	return err
+}
+
+// DeleteSearchDocuments xóa các tin nhắn của user khỏi index Elasticsearch
+func (s *mongoStore) DeleteSearchDocuments(ctx context.Context, userID primitive.ObjectID) error {
+	if s.es == nil {
+		return nil
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{
+				"sender_id.keyword": userID.Hex(),
+			},
+		},
+	}
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.es.DeleteByQuery(
+		[]string{"messages"},
+		bytes.NewReader(body),
+		s.es.DeleteByQuery.WithContext(ctx),
+		s.es.DeleteByQuery.WithRefresh(true),
+		s.es.DeleteByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.Printf("[ES] Delete user documents error: %s", res.Status())
+		return fmt.Errorf("es delete by query error: %s", res.Status())
+	}
+	return nil
+}
+
+// DeleteSentMedia xóa metadata media user đã gửi hoặc đã upload (kể cả chưa gửi), trả về object name của
+// file gốc và các biến thể (thumbnail, WebP, poster) để xóa trên MinIO
+func (s *mongoStore) DeleteSentMedia(ctx context.Context, userID primitive.ObjectID) ([]string, error) {
+	medias, err := s.ListSentMedia(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.db.Collection("medias").Find(ctx, bson.M{"uploaded_by": userID})
+	if err != nil {
+		return nil, err
+	}
+	var uploaded []chatModels.Media
+	if err := cursor.All(ctx, &uploaded); err != nil {
+		return nil, err
+	}
+	medias = append(medias, uploaded...)
+	if len(medias) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(medias))
+	ids := make([]primitive.ObjectID, 0, len(medias))
+	objects := make([]string, 0, len(medias))
+	for _, m := range medias {
+		if seen[m.ID] {
+			continue
+		}
+		seen[m.ID] = true
+		ids = append(ids, m.ID)
+		if m.URL != "" {
+			objects = append(objects, m.URL)
+		}
+		for _, v := range m.Variants {
+			if v.URL != "" {
+				objects = append(objects, v.URL)
+			}
+		}
+	}
+
+	if _, err := s.db.Collection("medias").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// DeleteRelations dọn các quan hệ của user: bạn bè, nhóm, cài đặt chat, role, trạng thái, hộp thư nhắc đến
+// (cả bản ghi người khác nhận từ user, vì có lưu bản xem trước nội dung)
+func (s *mongoStore) DeleteRelations(ctx context.Context, userID primitive.ObjectID) error {
+	hexID := userID.Hex()
+
+	filters := []struct {
+		collection string
+		filter     bson.M
+	}{
+		{"friend_ship", bson.M{"$or": []bson.M{{"user_id": hexID}, {"friend_id": hexID}}}},
+		{"friend_suggestion_dismissed", bson.M{"$or": []bson.M{{"user_id": hexID}, {"dismissed_user_id": hexID}}}},
+		{"group_user_roles", bson.M{"user_id": hexID}},
+		{"group_members", bson.M{"user_id": userID}},
+		{"user_chat_setting", bson.M{"$or": []bson.M{{"user_id": userID}, {"target_id": userID}}}},
+		{"user_roles", bson.M{"user_id": userID}},
+		{"user_status", bson.M{"user_id": userID}},
+		{"chat_seen_status", bson.M{"user_id": userID}},
+		{"conversation_tags", bson.M{"$or": []bson.M{{"user_id": userID}, {"target_id": userID}}}},
+		{"data_export_jobs", bson.M{"user_id": userID}},
+		{"mentions", bson.M{"$or": []bson.M{{"user_id": userID}, {"sender_id": userID}}}},
+	}
+
+	for _, f := range filters {
+		if _, err := s.db.Collection(f.collection).DeleteMany(ctx, f.filter); err != nil {
+			return fmt.Errorf("%s: %w", f.collection, err)
+		}
+	}
+	return nil
+}
+
+// AnonymizeTaskComments giữ bình luận trong task nhưng bỏ thông tin người viết
+func (s *mongoStore) AnonymizeTaskComments(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.db.Collection("task_comments").UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"user_id":   primitive.NilObjectID,
+			"user_name": "",
+			"content":   "",
+		},
+		"$unset": bson.M{"user_avatar": "", "attachment_ids": ""},
+	})
+	return err
+}
+
+// GetPasswordHash trả về mật khẩu đã mã hóa, rỗng với tài khoản chỉ đăng nhập qua OAuth
+func (s *mongoStore) GetPasswordHash(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	var user struct {
+		Password string `bson:"password"`
+	}
+	err := s.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	return user.Password, err
+}
+
+func (s *mongoStore) DeleteUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.db.Collection("users").DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"my-app/config"
+	chatModels "my-app/modules/chat/models"
+	"my-app/modules/privacy/models"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const bucketName = "unichat"
+
+type mongoStore struct {
+	db *mongo.Database
+	es *elasticsearch.Client
+}
+
+func NewMongoStore(db *mongo.Database, es *elasticsearch.Client) *mongoStore {
+	return &mongoStore{db: db, es: es}
+}
+
+func (s *mongoStore) CreateExportJob(ctx context.Context, job *models.DataExportJob) error {
+	_, err := s.db.Collection("data_export_jobs").InsertOne(ctx, job)
+	return err
+}
+
+func (s *mongoStore) UpdateExportJob(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+	_, err := s.db.Collection("data_export_jobs").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+func (s *mongoStore) FindExportJob(ctx context.Context, id primitive.ObjectID) (*models.DataExportJob, error) {
+	var job models.DataExportJob
+	if err := s.db.Collection("data_export_jobs").FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindRunningExportJob tránh việc 1 user tạo nhiều job cùng lúc. Job tạo trước startedAfter đã quá thời gian
+// chạy tối đa (process restart giữa chừng) nên không tính là đang chạy
+func (s *mongoStore) FindRunningExportJob(ctx context.Context, userID primitive.ObjectID, startedAfter time.Time) (*models.DataExportJob, error) {
+	var job models.DataExportJob
+	err := s.db.Collection("data_export_jobs").FindOne(ctx, bson.M{
+		"user_id":    userID,
+		"status":     bson.M{"$in": []models.JobStatus{models.JobPending, models.JobProcessing}},
+		"created_at": bson.M{"$gt": startedAfter},
+	}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetProfile lấy hồ sơ user (bỏ mật khẩu)
+func (s *mongoStore) GetProfile(ctx context.Context, userID primitive.ObjectID) (bson.M, error) {
+	var profile bson.M
+	err := s.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&profile)
+	if err != nil {
+		return nil, err
+	}
+	delete(profile, "password")
+	return profile, nil
+}
+
+func (s *mongoStore) each(ctx context.Context, collection string, filter bson.M, fn func(doc bson.M) error) error {
+	cursor, err := s.db.Collection(collection).Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// EachSentMessage duyệt toàn bộ tin nhắn do user gửi
+func (s *mongoStore) EachSentMessage(ctx context.Context, userID primitive.ObjectID, fn func(doc bson.M) error) error {
+	return s.each(ctx, "messages", bson.M{"sender_id": userID}, fn)
+}
+
+// EachTask duyệt các task user tạo hoặc được giao
+func (s *mongoStore) EachTask(ctx context.Context, userID primitive.ObjectID, fn func(doc bson.M) error) error {
+	return s.each(ctx, "tasks", bson.M{"$or": []bson.M{
+		{"creator_id": userID},
+		{"assignee_id": userID},
+		{"assignees.assignee_id": userID},
+	}}, fn)
+}
+
+func (s *mongoStore) EachTaskComment(ctx context.Context, userID primitive.ObjectID, fn func(doc bson.M) error) error {
+	return s.each(ctx, "task_comments", bson.M{"user_id": userID}, fn)
+}
+
+// ListSentMedia lấy metadata media đính kèm trong các tin nhắn user đã gửi
+func (s *mongoStore) ListSentMedia(ctx context.Context, userID primitive.ObjectID) ([]chatModels.Media, error) {
+	ids, err := s.db.Collection("messages").Distinct(ctx, "media_ids", bson.M{
+		"sender_id": userID,
+		"media_ids": bson.M{"$exists": true, "$ne": bson.A{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := s.db.Collection("medias").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var medias []chatModels.Media
+	if err := cursor.All(ctx, &medias); err != nil {
+		return nil, err
+	}
+	return medias, nil
+}
+
+func (s *mongoStore) OpenObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	obj, err := config.MinioClient.GetObject(ctx, bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject chỉ lỗi khi đọc, Stat để phát hiện sớm object không tồn tại
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *mongoStore) PutObject(ctx context.Context, objectName string, r io.Reader, size int64, contentType string) error {
+	_, err := config.MinioClient.PutObject(ctx, bucketName, objectName, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (s *mongoStore) RemoveObject(ctx context.Context, objectName string) error {
+	return config.MinioClient.RemoveObject(ctx, bucketName, objectName, minio.RemoveObjectOptions{})
+}
+
+// FailStaleExportJobs đánh dấu failed các job còn pending/processing nhưng tạo trước startedBefore
+func (s *mongoStore) FailStaleExportJobs(ctx context.Context, startedBefore time.Time) (int64, error) {
+	res, err := s.db.Collection("data_export_jobs").UpdateMany(ctx, bson.M{
+		"status":     bson.M{"$in": []models.JobStatus{models.JobPending, models.JobProcessing}},
+		"created_at": bson.M{"$lte": startedBefore},
+	}, bson.M{"$set": bson.M{
+		"status": models.JobFailed,
+		"error":  "tiến trình xuất dữ liệu bị gián đoạn",
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// ListExpiredExportJobs lấy các file export đã hết hạn để dọn
+func (s *mongoStore) ListExpiredExportJobs(ctx context.Context, now time.Time) ([]models.DataExportJob, error) {
+	cursor, err := s.db.Collection("data_export_jobs").Find(ctx, bson.M{
+		"status":     models.JobCompleted,
+		"expires_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.DataExportJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RemovePrefix xóa toàn bộ object có tiền tố prefix (VD: exports/<userID>/)
+func (s *mongoStore) RemovePrefix(ctx context.Context, prefix string) error {
+	objects := config.MinioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	for obj := range objects {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := s.RemoveObject(ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package ginPrivacy
+
+import (
+	"errors"
+	"my-app/common"
+	"my-app/modules/privacy/biz"
+	"my-app/modules/privacy/models"
+	"my-app/modules/privacy/storage"
+	"my-app/utils"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ScheduleDeletionHandler - user yêu cầu xóa vĩnh viễn tài khoản của mình.
+// Với route admin, userID lấy từ :id.
+func ScheduleDeletionHandler(db *mongo.Database, esClient *elasticsearch.Client, grace time.Duration, isAdmin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requesterID := c.GetString("userID")
+		if requesterID == "" {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.DeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		userID := requesterID
+		if isAdmin {
+			userID = c.Param("id")
+		}
+
+		store := storage.NewMongoStore(db, esClient)
+		business := biz.NewDeletionBiz(store, grace)
+
+		// User tự xóa phải xác thực lại, token bị lộ không đủ để xóa tài khoản
+		if !isAdmin {
+			if err := business.Reauthenticate(c.Request.Context(), userID, req.Password, c.GetTime("authTime")); err != nil {
+				if errors.Is(err, biz.ErrReauthRequired) {
+					c.JSON(http.StatusUnauthorized, common.NewUnauthorized(err, err.Error(), "reauthentication required", "REAUTH_REQUIRED"))
+					return
+				}
+				c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+				return
+			}
+		}
+
+		data, err := business.Schedule(c.Request.Context(), userID, requesterID, req.Reason)
+		if err != nil {
+			switch {
+			case errors.Is(err, biz.ErrDeletionExists):
+				c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), data))
+			case errors.Is(err, mongo.ErrNoDocuments):
+				c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, "Không tìm thấy người dùng", nil))
+			default:
+				c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Tài khoản sẽ bị xóa vĩnh viễn vào "+data.ScheduledAt.Format("02/01/2006 15:04"), data))
+	}
+}
+
+func GetDeletionHandler(db *mongo.Database, esClient *elasticsearch.Client, isAdmin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if isAdmin {
+			userID = c.Param("id")
+		}
+
+		store := storage.NewMongoStore(db, esClient)
+		business := biz.NewDeletionBiz(store, 0)
+
+		data, err := business.Get(c.Request.Context(), userID)
+		if err != nil {
+			writeDeletionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy lịch xóa tài khoản thành công", data))
+	}
+}
+
+// CancelDeletionHandler hủy yêu cầu xóa trong thời gian chờ
+func CancelDeletionHandler(db *mongo.Database, esClient *elasticsearch.Client, isAdmin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if isAdmin {
+			userID = c.Param("id")
+		}
+
+		store := storage.NewMongoStore(db, esClient)
+		business := biz.NewDeletionBiz(store, 0)
+
+		if err := business.Cancel(c.Request.Context(), userID); err != nil {
+			writeDeletionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Đã hủy yêu cầu xóa tài khoản", nil))
+	}
+}
+
+func writeDeletionError(c *gin.Context, err error) {
+	if errors.Is(err, biz.ErrDeletionNotFound) {
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+}
@@ -0,0 +1,109 @@
+package ginPrivacy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"my-app/common"
+	"my-app/modules/privacy/biz"
+	"my-app/modules/privacy/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RequestExportHandler - user tự yêu cầu xuất dữ liệu cá nhân
+func RequestExportHandler(db *mongo.Database, esClient *elasticsearch.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+		requestExport(c, db, esClient, userID, userID)
+	}
+}
+
+// AdminRequestExportHandler - admin xuất dữ liệu của user :id
+func AdminRequestExportHandler(db *mongo.Database, esClient *elasticsearch.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestExport(c, db, esClient, c.Param("id"), c.GetString("userID"))
+	}
+}
+
+func requestExport(c *gin.Context, db *mongo.Database, esClient *elasticsearch.Client, userID, requestedBy string) {
+	store := storage.NewMongoStore(db, esClient)
+	business := biz.NewExportBiz(store)
+
+	job, err := business.RequestExport(c.Request.Context(), userID, requestedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, biz.ErrExportRunning):
+			c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), job))
+		case errors.Is(err, mongo.ErrNoDocuments):
+			c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, "Không tìm thấy người dùng", nil))
+		default:
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, common.NewResponse(http.StatusAccepted, "Đang chuẩn bị dữ liệu, vui lòng kiểm tra lại sau", job))
+}
+
+// GetExportHandler xem trạng thái job, isAdmin = true cho route admin
+func GetExportHandler(db *mongo.Database, esClient *elasticsearch.Client, isAdmin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStore(db, esClient)
+		business := biz.NewExportBiz(store)
+
+		job, err := business.GetJob(c.Request.Context(), c.Param("id"), c.GetString("userID"), isAdmin)
+		if err != nil {
+			writeExportError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Lấy trạng thái xuất dữ liệu thành công", job))
+	}
+}
+
+// DownloadExportHandler tải file ZIP, stream trực tiếp từ MinIO
+func DownloadExportHandler(db *mongo.Database, esClient *elasticsearch.Client, isAdmin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStore(db, esClient)
+		business := biz.NewExportBiz(store)
+
+		job, reader, err := business.OpenDownload(c.Request.Context(), c.Param("id"), c.GetString("userID"), isAdmin)
+		if err != nil {
+			writeExportError(c, err)
+			return
+		}
+		defer reader.Close()
+
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="unichat_data_%s.zip"`, job.CreatedAt.Format("20060102_150405")))
+		if job.Size > 0 {
+			c.Header("Content-Length", strconv.FormatInt(job.Size, 10))
+		}
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, reader)
+	}
+}
+
+func writeExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, "Không tìm thấy yêu cầu xuất dữ liệu", nil))
+	case errors.Is(err, biz.ErrForbidden):
+		c.JSON(http.StatusForbidden, common.NewResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, biz.ErrExportNotReady):
+		c.JSON(http.StatusConflict, common.NewResponse(http.StatusConflict, err.Error(), nil))
+	case errors.Is(err, biz.ErrExportExpired):
+		c.JSON(http.StatusGone, common.NewResponse(http.StatusGone, err.Error(), nil))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+	}
+}
@@ -0,0 +1,309 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"my-app/modules/user/models"
+	"my-app/utils"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultImportRole = "user"
+	groupMemberRole   = "member"
+	maxImportRows     = 2000
+)
+
+type BulkImportStorage interface {
+	Create(ctx context.Context, data *models.User) error
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByPhone(ctx context.Context, phone string) (*models.User, error)
+	FindRoleIDsByCodes(ctx context.Context, codes []string) (map[string]primitive.ObjectID, error)
+	AssignRoles(ctx context.Context, userID primitive.ObjectID, roleIDs []primitive.ObjectID) error
+	GroupExists(ctx context.Context, groupID primitive.ObjectID) (bool, error)
+	AddUserToGroup(ctx context.Context, groupID, userID, roleID primitive.ObjectID) error
+	SaveImportReport(ctx context.Context, report *models.BulkImportReport) error
+	FindImportReport(ctx context.Context, id primitive.ObjectID) (*models.BulkImportReport, error)
+}
+
+type BulkImportBiz struct {
+	store BulkImportStorage
+}
+
+func NewBulkImportBiz(store BulkImportStorage) *BulkImportBiz {
+	return &BulkImportBiz{store: store}
+}
+
+// Import kiểm tra từng dòng, nếu dryRun = false thì tạo user, gán role và thêm vào nhóm.
+// canAssignRoles = false thì chỉ được để trống cột roles hoặc dùng role mặc định.
+// Kết quả luôn được lưu lại thành report để tải về.
+func (biz *BulkImportBiz) Import(ctx context.Context, importedBy, fileName string, rows []models.BulkImportRow, dryRun, canAssignRoles bool) (*models.BulkImportReport, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file không có dữ liệu")
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("mỗi lần chỉ import tối đa %d dòng", maxImportRows)
+	}
+
+	// Lấy trước toàn bộ role được dùng trong file
+	codeSet := map[string]bool{defaultImportRole: true, groupMemberRole: true}
+	for _, row := range rows {
+		for _, code := range row.Roles {
+			codeSet[strings.ToLower(code)] = true
+		}
+	}
+	codes := make([]string, 0, len(codeSet))
+	for code := range codeSet {
+		codes = append(codes, code)
+	}
+	roleIDs, err := biz.store.FindRoleIDsByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	groupCache := make(map[string]bool)
+	seenUsername := make(map[string]int)
+	seenEmail := make(map[string]int)
+	seenPhone := make(map[string]int)
+
+	report := &models.BulkImportReport{
+		ID:         primitive.NewObjectID(),
+		FileName:   fileName,
+		ImportedBy: importedBy,
+		DryRun:     dryRun,
+		Total:      len(rows),
+		CreatedAt:  time.Now(),
+	}
+
+	for _, row := range rows {
+		result := biz.validateRow(ctx, row, roleIDs, canAssignRoles, groupCache, seenUsername, seenEmail, seenPhone)
+
+		if len(result.Errors) == 0 && !dryRun {
+			biz.createRow(ctx, row, roleIDs, &result)
+		}
+
+		if len(result.Errors) > 0 {
+			result.Status = models.ImportRowError
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if err := biz.store.SaveImportReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (biz *BulkImportBiz) validateRow(
+	ctx context.Context,
+	row models.BulkImportRow,
+	roleIDs map[string]primitive.ObjectID,
+	canAssignRoles bool,
+	groupCache map[string]bool,
+	seenUsername, seenEmail, seenPhone map[string]int,
+) models.BulkImportRowResult {
+	result := models.BulkImportRowResult{
+		Row:      row.Line,
+		Username: row.Username,
+		Email:    row.Email,
+		Status:   models.ImportRowValid,
+		Groups:   row.Groups,
+	}
+
+	addErr := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	if err := utils.ValidateStruct(&row); err != nil {
+		// Dùng lại thông báo lỗi tiếng Việt của utils
+		if fields, ok := utils.HandleValidationErrors(err)["error"].(map[string]string); ok {
+			for _, msg := range fields {
+				addErr("%s", msg)
+			}
+		} else {
+			addErr("%v", err)
+		}
+	}
+
+	if row.Phone != "" {
+		if err := validatePhone(row.Phone); err != nil {
+			addErr("%v", err)
+		}
+	}
+
+	if row.Birthday != "" {
+		if _, err := parseImportDate(row.Birthday); err != nil {
+			addErr("Ngày sinh không hợp lệ (dd/mm/yyyy hoặc yyyy-mm-dd)")
+		}
+	}
+
+	// Trùng trong chính file import
+	if line, ok := seenUsername[strings.ToLower(row.Username)]; ok && row.Username != "" {
+		addErr("Username trùng với dòng %d", line)
+	}
+	if line, ok := seenEmail[row.Email]; ok && row.Email != "" {
+		addErr("Email trùng với dòng %d", line)
+	}
+	if line, ok := seenPhone[row.Phone]; ok && row.Phone != "" {
+		addErr("Số điện thoại trùng với dòng %d", line)
+	}
+	seenUsername[strings.ToLower(row.Username)] = row.Line
+	seenEmail[row.Email] = row.Line
+	if row.Phone != "" {
+		seenPhone[row.Phone] = row.Line
+	}
+
+	// Trùng với dữ liệu đã có
+	if row.Username != "" {
+		if u, err := biz.store.FindByUsername(ctx, row.Username); err == nil && u != nil {
+			addErr("Username đã tồn tại")
+		}
+	}
+	if row.Email != "" {
+		if u, err := biz.store.FindByEmail(ctx, row.Email); err == nil && u != nil {
+			addErr("Email đã tồn tại")
+		}
+	}
+	if row.Phone != "" {
+		if u, err := biz.store.FindByPhone(ctx, row.Phone); err == nil && u != nil {
+			addErr("Số điện thoại đã tồn tại")
+		}
+	}
+
+	roles := row.Roles
+	if len(roles) == 0 {
+		roles = []string{defaultImportRole}
+	}
+	for _, code := range roles {
+		code = strings.ToLower(code)
+		if _, ok := roleIDs[code]; !ok {
+			addErr("Role %s không tồn tại", code)
+			continue
+		}
+		if code != defaultImportRole && !canAssignRoles {
+			addErr("Bạn không có quyền gán role %s", code)
+			continue
+		}
+		result.Roles = append(result.Roles, code)
+	}
+
+	for _, gid := range row.Groups {
+		exists, checked := groupCache[gid]
+		if !checked {
+			oid, err := primitive.ObjectIDFromHex(gid)
+			if err == nil {
+				exists, _ = biz.store.GroupExists(ctx, oid)
+			}
+			groupCache[gid] = exists
+		}
+		if !exists {
+			addErr("Nhóm %s không tồn tại", gid)
+		}
+	}
+	if len(row.Groups) > 0 {
+		if _, ok := roleIDs[groupMemberRole]; !ok {
+			addErr("Chưa cấu hình role %s cho nhóm", groupMemberRole)
+		}
+	}
+
+	return result
+}
+
+func (biz *BulkImportBiz) createRow(ctx context.Context, row models.BulkImportRow, roleIDs map[string]primitive.ObjectID, result *models.BulkImportRowResult) {
+	password := row.Password
+	if password == "" {
+		password = generatePassword()
+		result.Password = password
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		result.Errors = append(result.Errors, "Không mã hóa được mật khẩu")
+		return
+	}
+
+	displayName := row.DisplayName
+	if displayName == "" {
+		displayName = row.Username
+	}
+
+	birthday, _ := parseImportDate(row.Birthday)
+	now := time.Now()
+
+	user := &models.User{
+		Username:          row.Username,
+		Email:             row.Email,
+		Phone:             row.Phone,
+		DisplayName:       displayName,
+		Password:          string(hash),
+		Gender:            row.Gender,
+		Birthday:          birthday,
+		IsProfileComplete: row.Phone != "" && row.Gender != "" && row.Birthday != "",
+		Type:              "normal",
+	}
+	user.ID = primitive.NewObjectID()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	if err := biz.store.Create(ctx, user); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Không tạo được user: %v", err))
+		return
+	}
+
+	result.Status = models.ImportRowCreated
+	result.UserID = user.ID.Hex()
+
+	// Lỗi gán role/nhóm không rollback user, chỉ ghi cảnh báo vào report
+	var ids []primitive.ObjectID
+	for _, code := range result.Roles {
+		ids = append(ids, roleIDs[code])
+	}
+	if err := biz.store.AssignRoles(ctx, user.ID, ids); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Gán role thất bại: %v", err))
+	}
+
+	for _, gid := range row.Groups {
+		groupID, _ := primitive.ObjectIDFromHex(gid)
+		if err := biz.store.AddUserToGroup(ctx, groupID, user.ID, roleIDs[groupMemberRole]); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Thêm vào nhóm %s thất bại: %v", gid, err))
+		}
+	}
+}
+
+// GetReport lấy lại kết quả của 1 lần import
+func (biz *BulkImportBiz) GetReport(ctx context.Context, id string) (*models.BulkImportReport, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("ID report không hợp lệ")
+	}
+	return biz.store.FindImportReport(ctx, oid)
+}
+
+func parseImportDate(value string) (time.Time, error) {
+	var lastErr error
+	for _, layout := range []string{"02/01/2006", "2006-01-02", "01-02-06"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
+
+func generatePassword() string {
+	b := make([]byte, 9)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,109 @@
+package biz
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"my-app/modules/user/models"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ParseImportFile đọc file CSV/XLSX, dòng đầu là header (username, email, password, phone,
+// display_name, gender, birthday, roles, groups). roles/groups ngăn cách bằng dấu phẩy hoặc ";".
+func ParseImportFile(fileName string, r io.Reader) ([]models.BulkImportRow, error) {
+	var records [][]string
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("không đọc được file CSV: %v", err)
+		}
+		records = rows
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("không đọc được file Excel: %v", err)
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("file Excel không có sheet nào")
+		}
+		rows, err := f.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("không đọc được sheet %s: %v", sheets[0], err)
+		}
+		records = rows
+	default:
+		return nil, fmt.Errorf("chỉ hỗ trợ file .csv hoặc .xlsx")
+	}
+
+	if len(records) < 2 {
+		return nil, fmt.Errorf("file không có dữ liệu")
+	}
+
+	header := make(map[string]int)
+	for i, col := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+		header[strings.ReplaceAll(key, " ", "_")] = i
+	}
+	for _, required := range []string{"username", "email"} {
+		if _, ok := header[required]; !ok {
+			return nil, fmt.Errorf("thiếu cột bắt buộc: %s", required)
+		}
+	}
+
+	cell := func(record []string, name string) string {
+		idx, ok := header[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	rows := make([]models.BulkImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // bỏ qua dòng trống
+		}
+
+		row := models.BulkImportRow{
+			Line:        i + 2,
+			Username:    cell(record, "username"),
+			Email:       strings.ToLower(cell(record, "email")),
+			Password:    cell(record, "password"),
+			Phone:       cell(record, "phone"),
+			DisplayName: cell(record, "display_name"),
+			Gender:      strings.ToLower(cell(record, "gender")),
+			Birthday:    cell(record, "birthday"),
+			Roles:       splitList(cell(record, "roles")),
+			Groups:      splitList(cell(record, "groups")),
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
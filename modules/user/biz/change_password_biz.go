@@ -2,7 +2,6 @@ package biz
 
 import (
 	"context"
-	"fmt"
 	"my-app/common"
 	"my-app/modules/user/models"
 
@@ -29,19 +28,16 @@ func (biz *changePasswordBiz) ChangePassword(ctx context.Context, userID string,
 	if err != nil {
 		return common.ErrCannotGetEntity("user", err)
 	}
-	fmt.Println("User1:", user)
 	// Verify old password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(data.OldPassword)); err != nil {
 		return common.ErrInvalidRequest(common.NewCustomError(err, "Mật khẩu cũ không chính xác", "ErrInvalidOldPassword"))
 	}
-	fmt.Println("User2:", user)
 
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return common.ErrInternal(err)
 	}
-	fmt.Println("User3:", user)
 
 	oid, _ := primitive.ObjectIDFromHex(userID)
 	if err := biz.store.UpdatePassword(ctx, oid, string(hashedPassword)); err != nil {
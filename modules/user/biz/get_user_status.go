@@ -3,20 +3,64 @@ package biz
 import (
 	"context"
 	"my-app/modules/user/models"
+	"time"
 )
 
 type UserStatusStore interface {
-	GetAll(ctx context.Context, currentUserID string) ([]models.UserStatusResponse, error)
+	GetAll(ctx context.Context, currentUserID string, excludedIDs []string) ([]models.UserStatusResponse, error)
+}
+
+// FriendRelationStore đọc quan hệ bạn bè/chặn, do friend storage cài đặt
+type FriendRelationStore interface {
+	GetFriendIDs(ctx context.Context, userID string) ([]string, error)
+	GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error)
 }
 
 type getUserStatusBiz struct {
-	store UserStatusStore
+	store     UserStatusStore
+	relations FriendRelationStore
 }
 
-func NewGetUserStatusBiz(store UserStatusStore) *getUserStatusBiz {
-	return &getUserStatusBiz{store: store}
+func NewGetUserStatusBiz(store UserStatusStore, relations FriendRelationStore) *getUserStatusBiz {
+	return &getUserStatusBiz{store: store, relations: relations}
 }
 
 func (biz *getUserStatusBiz) GetAll(ctx context.Context, currentUserID string) ([]models.UserStatusResponse, error) {
-	return biz.store.GetAll(ctx, currentUserID)
+	blockedIDs, err := biz.relations.GetBlockedUserIDs(ctx, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := biz.store.GetAll(ctx, currentUserID, blockedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	friendIDs, err := biz.relations.GetFriendIDs(ctx, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	friends := make(map[string]bool, len(friendIDs))
+	for _, id := range friendIDs {
+		friends[id] = true
+	}
+
+	now := time.Now()
+	for i := range results {
+		r := &results[i]
+
+		// Trạng thái tùy chỉnh hết hạn thì không trả về
+		if !r.CustomStatus.IsActive(now) {
+			r.CustomStatus = nil
+		}
+
+		// Ẩn last seen theo thiết lập quyền riêng tư của người kia
+		status := models.UserStatus{LastSeenPrivacy: r.LastSeenPrivacy}
+		if !status.CanSeeLastSeen(friends[r.UserID]) {
+			r.LastSeen = nil
+			r.UpdatedAt = time.Time{}
+		}
+	}
+
+	return results, nil
 }
@@ -3,7 +3,7 @@ package biz
 import (
 	"context"
 	"errors"
-	"fmt"
+	"my-app/common/logger"
 	"my-app/modules/user/models"
 	"my-app/utils"
 
@@ -40,7 +40,7 @@ func (biz *LoginBiz) Login(ctx context.Context, data *models.LoginRequest) (stri
 		roles = []string{}
 	}
 
-	fmt.Printf("[LOGIN BIZ] UserID: %s, Roles from storage: %v\n", user.ID.Hex(), roles)
+	logger.Ctx(ctx, "user").Debug().Str("user_id", user.ID.Hex()).Strs("roles", roles).Msg("login")
 
 	token, err := utils.GenerateJWT(user.ID.Hex(), roles)
 
@@ -0,0 +1,70 @@
+package biz
+
+import (
+	"context"
+	"my-app/modules/user/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PresenceStorage interface {
+	GetPresence(ctx context.Context, userID string) (*models.UserStatus, error)
+	UpdatePresence(ctx context.Context, userID primitive.ObjectID, set bson.M, unset bson.M) error
+}
+
+type PresenceBiz struct {
+	store PresenceStorage
+}
+
+func NewPresenceBiz(store PresenceStorage) *PresenceBiz {
+	return &PresenceBiz{store: store}
+}
+
+// Update lưu trạng thái thủ công, custom status và quyền xem last seen, trả về presence mới
+func (biz *PresenceBiz) Update(ctx context.Context, userID string, req *models.UpdatePresenceRequest) (*models.UserStatus, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+
+	switch req.Status {
+	case "":
+	case models.StatusOnline:
+		unset["manual_status"] = ""
+	default:
+		set["manual_status"] = req.Status
+	}
+
+	if req.ClearCustom {
+		unset["custom_status"] = ""
+	} else if req.CustomText != nil || req.CustomEmoji != nil {
+		custom := models.CustomStatus{}
+		if req.CustomText != nil {
+			custom.Text = strings.TrimSpace(*req.CustomText)
+		}
+		if req.CustomEmoji != nil {
+			custom.Emoji = strings.TrimSpace(*req.CustomEmoji)
+		}
+		if req.ExpiresInMinutes > 0 {
+			expiresAt := time.Now().Add(time.Duration(req.ExpiresInMinutes) * time.Minute)
+			custom.ExpiresAt = &expiresAt
+		}
+		set["custom_status"] = custom
+	}
+
+	if req.LastSeenPrivacy != "" {
+		set["last_seen_privacy"] = req.LastSeenPrivacy
+	}
+
+	if err := biz.store.UpdatePresence(ctx, oid, set, unset); err != nil {
+		return nil, err
+	}
+
+	return biz.store.GetPresence(ctx, userID)
+}
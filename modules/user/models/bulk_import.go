@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trạng thái của từng dòng khi import
+const (
+	ImportRowValid   = "valid"   // dry-run: dòng hợp lệ, sẽ được tạo
+	ImportRowCreated = "created" // đã tạo user
+	ImportRowError   = "error"
+)
+
+// BulkImportRow là 1 dòng trong file CSV/XLSX (tên cột = json tag)
+type BulkImportRow struct {
+	Line        int      `json:"-"` // số dòng trong file (tính cả header)
+	Username    string   `json:"username" binding:"required,min=3,max=50"`
+	Email       string   `json:"email" binding:"required,email"`
+	Password    string   `json:"password" binding:"omitempty,min=6"`
+	Phone       string   `json:"phone"`
+	DisplayName string   `json:"display_name" binding:"max=100"`
+	Gender      string   `json:"gender" binding:"omitempty,oneof=male female other"`
+	Birthday    string   `json:"birthday"`
+	Roles       []string `json:"roles"`  // mã role, mặc định "user"
+	Groups      []string `json:"groups"` // ID nhóm sẽ được thêm vào với vai trò member
+}
+
+type BulkImportRowResult struct {
+	Row      int      `bson:"row" json:"row"`
+	Username string   `bson:"username" json:"username"`
+	Email    string   `bson:"email" json:"email"`
+	Status   string   `bson:"status" json:"status"`
+	UserID   string   `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Password string   `bson:"-" json:"password,omitempty"` // mật khẩu sinh tự động, chỉ trả về 1 lần, không lưu DB
+	Roles    []string `bson:"roles,omitempty" json:"roles,omitempty"`
+	Groups   []string `bson:"groups,omitempty" json:"groups,omitempty"`
+	Errors   []string `bson:"errors,omitempty" json:"errors,omitempty"`
+	Warnings []string `bson:"warnings,omitempty" json:"warnings,omitempty"`
+}
+
+// BulkImportReport được lưu lại để admin tải kết quả về sau
+type BulkImportReport struct {
+	ID         primitive.ObjectID    `bson:"_id" json:"id"`
+	FileName   string                `bson:"file_name" json:"file_name"`
+	ImportedBy string                `bson:"imported_by" json:"imported_by"`
+	DryRun     bool                  `bson:"dry_run" json:"dry_run"`
+	Total      int                   `bson:"total" json:"total"`
+	Succeeded  int                   `bson:"succeeded" json:"succeeded"`
+	Failed     int                   `bson:"failed" json:"failed"`
+	Rows       []BulkImportRowResult `bson:"rows" json:"rows"`
+	CreatedAt  time.Time             `bson:"created_at" json:"created_at"`
+}
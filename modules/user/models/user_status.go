@@ -6,16 +6,76 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Trạng thái hiển thị của user
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+	StatusAway    = "away"
+	StatusBusy    = "busy"
+	StatusDND     = "dnd" // không làm phiền
+)
+
+// Ai được xem thời điểm truy cập gần nhất
+const (
+	LastSeenEveryone = "everyone"
+	LastSeenFriends  = "friends"
+	LastSeenNobody   = "nobody"
+)
+
 type UserStatus struct {
 	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Status    string             `bson:"status" json:"status"` // online || offline
+	Status    string             `bson:"status" json:"status"` // online || offline || away || busy || dnd
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+
+	LastSeen        *time.Time    `bson:"last_seen,omitempty" json:"last_seen,omitempty"`
+	ManualStatus    string        `bson:"manual_status,omitempty" json:"manual_status,omitempty"` // away/busy/dnd do user tự chọn
+	CustomStatus    *CustomStatus `bson:"custom_status,omitempty" json:"custom_status,omitempty"`
+	LastSeenPrivacy string        `bson:"last_seen_privacy,omitempty" json:"last_seen_privacy,omitempty"`
+}
+
+type CustomStatus struct {
+	Text      string     `bson:"text" json:"text"`
+	Emoji     string     `bson:"emoji,omitempty" json:"emoji,omitempty"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// IsActive trả về false nếu trạng thái tùy chỉnh đã hết hạn
+func (c *CustomStatus) IsActive(now time.Time) bool {
+	if c == nil {
+		return false
+	}
+	return c.ExpiresAt == nil || c.ExpiresAt.After(now)
+}
+
+// CanSeeLastSeen kiểm tra viewer có được xem last seen không
+func (s *UserStatus) CanSeeLastSeen(isFriend bool) bool {
+	switch s.LastSeenPrivacy {
+	case LastSeenNobody:
+		return false
+	case LastSeenFriends:
+		return isFriend
+	default:
+		return true
+	}
+}
+
+type UpdatePresenceRequest struct {
+	Status           string  `json:"status" binding:"omitempty,oneof=online away busy dnd"` // online = bỏ trạng thái thủ công
+	CustomText       *string `json:"custom_text" binding:"omitempty,max=100"`
+	CustomEmoji      *string `json:"custom_emoji" binding:"omitempty,max=16"`
+	ExpiresInMinutes int     `json:"expires_in_minutes" binding:"min=0,max=10080"` // 0 = không hết hạn
+	ClearCustom      bool    `json:"clear_custom"`
+	LastSeenPrivacy  string  `json:"last_seen_privacy" binding:"omitempty,oneof=everyone friends nobody"`
 }
 
 type UserStatusResponse struct {
-	UserID    string    `bson:"user_id" json:"user_id"`
-	Name      string    `bson:"name" json:"name"`
-	Avatar    string    `bson:"avatar" json:"avatar"`
-	Status    string    `bson:"status" json:"status"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	UserID       string        `bson:"user_id" json:"user_id"`
+	Name         string        `bson:"name" json:"name"`
+	Avatar       string        `bson:"avatar" json:"avatar"`
+	Status       string        `bson:"status" json:"status"`
+	UpdatedAt    time.Time     `bson:"updated_at" json:"updated_at"`
+	LastSeen     *time.Time    `bson:"last_seen,omitempty" json:"last_seen,omitempty"`
+	CustomStatus *CustomStatus `bson:"custom_status,omitempty" json:"custom_status,omitempty"`
+
+	LastSeenPrivacy string `bson:"last_seen_privacy,omitempty" json:"-"`
 }
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"my-app/modules/user/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindRoleIDsByCodes trả về map code -> role ID của các role còn hoạt động
+func (s *mongoStore) FindRoleIDsByCodes(ctx context.Context, codes []string) (map[string]primitive.ObjectID, error) {
+	result := make(map[string]primitive.ObjectID)
+	if len(codes) == 0 {
+		return result, nil
+	}
+
+	cursor, err := s.db.Collection("roles").Find(ctx, bson.M{
+		"code":       bson.M{"$in": codes},
+		"is_deleted": bson.M{"$ne": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var role struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Code string             `bson:"code"`
+		}
+		if err := cursor.Decode(&role); err == nil {
+			result[role.Code] = role.ID
+		}
+	}
+
+	return result, cursor.Err()
+}
+
+func (s *mongoStore) AssignRoles(ctx context.Context, userID primitive.ObjectID, roleIDs []primitive.ObjectID) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		docs = append(docs, bson.M{
+			"_id":        primitive.NewObjectID(),
+			"user_id":    userID,
+			"role_id":    roleID,
+			"created_at": now,
+			"updated_at": now,
+			"is_deleted": false,
+		})
+	}
+
+	_, err := s.db.Collection("user_roles").InsertMany(ctx, docs)
+	return err
+}
+
+func (s *mongoStore) GroupExists(ctx context.Context, groupID primitive.ObjectID) (bool, error) {
+	count, err := s.db.Collection("group").CountDocuments(ctx, bson.M{"_id": groupID})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddUserToGroup thêm user vào nhóm qua group_user_roles (role_id lưu dạng hex)
+func (s *mongoStore) AddUserToGroup(ctx context.Context, groupID, userID, roleID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := s.db.Collection("group_user_roles").UpdateOne(ctx,
+		bson.M{
+			"group_id": groupID.Hex(),
+			"user_id":  userID.Hex(),
+		},
+		bson.M{
+			"$set": bson.M{
+				"role_id":    roleID.Hex(),
+				"is_deleted": false,
+				"updated_at": now,
+			},
+			"$setOnInsert": bson.M{
+				"_id":        primitive.NewObjectID(),
+				"created_at": now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *mongoStore) SaveImportReport(ctx context.Context, report *models.BulkImportReport) error {
+	_, err := s.db.Collection("user_import_reports").InsertOne(ctx, report)
+	return err
+}
+
+func (s *mongoStore) FindImportReport(ctx context.Context, id primitive.ObjectID) (*models.BulkImportReport, error) {
+	var report models.BulkImportReport
+	if err := s.db.Collection("user_import_reports").FindOne(ctx, bson.M{"_id": id}).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -7,7 +7,8 @@ import (
 )
 
 func (s *mongoStore) CountOnlineUsers(ctx context.Context) (int64, error) {
-	filter := bson.M{"status": "online"}
+	// away / busy / dnd vẫn đang kết nối nên cũng tính là online
+	filter := bson.M{"status": bson.M{"$ne": "offline"}}
 	count, err := s.db.Collection("user_status").CountDocuments(ctx, filter)
 	if err != nil {
 		return 0, err
@@ -10,17 +10,63 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Upsert chỉ cập nhật trạng thái kết nối, giữ nguyên các thiết lập presence của user
 func (s *mongoStore) Upsert(ctx context.Context, status *models.UserStatus) error {
 	_, err := s.db.Collection("user_status").UpdateOne(
 		ctx,
 		bson.M{"user_id": status.UserID},
-		bson.M{"$set": status},
+		bson.M{"$set": bson.M{
+			"status":     status.Status,
+			"updated_at": status.UpdatedAt,
+			"last_seen":  status.UpdatedAt,
+		}},
 		options.Update().SetUpsert(true),
 	)
 	return err
 }
 
-func (s *mongoStore) GetAll(ctx context.Context, currentUserID string) ([]models.UserStatusResponse, error) {
+// GetPresence lấy thiết lập presence của user, chưa có thì trả về bản rỗng
+func (s *mongoStore) GetPresence(ctx context.Context, userID string) (*models.UserStatus, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var status models.UserStatus
+	err = s.db.Collection("user_status").FindOne(ctx, bson.M{"user_id": oid}).Decode(&status)
+	if err == mongo.ErrNoDocuments {
+		return &models.UserStatus{UserID: oid}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+func (s *mongoStore) UpdatePresence(ctx context.Context, userID primitive.ObjectID, set bson.M, unset bson.M) error {
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	_, err := s.db.Collection("user_status").UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		update,
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetAll lấy trạng thái mọi user trừ bản thân và excludedIDs (những người đang chặn/bị chặn)
+func (s *mongoStore) GetAll(ctx context.Context, currentUserID string, excludedIDs []string) ([]models.UserStatusResponse, error) {
 	collection := s.db.Collection("user_status")
 
 	oid, err := primitive.ObjectIDFromHex(currentUserID)
@@ -28,8 +74,15 @@ func (s *mongoStore) GetAll(ctx context.Context, currentUserID string) ([]models
 		return nil, err
 	}
 
+	excluded := []primitive.ObjectID{oid}
+	for _, id := range excludedIDs {
+		if otherID, err := primitive.ObjectIDFromHex(id); err == nil {
+			excluded = append(excluded, otherID)
+		}
+	}
+
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"user_id": bson.M{"$ne": oid}}}},
+		{{Key: "$match", Value: bson.M{"user_id": bson.M{"$nin": excluded}}}},
 
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "users",
@@ -41,11 +94,14 @@ func (s *mongoStore) GetAll(ctx context.Context, currentUserID string) ([]models
 		{{Key: "$unwind", Value: bson.M{"path": "$user_info", "preserveNullAndEmptyArrays": true}}},
 
 		{{Key: "$project", Value: bson.M{
-			"user_id":    "$user_id",
-			"name":       "$user_info.display_name",
-			"avatar":     "$user_info.avatar",
-			"status":     "$status",
-			"updated_at": "$updated_at",
+			"user_id":           "$user_id",
+			"name":              "$user_info.display_name",
+			"avatar":            "$user_info.avatar",
+			"status":            "$status",
+			"updated_at":        "$updated_at",
+			"last_seen":         "$last_seen",
+			"custom_status":     "$custom_status",
+			"last_seen_privacy": "$last_seen_privacy",
 		}}},
 
 		// Sắp xếp: online trước, sau đó theo updated_at giảm dần
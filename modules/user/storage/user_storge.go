@@ -2,7 +2,7 @@ package storage
 
 import (
 	"context"
-	"fmt"
+	"my-app/common/logger"
 	"my-app/modules/user/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -148,7 +148,7 @@ func (s *mongoStore) GetUserRoles(ctx context.Context, userID string) ([]string,
 		}
 	}
 
-	fmt.Printf("✅ [GetUserRoles] FINAL RESULT: %v\n", roles)
+	logger.Ctx(ctx, "user").Debug().Strs("roles", roles).Msg("GetUserRoles")
 	return roles, nil
 }
 
@@ -234,6 +234,6 @@ func (s *mongoStore) GetUserPermissions(ctx context.Context, userID string) ([]s
 		}
 	}
 
-	fmt.Printf("✅ [GetUserPermissions] FINAL RESULT: %v\n", permissions)
+	logger.Ctx(ctx, "user").Debug().Strs("permissions", permissions).Msg("GetUserPermissions")
 	return permissions, nil
 }
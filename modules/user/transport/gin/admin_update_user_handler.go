@@ -3,6 +3,7 @@ package ginUser
 import (
 	"fmt"
 	"my-app/common"
+	"my-app/common/logger"
 	"my-app/modules/user/biz"
 	"my-app/modules/user/models"
 	"my-app/modules/user/storage"
@@ -45,8 +46,6 @@ func AdminUpdateUserHandler(db *mongo.Database) gin.HandlerFunc {
 				req.Roles = rolesStr
 			}
 
-			fmt.Printf("🔍 [Admin Update] Received roles from form: %v (count=%d)\n", rolesStr, len(rolesStr))
-
 			birthdayStr := c.PostForm("birthday")
 			if birthdayStr != "" {
 				birthday, err := time.Parse("2006-01-02", birthdayStr)
@@ -83,7 +82,7 @@ func AdminUpdateUserHandler(db *mongo.Database) gin.HandlerFunc {
 
 		store := storage.NewMongoStore(db)
 		business := biz.NewAdminUpdateUserBiz(store)
-		fmt.Printf("🔍 [Admin Update] Request Roles: %v\n", req.Roles)
+		logger.Ctx(c.Request.Context(), "user").Debug().Str("target_user_id", userID.Hex()).Strs("roles", req.Roles).Msg("admin update user")
 		if err := business.UpdateUser(c.Request.Context(), userID, &req); err != nil {
 			c.JSON(http.StatusBadRequest, common.NewResponse(400, fmt.Sprintf("Cập nhật thất bại: %v", err), nil))
 			return
@@ -0,0 +1,138 @@
+package ginUser
+
+import (
+	"fmt"
+	"my-app/common"
+	permissionBiz "my-app/modules/permission/biz"
+	"my-app/modules/user/biz"
+	"my-app/modules/user/storage"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxImportFileSize = 10 << 20 // 10MB
+
+// Cột roles cần cùng quyền với luồng gán role cho từng user (PATCH /admin/user/:id)
+const assignRolePermission = "system:user:update_global"
+
+// BulkImportUsersHandler nhận file CSV/XLSX (field "file"), query dry_run=true để chỉ kiểm tra
+func BulkImportUsersHandler(db *mongo.Database, permBiz *permissionBiz.PermissionBiz) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, _ := c.Get("userID")
+		adminIDStr, _ := adminID.(string)
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "Vui lòng chọn file CSV hoặc XLSX", nil))
+			return
+		}
+		if fileHeader.Size > maxImportFileSize {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "File vượt quá 10MB", nil))
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, "Không mở được file", nil))
+			return
+		}
+		defer file.Close()
+
+		rows, err := biz.ParseImportFile(fileHeader.Filename, file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		dryRun := c.DefaultQuery("dry_run", "false") == "true"
+
+		canAssignRoles, err := permBiz.HasPermission(c.Request.Context(), c.GetStringSlice("roles"), assignRolePermission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, common.ErrDB(err))
+			return
+		}
+
+		store := storage.NewMongoStore(db)
+		business := biz.NewBulkImportBiz(store)
+
+		report, err := business.Import(c.Request.Context(), adminIDStr, fileHeader.Filename, rows, dryRun, canAssignRoles)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		message := "Import người dùng hoàn tất"
+		if dryRun {
+			message = "Kiểm tra dữ liệu import hoàn tất"
+		}
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, message, gin.H{
+			"report_id":  report.ID.Hex(),
+			"dry_run":    report.DryRun,
+			"total":      report.Total,
+			"succeeded":  report.Succeeded,
+			"failed":     report.Failed,
+			"rows":       report.Rows,
+			"report_url": fmt.Sprintf("%s/%s/report", strings.TrimSuffix(c.FullPath(), "/"), report.ID.Hex()),
+		}))
+	}
+}
+
+// DownloadImportReportHandler xuất kết quả import ra file Excel
+func DownloadImportReportHandler(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := storage.NewMongoStore(db)
+		business := biz.NewBulkImportBiz(store)
+
+		report, err := business.GetReport(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, common.NewResponse(http.StatusNotFound, "Không tìm thấy report", nil))
+				return
+			}
+			c.JSON(http.StatusBadRequest, common.NewResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+
+		f := excelize.NewFile()
+		defer f.Close()
+
+		sheet := "Kết quả import"
+		f.SetSheetName("Sheet1", sheet)
+
+		headers := []string{"Dòng", "Username", "Email", "Trạng thái", "User ID", "Role", "Nhóm", "Lỗi", "Cảnh báo"}
+		for i, h := range headers {
+			colName, _ := excelize.ColumnNumberToName(i + 1)
+			f.SetCellValue(sheet, colName+"1", h)
+		}
+
+		for i, item := range report.Rows {
+			row := i + 2
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), item.Row)
+			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), item.Username)
+			f.SetCellValue(sheet, fmt.Sprintf("C%d", row), item.Email)
+			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), item.Status)
+			f.SetCellValue(sheet, fmt.Sprintf("E%d", row), item.UserID)
+			f.SetCellValue(sheet, fmt.Sprintf("F%d", row), strings.Join(item.Roles, ", "))
+			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), strings.Join(item.Groups, ", "))
+			f.SetCellValue(sheet, fmt.Sprintf("H%d", row), strings.Join(item.Errors, "; "))
+			f.SetCellValue(sheet, fmt.Sprintf("I%d", row), strings.Join(item.Warnings, "; "))
+		}
+
+		f.SetColWidth(sheet, "A", "A", 8)
+		f.SetColWidth(sheet, "B", "G", 25)
+		f.SetColWidth(sheet, "H", "I", 50)
+
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import_report_%s.xlsx"`, report.CreatedAt.Format("20060102_150405")))
+
+		if err := f.Write(c.Writer); err != nil {
+			c.JSON(http.StatusInternalServerError, common.ErrInternal(err))
+			return
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package ginUser
 
 import (
-	"fmt"
 	"my-app/common"
 	"my-app/modules/user/biz"
 	"my-app/modules/user/storage"
@@ -42,11 +41,6 @@ func ListUsersWithStatusHandler(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
-		// DEBUG: Log response
-		if len(users) > 0 {
-			fmt.Printf("🔍 [Backend Handler] First user: Username=%s, Roles=%v\n", users[0].User.Username, users[0].Roles)
-		}
-
 		data := map[string]interface{}{
 			"users": users,
 			"page":  page,
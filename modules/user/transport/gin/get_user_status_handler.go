@@ -3,6 +3,7 @@ package ginUser
 import (
 	"context"
 	"my-app/common"
+	friendStorage "my-app/modules/friend/storage"
 	"my-app/modules/user/biz"
 	"my-app/modules/user/storage"
 	"net/http"
@@ -29,7 +30,7 @@ func GetUserStatusHandler(db *mongo.Database) gin.HandlerFunc {
 		}
 
 		store := storage.NewMongoStore(db)
-		business := biz.NewGetUserStatusBiz(store)
+		business := biz.NewGetUserStatusBiz(store, friendStorage.NewMongoStoreFriend(db))
 
 		result, err := business.GetAll(ctx, senderIDStr)
 		if err != nil {
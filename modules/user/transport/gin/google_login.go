@@ -2,7 +2,7 @@ package ginUser
 
 import (
 	"context"
-	"fmt"
+	"log"
 	"my-app/common"
 	"my-app/modules/user/biz"
 	"my-app/modules/user/models"
@@ -49,7 +49,7 @@ func GoogleLoginHandler(db *mongo.Database) gin.HandlerFunc {
 
 		minioURL, err := utils.UploadImageFromURLToMinio(picture)
 		if err != nil {
-			fmt.Printf(" Upload avatar Google thất bại: %v\n", err)
+			log.Printf("⚠️ Upload avatar Google thất bại: %v", err)
 		}
 
 		store := storage.NewMongoStore(db)
@@ -0,0 +1,43 @@
+package ginUser
+
+import (
+	"my-app/common"
+	"my-app/modules/chat/transport/websocket"
+	"my-app/modules/user/biz"
+	"my-app/modules/user/models"
+	"my-app/modules/user/storage"
+	"my-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func UpdatePresenceHandler(db *mongo.Database, hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, common.NewUnauthorized(nil, "Không tìm thấy userID trong token", "missing userID", "UNAUTHORIZED"))
+			return
+		}
+
+		var req models.UpdatePresenceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+
+		store := storage.NewMongoStore(db)
+		business := biz.NewPresenceBiz(store)
+
+		presence, err := business.Update(c.Request.Context(), userID.(string), &req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, common.NewResponse(http.StatusInternalServerError, err.Error(), nil))
+			return
+		}
+
+		hub.SetPresence(userID.(string), presence)
+
+		c.JSON(http.StatusOK, common.NewResponse(http.StatusOK, "Cập nhật trạng thái thành công", presence))
+	}
+}
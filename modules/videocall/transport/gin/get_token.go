@@ -41,6 +41,12 @@ func GetCallTokenHandler(cfg config.LiveKitConfig, hub *websocket.Hub, db *mongo
 			return
 		}
 
+		// Không cho gọi 1-1 khi hai người đang chặn nhau
+		if req.GroupID == "" && req.ReceiverID != "" && hub.IsBlocked(currentUser.ID.Hex(), req.ReceiverID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Không thể gọi cho người dùng này"})
+			return
+		}
+
 		at := auth.NewAccessToken(cfg.APIKey, cfg.APISecret)
 		grant := &auth.VideoGrant{
 			RoomJoin:       true,
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"my-app/config"
+	"my-app/internal/health"
 	"my-app/middleware"
 	"my-app/modules/chat/transport/websocket"
 	ginGroup "my-app/modules/group/transport/gin"
@@ -12,7 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func RegisterAdminRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket.Hub, permBiz *biz.PermissionBiz) {
+func RegisterAdminRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket.Hub, permBiz *biz.PermissionBiz, groupCfg config.GroupConfig, diagnostics *health.Diagnostics) {
 	admin := rg.Group("/admin")
 	{
 		// Kiểm tra quyền truy cập admin panel
@@ -31,6 +33,14 @@ func RegisterAdminRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket
 			middleware.RequirePermission("system:group:view_all", permBiz, db),
 			ginGroup.ListGroupMembersWithUserHandler(db))
 
+		// Khôi phục nhóm đã giải tán trong thời gian lưu giữ
+		admin.GET("/groups/dissolved",
+			middleware.RequirePermission("system:group:restore", permBiz, db),
+			ginGroup.ListDissolvedGroupsHandler(db, groupCfg.RestoreRetention))
+		admin.POST("/groups/:id/restore",
+			middleware.RequirePermission("system:group:restore", permBiz, db),
+			ginGroup.RestoreGroupHandler(db, hub, groupCfg.RestoreRetention))
+
 		// Thao tác trên người dùng
 		admin.DELETE("/user/:id",
 			middleware.RequirePermission("system:user:delete", permBiz, db),
@@ -42,9 +52,20 @@ func RegisterAdminRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket
 			middleware.RequirePermission("system:user:reset_password", permBiz, db),
 			ginUser.AdminResetPasswordHandler(db))
 
+		// Import hàng loạt người dùng từ CSV/XLSX
+		admin.POST("/users/import",
+			middleware.RequirePermission("system:user:create", permBiz, db),
+			ginUser.BulkImportUsersHandler(db, permBiz))
+		admin.GET("/users/import/:id/report",
+			middleware.RequirePermission("system:user:create", permBiz, db),
+			ginUser.DownloadImportReportHandler(db))
+
 		// Lấy danh sách role cho form chỉnh sửa user (không cần system:role:view)
 		admin.GET("/roles-for-update",
 			middleware.RequirePermission("system:user:update_global", permBiz, db),
 			ginRole.GetRolesForUpdateHandler(db))
+
+		// Chẩn đoán hệ thống: phụ thuộc, phiên WebSocket, producer, lag của consumer, trạng thái index
+		admin.GET("/diagnostics", health.DiagnosticsHandler(diagnostics))
 	}
 }
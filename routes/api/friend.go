@@ -1,18 +1,25 @@
 package api
 
 import (
+	"my-app/modules/chat/transport/websocket"
 	ginFriend "my-app/modules/friend/transport/gin"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func RegisterFriendRoutes(rg *gin.RouterGroup, db *mongo.Database) {
+func RegisterFriendRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket.Hub) {
 	friend := rg.Group("/friend")
 	{
 		friend.POST("/add", ginFriend.CreateFriendHandler(db))
 		friend.GET("/relation", ginFriend.GetRelationHandler(db))
 		friend.POST("/update-status", ginFriend.UpdateFriendStatusHandler(db))
 		friend.GET("/suggestions", ginFriend.SuggestFriendHandler(db))
+		friend.POST("/suggestions/dismiss", ginFriend.DismissSuggestionHandler(db))
+		friend.GET("/list", ginFriend.ListFriendHandler(db))
+		friend.POST("/block", ginFriend.BlockFriendHandler(db, hub))
+		friend.POST("/unblock", ginFriend.UnblockFriendHandler(db, hub))
+		friend.PATCH("/nickname", ginFriend.SetNicknameHandler(db))
+		friend.DELETE("/cancel-request", ginFriend.CancelRequestHandler(db))
 	}
 }
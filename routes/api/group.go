@@ -20,5 +20,31 @@ func GroupRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket.Hub) {
 		group.DELETE("/dissolve", ginGroup.DissolveGroupHandler(db, hub))
 		group.POST("/promote-admin", ginGroup.PromoteToAdminHandler(db, hub))
 		group.POST("/transfer-owner", ginGroup.TransferOwnerHandler(db, hub))
+
+		// Link mời & yêu cầu tham gia nhóm
+		group.POST("/invites", ginGroup.CreateInviteHandler(db))
+		group.GET("/invites", ginGroup.ListInvitesHandler(db))
+		group.DELETE("/invites/:id", ginGroup.RevokeInviteHandler(db))
+		group.GET("/invite/:code", ginGroup.PreviewInviteHandler(db))
+		group.POST("/invite/:code/join", ginGroup.JoinByInviteHandler(db, hub))
+		group.GET("/join-requests", ginGroup.ListJoinRequestsHandler(db))
+		group.POST("/join-requests/:id/review", ginGroup.ReviewJoinRequestHandler(db, hub))
+
+		// Cài đặt nhóm: quyền gửi/ghim/thêm thành viên, slow mode, chế độ thông báo
+		group.GET("/settings", ginGroup.GetGroupSettingsHandler(db))
+		group.PUT("/settings", ginGroup.UpdateGroupSettingsHandler(db, hub))
+
+		// Thông tin nhóm & lưu trữ
+		group.PATCH("/profile", ginGroup.UpdateGroupProfileHandler(db, hub))
+		group.POST("/archive", ginGroup.SetGroupArchivedHandler(db, hub, true))
+		group.POST("/unarchive", ginGroup.SetGroupArchivedHandler(db, hub, false))
+		group.GET("/archived", ginGroup.ListArchivedGroupsHandler(db))
+
+		// Cộng đồng & kênh
+		group.POST("/community", ginGroup.CreateCommunityHandler(db))
+		group.GET("/channels", ginGroup.ListChannelsHandler(db))
+		group.POST("/channels", ginGroup.CreateChannelHandler(db, hub))
+		group.POST("/channels/members", ginGroup.AddChannelMembersHandler(db, hub))
+		group.DELETE("/channels/members", ginGroup.RemoveChannelMembersHandler(db, hub))
 	}
 }
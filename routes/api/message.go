@@ -13,9 +13,12 @@ func MessageRoutes(rg *gin.RouterGroup, db *mongo.Database, esClient *elasticsea
 	{
 		message.GET("/get-message", ginMessage.GetMessages(db))
 		message.GET("/get-message-below", ginMessage.GetMessagesBelow(db))
-		message.GET("/search", ginMessage.SearchMessages(esClient))
+		message.GET("/search", ginMessage.SearchMessages(db, esClient))
 		message.GET("/get-message-by-id", ginMessage.GetMessageId(db))
 		message.GET("/pinned", ginMessage.GetPinnedMessages(db))
 		message.GET("/media-list", ginMessage.GetMediaList(db))
+		message.GET("/mentions", ginMessage.GetMentionInbox(db))
+		message.GET("/mentions/unread-count", ginMessage.CountUnreadMentions(db))
+		message.POST("/mentions/read", ginMessage.MarkMentionsRead(db))
 	}
 }
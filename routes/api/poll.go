@@ -0,0 +1,20 @@
+package api
+
+import (
+	ginMessage "my-app/modules/chat/transport/gin"
+	"my-app/modules/chat/transport/websocket"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func RegisterPollRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket.Hub) {
+	polls := rg.Group("/polls")
+	{
+		polls.POST("", ginMessage.CreatePollHandler(db, hub))
+		polls.GET("/:id", ginMessage.GetPollHandler(db))
+		polls.POST("/:id/vote", ginMessage.VotePollHandler(db, hub))
+		polls.POST("/:id/options", ginMessage.AddPollOptionHandler(db, hub))
+		polls.POST("/:id/close", ginMessage.ClosePollHandler(db, hub))
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"my-app/config"
+	"my-app/middleware"
+	"my-app/modules/permission/biz"
+	ginPrivacy "my-app/modules/privacy/transport/gin"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func RegisterPrivacyRoutes(rg *gin.RouterGroup, db *mongo.Database, esClient *elasticsearch.Client, cfg config.PrivacyConfig, permBiz *biz.PermissionBiz) {
+	privacy := rg.Group("/privacy")
+	{
+		// Xuất dữ liệu cá nhân
+		privacy.POST("/export", ginPrivacy.RequestExportHandler(db, esClient))
+		privacy.GET("/export/:id", ginPrivacy.GetExportHandler(db, esClient, false))
+		privacy.GET("/export/:id/download", ginPrivacy.DownloadExportHandler(db, esClient, false))
+
+		// Xóa vĩnh viễn tài khoản (có thời gian chờ)
+		privacy.POST("/delete-account", ginPrivacy.ScheduleDeletionHandler(db, esClient, cfg.DeletionGrace, false))
+		privacy.GET("/delete-account", ginPrivacy.GetDeletionHandler(db, esClient, false))
+		privacy.DELETE("/delete-account", ginPrivacy.CancelDeletionHandler(db, esClient, false))
+	}
+
+	admin := rg.Group("/admin/privacy")
+	{
+		admin.Use(middleware.RequirePermission("system:admin:access_admin_panel", permBiz, db))
+
+		admin.POST("/users/:id/export",
+			middleware.RequirePermission("system:user:view_details", permBiz, db),
+			ginPrivacy.AdminRequestExportHandler(db, esClient))
+		admin.GET("/export/:id",
+			middleware.RequirePermission("system:user:view_details", permBiz, db),
+			ginPrivacy.GetExportHandler(db, esClient, true))
+		admin.GET("/export/:id/download",
+			middleware.RequirePermission("system:user:view_details", permBiz, db),
+			ginPrivacy.DownloadExportHandler(db, esClient, true))
+
+		admin.POST("/users/:id/delete-account",
+			middleware.RequirePermission("system:user:delete", permBiz, db),
+			ginPrivacy.ScheduleDeletionHandler(db, esClient, cfg.DeletionGrace, true))
+		admin.GET("/users/:id/delete-account",
+			middleware.RequirePermission("system:user:delete", permBiz, db),
+			ginPrivacy.GetDeletionHandler(db, esClient, true))
+		admin.DELETE("/users/:id/delete-account",
+			middleware.RequirePermission("system:user:delete", permBiz, db),
+			ginPrivacy.CancelDeletionHandler(db, esClient, true))
+	}
+}
@@ -1,18 +1,37 @@
 package api
 
 import (
+	"my-app/config"
+	"my-app/middleware"
 	ginMessage "my-app/modules/chat/transport/gin"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func UploadRoutes(rg *gin.RouterGroup, db *mongo.Database) {
+func UploadRoutes(rg *gin.RouterGroup, db *mongo.Database, cfg config.UploadConfig, accessCfg config.MediaAccessConfig) {
 	upload := rg.Group("/upload")
 	{
-		upload.POST("/media", ginMessage.UploadMediaHandler(db))
-		upload.GET("/media/:objectName", ginMessage.GetMediaHandler())
-		upload.GET("/media/stream/:id", ginMessage.StreamMediaHandler(db))
-		upload.DELETE("/media/:mediaID", ginMessage.DeleteMediaHandler(db))
+		// Chỉ giới hạn route ghi, đặt sau AuthMiddleware để đếm theo user (văn phòng chung IP không bị chặn lẫn nhau)
+		uploadLimit := middleware.RateLimit("upload")
+
+		upload.POST("/media", middleware.AuthMiddleware(), uploadLimit, ginMessage.UploadMediaHandler(db))
+		upload.GET("/media/:objectName", ginMessage.GetMediaHandler(db, accessCfg))
+		upload.GET("/media/stream/:id", ginMessage.StreamMediaHandler(db, accessCfg))
+		upload.DELETE("/media/:mediaID", middleware.AuthMiddleware(), uploadLimit, ginMessage.DeleteMediaHandler(db, accessCfg))
+
+		// URL có chữ ký (HMAC, hết hạn ngắn) để thẻ <img>/<video> tải media không cần header Authorization
+		upload.POST("/media/sign", middleware.AuthMiddleware(), middleware.RateLimit("api"), ginMessage.SignMediaHandler(db, accessCfg))
+
+		// Upload trực tiếp lên MinIO: presigned PUT cho file nhỏ, multipart resume được cho file lớn
+		sessions := upload.Group("/sessions", middleware.AuthMiddleware())
+		{
+			sessions.POST("", uploadLimit, ginMessage.CreateUploadSessionHandler(db, cfg))
+			sessions.GET("/:id", middleware.RateLimit("api"), ginMessage.GetUploadSessionHandler(db, cfg))
+			sessions.POST("/:id/parts/presign", uploadLimit, ginMessage.PresignUploadPartsHandler(db, cfg))
+			sessions.PUT("/:id/parts/:number", uploadLimit, ginMessage.UploadPartHandler(db, cfg))
+			sessions.POST("/:id/complete", uploadLimit, ginMessage.CompleteUploadSessionHandler(db, cfg))
+			sessions.DELETE("/:id", uploadLimit, ginMessage.AbortUploadSessionHandler(db, cfg))
+		}
 	}
 }
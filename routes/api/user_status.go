@@ -1,15 +1,17 @@
 package api
 
 import (
+	"my-app/modules/chat/transport/websocket"
 	ginUser "my-app/modules/user/transport/gin"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func RegisterUserStatusRoutes(rg *gin.RouterGroup, db *mongo.Database) {
+func RegisterUserStatusRoutes(rg *gin.RouterGroup, db *mongo.Database, hub *websocket.Hub) {
 	users := rg.Group("/user-status")
 	{
 		users.GET("/status", ginUser.GetUserStatusHandler(db))
+		users.PUT("/presence", ginUser.UpdatePresenceHandler(db, hub))
 	}
 }
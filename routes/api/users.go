@@ -13,32 +13,37 @@ import (
 func RegisterUserRoutes(rg *gin.RouterGroup, db *mongo.Database, permBiz *biz.PermissionBiz) {
 	users := rg.Group("/users")
 	{
-		// public route (ko can auth)
+		// public route (ko can auth): giới hạn theo IP
+		public := users.Group("", middleware.RateLimit("public"))
 
-		users.POST("/login", cleanUser.LoginHandler(db))
-		users.POST("/login-open-dict", ginUser.OpenIddictCallbackHandler(db))
-		users.POST("/google-login", ginUser.GoogleLoginHandler(db))
+		// Chống dò mật khẩu: giới hạn riêng theo IP cho các route đăng nhập
+		public.POST("/login", middleware.RateLimit("auth"), cleanUser.LoginHandler(db))
+		public.POST("/login-open-dict", middleware.RateLimit("auth"), ginUser.OpenIddictCallbackHandler(db))
+		public.POST("/google-login", middleware.RateLimit("auth"), ginUser.GoogleLoginHandler(db))
 
-		users.GET("/profile", middleware.AuthMiddleware(), ginUser.ProfileHandler(db))
+		public.POST("/register-notification", ginUser.RegisterChanelNotificationHandler(db))
+		public.GET("/status", ginUser.GetUserStatusHandler(db))
+
+		// Route cần đăng nhập: giới hạn đặt sau AuthMiddleware để đếm theo user
+		authed := users.Group("", middleware.AuthMiddleware(), middleware.RateLimit("api"))
+
+		authed.GET("/profile", ginUser.ProfileHandler(db))
 		// tao moi ng dung dung api dang ki
-		users.POST("/register", middleware.AuthMiddleware(), middleware.RequirePermission("system:user:create", permBiz, db), ginUser.RegisterHandler(db))
-		users.PATCH("/update-profile", middleware.AuthMiddleware(), ginUser.UpdateProfileHandler(db))
-		users.PATCH("/change-password", middleware.AuthMiddleware(), ginUser.ChangePasswordHandler(db))
+		authed.POST("/register", middleware.RequirePermission("system:user:create", permBiz, db), ginUser.RegisterHandler(db))
+		authed.PATCH("/update-profile", ginUser.UpdateProfileHandler(db))
+		authed.PATCH("/change-password", ginUser.ChangePasswordHandler(db))
 
-		users.POST("/register-notification", ginUser.RegisterChanelNotificationHandler(db))
-		users.POST("/register-oauth", middleware.AuthMiddleware(), ginUser.CompleteProfileHandler(db))
+		authed.POST("/register-oauth", ginUser.CompleteProfileHandler(db))
 
-		users.GET("/status", ginUser.GetUserStatusHandler(db))
-		users.POST("/upsert-setting", middleware.AuthMiddleware(),
+		authed.POST("/upsert-setting",
 			middleware.ApiKeyMiddleware(), ginUser.UpsertSettingHandler(db))
 
-		users.GET("/get-setting", middleware.AuthMiddleware(),
+		authed.GET("/get-setting",
 			middleware.ApiKeyMiddleware(),
 			//  middleware.RequirePermission("system:setting:view", permBiz, db),
 			ginUser.GetSettingHandler(db))
 
-		users.GET("/get-pagination",
-			middleware.AuthMiddleware(),
+		authed.GET("/get-pagination",
 			middleware.RequirePermission("system:user:view_all", permBiz, db),
 			ginUser.ListUsersWithStatusHandler(db),
 		)
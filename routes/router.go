@@ -2,6 +2,7 @@ package routes
 
 import (
 	"my-app/config"
+	"my-app/internal/health"
 	"my-app/middleware"
 	"my-app/modules/chat/transport/websocket"
 	"my-app/modules/permission/biz"
@@ -13,12 +14,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func InitRouter(r *gin.Engine, db *mongo.Database, hub *websocket.Hub, esClient *elasticsearch.Client, cfg config.AppConfig) {
+func InitRouter(r *gin.Engine, db *mongo.Database, hub *websocket.Hub, esClient *elasticsearch.Client, cfg config.AppConfig, diagnostics *health.Diagnostics) {
 
 	permStore := storage.NewMongoStore(db)
 	permBiz := biz.NewPermissionBiz(permStore)
 	v1 := r.Group("/v1")
 
+	// Route user tự chia giới hạn: chưa đăng nhập theo IP (public), đã đăng nhập theo user (api)
 	v1.Use(
 		middleware.LoggerMiddleware(),
 	)
@@ -30,22 +32,25 @@ func InitRouter(r *gin.Engine, db *mongo.Database, hub *websocket.Hub, esClient
 	v1Protected.Use(
 		middleware.LoggerMiddleware(),
 		middleware.AuthMiddleware(),
+		middleware.RateLimit("api"),
 	)
 	{
 
-		api.RegisterAdminRoutes(v1Protected, db, hub, permBiz)
+		api.RegisterAdminRoutes(v1Protected, db, hub, permBiz, cfg.Group, diagnostics)
 		api.RegisterRoleRoutes(v1Protected, db, permBiz)
 		api.RegisterPermissionRoutes(v1Protected, db, permBiz)
 		api.RegisterModuleRoutes(v1Protected, db, permBiz)
 		api.RegisterPermissionMatrixRoutes(v1Protected, db, permBiz)
 
 		api.MessageRoutes(v1Protected, db, esClient)
-		api.RegisterFriendRoutes(v1Protected, db)
+		api.RegisterFriendRoutes(v1Protected, db, hub)
 		api.RegisterConversation(v1Protected, db)
 		api.GroupRoutes(v1Protected, db, hub)
-		api.RegisterUserStatusRoutes(v1Protected, db)
+		api.RegisterUserStatusRoutes(v1Protected, db, hub)
 		api.RegisterTaskRoutes(v1Protected, db)
+		api.RegisterPollRoutes(v1Protected, db, hub)
 		api.RegisterVideoCallRoutes(v1Protected, cfg.LiveKit, hub, db)
+		api.RegisterPrivacyRoutes(v1Protected, db, esClient, cfg.Privacy, permBiz)
 	}
 
 	v1NoMiddleware := r.Group("/v1")
@@ -60,9 +65,10 @@ func InitRouter(r *gin.Engine, db *mongo.Database, hub *websocket.Hub, esClient
 		api.RegisterLoadTestRoutes(v1LoadTest, db)
 	}
 
+	// Chỉ các route ghi (upload, xoá) giới hạn theo policy upload, xem UploadRoutes; tải media và thống kê không giới hạn
 	v1Upload := r.Group("/v1")
 	{
-		api.UploadRoutes(v1Upload, db)
+		api.UploadRoutes(v1Upload, db, cfg.Upload, cfg.MediaAccess)
 		api.RegisterStatisticalRoutes(v1Upload, db)
 	}
 
@@ -1,12 +1,15 @@
 package utils
 
-import "my-app/modules/chat/models"
+import (
+	"my-app/modules/chat/models"
+	"strings"
+)
 
 func DetectMediaType(contentType string) models.MediaType {
 	switch {
-	case contentType == "image/jpeg" || contentType == "image/png":
+	case strings.HasPrefix(contentType, "image/"):
 		return models.TypeImage
-	case contentType == "video/mp4":
+	case strings.HasPrefix(contentType, "video/"):
 		return models.TypeVideo
 	default:
 		return models.TypeFile
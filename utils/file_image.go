@@ -74,6 +74,9 @@ const (
 	maxImageSize = 10 * 1024 * 1024  // 10MB
 	maxVideoSize = 200 * 1024 * 1024 // 200MB
 	maxFileSize  = 100 * 1024 * 1024 // 100MB
+
+	// MaxResumableVideoSize - video upload trực tiếp lên MinIO (chunked / presigned) được phép lớn hơn
+	MaxResumableVideoSize = 2 * 1024 * 1024 * 1024 // 2GB
 )
 
 func ValidateAndSaveFile(fileHeader *multipart.FileHeader) error {
@@ -81,9 +84,8 @@ func ValidateAndSaveFile(fileHeader *multipart.FileHeader) error {
 		return errors.New("File trống")
 	}
 
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if !allowExts[ext] {
-		return fmt.Errorf("Sai định dạng file: %s", ext)
+	if err := ValidateUploadExt(fileHeader.Filename); err != nil {
+		return err
 	}
 
 	// đọc 512 byte đầu để kiểm tra MIME type
@@ -94,31 +96,99 @@ func ValidateAndSaveFile(fileHeader *multipart.FileHeader) error {
 	defer file.Close()
 
 	buffer := make([]byte, 512)
-	_, err = file.Read(buffer)
+	n, err := file.Read(buffer)
 	if err != nil {
 		return errors.New("Không đọc được file")
 	}
 
-	mimeType := http.DetectContentType(buffer)
+	return ValidateSniffedFile(fileHeader.Filename, http.DetectContentType(buffer[:n]), fileHeader.Size, maxVideoSize)
+}
+
+// SniffFileContentType đoán MIME type từ nội dung file (không tin header client gửi lên)
+func SniffFileContentType(fileHeader *multipart.FileHeader) string {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 512)
+	n, _ := file.Read(buffer)
+	return http.DetectContentType(buffer[:n])
+}
+
+// ValidateUploadExt kiểm tra đuôi file nằm trong danh sách cho phép
+func ValidateUploadExt(filename string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !allowExts[ext] {
+		return fmt.Errorf("Sai định dạng file: %s", ext)
+	}
+	return nil
+}
+
+// ValidateSniffedFile kiểm tra MIME type đọc từ nội dung và dung lượng theo loại file.
+// maxVideo cho phép upload trực tiếp lên MinIO dùng giới hạn video lớn hơn.
+func ValidateSniffedFile(filename, mimeType string, size int64, maxVideo int64) error {
 	if !allowMineTypes[mimeType] {
 		return fmt.Errorf("File không phải ảnh hợp lệ, MIME type: %s", mimeType)
 	}
 
+	// Đuôi file ảnh/video phải khớp với nội dung thật
+	ext := strings.ToLower(filepath.Ext(filename))
+	if (isImageExt(ext) && !strings.HasPrefix(mimeType, "image/")) ||
+		(isVideoExt(ext) && !strings.HasPrefix(mimeType, "video/")) {
+		return fmt.Errorf("Nội dung file (%s) không khớp với đuôi %s", mimeType, ext)
+	}
+
 	// Xác định loại file để kiểm tra dung lượng
 	switch {
 	case strings.HasPrefix(mimeType, "image/"):
-		if fileHeader.Size > maxImageSize {
+		if size > maxImageSize {
 			return fmt.Errorf("Ảnh vượt quá dung lượng cho phép (tối đa %.1fMB)", float64(maxImageSize)/(1024*1024))
 		}
 	case strings.HasPrefix(mimeType, "video/"):
-		if fileHeader.Size > maxVideoSize {
-			return fmt.Errorf("Video vượt quá dung lượng cho phép (tối đa %.1fMB)", float64(maxVideoSize)/(1024*1024))
+		if size > maxVideo {
+			return fmt.Errorf("Video vượt quá dung lượng cho phép (tối đa %.1fMB)", float64(maxVideo)/(1024*1024))
 		}
 	default:
-		if fileHeader.Size > maxFileSize {
+		if size > maxFileSize {
 			return fmt.Errorf("File vượt quá dung lượng cho phép (tối đa %.1fMB)", float64(maxFileSize)/(1024*1024))
 		}
 	}
 
 	return nil
 }
+
+// MaxUploadSize - dung lượng lớn nhất được phép upload qua mọi đường (form, upload trực tiếp lên MinIO)
+func MaxUploadSize() int64 {
+	return max(maxImageSize, maxVideoSize, maxFileSize, MaxResumableVideoSize)
+}
+
+// MaxUploadSizeForExt - dung lượng tối đa ước tính theo đuôi file, dùng để từ chối sớm trước khi upload
+func MaxUploadSizeForExt(filename string) int64 {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case isImageExt(ext):
+		return maxImageSize
+	case isVideoExt(ext):
+		return MaxResumableVideoSize
+	default:
+		return maxFileSize
+	}
+}
+
+func isImageExt(ext string) bool {
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp":
+		return true
+	}
+	return false
+}
+
+func isVideoExt(ext string) bool {
+	switch ext {
+	case ".mp4", ".avi", ".mov", ".mkv", ".webm":
+		return true
+	}
+	return false
+}
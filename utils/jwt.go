@@ -32,6 +32,7 @@ func GenerateJWT(userID string, roles []string) (string, error) {
 		Roles:  roles,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()), // thời điểm đăng nhập, dùng cho các thao tác cần xác thực lại
 		},
 	}
 
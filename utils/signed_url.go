@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// URLSigner ký và kiểm tra chữ ký HMAC-SHA256 cho URL tạm thời
+type URLSigner struct {
+	secret []byte
+}
+
+func NewURLSigner(secret string) *URLSigner {
+	return &URLSigner{secret: []byte(secret)}
+}
+
+// Sign ký các thành phần theo đúng thứ tự, trả về chuỗi base64 an toàn cho URL
+func (s *URLSigner) Sign(parts ...string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify so sánh chữ ký trong thời gian hằng số
+func (s *URLSigner) Verify(signature string, parts ...string) bool {
+	return hmac.Equal([]byte(signature), []byte(s.Sign(parts...)))
+}